@@ -0,0 +1,185 @@
+// Package webtest starts local HTTP servers that stand in for the web the
+// site's tooling talks to: the built site itself, upstreams that throttle
+// or fail before recovering, responders too slow to wait for, and redirect
+// chains that go on too long or never end. Each server behaves the same on
+// every run, so the checks that hit the network can be tested end to end
+// without a network, a build or a running hugo server.
+//
+// Every constructor takes the test and closes its server when the test
+// ends. A Server counts the requests it answered by path, so a test can
+// assert how many went out as well as what came back.
+package webtest
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a running test server.
+type Server struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+// Hits returns how many requests the server answered for urlPath.
+func (s *Server) Hits(urlPath string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[urlPath]
+}
+
+func start(t testing.TB, handler http.Handler) *Server {
+	s := &Server{hits: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Site serves pages, keyed by path, the way GitHub Pages serves the built
+// site: a directory path without its trailing slash redirects permanently
+// to the slash, and any other missing path answers 404 with the page at
+// /404.html, if there is one. A /robots.txt allows everything unless pages
+// has its own. Content types follow the extension, HTML for directories.
+func Site(t testing.TB, pages map[string]string) *Server {
+	return start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		body, ok := pages[p]
+		if !ok && p == "/robots.txt" {
+			body, ok = "User-agent: *\nAllow: /\n", true
+		}
+		if !ok {
+			if _, dir := pages[p+"/"]; dir {
+				http.Redirect(w, r, p+"/", http.StatusMovedPermanently)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, pages["/404.html"])
+			return
+		}
+		contentType := "text/html; charset=utf-8"
+		if ext := path.Ext(p); ext != "" && !strings.HasSuffix(p, "/") {
+			contentType = mime.TypeByExtension(ext)
+		}
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, body)
+	}))
+}
+
+// Flaky answers the first failures requests for each path with status,
+// then recovers and serves a page. retryAfter, when set, goes out as the
+// Retry-After header of every failure, as a throttling 429 or 503 sends it.
+func Flaky(t testing.TB, failures, status int, retryAfter string) *Server {
+	var s *Server
+	s = start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Hits(r.URL.Path) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		page(w, "recovered")
+	}))
+	return s
+}
+
+// Slow waits delay before serving each page, or until the client gives up
+// and cancels, whichever comes first.
+func Slow(t testing.TB, delay time.Duration) *Server {
+	return start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			page(w, "finally")
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+// RedirectMaze serves redirect chains:
+//
+//   - /hops/N redirects permanently to /hops/N-1, and /hops/0 is a page,
+//     so /hops/N is a chain of N permanent hops.
+//   - /temporary/N is the same chain with 302s.
+//   - /loop/a and /loop/b redirect permanently to each other, forever.
+//
+// Anything else is a 404.
+func RedirectMaze(t testing.TB) *Server {
+	return start(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		switch kind {
+		case "hops", "temporary":
+			n, err := strconv.Atoi(rest)
+			if err != nil || n < 0 {
+				break
+			}
+			if n == 0 {
+				page(w, "the end of the chain")
+				return
+			}
+			status := http.StatusMovedPermanently
+			if kind == "temporary" {
+				status = http.StatusFound
+			}
+			http.Redirect(w, r, fmt.Sprintf("/%s/%d", kind, n-1), status)
+			return
+		case "loop":
+			switch rest {
+			case "a":
+				http.Redirect(w, r, "/loop/b", http.StatusMovedPermanently)
+				return
+			case "b":
+				http.Redirect(w, r, "/loop/a", http.StatusMovedPermanently)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+}
+
+// Page returns a whole HTML document around body, shaped like one of the
+// site's posts. Checks that judge a page by its size or its title, like
+// linkcheck's soft-404 heuristics, take it for a real page; a bare
+// fragment reads as a placeholder.
+func Page(title, body string) string {
+	return fmt.Sprintf(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<link rel="stylesheet" href="/css/main.css">
+</head>
+<body>
+<header><nav><a href="/">Home</a> <a href="/archive/">Archive</a> <a href="/about/">About</a></nav></header>
+<main data-pagefind-body>
+<h1>%s</h1>
+%s
+</main>
+<footer><p>Served by webtest.</p></footer>
+</body>
+</html>
+`, title, title, body)
+}
+
+func page(w http.ResponseWriter, title string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, Page(title, "<p>"+title+".</p>"))
+}
@@ -0,0 +1,59 @@
+package webtest
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestSite(t *testing.T) {
+	site := Site(t, map[string]string{
+		"/go/retry/": "<h1>Retry</h1>",
+		"/feed.xml":  "<rss/>",
+		"/404.html":  "<h1>Not found</h1>",
+	})
+	client := site.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	for _, tc := range []struct {
+		path, status, contentType, body string
+	}{
+		{"/go/retry/", "200 OK", "text/html; charset=utf-8", "<h1>Retry</h1>"},
+		{"/go/retry", "301 Moved Permanently", "", ""},
+		{"/feed.xml", "200 OK", "text/xml; charset=utf-8", "<rss/>"},
+		{"/robots.txt", "200 OK", "text/plain; charset=utf-8", "User-agent: *\nAllow: /\n"},
+		{"/go/gone/", "404 Not Found", "text/html; charset=utf-8", "<h1>Not found</h1>"},
+	} {
+		resp, err := client.Get(site.URL + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.Status != tc.status || (tc.contentType != "" && resp.Header.Get("Content-Type") != tc.contentType) || (tc.body != "" && string(body) != tc.body) {
+			t.Errorf("%s: %s %q %q, want %s %q %q", tc.path, resp.Status, resp.Header.Get("Content-Type"), body, tc.status, tc.contentType, tc.body)
+		}
+	}
+	if site.Hits("/go/retry") != 1 {
+		t.Errorf("hits = %d, want 1", site.Hits("/go/retry"))
+	}
+}
+
+func TestFlakyRecoversPerPath(t *testing.T) {
+	s := Flaky(t, 2, http.StatusServiceUnavailable, "1")
+	var got []int
+	for _, p := range []string{"/a", "/a", "/b", "/a"} {
+		resp, err := http.Get(s.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		got = append(got, resp.StatusCode)
+		if resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "1" {
+			t.Errorf("Retry-After = %q", resp.Header.Get("Retry-After"))
+		}
+	}
+	if want := []int{503, 503, 503, 200}; !slices.Equal(got, want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+}
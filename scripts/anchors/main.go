@@ -0,0 +1,198 @@
+// Command anchors warns when a post loses a heading anchor it used to have.
+//
+// Other sites, and the blog's own posts, deep-link to headings as
+// /go/foo/#some-heading. Rewording the heading changes its generated id and
+// the link quietly lands at the top of the page instead. After each build,
+// anchors reads the heading ids of every post in public/, compares them with
+// the ones the last run recorded in its history file, and warns about each
+// id that vanished from a post that still exists, naming the posts in
+// content/ that link to it. Pin the old id with {#old-id} on the heading to
+// keep such links working.
+//
+// The history is rewritten after every run, so each loss is reported once.
+// Warnings don't fail the build; -strict makes them.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	contentDir     = "content"
+	publicDir      = "public"
+	defaultHistory = ".cache/anchors.json"
+)
+
+// headingIDPattern matches a heading element's id, quoted or, after
+// minification, not.
+var headingIDPattern = regexp.MustCompile(`(?i)<h[1-6]\b[^>]*?\sid=["']?([^"'\s>]+)`)
+
+// history maps each post's URL to its heading ids, sorted.
+type history map[string][]string
+
+func main() {
+	historyPath := flag.String("history", defaultHistory, "heading ids recorded by the last run")
+	strict := flag.Bool("strict", false, "exit non-zero when an anchor disappeared")
+	flag.Parse()
+
+	if _, err := os.Stat(filepath.Join(publicDir, "index.html")); err != nil {
+		fatal(fmt.Errorf("%s/ has no build; run hugo first", publicDir))
+	}
+	current, err := collectAnchors(publicDir)
+	if err != nil {
+		fatal(err)
+	}
+	previous, err := loadHistory(*historyPath)
+	if err != nil {
+		fatal(err)
+	}
+	lost := vanished(previous, current)
+	var linkers map[string][]string
+	if len(lost) > 0 {
+		if linkers, err = linkedFrom(contentDir, lost); err != nil {
+			fatal(err)
+		}
+	}
+	for _, target := range lost {
+		line := "warning: " + target + " no longer exists"
+		if files := linkers[target]; len(files) > 0 {
+			line += "; linked from " + strings.Join(files, ", ")
+		}
+		fmt.Println(line)
+	}
+	if err := current.save(*historyPath); err != nil {
+		fatal(err)
+	}
+	if *strict && len(lost) > 0 {
+		fatal(fmt.Errorf("%d heading anchor%s disappeared", len(lost), plural(len(lost))))
+	}
+}
+
+// collectAnchors reads the heading ids of every post in public: pages with
+// a data-pagefind-body element, which leaves out listings and redirects.
+func collectAnchors(public string) (history, error) {
+	anchors := history{}
+	err := filepath.WalkDir(public, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Base(filePath) != "index.html" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(raw, []byte("data-pagefind-body")) {
+			return nil
+		}
+		rel, err := filepath.Rel(public, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		var ids []string
+		for _, m := range headingIDPattern.FindAllSubmatch(raw, -1) {
+			ids = append(ids, string(m[1]))
+		}
+		slices.Sort(ids)
+		anchors[pageURL(filepath.ToSlash(rel))] = slices.Compact(ids)
+		return nil
+	})
+	return anchors, err
+}
+
+func pageURL(rel string) string {
+	if rel == "." {
+		return "/"
+	}
+	return "/" + rel + "/"
+}
+
+// vanished returns url#id for every id previous had on a page that current
+// still has, but without it. Pages that are gone entirely are left to the
+// alias and link checks.
+func vanished(previous, current history) []string {
+	var lost []string
+	for url, ids := range previous {
+		now, ok := current[url]
+		if !ok {
+			continue
+		}
+		for _, id := range ids {
+			if _, found := slices.BinarySearch(now, id); !found {
+				lost = append(lost, url+"#"+id)
+			}
+		}
+	}
+	slices.Sort(lost)
+	return lost
+}
+
+// linkedFrom maps each target to the Markdown files under content that
+// link to it.
+func linkedFrom(content string, targets []string) (map[string][]string, error) {
+	linkers := map[string][]string{}
+	err := filepath.WalkDir(content, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(filePath) != ".md" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if bytes.Contains(raw, []byte(target)) {
+				linkers[target] = append(linkers[target], filepath.ToSlash(filePath))
+			}
+		}
+		return nil
+	})
+	return linkers, err
+}
+
+// loadHistory reads the history file, treating a missing file as empty.
+func loadHistory(filePath string) (history, error) {
+	h := history{}
+	raw, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filePath, err)
+	}
+	return h, nil
+}
+
+func (h history) save(filePath string) error {
+	raw, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, append(raw, '\n'), 0o644)
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "anchors:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestVanishedAnchors(t *testing.T) {
+	site := t.TempDir()
+	mustWrite(t, filepath.Join(site, "index.html"), `<h2 id="latest">Latest</h2>`)
+	mustWrite(t, filepath.Join(site, "go", "retry", "index.html"),
+		`<div data-pagefind-body><h2 id=backoff>Backoff</h2><h3 class=x id="jitter-2">Jitter</h3><h2 id=backoff>Again</h2></div>`)
+	mustWrite(t, filepath.Join(site, "go", "old-name", "index.html"), `<meta http-equiv="refresh" content="0; url=/go/retry/">`)
+
+	current, err := collectAnchors(site)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) != 1 || !slices.Equal(current["/go/retry/"], []string{"backoff", "jitter-2"}) {
+		t.Fatalf("anchors = %q", current)
+	}
+
+	previous := history{
+		"/go/retry/":   {"backoff", "jitter", "why-retry"},
+		"/go/deleted/": {"gone"},
+	}
+	got := vanished(previous, current)
+	want := []string{"/go/retry/#jitter", "/go/retry/#why-retry"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("vanished = %q, want %q", got, want)
+	}
+
+	content := t.TempDir()
+	mustWrite(t, filepath.Join(content, "go", "timeouts.md"), "See [jitter](/go/retry/#jitter).")
+	mustWrite(t, filepath.Join(content, "go", "retry.md"), "# Retry")
+	linkers, err := linkedFrom(content, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := linkers["/go/retry/#jitter"]; len(files) != 1 || filepath.Base(files[0]) != "timeouts.md" {
+		t.Fatalf("linkers = %q", linkers)
+	}
+}
+
+func TestHistoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".cache", "anchors.json")
+	h, err := loadHistory(path)
+	if err != nil || len(h) != 0 {
+		t.Fatalf("missing history = %q, %v; want empty", h, err)
+	}
+	want := history{"/go/retry/": {"backoff"}}
+	if err := want.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = loadHistory(path); err != nil || !slices.Equal(h["/go/retry/"], want["/go/retry/"]) {
+		t.Fatalf("history = %q, %v; want %q", h, err, want)
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
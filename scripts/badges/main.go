@@ -0,0 +1,219 @@
+// Command badges writes small SVG status badges into static/badges/ for the
+// README and the about page: how many posts are published, how many words
+// they add up to, and when the last one went out. They are drawn locally, so
+// no external badge service sees the site's visitors.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentDir = "content"
+	badgeDir   = "static/badges"
+)
+
+// Badge colors: grey for the facts, darker for the labels.
+const (
+	colorNeutral = "#5b6770"
+	colorLabel   = "#444b52"
+)
+
+type siteConfig struct {
+	Params struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+	} `yaml:"params"`
+}
+
+// stats summarizes the published posts.
+type stats struct {
+	Posts      int
+	Words      int
+	LastPosted time.Time
+}
+
+type badge struct {
+	Name, Label, Value, Color string
+}
+
+func main() {
+	sections, err := loadSections("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	s, err := collectStats(contentDir, sections)
+	if err != nil {
+		fatal(err)
+	}
+
+	if err := os.MkdirAll(badgeDir, 0o755); err != nil {
+		fatal(err)
+	}
+	for _, b := range badges(s) {
+		path := filepath.Join(badgeDir, b.Name+".svg")
+		if err := os.WriteFile(path, render(b), 0o644); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("%s: %s %s\n", path, b.Label, b.Value)
+	}
+}
+
+func badges(s stats) []badge {
+	last := "never"
+	if !s.LastPosted.IsZero() {
+		last = s.LastPosted.Format("2006-01-02")
+	}
+	return []badge{
+		{"posts", "posts", strconv.Itoa(s.Posts), colorNeutral},
+		{"words", "words", humanCount(s.Words), colorNeutral},
+		{"last-published", "last published", last, colorNeutral},
+	}
+}
+
+var (
+	fencePattern = regexp.MustCompile("(?ms)^\\s*(```|~~~).*?^\\s*(```|~~~)\\s*$")
+	tagPattern   = regexp.MustCompile(`<[^>]*>|\{\{[<%].*?[%>]\}\}`)
+)
+
+// collectStats counts the posts under sections, their prose words (code
+// blocks, HTML and shortcodes excluded), and the latest publish date that
+// isn't in the future.
+func collectStats(root string, sections []string) (stats, error) {
+	var s stats
+	now := time.Now()
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		section, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if !slices.Contains(sections, section) {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, body, _ := splitFrontmatter(string(raw))
+		var fm struct {
+			Date string `yaml:"date"`
+		}
+		if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		date, err := parseDate(fm.Date)
+		if err != nil {
+			return fmt.Errorf("%s: date %q: %w", filePath, fm.Date, err)
+		}
+		if date.After(now) {
+			return nil
+		}
+
+		s.Posts++
+		s.Words += len(strings.Fields(tagPattern.ReplaceAllString(fencePattern.ReplaceAllString(body, ""), "")))
+		if date.After(s.LastPosted) {
+			s.LastPosted = date
+		}
+		return nil
+	})
+	return s, err
+}
+
+// render draws a flat two-part badge. Text width is estimated from the
+// character count, which is close enough for the badge font at 11px.
+func render(b badge) []byte {
+	labelWidth := textWidth(b.Label) + 12
+	valueWidth := textWidth(b.Value) + 12
+	width := labelWidth + valueWidth
+	label, value := html.EscapeString(b.Label), html.EscapeString(b.Value)
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+<title>%s: %s</title>
+<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="%d" height="20" fill="%s"/>
+<rect x="%d" width="%d" height="20" fill="%s"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%d" y="14">%s</text>
+<text x="%d" y="14">%s</text>
+</g>
+</svg>
+`, width, label, value, label, value, width, labelWidth, colorLabel, labelWidth, valueWidth, b.Color,
+		labelWidth/2, label, labelWidth+valueWidth/2, value)
+	return out.Bytes()
+}
+
+func textWidth(s string) int {
+	return len([]rune(s)) * 7
+}
+
+// humanCount shortens large counts: 1234 stays, 123456 becomes 123k.
+func humanCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", n/1000)
+	}
+	return strconv.Itoa(n)
+}
+
+func loadSections(configPath string) ([]string, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	sections := slices.Clone(config.Params.MainSections)
+	if config.Params.NotesSection != "" {
+		sections = append(sections, config.Params.NotesSection)
+	}
+	return sections, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a YYYY-MM-DD or RFC 3339 date")
+}
+
+func splitFrontmatter(raw string) (frontmatter, body string, ok bool) {
+	const start = "---\n"
+	if !strings.HasPrefix(raw, start) {
+		return "", raw, false
+	}
+	idx := strings.Index(raw[len(start):], "\n---\n")
+	if idx == -1 {
+		return "", raw, false
+	}
+	fmEnd := len(start) + idx
+	return raw[len(start):fmEnd], raw[fmEnd+len("\n---\n"):], true
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "badges:", err)
+	os.Exit(1)
+}
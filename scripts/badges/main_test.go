@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectStats(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "a.md"), "---\ndate: 2024-03-01\n---\nOne two three.\n\n```go\nfunc ignored() {}\n```\n\n{{< mermaid >}}\n")
+	mustWrite(t, filepath.Join(root, "go", "b.md"), "---\ndate: 2025-06-10\n---\nFour <em>five</em>.\n")
+	mustWrite(t, filepath.Join(root, "go", "_index.md"), "---\ntitle: Go\n---\nNot a post.\n")
+	mustWrite(t, filepath.Join(root, "go", "future.md"), "---\ndate: 2999-01-01\n---\nScheduled.\n")
+	mustWrite(t, filepath.Join(root, "about.md"), "---\ndate: 2026-01-01\n---\nNot in a section.\n")
+
+	got, err := collectStats(root, []string{"go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := stats{Posts: 2, Words: 5, LastPosted: time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)}
+	if got != want {
+		t.Fatalf("collectStats = %+v, want %+v", got, want)
+	}
+}
+
+func TestRenderEscapesAndSizes(t *testing.T) {
+	svg := string(render(badge{Name: "x", Label: "a<b", Value: "1 & 2", Color: colorNeutral}))
+	for _, want := range []string{`aria-label="a&lt;b: 1 &amp; 2"`, `fill="` + colorNeutral + `"`, `width="`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg missing %s:\n%s", want, svg)
+		}
+	}
+}
+
+func TestHumanCount(t *testing.T) {
+	for n, want := range map[int]string{999: "999", 9_999: "9999", 123_456: "123k", 2_500_000: "2.5M"} {
+		if got := humanCount(n); got != want {
+			t.Errorf("humanCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// hookNames are the git hooks install-hooks writes.
+var hookNames = []string{"pre-commit", "pre-push"}
+
+// hookMarker stamps a hook blogctl wrote with the version of its template.
+const hookMarker = "# blogctl-hooks-version: "
+
+// hookTemplate is every hook: a wrapper that hands over to `blogctl hook`,
+// so the checks themselves always come from the checked-out blogctl and
+// only the wrapper can go stale.
+const hookTemplate = `#!/bin/sh
+# Written by blogctl install-hooks; rerun it rather than editing this file.
+` + hookMarker + `%s
+exec go run ./scripts/blogctl hook %s "$@"
+`
+
+// zeroSHA is what git passes a pre-push hook for a ref that doesn't exist
+// on one side.
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// hooksVersion changes whenever the template or the set of hooks does.
+func hooksVersion() string {
+	sum := sha256.Sum256([]byte(hookTemplate + strings.Join(hookNames, ",")))
+	return hex.EncodeToString(sum[:6])
+}
+
+func hookScript(name string) string {
+	return fmt.Sprintf(hookTemplate, hooksVersion(), name)
+}
+
+// installHooks writes every hook into the repository's hooks directory,
+// wherever core.hooksPath puts it. A hook blogctl didn't write is left
+// alone and reported.
+func (a *app) installHooks() error {
+	dir, err := a.git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	for _, name := range hookNames {
+		path := filepath.Join(dir, name)
+		existing, err := os.ReadFile(path)
+		if err == nil && !strings.Contains(string(existing), hookMarker) {
+			return fmt.Errorf("%s is a hook blogctl didn't write; move it aside and rerun install-hooks", path)
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		fmt.Fprintf(a.out, "==> %s hook %s\n", name, path)
+		if a.dryRun {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(hookScript(name)), 0o755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshHooks reinstalls the hooks when one carries an older version
+// stamp, so a change to the wrapper reaches every clone on its next commit.
+func (a *app) refreshHooks() error {
+	dir, err := a.git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	for _, name := range hookNames {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		_, rest, ok := strings.Cut(string(raw), hookMarker)
+		version, _, _ := strings.Cut(rest, "\n")
+		if ok && version != hooksVersion() {
+			fmt.Fprintf(a.out, "==> git hooks are at %s, blogctl at %s; reinstalling\n", version, hooksVersion())
+			return a.installHooks()
+		}
+	}
+	return nil
+}
+
+// runHook runs the checks behind one hook.
+func (a *app) runHook(ctx context.Context, name string) error {
+	if err := a.refreshHooks(); err != nil {
+		return err
+	}
+	var steps []step
+	var err error
+	switch name {
+	case "pre-commit":
+		steps, err = a.preCommitSteps()
+	case "pre-push":
+		steps, err = a.prePushSteps(a.stdin)
+	default:
+		return fmt.Errorf("unknown hook %q; want %s", name, strings.Join(hookNames, " or "))
+	}
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Fprintf(a.out, "==> %s: nothing to check\n", name)
+		return nil
+	}
+	return a.pipeline(ctx, steps)
+}
+
+// preCommitSteps are the lints that take seconds, picked by what is
+// staged: the content lints for Markdown under content/, gofmt and vet for
+// Go. They check the working tree, which is what the commit holds unless
+// it was staged in part.
+func (a *app) preCommitSteps() ([]step, error) {
+	out, err := a.git("diff", "--cached", "--name-only", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	staged := strings.Fields(out)
+	var steps []step
+	if slices.ContainsFunc(staged, func(f string) bool { return strings.HasPrefix(f, "content/") && filepath.Ext(f) == ".md" }) {
+		steps = append(steps, []step{
+			{name: "code blocks", args: goRun("lintcodeblocks", "--check")},
+			{name: "encoding", args: goRun("encoding", "--check")},
+			{name: "frontmatter", args: goRun("frontmatter", "--check")},
+			{name: "media URLs", args: goRun("media", "--check")},
+			{name: "self links", args: goRun("selflinks", "--check")},
+		}...)
+	}
+	if slices.ContainsFunc(staged, func(f string) bool { return filepath.Ext(f) == ".go" }) {
+		steps = append(steps, []step{
+			{name: "gofmt", fn: a.gofmt},
+			{name: "go vet", args: []string{"go", "vet", "./..."}},
+		}...)
+	}
+	return steps, nil
+}
+
+// prePushSteps check the external links the push adds, and only those:
+// linkcheck -compare reports what the pushed commit has that the remote
+// doesn't. git describes the push on stdin, one ref per line; a new
+// branch is compared with its merge base on origin's default branch.
+func (a *app) prePushSteps(stdin io.Reader) ([]step, error) {
+	var steps []step
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] == zeroSHA {
+			continue
+		}
+		local, base := fields[1], fields[3]
+		if base == zeroSHA {
+			mergeBase, err := a.git("merge-base", local, "origin/HEAD")
+			if err != nil {
+				fmt.Fprintf(a.out, "==> %s: no merge base with origin/HEAD, skipping the link check\n", fields[0])
+				continue
+			}
+			base = mergeBase
+		}
+		changed, err := a.git("diff", "--name-only", base, local, "--", "content")
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(changed) == "" {
+			continue
+		}
+		steps = append(steps, step{
+			name: "new external links in " + fields[0],
+			args: goRun("linkcheck", "-compare", base, "-head", local),
+		})
+	}
+	return steps, scanner.Err()
+}
+
+func gitOutput(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
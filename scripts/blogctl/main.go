@@ -0,0 +1,445 @@
+// Command blogctl builds, checks, deploys and rolls back the site.
+//
+//	blogctl build     regenerate metadata, render with Hugo, index with Pagefind
+//	blogctl check     run every lint and the Go tests against public/
+//	blogctl deploy    build, check, and ship public/ only if both pass
+//	blogctl rollback  redeploy the release that was live before the current one
+//	blogctl install-hooks
+//	                  make git run the quick lints before each commit and
+//	                  check the new external links before each push
+//	blogctl update-check
+//	                  build HEAD with the installed Hugo and with
+//	                  -hugo-candidate, and say whether upgrading loses pages
+//
+// The other subcommands run the scripts/ tools the Makefile targets of the
+// same names stand for, like `blogctl linkcheck -uptime` or `blogctl csp`,
+// passing any arguments on to the tool; `blogctl -h` lists them.
+//
+// Every deploy is archived under .cache/releases/ before it ships, so a
+// rollback redeploys exactly the bytes that were live before, not a rebuild
+// of an older commit. The deploy itself is whatever -deploy-cmd says (it
+// runs under sh with $DEPLOY_DIR set to the directory to upload); CI still
+// ships through GitHub Pages and doesn't need it.
+//
+// The hooks hand over to `blogctl hook pre-commit|pre-push`, which runs the
+// checks. The wrapper scripts carry a version stamp; when blogctl's
+// template changes, the next hook run rewrites them.
+//
+// Every run ends with how long each step took and, for steps that print
+// a "cost: N requests" line the way linkcheck does, how many HTTP requests
+// they made. -time-budgets (or
+// $BLOGCTL_TIME_BUDGETS) gives steps a time budget each, say
+// "new external links=30s" to keep the pre-push hook quick; a step over
+// its budget is warned about, not failed.
+//
+// With -dry-run, every subcommand prints the steps it would take, the
+// command behind each, and what a deploy or rollback would upload, and
+// runs none of them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPagefind = "npx -y pagefind@1.5.2"
+	defaultPrettier = "npx -y prettier@3.9.5"
+	defaultReleases = ".cache/releases"
+	defaultKeep     = 10
+
+	// defaultBudget matches PAGES_SIZE_BUDGET_BYTES in ci.yml, which keeps
+	// public/ under GitHub Pages' 1 GB artifact limit with room to spare.
+	defaultBudget = 900_000_000
+)
+
+// unusedPagefindAssets are the Pagefind UI bundles the site doesn't load;
+// search.html talks to pagefind.js directly.
+var unusedPagefindAssets = []string{
+	"pagefind-component-ui.css",
+	"pagefind-component-ui.js",
+	"pagefind-highlight.js",
+	"pagefind-modular-ui.css",
+	"pagefind-modular-ui.js",
+	"wasm.unknown.pagefind",
+}
+
+// runner runs an external command, with env added to blogctl's own and
+// its output written to stdout.
+type runner func(ctx context.Context, env []string, stdout io.Writer, args ...string) error
+
+// step is one unit of a pipeline: an external command, run with env added
+// to blogctl's own, or an in-process check.
+type step struct {
+	name string
+	env  []string
+	args []string
+	fn   func() error
+}
+
+// app is the state every subcommand shares.
+type app struct {
+	run      runner
+	out      io.Writer
+	public   string
+	pagefind string
+	prettier string
+	wrangler string
+	budget   int64
+	noTests  bool
+	gofmt    func() error
+	deploy   string
+	releases *releaseStore
+	revision func() string
+	dryRun   bool
+	git      func(args ...string) (string, error)
+	stdin    io.Reader
+	now      func() time.Time
+	// candidate is the command running the Hugo update-check compares the
+	// installed one against.
+	candidate string
+	// timeBudgets are the per-step time budgets the timing breakdown
+	// warns about.
+	timeBudgets timeBudgets
+}
+
+func main() {
+	pagefind := flag.String("pagefind", defaultPagefind, "pagefind command")
+	prettier := flag.String("prettier", defaultPrettier, "prettier command")
+	wrangler := flag.String("wrangler", defaultWrangler, "img-upload: wrangler command")
+	budget := flag.Int64("budget", defaultBudget, "largest public/ size, in bytes, that build accepts")
+	noTests := flag.Bool("no-tests", false, "check: run the lints but skip go test")
+	deployCmd := flag.String("deploy-cmd", os.Getenv("BLOGCTL_DEPLOY_CMD"), "shell command that uploads $DEPLOY_DIR (default $BLOGCTL_DEPLOY_CMD)")
+	releases := flag.String("releases", defaultReleases, "directory holding archived releases")
+	keep := flag.Int("keep", defaultKeep, "number of releases to keep for rollback")
+	dryRun := flag.Bool("dry-run", false, "print the steps and commands the subcommand would run without running them")
+	candidate := flag.String("hugo-candidate", os.Getenv(candidateEnv), `update-check: command that runs the Hugo to upgrade to, like "go run -tags extended github.com/gohugoio/hugo@v0.165.0" (default $`+candidateEnv+`)`)
+	budgetsFlag := flag.String("time-budgets", os.Getenv("BLOGCTL_TIME_BUDGETS"), `per-step time budgets to warn about, like "go test=2m,new external links=30s"; a name covers every step it begins (default $BLOGCTL_TIME_BUDGETS)`)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: blogctl [flags] build|check|deploy|rollback|install-hooks|update-check")
+		fmt.Fprintln(flag.CommandLine.Output(), "       blogctl [flags] "+strings.ReplaceAll(toolNames(), ", ", "|")+" [args]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	budgets, err := parseTimeBudgets(*budgetsFlag)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	a := &app{
+		run:      execRunner,
+		out:      os.Stdout,
+		public:   "public",
+		pagefind: *pagefind,
+		prettier: *prettier,
+		wrangler: *wrangler,
+		budget:   *budget,
+		noTests:  *noTests,
+		gofmt:    checkGofmt,
+		deploy:   *deployCmd,
+		releases: &releaseStore{dir: *releases, keep: *keep, now: time.Now},
+		revision: gitRevision,
+		dryRun:   *dryRun,
+		git:      gitOutput,
+		stdin:    os.Stdin,
+		now:      time.Now,
+
+		candidate:   *candidate,
+		timeBudgets: budgets,
+	}
+	if err := a.command(ctx, flag.Arg(0), flag.Args()[1:]...); err != nil {
+		fatal(err)
+	}
+}
+
+func (a *app) command(ctx context.Context, name string, args ...string) error {
+	if tc, ok := toolCommands[name]; ok {
+		if !tc.takesArgs && len(args) > 0 {
+			return fmt.Errorf("%s takes no arguments, got %q", name, args)
+		}
+		steps := tc.steps(a, args)
+		if len(steps) == 1 && !a.dryRun {
+			// A lone tool's output is often the point, like urls' CSV,
+			// so no headings or timings are mixed into it.
+			return a.run(ctx, steps[0].env, a.out, steps[0].args...)
+		}
+		return a.pipeline(ctx, steps)
+	}
+	if name != "hook" && len(args) > 0 {
+		return fmt.Errorf("%s takes no arguments, got %q", name, args)
+	}
+	switch name {
+	case "build":
+		return a.pipeline(ctx, a.buildSteps())
+	case "check":
+		return a.pipeline(ctx, a.checkSteps())
+	case "deploy":
+		return a.deployRelease(ctx)
+	case "rollback":
+		return a.rollback(ctx)
+	case "install-hooks":
+		return a.installHooks()
+	case "update-check":
+		return a.updateCheck(ctx)
+	case "hook":
+		// git passes hooks arguments of their own, like the remote's
+		// name and URL for pre-push; the checks don't need them.
+		if len(args) == 0 {
+			return fmt.Errorf("hook takes the hook's name; want %s", strings.Join(hookNames, " or "))
+		}
+		return a.runHook(ctx, args[0])
+	default:
+		return fmt.Errorf("unknown command %q; want build, check, deploy, rollback, install-hooks, update-check or a tool: %s", name, toolNames())
+	}
+}
+
+func (a *app) buildSteps() []step {
+	return []step{
+		{name: "hugo version", args: goRun("hugoversion")},
+		{name: "frontmatter", args: goRun("frontmatter")},
+		{name: "reading times", args: goRun("readingtime")},
+		{name: "hugo", args: []string{"hugo", "--environment", "production", "--minify", "--gc", "--cleanDestinationDir"}},
+		{name: "sitemap index", args: goRun("curation", "sitemaps")},
+		{name: "heading anchors", args: goRun("anchors")},
+		{name: "pagefind", args: strings.Fields(a.pagefind)},
+		{name: "prune pagefind assets", fn: func() error { return prunePagefind(a.public) }},
+		{name: "search index", args: goRun("searchindex")},
+		{name: "size budget", fn: func() error { return checkBudget(a.public, a.budget) }},
+	}
+}
+
+// checkSteps are the lints and, unless noTests is set, the Go tests, which
+// drive a browser against public/ and so need a build first.
+func (a *app) checkSteps() []step {
+	var steps []step
+	if !a.noTests {
+		steps = append(steps, step{name: "build output", fn: func() error { return requireBuild(a.public) }})
+	}
+	steps = append(steps, []step{
+		{name: "go vet", args: []string{"go", "vet", "./..."}},
+		{name: "gofmt", fn: a.gofmt},
+		{name: "code blocks", args: goRun("lintcodeblocks", "--check")},
+		{name: "encoding", args: goRun("encoding", "--check")},
+		{name: "frontmatter", args: goRun("frontmatter", "--check")},
+		{name: "reading times", args: goRun("readingtime", "--check")},
+		{name: "rendered word counts", args: goRun("readingtime", "--drift")},
+		{name: "media URLs", args: goRun("media", "--check")},
+		{name: "self links", args: goRun("selflinks", "--check")},
+		{name: "curation", args: goRun("curation")},
+		{name: "layout refs", args: goRun("layoutrefs")},
+		{name: "shortcodes", args: goRun("shortcodes")},
+		{name: "prettier", args: append(strings.Fields(a.prettier), "--check", ".")},
+	}...)
+	if !a.noTests {
+		steps = append(steps, step{name: "go test", args: []string{"go", "test", "-count=1", "./..."}})
+	}
+	return steps
+}
+
+// deployRelease ships a fresh build, and only once it has passed every
+// check: a red pipeline leaves the live site and the release history alone.
+func (a *app) deployRelease(ctx context.Context) error {
+	if a.deploy == "" {
+		return errors.New("no deploy command; pass -deploy-cmd or set BLOGCTL_DEPLOY_CMD")
+	}
+	if err := a.pipeline(ctx, append(a.buildSteps(), a.checkSteps()...)); err != nil {
+		return fmt.Errorf("not deploying: %w", err)
+	}
+	if a.dryRun {
+		fmt.Fprintf(a.out, "==> deploy\n    archive %s/ as a release of %s under %s\n    DEPLOY_DIR=%s sh -c %q\n", a.public, a.revision(), a.releases.dir, a.public, a.deploy)
+		return nil
+	}
+	rel, err := a.releases.archive(a.public, a.revision())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "==> deploy %s\n", rel)
+	if err := a.ship(ctx, a.public); err != nil {
+		return err
+	}
+	return a.releases.markLive(rel)
+}
+
+// rollback redeploys the archived release that was live before the current
+// one. Rolling back again steps further back through what was live, so a
+// release rolled back from is never returned to.
+func (a *app) rollback(ctx context.Context) error {
+	if a.deploy == "" {
+		return errors.New("no deploy command; pass -deploy-cmd or set BLOGCTL_DEPLOY_CMD")
+	}
+	rel, err := a.releases.previous()
+	if err != nil {
+		return err
+	}
+	if a.dryRun {
+		fmt.Fprintf(a.out, "==> roll back to %s\n    extract %s\n    DEPLOY_DIR=<extracted release> sh -c %q\n", rel, a.releases.path(rel), a.deploy)
+		return nil
+	}
+	dir, err := os.MkdirTemp("", "blogctl-rollback-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := a.releases.extract(rel, dir); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "==> roll back to %s\n", rel)
+	if err := a.ship(ctx, dir); err != nil {
+		return err
+	}
+	return a.releases.markRolledBack(rel)
+}
+
+func (a *app) ship(ctx context.Context, dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := a.run(ctx, []string{"DEPLOY_DIR=" + abs}, a.out, "sh", "-c", a.deploy); err != nil {
+		return fmt.Errorf("deploy: %w", err)
+	}
+	return nil
+}
+
+// pipeline runs steps in order and stops at the first failure. Either
+// way, it ends with how long each step that ran took.
+func (a *app) pipeline(ctx context.Context, steps []step) error {
+	var timings []stepTiming
+	defer func() {
+		if len(timings) > 0 {
+			writeTimings(a.out, timings)
+		}
+	}()
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "==> %s\n", s.name)
+		if a.dryRun {
+			if s.fn != nil {
+				fmt.Fprintln(a.out, "    (runs in blogctl)")
+			} else {
+				fmt.Fprintf(a.out, "    %s\n", strings.Join(slices.Concat(s.env, s.args), " "))
+			}
+			continue
+		}
+		start := a.now()
+		requests := int64(-1)
+		var err error
+		if s.fn != nil {
+			err = s.fn()
+		} else {
+			requests, err = a.runCounted(ctx, s.env, s.args)
+		}
+		timings = append(timings, stepTiming{name: s.name, elapsed: a.now().Sub(start), requests: requests, budget: a.timeBudgets.of(s.name)})
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func goRun(tool string, args ...string) []string {
+	return append([]string{"go", "run", "./scripts/" + tool}, args...)
+}
+
+func execRunner(ctx context.Context, env []string, stdout io.Writer, args ...string) error {
+	if len(args) == 0 {
+		return errors.New("empty command")
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
+
+// checkGofmt fails when gofmt would rewrite any Go file in scripts/ or
+// tests/. gofmt -l exits zero either way, so its output is the verdict.
+func checkGofmt() error {
+	out, err := exec.Command("gofmt", "-l", "scripts", "tests").Output()
+	if err != nil {
+		return err
+	}
+	if files := strings.TrimSpace(string(out)); files != "" {
+		return fmt.Errorf("gofmt needed:\n  %s", strings.ReplaceAll(files, "\n", "\n  "))
+	}
+	return nil
+}
+
+func requireBuild(dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, "index.html")); err != nil {
+		return fmt.Errorf("%s/ has no build; run `blogctl build` first", dir)
+	}
+	return nil
+}
+
+func prunePagefind(dir string) error {
+	for _, name := range unusedPagefindAssets {
+		if err := os.Remove(filepath.Join(dir, "pagefind", name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBudget fails when the files under dir add up to more than budget
+// bytes.
+func checkBudget(dir string, budget int64) error {
+	size, err := dirSize(dir)
+	if err != nil {
+		return err
+	}
+	if size > budget {
+		return fmt.Errorf("%s/ is %d bytes, over the %d-byte budget", dir, size, budget)
+	}
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+func gitRevision() string {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "blogctl: %v\n", err)
+	os.Exit(1)
+}
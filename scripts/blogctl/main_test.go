@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSite is an app whose commands are recorded instead of run. Commands
+// starting with fail exit non-zero, hugo writes a page into public/ so the
+// in-process steps have a build to look at, and the deploy command records
+// the page it was asked to ship.
+type fakeSite struct {
+	*app
+	ran      []string
+	deployed []string
+	fail     string
+}
+
+func newFakeSite(t *testing.T) *fakeSite {
+	t.Helper()
+	dir := t.TempDir()
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	f := &fakeSite{}
+	f.app = &app{
+		out:      io.Discard,
+		public:   filepath.Join(dir, "public"),
+		pagefind: defaultPagefind,
+		prettier: defaultPrettier,
+		budget:   defaultBudget,
+		gofmt:    func() error { return nil },
+		deploy:   "upload",
+		releases: &releaseStore{dir: filepath.Join(dir, "releases"), keep: 2, now: func() time.Time {
+			clock = clock.Add(time.Minute)
+			return clock
+		}},
+		revision: func() string { return "abc1234" },
+		now:      func() time.Time { return clock },
+	}
+	f.run = func(ctx context.Context, env []string, stdout io.Writer, args ...string) error {
+		cmd := strings.Join(args, " ")
+		f.ran = append(f.ran, cmd)
+		switch {
+		case f.fail != "" && strings.HasPrefix(cmd, f.fail):
+			return errors.New("exit status 1")
+		case args[0] == "hugo":
+			mustWrite(t, filepath.Join(f.public, "index.html"), "build "+clock.Format(time.Kitchen))
+		case args[0] == "sh":
+			dir := strings.TrimPrefix(env[0], "DEPLOY_DIR=")
+			data, err := os.ReadFile(filepath.Join(dir, "index.html"))
+			if err != nil {
+				return err
+			}
+			f.deployed = append(f.deployed, string(data))
+		}
+		return nil
+	}
+	return f
+}
+
+func TestBuildRunsTheMakefilePipeline(t *testing.T) {
+	site := newFakeSite(t)
+	mustWrite(t, filepath.Join(site.public, "pagefind", "pagefind-modular-ui.js"), "unused")
+	mustWrite(t, filepath.Join(site.public, "pagefind", "pagefind.js"), "used")
+
+	if err := site.command(context.Background(), "build"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"go run ./scripts/hugoversion",
+		"go run ./scripts/frontmatter",
+		"go run ./scripts/readingtime",
+		"hugo --environment production --minify --gc --cleanDestinationDir",
+		"go run ./scripts/curation sitemaps",
+		"go run ./scripts/anchors",
+		defaultPagefind,
+		"go run ./scripts/searchindex",
+	}
+	if !slices.Equal(site.ran, want) {
+		t.Fatalf("ran %q, want %q", site.ran, want)
+	}
+	if _, err := os.Stat(filepath.Join(site.public, "pagefind", "pagefind-modular-ui.js")); !os.IsNotExist(err) {
+		t.Fatal("unused Pagefind asset survived the build")
+	}
+	if _, err := os.Stat(filepath.Join(site.public, "pagefind", "pagefind.js")); err != nil {
+		t.Fatal("pruning removed pagefind.js")
+	}
+}
+
+func TestBuildEnforcesSizeBudget(t *testing.T) {
+	site := newFakeSite(t)
+	site.budget = 4
+	err := site.command(context.Background(), "build")
+	if err == nil || !strings.Contains(err.Error(), "size budget") {
+		t.Fatalf("err = %v, want a size budget failure", err)
+	}
+}
+
+func TestCheckNeedsABuild(t *testing.T) {
+	site := newFakeSite(t)
+	if err := site.command(context.Background(), "check"); err == nil || !strings.Contains(err.Error(), "blogctl build") {
+		t.Fatalf("err = %v, want a pointer to blogctl build", err)
+	}
+	if len(site.ran) != 0 {
+		t.Fatalf("ran %q without a build", site.ran)
+	}
+}
+
+func TestDeployShipsOnlyGreenBuilds(t *testing.T) {
+	site := newFakeSite(t)
+	site.fail = "go run ./scripts/shortcodes"
+	err := site.command(context.Background(), "deploy")
+	if err == nil || !strings.Contains(err.Error(), "not deploying: shortcodes") {
+		t.Fatalf("err = %v, want the failing check", err)
+	}
+	if len(site.deployed) != 0 || slices.Contains(site.ran, "go test -count=1 ./...") {
+		t.Fatalf("pipeline kept going after a failed check: %q", site.ran)
+	}
+	if _, err := site.releases.previous(); err == nil {
+		t.Fatal("a failed deploy was recorded as a release")
+	}
+
+	site.fail = ""
+	site.ran = nil
+	if err := site.command(context.Background(), "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(site.ran, "go test -count=1 ./...") || len(site.deployed) != 1 {
+		t.Fatalf("ran %q, deployed %q", site.ran, site.deployed)
+	}
+}
+
+func TestRollbackRedeploysThePreviousRelease(t *testing.T) {
+	site := newFakeSite(t)
+	if err := site.command(context.Background(), "rollback"); err == nil {
+		t.Fatal("rollback with no releases succeeded")
+	}
+	for range 3 {
+		if err := site.command(context.Background(), "deploy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := site.command(context.Background(), "rollback"); err != nil {
+		t.Fatal(err)
+	}
+	if got := site.deployed[3]; got != site.deployed[1] {
+		t.Fatalf("rollback shipped %q, want the second deploy %q", got, site.deployed[1])
+	}
+	// keep is 2, so the first deploy is gone and there's nothing older.
+	if err := site.command(context.Background(), "rollback"); err == nil || !strings.Contains(err.Error(), "oldest kept release") {
+		t.Fatalf("err = %v, want nothing left to roll back to", err)
+	}
+	archives, _ := filepath.Glob(filepath.Join(site.releases.dir, "*.tar.gz"))
+	if len(archives) != 2 {
+		t.Fatalf("kept %d archives, want 2", len(archives))
+	}
+}
+
+func TestRollbackSkipsAReleaseAlreadyRolledBackFrom(t *testing.T) {
+	site := newFakeSite(t)
+	site.releases.keep = 5
+	deploy := func() {
+		t.Helper()
+		if err := site.command(context.Background(), "deploy"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rollback := func() {
+		t.Helper()
+		if err := site.command(context.Background(), "rollback"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deploy()   // R1
+	deploy()   // R2, bad
+	rollback() // back to R1
+	deploy()   // R3
+	rollback()
+	r1 := site.deployed[0]
+	if got := site.deployed[4]; got != r1 {
+		t.Fatalf("rollback after R1, R2, rollback, R3 shipped %q, want R1 %q, not the bad R2 %q", got, r1, site.deployed[1])
+	}
+	if err := site.command(context.Background(), "rollback"); err == nil || !strings.Contains(err.Error(), "oldest kept release") {
+		t.Fatalf("err = %v, want nothing before R1", err)
+	}
+}
+
+func TestDeployNeedsACommand(t *testing.T) {
+	site := newFakeSite(t)
+	site.deploy = ""
+	if err := site.command(context.Background(), "deploy"); err == nil || len(site.ran) != 0 {
+		t.Fatalf("err = %v after running %q, want an early failure", err, site.ran)
+	}
+}
+
+func TestDryRunRunsNothing(t *testing.T) {
+	site := newFakeSite(t)
+	site.dryRun = true
+	var out strings.Builder
+	site.out = &out
+	if err := site.command(context.Background(), "deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if len(site.ran) != 0 || len(site.deployed) != 0 {
+		t.Fatalf("dry run ran %q and deployed %q", site.ran, site.deployed)
+	}
+	for _, want := range []string{"==> hugo\n    hugo --environment production", "==> size budget\n    (runs in blogctl)", `sh -c "upload"`} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if archives, _ := filepath.Glob(filepath.Join(site.releases.dir, "*")); len(archives) != 0 {
+		t.Fatalf("dry run wrote %q", archives)
+	}
+}
+
+func TestToolCommandsRunTheirTool(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"linkcheck", "-uptime"}, []string{"go run ./scripts/linkcheck -uptime"}},
+		{[]string{"urls", "-format", "csv"}, []string{"go run ./scripts/curation export urls -format csv"}},
+		{[]string{"sitemaps"}, []string{"go run ./scripts/curation sitemaps -verify"}},
+		{[]string{"img-upload", "--post", "a.md"}, []string{"go run ./scripts/media --post a.md --wrangler " + defaultWrangler}},
+		{[]string{"build-profile", "-update"}, []string{"go run ./scripts/hugoversion", "go run ./scripts/buildprofile -update"}},
+	} {
+		t.Run(tc.args[0], func(t *testing.T) {
+			site := newFakeSite(t)
+			site.wrangler = defaultWrangler
+			if err := site.command(context.Background(), tc.args[0], tc.args[1:]...); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(site.ran, tc.want) {
+				t.Fatalf("ran %q, want %q", site.ran, tc.want)
+			}
+		})
+	}
+}
+
+func TestToolCommandsPassTheirEnvironment(t *testing.T) {
+	site := newFakeSite(t)
+	var env []string
+	run := site.run
+	site.run = func(ctx context.Context, e []string, stdout io.Writer, args ...string) error {
+		env = append(env, e...)
+		return run(ctx, e, stdout, args...)
+	}
+	if err := site.command(context.Background(), "csp"); err != nil {
+		t.Fatal(err)
+	}
+	if last := site.ran[len(site.ran)-1]; last != "go test -count=1 -run TestContentSecurityPolicy ./tests" || !slices.Contains(env, "UPDATE_CSP=1") {
+		t.Fatalf("csp ran %q with %q, want the build, then the CSP test with UPDATE_CSP=1", site.ran, env)
+	}
+	if err := site.command(context.Background(), "format", "x"); err == nil {
+		t.Fatal("format took an argument it has no use for")
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallHooksRefreshesStaleOnes(t *testing.T) {
+	site := newFakeSite(t)
+	hooks := filepath.Join(t.TempDir(), "hooks")
+	site.git = func(args ...string) (string, error) {
+		if slices.Equal(args, []string{"rev-parse", "--git-path", "hooks"}) {
+			return hooks, nil
+		}
+		return "", nil
+	}
+	if err := site.command(context.Background(), "install-hooks"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range hookNames {
+		info, err := os.Stat(filepath.Join(hooks, name))
+		if err != nil || info.Mode().Perm()&0o100 == 0 {
+			t.Fatalf("%s hook: %v, mode %v; want an executable", name, err, info.Mode())
+		}
+	}
+
+	stale := strings.Replace(hookScript("pre-push"), hooksVersion(), "000000000000", 1)
+	mustWrite(t, filepath.Join(hooks, "pre-push"), stale)
+	if err := site.command(context.Background(), "hook", "pre-commit"); err != nil {
+		t.Fatal(err)
+	}
+	if raw, _ := os.ReadFile(filepath.Join(hooks, "pre-push")); string(raw) != hookScript("pre-push") {
+		t.Fatalf("stale pre-push hook wasn't rewritten:\n%s", raw)
+	}
+
+	mustWrite(t, filepath.Join(hooks, "pre-commit"), "#!/bin/sh\nmake lint\n")
+	if err := site.command(context.Background(), "install-hooks"); err == nil || !strings.Contains(err.Error(), "didn't write") {
+		t.Fatalf("err = %v, want a hand-written hook left alone", err)
+	}
+}
+
+func TestPreCommitChecksWhatIsStaged(t *testing.T) {
+	for _, tc := range []struct {
+		staged string
+		want   []string
+	}{
+		{"README.md", nil},
+		{"content/go/retry.md", []string{"code blocks", "encoding", "frontmatter", "media URLs", "self links"}},
+		{"scripts/blogctl/main.go\nstatic/x.png", []string{"gofmt", "go vet"}},
+	} {
+		site := newFakeSite(t)
+		site.git = func(args ...string) (string, error) { return tc.staged, nil }
+		steps, err := site.preCommitSteps()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, s := range steps {
+			got = append(got, s.name)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("staged %q: steps %q, want %q", tc.staged, got, tc.want)
+		}
+	}
+}
+
+func TestPrePushComparesWithTheRemote(t *testing.T) {
+	site := newFakeSite(t)
+	site.git = func(args ...string) (string, error) {
+		switch {
+		case args[0] == "merge-base":
+			return "base111", nil
+		case args[0] == "diff" && args[2] == "remote222":
+			// Nothing under content/ changed on this branch.
+			return "", nil
+		}
+		return "content/go/retry.md", nil
+	}
+	stdin := strings.NewReader(strings.Join([]string{
+		"refs/heads/main local111 refs/heads/main remote111",
+		"refs/heads/new local222 refs/heads/new " + zeroSHA,
+		"refs/heads/docs local333 refs/heads/docs remote222",
+		"(delete) " + zeroSHA + " refs/heads/old remote333",
+	}, "\n"))
+	steps, err := site.prePushSteps(stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range steps {
+		got = append(got, strings.Join(s.args, " "))
+	}
+	want := []string{
+		"go run ./scripts/linkcheck -compare remote111 -head local111",
+		"go run ./scripts/linkcheck -compare base111 -head local222",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("steps = %q, want %q", got, want)
+	}
+}
+
+func TestPipelineReportsTimingsAndBudgets(t *testing.T) {
+	site := newFakeSite(t)
+	var out strings.Builder
+	site.out = &out
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	site.now = func() time.Time {
+		clock = clock.Add(1500 * time.Millisecond)
+		return clock
+	}
+	run := site.run
+	site.run = func(ctx context.Context, env []string, stdout io.Writer, args ...string) error {
+		if args[len(args)-1] == "./scripts/linkcheck" {
+			fmt.Fprint(stdout, "0 findings\ncost: 42 requests, 1.2 MiB downloaded, 0 retries, 3 cache hits\n")
+		}
+		return run(ctx, env, stdout, args...)
+	}
+	site.timeBudgets = timeBudgets{"link": time.Second, "lint": time.Minute}
+
+	steps := []step{
+		{name: "lint", fn: func() error { return nil }},
+		{name: "links", args: goRun("linkcheck")},
+	}
+	if err := site.pipeline(context.Background(), steps); err != nil {
+		t.Fatal(err)
+	}
+	want := `==> lint
+==> links
+0 findings
+cost: 42 requests, 1.2 MiB downloaded, 0 retries, 3 cache hits
+==> timing
+    lint       1.5s
+    links      1.5s  42 requests
+    total        3s
+warning: links took 1.5s, over its 1s budget
+`
+	if out.String() != want {
+		t.Fatalf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestParseTimeBudgets(t *testing.T) {
+	budgets, err := parseTimeBudgets("go test=2m, new external links = 30s,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := budgets.of("new external links in refs/heads/main"); got != 30*time.Second {
+		t.Errorf("pre-push budget = %s, want 30s", got)
+	}
+	if got := budgets.of("go vet"); got != 0 {
+		t.Errorf("go vet budget = %s, want none", got)
+	}
+	if _, err := parseTimeBudgets("go test"); err == nil {
+		t.Error("a budget without a duration parsed")
+	}
+}
+
+func TestUpdateCheckComparesBuilds(t *testing.T) {
+	const candidate = "go run github.com/gohugoio/hugo@v0.165.0"
+	for _, tc := range []struct {
+		name string
+		// next is the candidate's build; the installed Hugo's is current.
+		current, next map[string]string
+		wantErr       bool
+		want          []string
+	}{
+		{
+			name:    "only the generator differs",
+			current: map[string]string{"index.xml": "<generator>Hugo -- 0.164.0</generator>", "go/a/index.html": "<p>a"},
+			next:    map[string]string{"index.xml": "<generator>Hugo -- 0.165.0</generator>", "go/a/index.html": "<p>a"},
+			want:    []string{"files 0 changed, 0 added, 0 removed", "safe to merge"},
+		},
+		{
+			name:    "a page renders differently",
+			current: map[string]string{"go/a/index.html": "<p>a", "sitemap.xml": "<loc>https://rednafi.com/go/a/</loc>"},
+			next:    map[string]string{"go/a/index.html": "<p>a</p>", "go/b/index.html": "<p>b", "sitemap.xml": "<loc>https://rednafi.com/go/a/</loc>"},
+			want:    []string{"files 1 changed, 1 added, 0 removed", "rendered differently, worth a look:\n  go/a/index.html", "safe to merge"},
+		},
+		{
+			name:    "a page goes missing",
+			current: map[string]string{"go/a/index.html": "<p>a", "sitemap.xml": "<loc>https://rednafi.com/go/a/</loc>"},
+			next:    map[string]string{"sitemap.xml": ""},
+			wantErr: true,
+			want:    []string{"sitemap URLs lost:\n  https://rednafi.com/go/a/", "removed:\n  go/a/index.html"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			site := newFakeSite(t)
+			site.candidate = candidate
+			var out strings.Builder
+			site.out = &out
+			run := site.run
+			site.run = func(ctx context.Context, env []string, stdout io.Writer, args ...string) error {
+				if i := slices.Index(args, "--destination"); i >= 0 {
+					files := tc.current
+					if args[0] != "hugo" {
+						files = tc.next
+					}
+					for name, content := range files {
+						mustWrite(t, filepath.Join(args[i+1], name), content)
+					}
+				}
+				return run(ctx, env, stdout, args...)
+			}
+
+			err := site.command(context.Background(), "update-check")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("update-check = %v, want an error: %v\n%s", err, tc.wantErr, out.String())
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output lacks %q:\n%s", want, out.String())
+				}
+			}
+			if !strings.HasPrefix(site.ran[0], "git worktree add --detach ") || site.ran[1] != candidate+" version" ||
+				!strings.HasPrefix(site.ran[len(site.ran)-1], "git worktree remove --force ") {
+				t.Errorf("ran %q, want a worktree set up, the candidate's version, and the worktree removed", site.ran)
+			}
+		})
+	}
+}
+
+func TestUpdateCheckNeedsACandidate(t *testing.T) {
+	site := newFakeSite(t)
+	if err := site.command(context.Background(), "update-check"); err == nil || len(site.ran) != 0 {
+		t.Fatalf("update-check without a candidate = %v, ran %q", err, site.ran)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// releaseStore keeps the last few deployed builds as tarballs, plus an
+// index of which one is live.
+type releaseStore struct {
+	dir  string
+	keep int
+	now  func() time.Time
+}
+
+// releaseIndex is releases.json. Releases is oldest first. History is the
+// releases that have been live, the current one last: a deploy pushes onto
+// it and a rollback pops it, so rolling back returns to what was live
+// before, not to what was deployed before.
+type releaseIndex struct {
+	Live     string   `json:"live"`
+	Releases []string `json:"releases"`
+	History  []string `json:"history"`
+}
+
+// history returns idx.History, or, for an index written before there was
+// one, the releases up to the live one.
+func (idx releaseIndex) history() []string {
+	if len(idx.History) > 0 || idx.Live == "" {
+		return idx.History
+	}
+	if i := slices.Index(idx.Releases, idx.Live); i >= 0 {
+		return slices.Clone(idx.Releases[:i+1])
+	}
+	return nil
+}
+
+// archive packs src into a new release named after the current time and
+// git revision, and returns that name. The release isn't in the index
+// until markLive records it, so a failed deploy leaves no trace but the
+// tarball, which the next prune removes.
+func (s *releaseStore) archive(src, revision string) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", err
+	}
+	id := s.now().UTC().Format("20060102T150405Z") + "-" + revision
+	tmp := s.path(id) + ".tmp"
+	if err := writeTarball(tmp, src); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("archive %s: %w", src, err)
+	}
+	return id, os.Rename(tmp, s.path(id))
+}
+
+// markLive records a freshly deployed id as the live release, pushing it
+// onto the history and dropping the oldest releases beyond keep, along
+// with their places in the history.
+func (s *releaseStore) markLive(id string) error {
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	history := idx.history()
+	if !slices.Contains(idx.Releases, id) {
+		idx.Releases = append(idx.Releases, id)
+	}
+	idx.Live = id
+	if extra := len(idx.Releases) - max(s.keep, 1); extra > 0 {
+		idx.Releases = slices.Clone(idx.Releases[extra:])
+	}
+	idx.History = slices.DeleteFunc(append(history, id), func(h string) bool { return !slices.Contains(idx.Releases, h) })
+	if err := s.save(idx); err != nil {
+		return err
+	}
+	return s.prune(idx)
+}
+
+// markRolledBack records that id, which previous returned, is live again,
+// popping the release it replaced off the history.
+func (s *releaseStore) markRolledBack(id string) error {
+	idx, err := s.load()
+	if err != nil {
+		return err
+	}
+	history := idx.history()
+	if n := len(history); n < 2 || history[n-2] != id {
+		return fmt.Errorf("%s was not live before %s", id, idx.Live)
+	}
+	idx.History = history[:len(history)-1]
+	idx.Live = id
+	return s.save(idx)
+}
+
+// previous is the release that was live before the current one.
+func (s *releaseStore) previous() (string, error) {
+	idx, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	history := idx.history()
+	switch len(history) {
+	case 0:
+		return "", fmt.Errorf("no live release recorded in %s", s.dir)
+	case 1:
+		return "", fmt.Errorf("%s is the oldest kept release; nothing to roll back to", idx.Live)
+	}
+	return history[len(history)-2], nil
+}
+
+// extract unpacks release id into dir.
+func (s *releaseStore) extract(id, dir string) error {
+	f, err := os.Open(s.path(id))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", id, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if !filepath.IsLocal(hdr.Name) {
+			return fmt.Errorf("%s: entry %q escapes the release", id, hdr.Name)
+		}
+		target := filepath.Join(dir, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o755)
+		case tar.TypeReg:
+			err = writeFile(target, tr, hdr.FileInfo().Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// prune deletes tarballs that fell out of the index, including ones a
+// failed deploy left behind.
+func (s *releaseStore) prune(idx releaseIndex) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".tar.gz")
+		if ok && !slices.Contains(idx.Releases, id) {
+			if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *releaseStore) path(id string) string {
+	return filepath.Join(s.dir, id+".tar.gz")
+}
+
+func (s *releaseStore) load() (releaseIndex, error) {
+	var idx releaseIndex
+	data, err := os.ReadFile(filepath.Join(s.dir, "releases.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return idx, err
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return idx, fmt.Errorf("%s: %w", filepath.Join(s.dir, "releases.json"), err)
+	}
+	return idx, nil
+}
+
+func (s *releaseStore) save(idx releaseIndex) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, "releases.json"), append(data, '\n'), 0o644)
+}
+
+func writeTarball(path, src string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.AddFS(os.DirFS(src)); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func writeFile(path string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// costPattern matches the usage line linkcheck ends its report with,
+// "cost: 42 requests, 1.2 MiB downloaded, ...".
+var costPattern = regexp.MustCompile(`(?m)^cost: (\d+) requests?\b`)
+
+// stepTiming is how long one step ran and, if it said, how many requests
+// it made; requests is -1 when it didn't.
+type stepTiming struct {
+	name     string
+	elapsed  time.Duration
+	requests int64
+	budget   time.Duration
+}
+
+// timeBudgets caps how long steps may take, by the start of their names,
+// so that "new external links" covers the pre-push check of every ref.
+// A step over budget is only warned about: the breakdown is there to keep
+// the hooks quick, not to fail them.
+type timeBudgets map[string]time.Duration
+
+// parseTimeBudgets reads "name=duration" pairs separated by commas, like
+// "go test=2m,new external links=30s".
+func parseTimeBudgets(s string) (timeBudgets, error) {
+	budgets := timeBudgets{}
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("time budget %q: want name=duration", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("time budget %q: %w", pair, err)
+		}
+		budgets[strings.TrimSpace(name)] = d
+	}
+	return budgets, nil
+}
+
+// of returns the budget of the step called name, from the longest prefix
+// that matches, or 0 for none.
+func (b timeBudgets) of(name string) time.Duration {
+	var budget time.Duration
+	longest := -1
+	for prefix, d := range b {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			budget, longest = d, len(prefix)
+		}
+	}
+	return budget
+}
+
+// runCounted runs an external step, passing its output through, and
+// returns the requests its last cost line counted, or -1 when it printed
+// none. A step that fails may still have counted, like linkcheck finding
+// a broken link.
+func (a *app) runCounted(ctx context.Context, env, args []string) (int64, error) {
+	var out bytes.Buffer
+	runErr := a.run(ctx, env, io.MultiWriter(a.out, &out), args...)
+	matches := costPattern.FindAllSubmatch(out.Bytes(), -1)
+	if len(matches) == 0 {
+		return -1, runErr
+	}
+	n, err := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+	if err != nil {
+		return -1, runErr
+	}
+	return n, runErr
+}
+
+// writeTimings prints how long each step took, its requests and the total,
+// then a warning for every step over its budget.
+func writeTimings(w io.Writer, timings []stepTiming) {
+	width := len("total")
+	var total time.Duration
+	for _, t := range timings {
+		width = max(width, len(t.name))
+		total += t.elapsed
+	}
+	fmt.Fprintln(w, "==> timing")
+	for _, t := range timings {
+		line := fmt.Sprintf("    %-*s %9s", width, t.name, roundDuration(t.elapsed))
+		if t.requests >= 0 {
+			line += fmt.Sprintf("  %d requests", t.requests)
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintf(w, "    %-*s %9s\n", width, "total", roundDuration(total))
+	for _, t := range timings {
+		if t.budget > 0 && t.elapsed > t.budget {
+			fmt.Fprintf(w, "warning: %s took %s, over its %s budget\n", t.name, roundDuration(t.elapsed), t.budget)
+		}
+	}
+}
+
+// roundDuration keeps a duration to two significant places or so: 1.24s,
+// 35ms, 2m3.5s.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Minute:
+		return d.Round(100 * time.Millisecond)
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	}
+	return d.Round(time.Millisecond)
+}
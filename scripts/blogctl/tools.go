@@ -0,0 +1,82 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// defaultWrangler runs the Wrangler that media uploads post images with.
+const defaultWrangler = "npx -y wrangler@4.112.0"
+
+// toolCommand is a subcommand that runs one of the scripts/ tools, or a
+// short sequence ending in one, so the Makefile needn't call them itself.
+type toolCommand struct {
+	// takesArgs says whether the arguments after the subcommand go on to
+	// the last step; the others refuse any.
+	takesArgs bool
+	steps     func(a *app, args []string) []step
+}
+
+// toolCommands are the tool subcommands by name, which match the Makefile
+// targets that call them.
+var toolCommands = map[string]toolCommand{
+	"linkcheck":   tool("linkcheck"),
+	"describe":    tool("describe"),
+	"interlink":   tool("interlink"),
+	"freshness":   tool("freshness"),
+	"badges":      tool("badges"),
+	"urls":        tool("curation", "export", "urls"),
+	"changelog":   tool("curation", "changelog"),
+	"calendar":    tool("curation", "calendar"),
+	"syndication": tool("curation", "syndication"),
+	"redirects":   tool("curation", "redirects"),
+	"sitemaps":    tool("curation", "sitemaps", "-verify"),
+	"build-profile": {takesArgs: true, steps: func(_ *app, args []string) []step {
+		return []step{
+			{name: "hugo version", args: goRun("hugoversion")},
+			{name: "buildprofile", args: goRun("buildprofile", args...)},
+		}
+	}},
+	"visual-baseline": {steps: func(a *app, _ []string) []step {
+		return append(a.buildSteps(), step{
+			name: "visual baselines",
+			env:  []string{"UPDATE_VISUAL=1"},
+			args: []string{"go", "test", "-count=1", "-run", "TestVisualRegression", "./tests"},
+		})
+	}},
+	"csp": {steps: func(a *app, _ []string) []step {
+		return append(a.buildSteps(), step{
+			name: "content security policy",
+			env:  []string{"UPDATE_CSP=1"},
+			args: []string{"go", "test", "-count=1", "-run", "TestContentSecurityPolicy", "./tests"},
+		})
+	}},
+	"format": {steps: func(a *app, _ []string) []step {
+		return []step{
+			{name: "gofmt", args: []string{"gofmt", "-w", "scripts", "tests"}},
+			{name: "code blocks", args: goRun("lintcodeblocks")},
+			{name: "encoding", args: goRun("encoding")},
+			{name: "self links", args: goRun("selflinks")},
+			{name: "frontmatter", args: goRun("frontmatter")},
+			{name: "reading times", args: goRun("readingtime")},
+			{name: "prettier", args: append(strings.Fields(a.prettier), "--write", ".")},
+		}
+	}},
+	"img-upload": {takesArgs: true, steps: func(a *app, args []string) []step {
+		return []step{{name: "media", args: goRun("media", slices.Concat(args, []string{"--wrangler", a.wrangler})...)}}
+	}},
+}
+
+// tool is the toolCommand that runs scripts/name with fixed, then the
+// subcommand's arguments.
+func tool(name string, fixed ...string) toolCommand {
+	return toolCommand{takesArgs: true, steps: func(_ *app, args []string) []step {
+		return []step{{name: name, args: goRun(name, slices.Concat(fixed, args)...)}}
+	}}
+}
+
+// toolNames lists the tool subcommands for usage messages.
+func toolNames() string {
+	return strings.Join(slices.Sorted(maps.Keys(toolCommands)), ", ")
+}
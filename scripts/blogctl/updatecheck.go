@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// candidateEnv holds the default -hugo-candidate.
+const candidateEnv = "BLOGCTL_HUGO_CANDIDATE"
+
+// maxChangedListed caps how many changed pages update-check names; the
+// rest are counted.
+const maxChangedListed = 20
+
+// generatorPattern matches the places a build names the Hugo that made it,
+// which differ between any two versions and say nothing about the site.
+var generatorPattern = regexp.MustCompile(`<generator>Hugo[^<]*</generator>|<meta name="?generator"?[^>]*>`)
+
+var sitemapLocPattern = regexp.MustCompile(`<loc>([^<]+)</loc>`)
+
+// buildDiff is how a candidate build differs from the current one.
+type buildDiff struct {
+	added, removed, changed []string
+	// lostURLs are sitemap URLs the candidate build dropped.
+	lostURLs []string
+}
+
+// safe reports whether the candidate build keeps every page and URL.
+func (d buildDiff) safe() bool {
+	return len(d.removed) == 0 && len(d.lostURLs) == 0
+}
+
+// updateCheck is the preflight for a Hugo upgrade. The site has no theme or
+// Hugo modules to bump; Hugo itself is the dependency that changes under
+// it. It checks HEAD out into a scratch worktree, builds it with the
+// installed Hugo and with a.candidate, and compares the two builds: a page
+// or sitemap URL the candidate loses makes the upgrade unsafe, and the
+// pages it renders differently are listed for a look.
+func (a *app) updateCheck(ctx context.Context) error {
+	if a.candidate == "" {
+		return fmt.Errorf("no candidate Hugo; pass -hugo-candidate or set %s", candidateEnv)
+	}
+	dir := filepath.Join(os.TempDir(), "blogctl-update-check")
+	if !a.dryRun {
+		var err error
+		if dir, err = os.MkdirTemp("", "blogctl-update-check-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+	tree := filepath.Join(dir, "tree")
+	current, next := filepath.Join(dir, "current"), filepath.Join(dir, "candidate")
+	build := func(hugo []string, dest string) []string {
+		return append(slices.Clone(hugo), "--source", tree, "--environment", "production", "--minify", "--destination", dest)
+	}
+	candidate := strings.Fields(a.candidate)
+
+	var diff buildDiff
+	steps := []step{
+		{name: "worktree", args: []string{"git", "worktree", "add", "--detach", tree, "HEAD"}},
+		{name: "candidate version", args: append(slices.Clone(candidate), "version")},
+		{name: "hugo (installed)", args: build([]string{"hugo"}, current)},
+		{name: "hugo (candidate)", args: build(candidate, next)},
+		{name: "compare builds", fn: func() (err error) {
+			diff, err = compareBuilds(current, next)
+			return err
+		}},
+	}
+	if !a.dryRun {
+		defer a.run(context.WithoutCancel(ctx), nil, a.out, "git", "worktree", "remove", "--force", tree)
+	}
+	if err := a.pipeline(ctx, steps); err != nil {
+		return fmt.Errorf("update check: %w", err)
+	}
+	if a.dryRun {
+		return nil
+	}
+	writeBuildDiff(a.out, diff)
+	if !diff.safe() {
+		return errors.New("the candidate Hugo loses pages; not safe to merge")
+	}
+	fmt.Fprintln(a.out, "safe to merge: bump HUGO_VERSION in .github/workflows/ci.yml, and module.hugoVersion in config.yml if the site now needs it")
+	return nil
+}
+
+// compareBuilds compares the files under two builds, ignoring which Hugo
+// generated them, and the URLs their sitemaps list.
+func compareBuilds(current, next string) (buildDiff, error) {
+	before, err := readBuild(current)
+	if err != nil {
+		return buildDiff{}, err
+	}
+	after, err := readBuild(next)
+	if err != nil {
+		return buildDiff{}, err
+	}
+	var d buildDiff
+	for file, content := range before {
+		other, ok := after[file]
+		switch {
+		case !ok:
+			d.removed = append(d.removed, file)
+		case !bytes.Equal(content, other):
+			d.changed = append(d.changed, file)
+		}
+	}
+	for file := range after {
+		if _, ok := before[file]; !ok {
+			d.added = append(d.added, file)
+		}
+	}
+	kept := map[string]bool{}
+	for _, loc := range sitemapURLs(after) {
+		kept[loc] = true
+	}
+	for _, loc := range sitemapURLs(before) {
+		if !kept[loc] {
+			d.lostURLs = append(d.lostURLs, loc)
+		}
+	}
+	for _, list := range [][]string{d.added, d.removed, d.changed, d.lostURLs} {
+		slices.Sort(list)
+	}
+	return d, nil
+}
+
+// readBuild reads every file under dir, keyed by slash-separated path,
+// with the generator stamps blanked out.
+func readBuild(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = generatorPattern.ReplaceAll(data, nil)
+		return nil
+	})
+	return files, err
+}
+
+// sitemapURLs lists the <loc>s of every sitemap in a build.
+func sitemapURLs(files map[string][]byte) []string {
+	var locs []string
+	for file, content := range files {
+		if !strings.HasPrefix(filepath.Base(file), "sitemap") || filepath.Ext(file) != ".xml" {
+			continue
+		}
+		for _, m := range sitemapLocPattern.FindAllSubmatch(content, -1) {
+			locs = append(locs, string(m[1]))
+		}
+	}
+	return locs
+}
+
+func writeBuildDiff(w io.Writer, d buildDiff) {
+	fmt.Fprintf(w, "==> candidate build: files %d changed, %d added, %d removed\n", len(d.changed), len(d.added), len(d.removed))
+	for _, group := range []struct {
+		title string
+		files []string
+	}{
+		{"sitemap URLs lost", d.lostURLs},
+		{"removed", d.removed},
+	} {
+		if len(group.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", group.title)
+		for _, file := range group.files {
+			fmt.Fprintf(w, "  %s\n", file)
+		}
+	}
+	var pages []string
+	for _, file := range d.changed {
+		if filepath.Ext(file) == ".html" {
+			pages = append(pages, file)
+		}
+	}
+	if len(pages) > 0 {
+		fmt.Fprintln(w, "pages rendered differently, worth a look:")
+		for _, page := range pages[:min(len(pages), maxChangedListed)] {
+			fmt.Fprintf(w, "  %s\n", page)
+		}
+		if n := len(pages) - maxChangedListed; n > 0 {
+			fmt.Fprintf(w, "  and %d more\n", n)
+		}
+	}
+}
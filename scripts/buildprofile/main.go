@@ -0,0 +1,188 @@
+// Command buildprofile times a production Hugo build and tracks it against a
+// baseline.
+//
+// It renders the site in memory with --templateMetrics, prints the slowest
+// templates and the total build time next to the stored baseline, and exits
+// non-zero when the build got slower than the allowed regression. Build
+// times are machine-specific, so the baseline lives under .cache/ and is
+// (re)written with -update.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBaseline = ".cache/build-profile.json"
+
+// profile is one build's timings, as stored in the baseline file.
+type profile struct {
+	Total     time.Duration            `json:"total"`
+	Templates map[string]time.Duration `json:"templates"`
+}
+
+func main() {
+	baselinePath := flag.String("baseline", defaultBaseline, "baseline profile to compare against")
+	update := flag.Bool("update", false, "write this build's profile as the new baseline")
+	top := flag.Int("top", 10, "number of slowest templates to report")
+	maxRegression := flag.Float64("max-regression", 0.25, "fail when the total build time grows by more than this fraction")
+	flag.Parse()
+
+	output, err := runHugo()
+	if err != nil {
+		fatal(err)
+	}
+	current, err := parseMetrics(output)
+	if err != nil {
+		fatal(err)
+	}
+
+	baseline, err := loadBaseline(*baselinePath)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Print(report(current, baseline, *top))
+
+	if *update {
+		if err := saveBaseline(*baselinePath, current); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("wrote baseline %s\n", *baselinePath)
+		return
+	}
+	if regressed(current, baseline, *maxRegression) {
+		fatal(fmt.Errorf("build time %s regressed more than %.0f%% over baseline %s",
+			current.Total.Round(time.Millisecond), *maxRegression*100, baseline.Total.Round(time.Millisecond)))
+	}
+}
+
+func runHugo() ([]byte, error) {
+	cmd := exec.Command("hugo", "--environment", "production", "--renderToMemory",
+		"--templateMetrics", "--logLevel", "info")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		os.Stderr.Write(out.Bytes())
+		return nil, fmt.Errorf("hugo: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+var totalPattern = regexp.MustCompile(`Total in (\d+) ms`)
+
+// parseMetrics pulls the per-template cumulative durations out of Hugo's
+// template metrics table and the total build time out of its summary line.
+func parseMetrics(output []byte) (profile, error) {
+	p := profile{Templates: map[string]time.Duration{}}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := totalPattern.FindStringSubmatch(line); m != nil {
+			ms, _ := strconv.Atoi(m[1])
+			p.Total = time.Duration(ms) * time.Millisecond
+			continue
+		}
+
+		// cumulative average maximum potential cached cached-count count template
+		fields := strings.Fields(line)
+		if len(fields) != 8 {
+			continue
+		}
+		cumulative, err := time.ParseDuration(fields[0])
+		if err != nil {
+			continue
+		}
+		p.Templates[fields[7]] = cumulative
+	}
+	if err := scanner.Err(); err != nil {
+		return profile{}, err
+	}
+	if p.Total == 0 {
+		return profile{}, fmt.Errorf("hugo output has no total build time")
+	}
+	if len(p.Templates) == 0 {
+		return profile{}, fmt.Errorf("hugo output has no template metrics")
+	}
+	return p, nil
+}
+
+// report renders the total and the slowest templates, each with its change
+// against the baseline when one exists.
+func report(current, baseline profile, top int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "total build time: %s%s\n", current.Total.Round(time.Millisecond), delta(current.Total, baseline.Total))
+
+	names := make([]string, 0, len(current.Templates))
+	for name := range current.Templates {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(current.Templates[b], current.Templates[a]), strings.Compare(a, b))
+	})
+
+	fmt.Fprintf(&b, "slowest templates:\n")
+	for _, name := range names[:min(top, len(names))] {
+		d := current.Templates[name]
+		fmt.Fprintf(&b, "  %10s  %s%s\n", d.Round(time.Millisecond), name, delta(d, baseline.Templates[name]))
+	}
+	return b.String()
+}
+
+func delta(current, baseline time.Duration) string {
+	if baseline == 0 {
+		return ""
+	}
+	change := float64(current-baseline) / float64(baseline) * 100
+	return fmt.Sprintf(" (%+.0f%% vs %s)", change, baseline.Round(time.Millisecond))
+}
+
+func regressed(current, baseline profile, maxRegression float64) bool {
+	if baseline.Total == 0 {
+		return false
+	}
+	return float64(current.Total) > float64(baseline.Total)*(1+maxRegression)
+}
+
+func loadBaseline(path string) (profile, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return profile{}, nil
+	}
+	if err != nil {
+		return profile{}, err
+	}
+	var p profile
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return profile{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func saveBaseline(path string, p profile) error {
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "buildprofile:", err)
+	os.Exit(1)
+}
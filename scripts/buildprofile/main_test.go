@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleOutput = `Start building sites …
+INFO  build:  step process substep collect files 251 files_total 251 duration 12.3ms
+
+Template Metrics:
+
+     cumulative       average       maximum      cache  percent  cached  total
+       duration      duration      duration  potential   cached   count  count  template
+     ----------      --------      --------  ---------  -------  ------  -----  --------
+   1.204113917s    4.832987ms   34.106875ms          0        0       0    249  _default/single.html
+    612.20325ms    2.458647ms   20.123542ms         21        0       0    249  partials/head.html
+      1.542ms        1.542ms       1.542ms          0        0       0      1  robots.txt
+
+                   | EN
+-------------------+------
+  Pages            | 612
+
+Total in 2310 ms
+`
+
+func TestParseMetrics(t *testing.T) {
+	p, err := parseMetrics([]byte(sampleOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != 2310*time.Millisecond {
+		t.Errorf("Total = %s, want 2.31s", p.Total)
+	}
+	if got := p.Templates["partials/head.html"]; got != 612203250*time.Nanosecond {
+		t.Errorf("head.html = %s, want 612.20325ms", got)
+	}
+	if len(p.Templates) != 3 {
+		t.Errorf("parsed %d templates, want 3: %v", len(p.Templates), p.Templates)
+	}
+}
+
+func TestParseMetricsRequiresTotal(t *testing.T) {
+	if _, err := parseMetrics([]byte("Template Metrics:\n")); err == nil {
+		t.Fatal("expected an error for output without a total")
+	}
+}
+
+func TestReportOrdersSlowestFirstWithDeltas(t *testing.T) {
+	current := profile{Total: 3 * time.Second, Templates: map[string]time.Duration{
+		"fast.html": 10 * time.Millisecond,
+		"slow.html": 2 * time.Second,
+	}}
+	baseline := profile{Total: 2 * time.Second, Templates: map[string]time.Duration{
+		"slow.html": time.Second,
+	}}
+
+	got := report(current, baseline, 1)
+	want := "total build time: 3s (+50% vs 2s)\nslowest templates:\n          2s  slow.html (+100% vs 1s)\n"
+	if got != want {
+		t.Fatalf("report =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got, "fast.html") {
+		t.Fatal("report should honor top")
+	}
+}
+
+func TestRegressed(t *testing.T) {
+	baseline := profile{Total: 2 * time.Second}
+	if regressed(profile{Total: 2400 * time.Millisecond}, baseline, 0.25) {
+		t.Error("a 20% slowdown should stay under a 25% budget")
+	}
+	if !regressed(profile{Total: 2600 * time.Millisecond}, baseline, 0.25) {
+		t.Error("a 30% slowdown should exceed a 25% budget")
+	}
+	if regressed(profile{Total: time.Hour}, profile{}, 0.25) {
+		t.Error("no baseline should never regress")
+	}
+}
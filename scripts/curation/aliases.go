@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultMinScore is the lowest similarity that import aliases suggests.
+// Below it, a legacy slug shares a word or two with a post but is as likely
+// to be a different post that no longer exists.
+const defaultMinScore = 0.6
+
+// ambiguityMargin is how close the runner-up may score to the best match
+// before neither is suggested: two posts that fit about equally well need
+// a human to pick.
+const ambiguityMargin = 0.05
+
+// aliasSuggestion maps one legacy path to the post that should carry it as
+// an alias, or says why no post could be picked.
+type aliasSuggestion struct {
+	Legacy  string
+	Post    string
+	Score   float64
+	Problem string
+}
+
+// importAliases reads the legacy URLs in listPath, prints the alias each
+// post should gain and the URLs that need mapping by hand, and with apply
+// writes the aliases into the posts' frontmatter. With dryRun it lists the
+// posts apply would write instead, and writes none.
+func importAliases(w io.Writer, inv inventory, listPath string, minScore float64, apply, dryRun bool) error {
+	raw, err := os.ReadFile(listPath)
+	if err != nil {
+		return err
+	}
+	byFile := map[string][]aliasSuggestion{}
+	var problems []aliasSuggestion
+	for _, s := range suggestAliases(inv, legacyPaths(string(raw)), minScore) {
+		if s.Problem != "" {
+			problems = append(problems, s)
+			continue
+		}
+		byFile[inv.posts[s.Post]] = append(byFile[inv.posts[s.Post]], s)
+	}
+
+	files := slices.Sorted(maps.Keys(byFile))
+	for _, file := range files {
+		fmt.Fprintf(w, "%s (%s):\n", file, byFile[file][0].Post)
+		for _, s := range byFile[file] {
+			fmt.Fprintf(w, "  %s  %.2f\n", s.Legacy, s.Score)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%d legacy URL%s need mapping by hand:\n", len(problems), plural(len(problems)))
+		for _, s := range problems {
+			fmt.Fprintf(w, "  %s: %s\n", s.Legacy, s.Problem)
+		}
+	}
+	if dryRun {
+		p := &plan{}
+		added := 0
+		for _, file := range files {
+			p.write(file)
+			added += len(byFile[file])
+		}
+		p.note("-apply would add %d alias%s to %d post%s", added, pluralES(added), len(files), plural(len(files)))
+		return p.print(w)
+	}
+	if !apply {
+		return nil
+	}
+	added := 0
+	for _, file := range files {
+		var aliases []string
+		for _, s := range byFile[file] {
+			aliases = append(aliases, s.Legacy)
+		}
+		if err := addAliases(file, aliases); err != nil {
+			return err
+		}
+		added += len(aliases)
+	}
+	fmt.Fprintf(w, "added %d alias%s to %d post%s\n", added, pluralES(added), len(files), plural(len(files)))
+	return nil
+}
+
+// legacyPaths pulls one URL path out of each line of list, skipping blank
+// lines and # comments. A line can be a bare path, a full URL on any host,
+// or a server log line, where the first field that is either wins. Query
+// strings are kept so suggestAliases can refuse them; fragments never
+// reach the server and are dropped.
+func legacyPaths(list string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for line := range strings.Lines(list) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			field = strings.Trim(field, `"'`)
+			if !strings.HasPrefix(field, "/") && !strings.HasPrefix(field, "http://") && !strings.HasPrefix(field, "https://") {
+				continue
+			}
+			u, err := url.Parse(field)
+			if err != nil || u.Path == "" {
+				continue
+			}
+			legacy := u.Path
+			if u.RawQuery != "" {
+				legacy += "?" + u.RawQuery
+			}
+			if !seen[legacy] {
+				seen[legacy] = true
+				paths = append(paths, legacy)
+			}
+			break
+		}
+	}
+	return paths
+}
+
+// suggestAliases picks, for every legacy path that doesn't already resolve
+// to a post, the post whose slug, file name or title it resembles most.
+func suggestAliases(inv inventory, legacy []string, minScore float64) []aliasSuggestion {
+	candidates := map[string][]string{}
+	for postURL, file := range inv.posts {
+		name := strings.TrimSuffix(path.Base(file), ".md")
+		if name == "index" {
+			name = path.Base(path.Dir(file))
+		}
+		candidates[postURL] = []string{
+			matchKey(path.Base(postURL)),
+			matchKey(name),
+			matchKey(inv.frontmatter[postURL].Title),
+		}
+	}
+	posts := slices.Sorted(maps.Keys(candidates))
+
+	var out []aliasSuggestion
+	for _, l := range legacy {
+		s := aliasSuggestion{Legacy: l}
+		if strings.Contains(l, "?") {
+			s.Problem = "has a query string, which a static alias can't match"
+			out = append(out, s)
+			continue
+		}
+		link := normalizeLink(l)
+		if _, ok := inv.posts[link]; ok {
+			continue
+		}
+		if _, ok := inv.aliases[link]; ok {
+			continue
+		}
+		key := legacyKey(l)
+		if key == "" {
+			s.Problem = "has no words to match a post on"
+			out = append(out, s)
+			continue
+		}
+
+		var runnerUp string
+		var runnerUpScore float64
+		for _, postURL := range posts {
+			score := 0.0
+			for _, candidate := range candidates[postURL] {
+				score = max(score, similarity(key, candidate))
+			}
+			switch {
+			case score > s.Score:
+				runnerUp, runnerUpScore = s.Post, s.Score
+				s.Post, s.Score = postURL, score
+			case score > runnerUpScore:
+				runnerUp, runnerUpScore = postURL, score
+			}
+		}
+		switch {
+		case s.Score < minScore:
+			s.Problem = fmt.Sprintf("no post scores %.2f or more; closest is %s at %.2f", minScore, s.Post, s.Score)
+		case s.Score-runnerUpScore < ambiguityMargin:
+			s.Problem = fmt.Sprintf("fits %s and %s about equally (%.2f, %.2f)", s.Post, runnerUp, s.Score, runnerUpScore)
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// legacyKey returns the match key of the last segment of legacy that has a
+// word in it, so date directories, page numbers and index.html are skipped.
+func legacyKey(legacy string) string {
+	segments := strings.Split(strings.Trim(legacy, "/"), "/")
+	for _, segment := range slices.Backward(segments) {
+		segment = strings.TrimSuffix(segment, path.Ext(segment))
+		if key := matchKey(segment); key != "" && key != "index" {
+			return key
+		}
+	}
+	return ""
+}
+
+// matchKey lowercases s and reduces it to its words joined by hyphens.
+// Numbers are dropped, since legacy platforms put dates and ids in slugs
+// that the posts here don't have.
+func matchKey(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words = slices.DeleteFunc(words, func(word string) bool {
+		return strings.IndexFunc(word, unicode.IsLetter) == -1
+	})
+	return strings.Join(words, "-")
+}
+
+// similarity is the Dice coefficient of a's and b's letter pairs: 1 for
+// the same key, and forgiving of typos, pluralization and reordered words.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	pairs := func(s string) map[string]int {
+		counts := map[string]int{}
+		r := []rune(s)
+		for i := 0; i+1 < len(r); i++ {
+			counts[string(r[i:i+2])]++
+		}
+		return counts
+	}
+	pa, pb := pairs(a), pairs(b)
+	total, shared := 0, 0
+	for pair, n := range pa {
+		total += n
+		shared += min(n, pb[pair])
+	}
+	for _, n := range pb {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+var aliasesPattern = regexp.MustCompile(`(?m)^aliases:.*\n(?:(?:[ \t]+|- ).*\n)*`)
+
+// addAliases appends aliases to filePath's aliases list, skipping those it
+// already has, and rewrites the list in the layout scripts/frontmatter
+// produces.
+func addAliases(filePath string, aliases []string) error {
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	fmRaw, body, ok := splitFrontmatter(string(raw))
+	if !ok {
+		return fmt.Errorf("%s: missing YAML frontmatter", filePath)
+	}
+	var fm struct {
+		Aliases []string `yaml:"aliases"`
+	}
+	if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+		return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+	}
+	merged := fm.Aliases
+	for _, alias := range aliases {
+		if !slices.Contains(merged, alias) {
+			merged = append(merged, alias)
+		}
+	}
+
+	var block strings.Builder
+	block.WriteString("aliases:\n")
+	for _, alias := range merged {
+		block.WriteString("    - " + quoteYAML(alias) + "\n")
+	}
+	fmRaw += "\n"
+	if aliasesPattern.MatchString(fmRaw) {
+		fmRaw = aliasesPattern.ReplaceAllLiteralString(fmRaw, block.String())
+	} else {
+		fmRaw += block.String()
+	}
+	return os.WriteFile(filePath, []byte("---\n"+fmRaw+"---\n"+body), 0o644)
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+func pluralES(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "es"
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultCalendar is the file of post ideas `curation calendar` plans from.
+const defaultCalendar = "calendar.yml"
+
+// Idea statuses. An idea moves from idea to drafting once a draft exists,
+// and to published once the post is out; dropped ones stay on file so the
+// idea isn't had twice.
+const (
+	statusIdea      = "idea"
+	statusDrafting  = "drafting"
+	statusPublished = "published"
+	statusDropped   = "dropped"
+)
+
+var ideaStatuses = []string{statusIdea, statusDrafting, statusPublished, statusDropped}
+
+// idea is one entry of the calendar. Draft, the draft's file, may be left
+// out: a draft whose title matches the idea's is found on its own.
+type idea struct {
+	Title  string `yaml:"title"`
+	Target string `yaml:"target"`
+	Status string `yaml:"status"`
+	Draft  string `yaml:"draft"`
+	Notes  string `yaml:"notes"`
+}
+
+func loadCalendar(path string) ([]idea, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Ideas []idea `yaml:"ideas"`
+	}
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, it := range file.Ideas {
+		if it.Title == "" {
+			return nil, fmt.Errorf("%s: idea %d has no title", path, i+1)
+		}
+		file.Ideas[i].Status = cmp.Or(it.Status, statusIdea)
+		if !slices.Contains(ideaStatuses, file.Ideas[i].Status) {
+			return nil, fmt.Errorf("%s: %q: status %q; want one of %s", path, it.Title, it.Status, strings.Join(ideaStatuses, ", "))
+		}
+		if it.Target != "" {
+			if _, err := time.Parse(time.DateOnly, it.Target); err != nil {
+				return nil, fmt.Errorf("%s: %q: target %q is not a YYYY-MM-DD date", path, it.Title, it.Target)
+			}
+		}
+	}
+	return file.Ideas, nil
+}
+
+// plannedIdea is an idea with what the content tree says about it.
+type plannedIdea struct {
+	idea
+	// draftFile is the draft the idea links to, named or matched by title.
+	draftFile string
+	// published is the URL of the post the idea became, if it is out.
+	published string
+}
+
+// planCalendar matches ideas against the drafts and published posts in
+// inv: an idea's draft is the file it names or, failing that, the draft
+// whose title it most resembles; it is out once a published post carries
+// its title or comes from its draft file. It also returns the drafts no
+// idea claims.
+func planCalendar(ideas []idea, inv inventory, minScore float64) ([]plannedIdea, []string) {
+	claimed := map[string]bool{}
+	planned := make([]plannedIdea, len(ideas))
+	for i, it := range ideas {
+		p := plannedIdea{idea: it, draftFile: it.Draft}
+		if p.draftFile == "" && it.Status != statusPublished && it.Status != statusDropped {
+			p.draftFile = closestDraft(it.Title, inv.drafts, minScore)
+		}
+		claimed[p.draftFile] = true
+		for _, postURL := range slices.Sorted(maps.Keys(inv.posts)) {
+			if inv.posts[postURL] == p.draftFile || matchKey(inv.frontmatter[postURL].Title) == matchKey(it.Title) {
+				p.published = postURL
+				break
+			}
+		}
+		planned[i] = p
+	}
+	var unplanned []string
+	for _, file := range slices.Sorted(maps.Keys(inv.drafts)) {
+		if !claimed[file] {
+			unplanned = append(unplanned, file)
+		}
+	}
+	return planned, unplanned
+}
+
+// closestDraft returns the draft whose title resembles title most, if it
+// scores at least minScore.
+func closestDraft(title string, drafts map[string]postFrontmatter, minScore float64) string {
+	var best float64
+	var file string
+	key := matchKey(title)
+	for _, candidate := range slices.Sorted(maps.Keys(drafts)) {
+		if score := similarity(key, matchKey(drafts[candidate].Title)); score > best {
+			best, file = score, candidate
+		}
+	}
+	if best < minScore {
+		return ""
+	}
+	return file
+}
+
+// writeCalendar prints the ideas due before today, those due in the days
+// after it, and a count of the rest, then a warning for every idea whose
+// target passed without a publish and every mismatch between an idea's
+// status and what the content tree says.
+func writeCalendar(w io.Writer, planned []plannedIdea, unplanned []string, today time.Time, days int) {
+	horizon := today.AddDate(0, 0, days)
+	var overdue, upcoming []plannedIdea
+	later, unscheduled, done := 0, 0, 0
+	var warnings []string
+	for _, p := range planned {
+		if p.Status == statusDropped {
+			done++
+			continue
+		}
+		if p.published != "" {
+			done++
+			if p.Status != statusPublished {
+				warnings = append(warnings, fmt.Sprintf("%q is out as %s; mark it published", p.Title, p.published))
+			}
+			continue
+		}
+		if p.Status == statusPublished {
+			done++
+			warnings = append(warnings, fmt.Sprintf("%q is marked published, but no published post carries its title", p.Title))
+			continue
+		}
+		if p.Draft != "" {
+			if _, err := os.Stat(p.Draft); err != nil {
+				warnings = append(warnings, fmt.Sprintf("%q names the draft %s, which doesn't exist", p.Title, p.Draft))
+			}
+		}
+		if p.Target == "" {
+			unscheduled++
+			continue
+		}
+		target, _ := time.Parse(time.DateOnly, p.Target)
+		switch {
+		case target.Before(today):
+			overdue = append(overdue, p)
+			warnings = append(warnings, fmt.Sprintf("%q was due %s and isn't published", p.Title, p.Target))
+		case !target.After(horizon):
+			upcoming = append(upcoming, p)
+		default:
+			later++
+		}
+	}
+
+	byTarget := func(a, b plannedIdea) int { return cmp.Compare(a.Target, b.Target) }
+	slices.SortStableFunc(overdue, byTarget)
+	slices.SortStableFunc(upcoming, byTarget)
+	for _, group := range []struct {
+		title string
+		ideas []plannedIdea
+	}{
+		{"Overdue", overdue},
+		{fmt.Sprintf("Due in the next %d days", days), upcoming},
+	} {
+		if len(group.ideas) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", group.title)
+		for _, p := range group.ideas {
+			draft := "no draft yet"
+			if p.draftFile != "" {
+				draft = p.draftFile
+			}
+			fmt.Fprintf(w, "  %s  %s (%s, %s)\n", p.Target, p.Title, p.Status, draft)
+		}
+	}
+	fmt.Fprintf(w, "%d later, %d unscheduled, %d published or dropped\n", later, unscheduled, done)
+	if len(unplanned) > 0 {
+		fmt.Fprintf(w, "Draft%s not on the calendar:\n", plural(len(unplanned)))
+		for _, file := range unplanned {
+			fmt.Fprintf(w, "  %s\n", file)
+		}
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// snapshot is the inventory of one git ref, checked out under dir.
+type snapshot struct {
+	dir string
+	inv inventory
+}
+
+// source returns postURL's file relative to the repository root, as git
+// names it.
+func (s snapshot) source(postURL string) string {
+	return strings.TrimPrefix(s.inv.posts[postURL], filepath.ToSlash(s.dir)+"/")
+}
+
+// body returns postURL's Markdown without its frontmatter, so that retags
+// and other metadata edits don't count as updates.
+func (s snapshot) body(postURL string) (string, error) {
+	raw, err := os.ReadFile(s.inv.posts[postURL])
+	if err != nil {
+		return "", err
+	}
+	_, body, _ := splitFrontmatter(string(raw))
+	return body, nil
+}
+
+// change is one post in a changelog. Date is the post's own date for a new
+// post and the last commit to it in the range for an updated one. For a
+// removed post, RedirectsTo is the post whose aliases now carry its URL,
+// or empty when the URL is gone for good.
+type change struct {
+	Path        string `json:"path"`
+	Canonical   string `json:"canonical"`
+	Title       string `json:"title"`
+	Section     string `json:"section"`
+	Date        string `json:"date,omitempty"`
+	RedirectsTo string `json:"redirects_to,omitempty"`
+}
+
+// changelog is what changed in content/ between two refs.
+type changelog struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	New     []change `json:"new"`
+	Updated []change `json:"updated"`
+	Removed []change `json:"removed"`
+}
+
+// diffSnapshots compares two snapshots. A post is matched by URL, so a post
+// whose old URL the new one carries as an alias moved rather than being
+// new: it is listed as removed, with the redirect. touched maps a
+// repository path to the date of its last commit in the range.
+func diffSnapshots(before, after snapshot, touched map[string]string) (changelog, error) {
+	var log changelog
+	entry := func(s snapshot, postURL string) change {
+		fm := s.inv.frontmatter[postURL]
+		return change{
+			Path:      postURL,
+			Canonical: siteURL + postURL,
+			Title:     fm.Title,
+			Section:   s.inv.sections[postURL],
+		}
+	}
+
+	for _, postURL := range slices.Sorted(maps.Keys(before.inv.posts)) {
+		if _, ok := after.inv.posts[postURL]; !ok {
+			c := entry(before, postURL)
+			c.RedirectsTo = after.inv.aliases[postURL]
+			log.Removed = append(log.Removed, c)
+			continue
+		}
+		old, err := before.body(postURL)
+		if err != nil {
+			return log, err
+		}
+		current, err := after.body(postURL)
+		if err != nil {
+			return log, err
+		}
+		if old != current {
+			c := entry(after, postURL)
+			c.Date = touched[after.source(postURL)]
+			log.Updated = append(log.Updated, c)
+		}
+	}
+
+	for _, postURL := range slices.Sorted(maps.Keys(after.inv.posts)) {
+		if _, ok := before.inv.posts[postURL]; ok {
+			continue
+		}
+		moved := slices.ContainsFunc(after.inv.frontmatter[postURL].Aliases, func(alias string) bool {
+			_, ok := before.inv.posts[normalizeLink(alias)]
+			return ok
+		})
+		if moved {
+			continue
+		}
+		c := entry(after, postURL)
+		c.Date = shortDate(after.inv.frontmatter[postURL].Date)
+		log.New = append(log.New, c)
+	}
+
+	// Newest first: that's the order a "recently updated" list wants.
+	for _, list := range [][]change{log.New, log.Updated} {
+		slices.SortStableFunc(list, func(a, b change) int { return strings.Compare(b.Date, a.Date) })
+	}
+	return log, nil
+}
+
+// writeChangelog writes log to w as Markdown, for a deploy commit message,
+// or as JSON, for a data file a "recently updated" page can range over.
+func writeChangelog(w io.Writer, log changelog, format string) error {
+	switch format {
+	case "json":
+		log.New, log.Updated, log.Removed = nonNilChanges(log.New), nonNilChanges(log.Updated), nonNilChanges(log.Removed)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(log)
+	case "markdown":
+	default:
+		return fmt.Errorf("unknown format %q; want markdown or json", format)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Content changes %s..%s\n", log.From, log.To)
+	if len(log.New)+len(log.Updated)+len(log.Removed) == 0 {
+		b.WriteString("\nNo posts changed.\n")
+	}
+	for _, group := range []struct {
+		title   string
+		changes []change
+	}{
+		{"New", log.New},
+		{"Updated", log.Updated},
+		{"Removed", log.Removed},
+	} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n\n", group.title, len(group.changes))
+		for _, c := range group.changes {
+			switch {
+			case group.title == "Removed" && c.RedirectsTo != "":
+				fmt.Fprintf(&b, "- %s (%s), redirects to %s\n", c.Title, c.Path, c.RedirectsTo)
+			case group.title == "Removed":
+				fmt.Fprintf(&b, "- %s (%s), no redirect: the URL now 404s\n", c.Title, c.Path)
+			case c.Date != "":
+				fmt.Fprintf(&b, "- [%s](%s), %s\n", c.Title, c.Canonical, c.Date)
+			default:
+				fmt.Fprintf(&b, "- [%s](%s)\n", c.Title, c.Canonical)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// changelogBetween builds the changelog of content/ from one ref to
+// another, checking each out into a temporary worktree so that the
+// working tree is left alone.
+func changelogBetween(from, to string) (changelog, error) {
+	var snapshots []snapshot
+	for _, ref := range []string{from, to} {
+		dir, cleanup, err := worktree(ref)
+		if err != nil {
+			return changelog{}, err
+		}
+		defer cleanup()
+		sections, patterns, err := loadSections(filepath.Join(dir, "config.yml"))
+		if err != nil {
+			return changelog{}, fmt.Errorf("%s: %w", ref, err)
+		}
+		inv, err := collectPosts(filepath.Join(dir, contentDir), sections, patterns)
+		if err != nil {
+			return changelog{}, fmt.Errorf("%s: %w", ref, err)
+		}
+		snapshots = append(snapshots, snapshot{dir: dir, inv: inv})
+	}
+
+	out, err := exec.Command("git", "log", "--format=%x00%cs", "--name-only", from+".."+to, "--", contentDir).Output()
+	if err != nil {
+		return changelog{}, fmt.Errorf("git log %s..%s: %w", from, to, err)
+	}
+	log, err := diffSnapshots(snapshots[0], snapshots[1], parseGitLog(out))
+	log.From, log.To = from, to
+	return log, err
+}
+
+// worktree checks ref out into a temporary git worktree and returns its
+// directory and the func that removes it.
+func worktree(ref string) (string, func(), error) {
+	parent, err := os.MkdirTemp("", "curation-")
+	if err != nil {
+		return "", nil, err
+	}
+	dir := filepath.Join(parent, "tree")
+	if out, err := exec.Command("git", "worktree", "add", "--detach", "--quiet", dir, ref).CombinedOutput(); err != nil {
+		os.RemoveAll(parent)
+		return "", nil, fmt.Errorf("git worktree add %s: %v: %s", ref, err, strings.TrimSpace(string(out)))
+	}
+	cleanup := func() {
+		exec.Command("git", "worktree", "remove", "--force", dir).Run()
+		os.RemoveAll(parent)
+	}
+	return dir, cleanup, nil
+}
+
+// parseGitLog reads `git log --format=%x00%cs --name-only` output, newest
+// commit first, and keeps the first date seen for each file.
+func parseGitLog(out []byte) map[string]string {
+	touched := map[string]string{}
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if date, ok := strings.CutPrefix(line, "\x00"); ok {
+			current = date
+			continue
+		}
+		if _, seen := touched[line]; line != "" && !seen {
+			touched[line] = current
+		}
+	}
+	return touched
+}
+
+// nonNilChanges keeps empty groups as [] rather than null in the JSON
+// output.
+func nonNilChanges(c []change) []change {
+	if c == nil {
+		return []change{}
+	}
+	return c
+}
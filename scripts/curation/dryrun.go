@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// plan is what -dry-run prints instead of running a command: every request
+// it would send, every file it would write and every outside service it
+// would talk to. What depends on answers a dry run never gets, like the
+// pages the dev.to API would point at, is described in notes.
+type plan struct {
+	requests []string
+	writes   []string
+	services []string
+	notes    []string
+}
+
+func (p *plan) request(format string, args ...any) {
+	p.requests = append(p.requests, fmt.Sprintf(format, args...))
+}
+
+func (p *plan) write(file string) {
+	if !slices.Contains(p.writes, file) {
+		p.writes = append(p.writes, file)
+	}
+}
+
+func (p *plan) service(format string, args ...any) {
+	p.services = append(p.services, fmt.Sprintf(format, args...))
+}
+
+func (p *plan) note(format string, args ...any) {
+	p.notes = append(p.notes, fmt.Sprintf(format, args...))
+}
+
+// print writes the plan to w, one section per kind of work.
+func (p *plan) print(w io.Writer) error {
+	var b strings.Builder
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"requests", p.requests},
+		{"files written", p.writes},
+		{"outside services", p.services},
+		{"notes", p.notes},
+	} {
+		if len(section.lines) == 0 {
+			if section.title != "notes" {
+				fmt.Fprintf(&b, "%s: none\n", section.title)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%d):\n", section.title, len(section.lines))
+		for _, line := range section.lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// planSyndication plans checkSyndication: one request to api per copy.
+// Each copy's own page is requested too, at the URL the API answers with.
+func planSyndication(api string, inv inventory) *plan {
+	p := &plan{}
+	for _, postURL := range slices.Sorted(maps.Keys(inv.frontmatter)) {
+		if id := inv.frontmatter[postURL].DevTo; id != 0 {
+			p.request("GET %s%d", api, id)
+		}
+	}
+	if n := len(p.requests); n > 0 {
+		p.service("dev.to: %d article%s from its API, then the cross-posted page%s", n, plural(n), plural(n))
+		p.note("each copy's page is requested at the URL the API returns for it")
+	}
+	return p
+}
+
+// planRedirects plans verifyRedirects: one request to base per alias.
+func planRedirects(base string, inv inventory) *plan {
+	p := &plan{}
+	for _, alias := range slices.Sorted(maps.Keys(inv.aliases)) {
+		p.request("GET %s%s, not following redirects", strings.TrimSuffix(base, "/"), alias)
+	}
+	return p
+}
+
+// planSitemaps plans writeSitemaps: the child sitemaps Hugo's sitemap in
+// public splits into, and the index of them.
+func planSitemaps(public string, inv inventory, limit int) (*plan, error) {
+	urls, err := readHugoSitemap(public)
+	if err != nil {
+		return nil, err
+	}
+	p := &plan{}
+	for _, child := range splitSitemap(urls, inv, limit) {
+		p.write(filepath.Join(public, filepath.FromSlash(child.file())))
+	}
+	p.write(filepath.Join(public, sitemapIndexFile))
+	p.note("%s is emptied first", filepath.Join(public, sitemapDir))
+	return p, nil
+}
+
+// planSitemapsVerify plans verifySitemaps, which requests the index from
+// base and then every sitemap the index lists.
+func planSitemapsVerify(base string) *plan {
+	p := &plan{}
+	p.request("GET %s/%s", strings.TrimSuffix(base, "/"), sitemapIndexFile)
+	p.note("every sitemap the deployed index lists is requested after it")
+	return p
+}
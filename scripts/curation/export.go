@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// urlRecord is one published post in the exported inventory. Canonical is
+// the absolute URL; Path and Aliases are site-relative, as redirects and
+// frontmatter write them.
+type urlRecord struct {
+	Canonical string   `json:"canonical"`
+	Path      string   `json:"path"`
+	Aliases   []string `json:"aliases"`
+	Source    string   `json:"source"`
+	Section   string   `json:"section"`
+	Title     string   `json:"title"`
+	Date      string   `json:"date"`
+	Tags      []string `json:"tags"`
+}
+
+// urlRecords lists every post in inv, sorted by path, then every tag term
+// page that carries aliases, so the redirects they serve are in the export
+// too.
+func urlRecords(inv inventory) []urlRecord {
+	aliases := map[string][]string{}
+	for alias, postURL := range inv.aliases {
+		aliases[postURL] = append(aliases[postURL], alias)
+	}
+	var records []urlRecord
+	for _, postURL := range slices.Sorted(maps.Keys(inv.posts)) {
+		fm := inv.frontmatter[postURL]
+		slices.Sort(aliases[postURL])
+		records = append(records, urlRecord{
+			Canonical: siteURL + postURL,
+			Path:      postURL,
+			Aliases:   nonNil(aliases[postURL]),
+			Source:    inv.posts[postURL],
+			Section:   inv.sections[postURL],
+			Title:     fm.Title,
+			Date:      shortDate(fm.Date),
+			Tags:      nonNil(fm.Tags),
+		})
+	}
+	for _, termURL := range slices.Sorted(maps.Keys(inv.terms)) {
+		page := inv.terms[termURL]
+		if len(page.Aliases) == 0 {
+			continue
+		}
+		termAliases := make([]string, len(page.Aliases))
+		for i, alias := range page.Aliases {
+			termAliases[i] = normalizeLink(alias)
+		}
+		slices.Sort(termAliases)
+		records = append(records, urlRecord{
+			Canonical: siteURL + termURL,
+			Path:      termURL,
+			Aliases:   termAliases,
+			Source:    page.Source,
+			Section:   strings.Trim(tagsPath, "/"),
+			Title:     page.Title,
+			Tags:      []string{},
+		})
+	}
+	return records
+}
+
+// exportURLs writes the inventory to w as a JSON array or as CSV with a
+// header row, where aliases and tags are joined with spaces and
+// semicolons respectively, since tags can contain spaces.
+func exportURLs(w io.Writer, inv inventory, format string) error {
+	records := urlRecords(inv)
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"canonical", "path", "aliases", "source", "section", "title", "date", "tags"})
+		for _, r := range records {
+			cw.Write([]string{r.Canonical, r.Path, strings.Join(r.Aliases, " "), r.Source, r.Section, r.Title, r.Date, strings.Join(r.Tags, ";")})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q; want json or csv", format)
+	}
+}
+
+// shortDate trims a frontmatter date to the day.
+func shortDate(date string) string {
+	if len(date) > len("2006-01-02") {
+		return date[:len("2006-01-02")]
+	}
+	return date
+}
+
+// nonNil keeps empty lists as [] rather than null in the JSON output.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
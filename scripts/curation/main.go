@@ -0,0 +1,846 @@
+// Command curation checks the hand-curated links in section _index.md files.
+//
+// Section indexes feature a handful of posts by linking to them from the
+// index body. Every internal link there must point at a published post's
+// canonical URL, not at an alias or a post that has since been deleted. A
+// section can also opt into full curation by setting `curated: true` in its
+// _index.md frontmatter; then every published post in the section must be
+// linked from the index or listed under `curated_exclude`.
+//
+// The inventory of posts the check builds is also what other tooling
+// wants, for analytics joins, redirect generation or search submission:
+//
+//	go run ./scripts/curation export urls [-format json|csv]
+//
+// writes every published post's canonical URL, aliases, source file,
+// title, date and tags to stdout. Going the other way,
+//
+//	go run ./scripts/curation import aliases [-apply] [-min 0.6] legacy.txt
+//
+// matches a list of old URLs, from a previous platform or the server logs,
+// against the posts and suggests which post should carry each as an alias;
+// -apply adds them to the frontmatter.
+//
+//	go run ./scripts/curation tags [-apply] [-min 0.6] [legacy.txt]
+//
+// finds tag pages that no published post's tags serve any more but that
+// posts, a term page left behind, or the legacy list still point at, and
+// suggests a redirect to the live tag they most resemble or, failing
+// that, a tombstone page; -apply writes them into content/tags/. Term
+// pages' aliases show up in export urls with the posts'. And
+//
+//	go run ./scripts/curation changelog [-format markdown|json] FROM [TO]
+//
+// lists the posts added, updated and removed between two git refs, TO
+// defaulting to HEAD, with whether each removed post's URL still
+// redirects somewhere: Markdown for a deploy commit message, JSON for a
+// "recently updated" data file.
+//
+//	go run ./scripts/curation syndication [-timeout 30s]
+//
+// checks the copies cross-posted to dev.to, those whose post records the
+// article id as `devto` in its frontmatter, and fails when a copy is gone
+// or when dev.to's API or the copy's rel=canonical link has dropped or
+// mangled the link back to the post. It is meant for a weekly cron job,
+// which mails the failure:
+//
+//	0 6 * * 1 cd ~/rednafi.com && make syndication
+//
+//	go run ./scripts/curation redirects [-base https://rednafi.com] [-edge]
+//
+// requests every alias, the posts' and the tag pages', from the live site
+// without following redirects, and fails unless each answers a permanent
+// redirect to the page that claims it. GitHub Pages can't redirect, so it
+// serves Hugo's meta-refresh alias page instead, which passes as long as
+// it points at the right page; -edge fails those too, for when a server
+// in front of the site is meant to redirect from its own map. CI runs it
+// after each deploy.
+//
+//	go run ./scripts/curation calendar [-file calendar.yml] [-days 30]
+//
+// plans from calendar.yml, a list of post ideas with target dates and
+// statuses: it lists the ideas overdue and those due in the next -days,
+// each with its draft, the file it names or the draft whose title matches,
+// and warns about targets that passed without a publish, ideas already
+// out but not marked published, and drafts no idea claims.
+//
+//	go run ./scripts/curation sitemaps [-public public] [-max 50000]
+//
+// splits the sitemap.xml Hugo built into one sitemap per section, by the
+// section each post belongs to, plus one for the pages that aren't posts,
+// and writes public/sitemap_index.xml listing them; a section past -max
+// URLs, the protocol's limit, is split into numbered parts. The build runs
+// it after Hugo. With -verify [-base https://rednafi.com], it instead
+// fetches the deployed index and every sitemap it lists, and fails on a
+// child that doesn't answer or parse, one over the protocol's limits, a
+// URL listed twice, or a published post missing from its section's
+// sitemaps. CI runs that after each deploy.
+//
+// Every command that writes files or sends requests takes -dry-run, which
+// prints the requests it would send, the files it would write and the
+// outside services it would call, and does none of it.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentDir = "content"
+	siteURL    = "https://rednafi.com"
+)
+
+type siteConfig struct {
+	Permalinks map[string]any `yaml:"permalinks"`
+	Params     struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+	} `yaml:"params"`
+}
+
+type indexFrontmatter struct {
+	Curated        bool     `yaml:"curated"`
+	CuratedExclude []string `yaml:"curated_exclude"`
+}
+
+type postFrontmatter struct {
+	Title   string   `yaml:"title"`
+	Slug    string   `yaml:"slug"`
+	Date    string   `yaml:"date"`
+	URL     string   `yaml:"url"`
+	Draft   bool     `yaml:"draft"`
+	Aliases []string `yaml:"aliases"`
+	Tags    []string `yaml:"tags"`
+	DevTo   int      `yaml:"devto"`
+	// RobotsNoIndex keeps the post out of the sitemap.
+	RobotsNoIndex bool `yaml:"robotsNoIndex"`
+}
+
+// inventory maps every published post URL to its source file, every alias
+// to the canonical URL it redirects to, and every page bundle resource's URL
+// to its file. sections records which section each post belongs to, since
+// a permalink pattern can serve a post from outside its section's path, and
+// frontmatter its effective frontmatter. drafts holds the effective
+// frontmatter of the drafts, by file. terms holds the tags' term pages,
+// by URL, once collectTerms has run.
+type inventory struct {
+	posts       map[string]string
+	sections    map[string]string
+	frontmatter map[string]postFrontmatter
+	aliases     map[string]string
+	resources   map[string]string
+	drafts      map[string]postFrontmatter
+	terms       map[string]termPage
+}
+
+func main() {
+	args := os.Args[1:]
+	var command string
+	if len(args) >= 2 {
+		command = args[0] + " " + args[1]
+	}
+	if len(args) > 0 && args[0] == "changelog" {
+		flags := flag.NewFlagSet("changelog", flag.ExitOnError)
+		format := flags.String("format", "markdown", "output format: markdown or json")
+		flags.Parse(args[1:])
+		if flags.NArg() < 1 || flags.NArg() > 2 {
+			fatal(errors.New("changelog: want a ref to start from and, optionally, one to end at"))
+		}
+		to := "HEAD"
+		if flags.NArg() == 2 {
+			to = flags.Arg(1)
+		}
+		log, err := changelogBetween(flags.Arg(0), to)
+		if err != nil {
+			fatal(err)
+		}
+		if err := writeChangelog(os.Stdout, log, *format); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if len(args) > 0 && (args[0] == "tags" || args[0] == "syndication" || args[0] == "redirects" || args[0] == "calendar" || args[0] == "sitemaps") {
+		command = args[0]
+	}
+	if len(args) > 0 && command != "export urls" && command != "import aliases" && command != "tags" && command != "syndication" && command != "redirects" && command != "calendar" && command != "sitemaps" {
+		fmt.Fprintln(os.Stderr, "usage: curation [export urls [-format json|csv] | import aliases [-apply] [-dry-run] [-min score] file | tags [-apply] [-dry-run] [-min score] [file] | changelog [-format markdown|json] from [to] | syndication [-timeout d] [-dry-run] | redirects [-base url] [-edge] [-timeout d] [-dry-run] | calendar [-file path] [-days n] | sitemaps [-public dir] [-max n] [-dry-run] | sitemaps -verify [-base url] [-timeout d] [-dry-run]]")
+		os.Exit(2)
+	}
+	sections, patterns, err := loadSections("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+
+	inv, err := collectPosts(contentDir, sections, patterns)
+	if err != nil {
+		fatal(err)
+	}
+	if err := collectTerms(contentDir, &inv); err != nil {
+		fatal(err)
+	}
+	switch command {
+	case "export urls":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		format := flags.String("format", "json", "output format: json or csv")
+		flags.Parse(args[2:])
+		if err := exportURLs(os.Stdout, inv, *format); err != nil {
+			fatal(err)
+		}
+		return
+	case "import aliases":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		apply := flags.Bool("apply", false, "add the suggested aliases to the posts' frontmatter")
+		minScore := flags.Float64("min", defaultMinScore, "lowest similarity, 0 to 1, that counts as a match")
+		dryRun := flags.Bool("dry-run", false, "list the posts -apply would write without writing them")
+		flags.Parse(args[2:])
+		if flags.NArg() != 1 {
+			fatal(errors.New("import aliases: want one file of legacy URLs"))
+		}
+		if err := importAliases(os.Stdout, inv, flags.Arg(0), *minScore, *apply, *dryRun); err != nil {
+			fatal(err)
+		}
+		return
+	case "tags":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		apply := flags.Bool("apply", false, "add the redirects and write the tombstone pages")
+		minScore := flags.Float64("min", defaultMinScore, "lowest similarity, 0 to 1, for redirecting to a live tag")
+		dryRun := flags.Bool("dry-run", false, "list the term pages -apply would write without writing them")
+		flags.Parse(args[1:])
+		var legacy []string
+		if flags.NArg() > 1 {
+			fatal(errors.New("tags: want at most one file of legacy URLs"))
+		}
+		if flags.NArg() == 1 {
+			raw, err := os.ReadFile(flags.Arg(0))
+			if err != nil {
+				fatal(err)
+			}
+			legacy = legacyPaths(string(raw))
+		}
+		dead, err := deadTags(contentDir, inv, legacy, *minScore)
+		if err != nil {
+			fatal(err)
+		}
+		if err := reportDeadTags(os.Stdout, contentDir, inv, dead, *apply, *dryRun); err != nil {
+			fatal(err)
+		}
+		return
+	case "syndication":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		timeout := flags.Duration("timeout", 30*time.Second, "per-request timeout")
+		dryRun := flags.Bool("dry-run", false, "list the requests the check would send without sending them")
+		flags.Parse(args[1:])
+		if *dryRun {
+			if err := planSyndication(devtoAPI, inv).print(os.Stdout); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		client := &http.Client{Timeout: *timeout}
+		problems, checked, err := checkSyndication(context.Background(), client, devtoAPI, inv)
+		if err != nil {
+			fatal(err)
+		}
+		if len(problems) > 0 {
+			fatal(fmt.Errorf("syndicated copies no longer claim the blog as canonical:\n  %s", strings.Join(problems, "\n  ")))
+		}
+		copies := "copies"
+		if checked == 1 {
+			copies = "copy"
+		}
+		fmt.Printf("%d syndicated %s checked, all canonical to the blog\n", checked, copies)
+		return
+	case "redirects":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		base := flags.String("base", siteURL, "site to request the aliases from")
+		edge := flags.Bool("edge", false, "require real HTTP redirects; fail aliases served by Hugo's meta-refresh page")
+		timeout := flags.Duration("timeout", 30*time.Second, "per-request timeout")
+		dryRun := flags.Bool("dry-run", false, "list the alias requests without sending them")
+		flags.Parse(args[1:])
+		if *dryRun {
+			if err := planRedirects(*base, inv).print(os.Stdout); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		client := &http.Client{Timeout: *timeout}
+		problems, stats, err := verifyRedirects(context.Background(), client, *base, inv, *edge)
+		if err != nil {
+			fatal(err)
+		}
+		if len(problems) > 0 {
+			fatal(fmt.Errorf("aliases don't redirect as they should on %s:\n  %s", *base, strings.Join(problems, "\n  ")))
+		}
+		fmt.Println(stats)
+		return
+	case "calendar":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		file := flags.String("file", defaultCalendar, "post ideas with target dates and statuses")
+		days := flags.Int("days", 30, "how far ahead to list the ideas coming due")
+		flags.Parse(args[1:])
+		ideas, err := loadCalendar(*file)
+		if err != nil {
+			fatal(err)
+		}
+		planned, unplanned := planCalendar(ideas, inv, defaultMinScore)
+		today, _ := time.Parse(time.DateOnly, time.Now().Format(time.DateOnly))
+		writeCalendar(os.Stdout, planned, unplanned, today, *days)
+		return
+	case "sitemaps":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		public := flags.String("public", "public", "built site holding Hugo's sitemap.xml")
+		limit := flags.Int("max", maxSitemapURLs, "most URLs one sitemap lists")
+		verify := flags.Bool("verify", false, "check the deployed sitemap index instead of writing one")
+		base := flags.String("base", siteURL, "-verify: site to request the sitemaps from")
+		timeout := flags.Duration("timeout", 30*time.Second, "-verify: per-request timeout")
+		dryRun := flags.Bool("dry-run", false, "list the files it would write, or with -verify the requests, and do neither")
+		flags.Parse(args[1:])
+		if !*verify {
+			if *limit < 1 || *limit > maxSitemapURLs {
+				fatal(fmt.Errorf("sitemaps: -max must be between 1 and %d", maxSitemapURLs))
+			}
+			if *dryRun {
+				p, err := planSitemaps(*public, inv, *limit)
+				if err == nil {
+					err = p.print(os.Stdout)
+				}
+				if err != nil {
+					fatal(err)
+				}
+				return
+			}
+			children, err := writeSitemaps(*public, inv, *limit)
+			if err != nil {
+				fatal(err)
+			}
+			writeSitemapSummary(os.Stdout, children)
+			return
+		}
+		if *dryRun {
+			if err := planSitemapsVerify(*base).print(os.Stdout); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		client := &http.Client{Timeout: *timeout}
+		problems, summary, err := verifySitemaps(context.Background(), client, *base, inv)
+		if err != nil {
+			fatal(err)
+		}
+		if len(problems) > 0 {
+			fatal(fmt.Errorf("the sitemap index on %s doesn't cover the site:\n  %s", *base, strings.Join(problems, "\n  ")))
+		}
+		fmt.Println(summary)
+		return
+	}
+
+	var problems []string
+	for _, section := range sections {
+		indexPath := filepath.Join(contentDir, section, "_index.md")
+		raw, err := os.ReadFile(indexPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			fatal(err)
+		}
+		found, err := checkIndex(filepath.ToSlash(indexPath), section, string(raw), inv)
+		if err != nil {
+			fatal(err)
+		}
+		problems = append(problems, found...)
+	}
+
+	if len(problems) > 0 {
+		fatal(fmt.Errorf("curated section indexes are out of date:\n  %s", strings.Join(problems, "\n  ")))
+	}
+}
+
+// checkIndex returns every dead curated link in one section index and, when
+// the section opts into full curation, every post it forgot.
+func checkIndex(indexPath, section, raw string, inv inventory) ([]string, error) {
+	fmRaw, body, _ := splitFrontmatter(raw)
+	var fm indexFrontmatter
+	if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+		return nil, fmt.Errorf("%s: parse frontmatter: %w", indexPath, err)
+	}
+
+	var problems []string
+	linked := map[string]bool{}
+	for _, link := range internalLinks(body) {
+		if isAsset(link) {
+			if _, ok := inv.resources[link]; !ok && isPostURL(link, inv) {
+				problems = append(problems, fmt.Sprintf("%s: links to %s, which no page bundle provides", indexPath, link))
+			}
+			continue
+		}
+		if _, ok := inv.posts[link]; ok {
+			linked[link] = true
+			continue
+		}
+		if canonical, ok := inv.aliases[link]; ok {
+			problems = append(problems, fmt.Sprintf("%s: links to alias %s; use %s", indexPath, link, canonical))
+			linked[canonical] = true
+			continue
+		}
+		if isPostURL(link, inv) {
+			problems = append(problems, fmt.Sprintf("%s: links to %s, which is not a published post", indexPath, link))
+		}
+	}
+
+	if !fm.Curated {
+		return problems, nil
+	}
+
+	excluded := map[string]bool{}
+	for _, link := range fm.CuratedExclude {
+		link = normalizeLink(link)
+		if _, ok := inv.posts[link]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: curated_exclude lists %s, which is not a published post", indexPath, link))
+		}
+		excluded[link] = true
+	}
+
+	var missing []string
+	for postURL := range inv.posts {
+		if inv.sections[postURL] == section && !linked[postURL] && !excluded[postURL] {
+			missing = append(missing, postURL)
+		}
+	}
+	slices.Sort(missing)
+	for _, postURL := range missing {
+		problems = append(problems, fmt.Sprintf("%s: %s is neither linked nor in curated_exclude", indexPath, postURL))
+	}
+	return problems, nil
+}
+
+// isPostURL reports whether link sits under a section that holds posts, so
+// links to pages like /about/ aren't mistaken for deleted posts.
+func isPostURL(link string, inv inventory) bool {
+	section, rest, _ := strings.Cut(strings.TrimPrefix(link, "/"), "/")
+	if rest == "" {
+		return false
+	}
+	for postURL := range inv.posts {
+		if strings.HasPrefix(postURL, "/"+section+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	inlineLinkPattern    = regexp.MustCompile(`\]\(([^)\s]+)`)
+	referenceLinkPattern = regexp.MustCompile(`(?m)^\s*\[[^\]]+\]:\s+(\S+)`)
+)
+
+// internalLinks returns the normalized site-internal targets of every inline
+// and reference-style Markdown link in body.
+func internalLinks(body string) []string {
+	var links []string
+	for _, pattern := range []*regexp.Regexp{inlineLinkPattern, referenceLinkPattern} {
+		for _, m := range pattern.FindAllStringSubmatch(body, -1) {
+			target := strings.TrimPrefix(m[1], siteURL)
+			if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+				continue
+			}
+			links = append(links, normalizeLink(target))
+		}
+	}
+	return links
+}
+
+func normalizeLink(link string) string {
+	link = strings.TrimPrefix(strings.TrimSpace(link), siteURL)
+	link, _, _ = strings.Cut(link, "#")
+	link, _, _ = strings.Cut(link, "?")
+	if !strings.HasSuffix(link, "/") && !isAsset(link) {
+		link += "/"
+	}
+	return link
+}
+
+// isAsset reports whether link names a file, like a bundle's image, rather
+// than a page.
+func isAsset(link string) bool {
+	return path.Ext(link) != "" && !strings.HasSuffix(link, "/")
+}
+
+// collectPosts finds every published post under the given sections, judged
+// by the effective frontmatter Hugo would see (see cascadeIndex). A post
+// is either a flat section/post.md file or a leaf bundle, section/post/index.md,
+// whose other files are the post's resources rather than pages of their own.
+// Branch bundles, directories with an _index.md, hold resources too, but
+// only directly. URLs follow the section's permalink pattern, or Hugo's
+// default without one: the post's directory, then its slug, which falls
+// back to the file or bundle name.
+func collectPosts(root string, sections []string, patterns permalinks) (inventory, error) {
+	inv := inventory{
+		posts:       map[string]string{},
+		sections:    map[string]string{},
+		frontmatter: map[string]postFrontmatter{},
+		aliases:     map[string]string{},
+		resources:   map[string]string{},
+		drafts:      map[string]postFrontmatter{},
+	}
+	var files []string
+	leaves, branches := map[string]bool{}, map[string]bool{}
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		section, rest, _ := strings.Cut(rel, "/")
+		if !slices.Contains(sections, section) || rest == "" {
+			return nil
+		}
+		switch path.Base(rel) {
+		case "index.md":
+			leaves[path.Dir(rel)] = true
+		case "_index.md":
+			branches[path.Dir(rel)] = true
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return inv, err
+	}
+
+	cascades := cascadeIndex{root: root, byDir: map[string][]cascade{}}
+	bundleURLs := map[string]string{}
+	for dir := range branches {
+		bundleURLs[dir] = "/" + dir + "/"
+	}
+	for _, rel := range files {
+		leaf := leafBundle(rel, leaves)
+		if path.Ext(rel) != ".md" || path.Base(rel) == "_index.md" || (leaf != "" && rel != leaf+"/index.md") {
+			continue
+		}
+		filePath := filepath.Join(root, filepath.FromSlash(rel))
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return inv, err
+		}
+		fmRaw, _, _ := splitFrontmatter(string(raw))
+		fm, err := cascades.effective(rel, fmRaw)
+		if err != nil {
+			return inv, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if fm.Draft {
+			inv.drafts[filepath.ToSlash(filePath)] = fm
+			continue
+		}
+
+		dir, name := path.Dir(rel), strings.TrimSuffix(path.Base(rel), ".md")
+		if leaf != "" {
+			dir, name = path.Dir(leaf), path.Base(leaf)
+		}
+		slug := strings.TrimSpace(fm.Slug)
+		if slug == "" {
+			slug = name
+		}
+		postURL, err := patterns.path(dir, name, slug, fm.Date)
+		if err != nil {
+			return inv, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if fm.URL != "" {
+			postURL = normalizeLink(fm.URL)
+		}
+		if leaf != "" {
+			bundleURLs[leaf] = postURL
+		}
+
+		inv.posts[postURL] = filepath.ToSlash(filePath)
+		inv.sections[postURL], _, _ = strings.Cut(rel, "/")
+		inv.frontmatter[postURL] = fm
+		for _, alias := range fm.Aliases {
+			inv.aliases[normalizeLink(alias)] = postURL
+		}
+	}
+
+	for _, rel := range files {
+		if path.Ext(rel) == ".md" {
+			continue
+		}
+		bundle := leafBundle(rel, leaves)
+		if bundle == "" && branches[path.Dir(rel)] {
+			bundle = path.Dir(rel)
+		}
+		if bundleURLs[bundle] == "" {
+			continue
+		}
+		resourceURL := bundleURLs[bundle] + strings.TrimPrefix(rel, bundle+"/")
+		inv.resources[resourceURL] = filepath.ToSlash(filepath.Join(root, filepath.FromSlash(rel)))
+	}
+	return inv, nil
+}
+
+// cascade is one entry of an _index.md cascade block: frontmatter values
+// Hugo pushes down to the pages below that match the target.
+type cascade struct {
+	path        string
+	kind        string
+	environment string
+	values      map[string]any
+}
+
+// cascadeIndex resolves a post's effective frontmatter from the _index.md
+// files above it, loading each one once.
+type cascadeIndex struct {
+	root  string
+	byDir map[string][]cascade
+}
+
+// effective merges the cascades of every ancestor _index.md, nearest last
+// so it wins, and then the post's own frontmatter on top, as Hugo does. A
+// section whose _index.md is itself a draft drafts everything below it.
+func (c *cascadeIndex) effective(rel, fmRaw string) (postFrontmatter, error) {
+	var dirs []string
+	for dir := path.Dir(rel); ; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == "." {
+			break
+		}
+	}
+	pagePath := "/" + strings.TrimSuffix(strings.TrimSuffix(rel, ".md"), "/index")
+
+	merged := map[string]any{}
+	for _, dir := range slices.Backward(dirs) {
+		entries, err := c.load(dir)
+		if err != nil {
+			return postFrontmatter{}, err
+		}
+		for _, entry := range entries {
+			if entry.matches(pagePath) {
+				maps.Copy(merged, entry.values)
+			}
+		}
+	}
+	var own map[string]any
+	if err := yaml.Unmarshal([]byte(fmRaw), &own); err != nil {
+		return postFrontmatter{}, fmt.Errorf("parse frontmatter: %w", err)
+	}
+	maps.Copy(merged, own)
+
+	var fm postFrontmatter
+	data, err := yaml.Marshal(merged)
+	if err == nil {
+		err = yaml.Unmarshal(data, &fm)
+	}
+	if err != nil {
+		return postFrontmatter{}, fmt.Errorf("effective frontmatter: %w", err)
+	}
+	return fm, nil
+}
+
+func (c *cascadeIndex) load(dir string) ([]cascade, error) {
+	if entries, ok := c.byDir[dir]; ok {
+		return entries, nil
+	}
+	indexPath := filepath.Join(c.root, filepath.FromSlash(dir), "_index.md")
+	raw, err := os.ReadFile(indexPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	fmRaw, _, _ := splitFrontmatter(string(raw))
+	var fm map[string]any
+	if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+		return nil, fmt.Errorf("%s: parse frontmatter: %w", indexPath, err)
+	}
+	entries := parseCascade(fm["cascade"])
+	if draft, _ := fm["draft"].(bool); draft {
+		entries = append(entries, cascade{values: map[string]any{"draft": true}})
+	}
+	c.byDir[dir] = entries
+	return entries, nil
+}
+
+// parseCascade reads both forms Hugo accepts: a single map, or a list of
+// maps, each optionally scoped by _target (or target) to a path glob, page
+// kind and environment.
+func parseCascade(v any) []cascade {
+	var raw []map[string]any
+	switch v := v.(type) {
+	case map[string]any:
+		raw = append(raw, v)
+	case []any:
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				raw = append(raw, m)
+			}
+		}
+	}
+	var entries []cascade
+	for _, m := range raw {
+		entry := cascade{values: map[string]any{}}
+		for key, value := range m {
+			if key != "_target" && key != "target" {
+				entry.values[key] = value
+				continue
+			}
+			target, _ := value.(map[string]any)
+			entry.path, _ = target["path"].(string)
+			entry.kind, _ = target["kind"].(string)
+			entry.environment, _ = target["environment"].(string)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// matches reports whether the entry applies to the regular page at
+// pagePath in a production build.
+func (c cascade) matches(pagePath string) bool {
+	if c.kind != "" && c.kind != "page" {
+		return false
+	}
+	if c.environment != "" && !globMatch(c.environment, "production") {
+		return false
+	}
+	return c.path == "" || globMatch(strings.ToLower(c.path), strings.ToLower(pagePath))
+}
+
+// globMatch matches Hugo's target globs, where * stays within one path
+// segment and ** crosses them.
+func globMatch(pattern, name string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(name)
+}
+
+// leafBundle returns the leaf bundle directory that holds rel, if any.
+func leafBundle(rel string, leaves map[string]bool) string {
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		if leaves[dir] {
+			return dir
+		}
+	}
+	return ""
+}
+
+// permalinks maps a section to the Hugo permalink pattern its pages use,
+// from the permalinks block of config.yml.
+type permalinks map[string]string
+
+var permalinkToken = regexp.MustCompile(`:[a-z]+`)
+
+// path expands the permalink pattern of the section dir sits in for a post
+// named name. Without a pattern, the post lives under dir.
+func (p permalinks) path(dir, name, slug, date string) (string, error) {
+	section, _, _ := strings.Cut(dir, "/")
+	pattern, ok := p[section]
+	if !ok {
+		return "/" + dir + "/" + slug + "/", nil
+	}
+	var problem string
+	expanded := permalinkToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		switch token {
+		case ":year", ":month", ":day":
+			if len(date) < len("2006-01-02") {
+				problem = token + " needs a date"
+				return ""
+			}
+			return map[string]string{":year": date[:4], ":month": date[5:7], ":day": date[8:10]}[token]
+		case ":section":
+			return section
+		case ":sections":
+			return dir
+		case ":slug", ":slugorfilename", ":slugorcontentbasename":
+			return slug
+		case ":filename", ":contentbasename":
+			return name
+		}
+		problem = "unsupported token " + token
+		return ""
+	})
+	if problem != "" {
+		return "", fmt.Errorf("permalink %q: %s", pattern, problem)
+	}
+	return "/" + strings.TrimLeft(expanded, "/"), nil
+}
+
+func loadSections(configPath string) ([]string, permalinks, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	sections := slices.Clone(config.Params.MainSections)
+	if config.Params.NotesSection != "" {
+		sections = append(sections, config.Params.NotesSection)
+	}
+
+	// Hugo takes page patterns under a page key, or a flat map from its
+	// older releases; section, term and taxonomy patterns don't move posts.
+	patterns := permalinks{}
+	pages := config.Permalinks
+	if nested, ok := pages["page"].(map[string]any); ok {
+		pages = nested
+	}
+	for section, v := range pages {
+		switch pattern := v.(type) {
+		case string:
+			patterns[section] = pattern
+		case map[string]any:
+		default:
+			return nil, nil, fmt.Errorf("%s: permalinks.%s: want a pattern, got %v", configPath, section, v)
+		}
+	}
+	return sections, patterns, nil
+}
+
+func splitFrontmatter(raw string) (frontmatter, body string, ok bool) {
+	const start = "---\n"
+	if !strings.HasPrefix(raw, start) {
+		return "", raw, false
+	}
+	idx := strings.Index(raw[len(start):], "\n---\n")
+	if idx == -1 {
+		return "", raw, false
+	}
+	fmEnd := len(start) + idx
+	return raw[len(start):fmEnd], raw[fmEnd+len("\n---\n"):], true
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "curation:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,781 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"rednafi.com/internal/webtest"
+)
+
+func TestInternalLinks(t *testing.T) {
+	body := `**Featured:** [One](/go/one/) · [Two](https://rednafi.com/go/two#intro) ·
+[External](https://go.dev/) · [Three][3]
+
+[3]:
+    /go/three
+`
+	got := internalLinks(body)
+	want := []string{"/go/one/", "/go/two/", "/go/three/"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("internalLinks = %q, want %q", got, want)
+	}
+}
+
+func TestCheckIndexFlagsDeadAndAliasedLinks(t *testing.T) {
+	inv := testInventory(t)
+	raw := "---\ntitle: Go\n---\n[Live](/go/live/) · [Moved](/go/old-name/) · [Gone](/go/deleted/) · [About](/about/)\n"
+
+	got, err := checkIndex("content/go/_index.md", "go", raw, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"content/go/_index.md: links to alias /go/old-name/; use /go/renamed/",
+		"content/go/_index.md: links to /go/deleted/, which is not a published post",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("checkIndex =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestCheckIndexRequiresCoverageWhenCurated(t *testing.T) {
+	inv := testInventory(t)
+	raw := `---
+title: Go
+curated: true
+curated_exclude:
+    - /go/renamed/
+    - /go/deleted/
+---
+[Live](/go/live/)
+`
+
+	got, err := checkIndex("content/go/_index.md", "go", raw, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"content/go/_index.md: curated_exclude lists /go/deleted/, which is not a published post",
+		"content/go/_index.md: /go/unlinked/ is neither linked nor in curated_exclude",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("checkIndex =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestCollectPostsFromPageBundles(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "flat.md"), "---\nslug: flat\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "bundled_post", "index.md"), "---\ntitle: Bundled\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "bundled_post", "diagram.png"), "png")
+	mustWrite(t, filepath.Join(root, "go", "bundled_post", "img", "wide.png"), "png")
+	mustWrite(t, filepath.Join(root, "go", "bundled_post", "snippet.md"), "a resource, not a post")
+	mustWrite(t, filepath.Join(root, "go", "renamed_bundle", "index.md"), "---\nslug: pretty\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "series", "_index.md"), "---\ntitle: Series\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "series", "cover.png"), "png")
+	mustWrite(t, filepath.Join(root, "go", "series", "part_one.md"), "---\nslug: part-one\n---\n")
+	mustWrite(t, filepath.Join(root, "shards", "2026", "03", "note", "index.md"), "---\nslug: note\n---\n")
+
+	inv, err := collectPosts(root, []string{"go", "shards"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := slices.Sorted(maps.Keys(inv.posts))
+	wantPosts := []string{"/go/bundled_post/", "/go/flat/", "/go/pretty/", "/go/series/part-one/", "/shards/2026/03/note/"}
+	if !slices.Equal(posts, wantPosts) {
+		t.Fatalf("posts = %q, want %q", posts, wantPosts)
+	}
+	resources := slices.Sorted(maps.Keys(inv.resources))
+	wantResources := []string{"/go/bundled_post/diagram.png", "/go/bundled_post/img/wide.png", "/go/series/cover.png"}
+	if !slices.Equal(resources, wantResources) {
+		t.Fatalf("resources = %q, want %q", resources, wantResources)
+	}
+
+	raw := "[Diagram](/go/bundled_post/diagram.png) · [Missing](/go/pretty/missing.png) · [Post](/go/pretty/)\n"
+	got, err := checkIndex("content/go/_index.md", "go", raw, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"content/go/_index.md: links to /go/pretty/missing.png, which no page bundle provides"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("checkIndex = %q, want %q", got, want)
+	}
+}
+
+func TestCollectPostsHonorsCascade(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "_index.md"), "---\ncascade:\n    - _target:\n          path: /go/wip/**\n      draft: true\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "_index.md"), "---\ntitle: Go\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "live.md"), "---\nslug: live\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "moved.md"), "---\nslug: moved\nurl: /go/elsewhere\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "wip", "_index.md"), "---\ntitle: WIP\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "wip", "idea.md"), "---\nslug: idea\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "wip", "ready.md"), "---\nslug: ready\ndraft: false\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "hidden", "_index.md"), "---\ndraft: true\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "hidden", "post.md"), "---\nslug: post\n---\n")
+	mustWrite(t, filepath.Join(root, "python", "_index.md"), "---\ncascade:\n    draft: true\n---\n")
+	mustWrite(t, filepath.Join(root, "python", "old.md"), "---\nslug: old\n---\n")
+
+	inv, err := collectPosts(root, []string{"go", "python"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Sorted(maps.Keys(inv.posts))
+	want := []string{"/go/elsewhere/", "/go/live/", "/go/wip/ready/"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("posts = %q, want %q", got, want)
+	}
+}
+
+func testInventory(t *testing.T) inventory {
+	t.Helper()
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "live.md"), "---\nslug: live\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "unlinked.md"), "---\nslug: unlinked\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "renamed.md"), "---\nslug: renamed\naliases:\n    - /go/old-name/\n---\n")
+	mustWrite(t, filepath.Join(root, "python", "other.md"), "---\nslug: other\n---\n")
+
+	inv, err := collectPosts(root, []string{"go", "python"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inv
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectPostsFollowsPermalinks(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "flat.md"), "---\nslug: flat\ndate: 2026-03-01\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "bundled_post", "index.md"), "---\ndate: 2025-11-20\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "bundled_post", "diagram.png"), "png")
+	mustWrite(t, filepath.Join(root, "python", "gil.md"), "---\nslug: gil\n---\n")
+
+	inv, err := collectPosts(root, []string{"go", "python"}, permalinks{"go": "/:year/:month/:slug/", "python": "/:filename/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := slices.Sorted(maps.Keys(inv.posts))
+	wantPosts := []string{"/2025/11/bundled_post/", "/2026/03/flat/", "/gil/"}
+	if !slices.Equal(posts, wantPosts) {
+		t.Fatalf("posts = %q, want %q", posts, wantPosts)
+	}
+	if _, ok := inv.resources["/2025/11/bundled_post/diagram.png"]; !ok {
+		t.Fatalf("resources = %q, want the bundle's diagram under its permalink", slices.Sorted(maps.Keys(inv.resources)))
+	}
+
+	got, err := checkIndex("content/go/_index.md", "go", "---\ncurated: true\n---\n[Flat](/2026/03/flat/)\n", inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"content/go/_index.md: /2025/11/bundled_post/ is neither linked nor in curated_exclude"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("checkIndex = %q, want %q", got, want)
+	}
+
+	mustWrite(t, filepath.Join(root, "python", "undated.md"), "---\nslug: undated\n---\n")
+	if _, err := collectPosts(root, []string{"python"}, permalinks{"python": "/:year/:slug/"}); err == nil || !strings.Contains(err.Error(), ":year needs a date") {
+		t.Fatalf("err = %v, want a missing date", err)
+	}
+}
+
+func TestExportURLs(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "renamed.md"), `---
+title: "Renamed, twice"
+slug: renamed
+date: 2026-03-01
+tags: [Go, "Error Handling"]
+aliases: [/go/old-name/, /go/older-name]
+---
+`)
+	mustWrite(t, filepath.Join(root, "python", "gil.md"), "---\ntitle: GIL\nslug: gil\n---\n")
+	inv, err := collectPosts(root, []string{"go", "python"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var csvOut strings.Builder
+	if err := exportURLs(&csvOut, inv, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	want := `canonical,path,aliases,source,section,title,date,tags
+https://rednafi.com/go/renamed/,/go/renamed/,/go/old-name/ /go/older-name/,` + filepath.ToSlash(filepath.Join(root, "go", "renamed.md")) + `,go,"Renamed, twice",2026-03-01,Go;Error Handling
+https://rednafi.com/python/gil/,/python/gil/,,` + filepath.ToSlash(filepath.Join(root, "python", "gil.md")) + `,python,GIL,,
+`
+	if csvOut.String() != want {
+		t.Fatalf("csv =\n%s\nwant\n%s", csvOut.String(), want)
+	}
+
+	var jsonOut strings.Builder
+	if err := exportURLs(&jsonOut, inv, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(jsonOut.String(), `"aliases": [],`) || !strings.Contains(jsonOut.String(), `"tags": []`) {
+		t.Fatalf("json should write empty lists as []:\n%s", jsonOut.String())
+	}
+	if err := exportURLs(&jsonOut, inv, "xml"); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
+
+func TestLegacyPaths(t *testing.T) {
+	list := `# exported from the old platform
+https://old.example.com/2019/03/retry-backoff.html#comments
+/blog/retry-backoff.html
+
+127.0.0.1 - - [10/Oct/2023:13:55:36 +0000] "GET /?p=123 HTTP/1.1" 404 0
+`
+	got := legacyPaths(list)
+	want := []string{"/2019/03/retry-backoff.html", "/blog/retry-backoff.html", "/?p=123"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("legacyPaths = %q, want %q", got, want)
+	}
+}
+
+func TestSuggestAliases(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "retry.md"), "---\ntitle: Retrying with exponential backoff\nslug: retry-backoff\naliases: [/go/old-retry/]\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "context_keys.md"), "---\ntitle: Context keys\nslug: context-keys\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "context_tips.md"), "---\ntitle: Context tips\nslug: context-tips\n---\n")
+	inv, err := collectPosts(root, []string{"go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := suggestAliases(inv, []string{
+		"/2019/03/retrying-with-exponential-backof.html",
+		"/blog/context_key/",
+		"/blog/context/",
+		"/go/retry-backoff/",
+		"/go/old-retry/",
+		"/2020/01/",
+		"/?p=123",
+		"/about-me/",
+	}, defaultMinScore)
+	var lines []string
+	for _, s := range got {
+		if s.Problem != "" {
+			lines = append(lines, s.Legacy+": "+s.Problem)
+		} else {
+			lines = append(lines, s.Legacy+" -> "+s.Post)
+		}
+	}
+	want := []string{
+		"/2019/03/retrying-with-exponential-backof.html -> /go/retry-backoff/",
+		"/blog/context_key/ -> /go/context-keys/",
+		"/blog/context/: fits /go/context-keys/ and /go/context-tips/ about equally (0.71, 0.71)",
+		"/2020/01/: has no words to match a post on",
+		"/?p=123: has a query string, which a static alias can't match",
+		"/about-me/: no post scores 0.60 or more; closest is /go/context-keys/ at 0.11",
+	}
+	if !slices.Equal(lines, want) {
+		t.Fatalf("suggestAliases =\n  %s\nwant\n  %s", strings.Join(lines, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestAddAliases(t *testing.T) {
+	for _, tc := range []struct {
+		name, fm, want string
+	}{
+		{"empty list", "title: Retry\naliases: []\nmermaid: false\n", "title: Retry\naliases:\n    - /old/retry.html\nmermaid: false\n"},
+		{"block list", "aliases:\n    - /go/old-retry/\n    - /old/retry.html\ntags: []\n", "aliases:\n    - /go/old-retry/\n    - /old/retry.html\ntags: []\n"},
+		{"no key", "title: Retry\n", "title: Retry\naliases:\n    - /old/retry.html\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "retry.md")
+			mustWrite(t, path, "---\n"+tc.fm+"---\nBody.\n")
+			if err := addAliases(path, []string{"/old/retry.html"}); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "---\n" + tc.want + "---\nBody.\n"; string(raw) != want {
+				t.Fatalf("file =\n%s\nwant\n%s", raw, want)
+			}
+		})
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before, after := t.TempDir(), t.TempDir()
+	mustWrite(t, filepath.Join(before, "content", "go", "kept.md"), "---\ntitle: Kept\n---\nSame.\n")
+	mustWrite(t, filepath.Join(before, "content", "go", "retagged.md"), "---\ntitle: Retagged\ntags: [go]\n---\nSame.\n")
+	mustWrite(t, filepath.Join(before, "content", "go", "edited.md"), "---\ntitle: Edited\n---\nFirst draft.\n")
+	mustWrite(t, filepath.Join(before, "content", "go", "old-name.md"), "---\ntitle: Old name\n---\nBody.\n")
+	mustWrite(t, filepath.Join(before, "content", "go", "gone.md"), "---\ntitle: Gone\n---\nBody.\n")
+
+	mustWrite(t, filepath.Join(after, "content", "go", "kept.md"), "---\ntitle: Kept\n---\nSame.\n")
+	mustWrite(t, filepath.Join(after, "content", "go", "retagged.md"), "---\ntitle: Retagged\ntags: [go, testing]\n---\nSame.\n")
+	mustWrite(t, filepath.Join(after, "content", "go", "edited.md"), "---\ntitle: Edited\n---\nSecond draft.\n")
+	mustWrite(t, filepath.Join(after, "content", "go", "new-name.md"), "---\ntitle: New name\naliases: [/go/old-name/]\n---\nBody.\n")
+	mustWrite(t, filepath.Join(after, "content", "go", "fresh.md"), "---\ntitle: Fresh\ndate: 2026-10-01T09:00:00Z\n---\nBody.\n")
+
+	var snapshots []snapshot
+	for _, dir := range []string{before, after} {
+		inv, err := collectPosts(filepath.Join(dir, "content"), []string{"go"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshots = append(snapshots, snapshot{dir: dir, inv: inv})
+	}
+	log, err := diffSnapshots(snapshots[0], snapshots[1], map[string]string{"content/go/edited.md": "2026-10-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.From, log.To = "v1", "v2"
+
+	var out strings.Builder
+	if err := writeChangelog(&out, log, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	want := `Content changes v1..v2
+
+New (1):
+
+- [Fresh](https://rednafi.com/go/fresh/), 2026-10-01
+
+Updated (1):
+
+- [Edited](https://rednafi.com/go/edited/), 2026-10-12
+
+Removed (2):
+
+- Gone (/go/gone/), no redirect: the URL now 404s
+- Old name (/go/old-name/), redirects to /go/new-name/
+`
+	if out.String() != want {
+		t.Fatalf("changelog =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := writeChangelog(&out, changelog{From: "v2", To: "v2"}, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"removed": []`) {
+		t.Fatalf("json should write empty groups as []:\n%s", out.String())
+	}
+}
+
+func TestParseGitLogKeepsTheLatestDate(t *testing.T) {
+	out := "\x002026-10-12\n\ncontent/go/a.md\n\x002026-09-01\n\ncontent/go/a.md\ncontent/go/b.md\n"
+	got := parseGitLog([]byte(out))
+	if got["content/go/a.md"] != "2026-10-12" || got["content/go/b.md"] != "2026-09-01" {
+		t.Fatalf("touched = %v", got)
+	}
+}
+
+func TestDeadTags(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "a.md"), "---\ntitle: A\ntags: [Go, Testing]\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "b.md"), `---
+title: B
+tags: [Go]
+---
+More in [tests](/tags/tests/), [k8s](https://rednafi.com/tags/kubernetes/) and [Go](/tags/go/).
+`)
+	mustWrite(t, filepath.Join(root, "tags", "retired", "_index.md"), "---\ntitle: Retired\ntombstone: true\n---\n")
+	mustWrite(t, filepath.Join(root, "tags", "old-stuff", "_index.md"), "---\ntitle: Old stuff\n---\n")
+	collect := func() inventory {
+		t.Helper()
+		inv, err := collectPosts(root, []string{"go"}, nil)
+		if err == nil {
+			err = collectTerms(root, &inv)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inv
+	}
+
+	inv := collect()
+	dead, err := deadTags(root, inv, []string{"/tags/retired/", "/tags/kubernetes?page=2"}, defaultMinScore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := reportDeadTags(&out, root, inv, dead, true, false); err != nil {
+		t.Fatal(err)
+	}
+	b := filepath.ToSlash(filepath.Join(root, "go", "b.md"))
+	want := `/tags/kubernetes/: keep a tombstone page
+  linked from ` + b + `
+/tags/old-stuff/: keep a tombstone page
+  ` + filepath.ToSlash(filepath.Join(root, "tags", "old-stuff", "_index.md")) + ` still renders it, with no posts
+/tags/tests/: redirect to /tags/testing/ (0.60)
+  linked from ` + b + `
+`
+	if out.String() != want {
+		t.Fatalf("report =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(root, "tags", "testing", "_index.md"))
+	if err != nil || string(raw) != "---\ntitle: Testing\naliases:\n    - /tags/tests/\n---\n" {
+		t.Fatalf("testing term page = %q, %v", raw, err)
+	}
+	raw, _ = os.ReadFile(filepath.Join(root, "tags", "old-stuff", "_index.md"))
+	if !strings.Contains(string(raw), "title: Old stuff\n") || !strings.Contains(string(raw), "tombstone: true\n") {
+		t.Fatalf("old-stuff wasn't made a tombstone:\n%s", raw)
+	}
+
+	inv = collect()
+	if dead, _ := deadTags(root, inv, nil, defaultMinScore); len(dead) != 0 {
+		t.Fatalf("after -apply, still dead: %+v", dead)
+	}
+	records := urlRecords(inv)
+	last := records[len(records)-1]
+	if last.Path != "/tags/testing/" || !slices.Equal(last.Aliases, []string{"/tags/tests/"}) || last.Section != "tags" {
+		t.Fatalf("export lacks the tag redirect: %+v", last)
+	}
+}
+
+func TestDryRunsWriteAndRequestNothing(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "a.md"), "---\ntitle: A\ntags: [Testing]\naliases: [/old/a/]\n---\nMore in [tests](/tags/tests/).\n")
+	inv, err := collectPosts(root, []string{"go"}, nil)
+	if err == nil {
+		err = collectTerms(root, &inv)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dead, err := deadTags(root, inv, nil, defaultMinScore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := reportDeadTags(&out, root, inv, dead, true, true); err != nil {
+		t.Fatal(err)
+	}
+	term := filepath.Join(root, "tags", "testing", "_index.md")
+	if want := "files written (1):\n  " + term + "\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("tags -dry-run printed\n%s\nwant it to list %s", out.String(), term)
+	}
+	if _, err := os.Stat(term); !os.IsNotExist(err) {
+		t.Errorf("tags -dry-run wrote %s: %v", term, err)
+	}
+
+	out.Reset()
+	if err := planRedirects("https://rednafi.com/", inv).print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "requests (1):\n  GET https://rednafi.com/old/a/, not following redirects\n"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("redirects -dry-run printed\n%s\nwant it to start with\n%s", out.String(), want)
+	}
+}
+
+func TestTermSlug(t *testing.T) {
+	for tag, want := range map[string]string{
+		"Go":             "go",
+		"Error Handling": "error-handling",
+		"C++":            "c++",
+		"TIL!":           "til",
+	} {
+		if got := termSlug(tag); got != want {
+			t.Errorf("termSlug(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestCheckSyndication(t *testing.T) {
+	root := t.TempDir()
+	for i, post := range []string{"kept", "dropped", "mangled", "aliased", "gone"} {
+		mustWrite(t, filepath.Join(root, "go", post+".md"), fmt.Sprintf("---\nslug: %s\ndevto: %d\n---\n", post, i+1))
+	}
+	mustWrite(t, filepath.Join(root, "go", "renamed.md"), "---\nslug: renamed\naliases:\n    - /go/old-name/\n---\n")
+	mustWrite(t, filepath.Join(root, "go", "local.md"), "---\nslug: local\n---\n")
+	inv, err := collectPosts(root, []string{"go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.aliases["/go/aliased-before/"] = "/go/aliased/"
+
+	pages := map[string]string{}
+	devto := webtest.Site(t, pages)
+	copyOf := func(id, canonical, pageCanonical string) {
+		copyPath := "/rednafi/post-" + id
+		pages["/api/articles/"+id] = `{"url": "` + devto.URL + copyPath + `", "canonical_url": "` + canonical + `"}`
+		pages[copyPath] = webtest.Page("post", "") + `<link href="` + pageCanonical + `" rel="canonical">`
+	}
+	copyOf("1", siteURL+"/go/kept/", siteURL+"/go/kept/")
+	copyOf("2", devto.URL+"/rednafi/post-2", devto.URL+"/rednafi/post-2")
+	copyOf("3", siteURL+"/go/mangled/", "http://www.rednafi.com/go/mangled?utm_source=devto")
+	copyOf("4", siteURL+"/go/aliased-before/", siteURL+"/go/aliased/")
+
+	problems, checked, err := checkSyndication(context.Background(), devto.Client(), devto.URL+"/api/articles/", inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 5 {
+		t.Errorf("checked %d copies, want 5", checked)
+	}
+	source := filepath.ToSlash(root) + "/go/"
+	want := []string{
+		source + "aliased.md: dev.to article 4 claims https://rednafi.com/go/aliased-before/, an alias that redirects to https://rednafi.com/go/aliased/",
+		source + "dropped.md: dev.to article 2 dropped the canonical link to https://rednafi.com/go/dropped/",
+		source + "dropped.md: " + devto.URL + "/rednafi/post-2's rel=canonical dropped the canonical link to https://rednafi.com/go/dropped/",
+		source + "gone.md: dev.to article 5 is gone",
+		source + "mangled.md: " + devto.URL + "/rednafi/post-3's rel=canonical mangled the canonical link to https://rednafi.com/go/mangled/ into http://www.rednafi.com/go/mangled?utm_source=devto",
+	}
+	if !slices.Equal(problems, want) {
+		t.Fatalf("checkSyndication =\n  %s\nwant\n  %s", strings.Join(problems, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestPageCanonical(t *testing.T) {
+	for page, want := range map[string]string{
+		`<link rel="canonical" href="https://rednafi.com/go/a/">`:                                         "https://rednafi.com/go/a/",
+		`<LINK HREF='https://rednafi.com/go/a/?x=1&amp;y=2' REL='Canonical'>`:                             "https://rednafi.com/go/a/?x=1&y=2",
+		`<link rel="alternate" href="https://dev.to/feed"><link rel=canonical href=https://rednafi.com/>`: "https://rednafi.com/",
+		`<link rel="stylesheet" href="/main.css">`:                                                        "",
+	} {
+		if got := pageCanonical(page); got != want {
+			t.Errorf("pageCanonical(%q) = %q, want %q", page, got, want)
+		}
+	}
+}
+
+func TestVerifyRedirects(t *testing.T) {
+	inv := inventory{aliases: map[string]string{
+		"/go/hugo-page/": "/go/post/",
+		"/go/edge/":      "/go/post/",
+		"/go/temporary/": "/go/post/",
+		"/go/elsewhere/": "/go/post/",
+		"/go/missing/":   "/go/post/",
+		"/go/squatted/":  "/go/post/",
+	}}
+	aliasPage := func(target string) string {
+		return `<!doctype html><html lang="en"><head><title>` + siteURL + target + `</title><link rel="canonical" href="` + siteURL + target + `"><meta charset="utf-8"><meta name="robots" content="noindex, follow"><meta http-equiv="refresh" content="0; url=` + target + `"></head></html>`
+	}
+	site := webtest.Site(t, map[string]string{
+		"/go/hugo-page/": aliasPage("/go/post/"),
+		"/go/elsewhere/": aliasPage("/go/other/"),
+		"/go/squatted/":  webtest.Page("Squatter", "<p>A page of its own.</p>"),
+	})
+	edge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/go/edge/":
+			http.Redirect(w, r, siteURL+"/go/post/", http.StatusMovedPermanently)
+		case "/go/temporary/":
+			http.Redirect(w, r, "/go/post/", http.StatusFound)
+		default:
+			site.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+	defer edge.Close()
+
+	problems, stats, err := verifyRedirects(context.Background(), edge.Client(), edge.URL, inv, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/go/elsewhere/: refreshes to " + edge.URL + "/go/other/; want /go/post/",
+		"/go/missing/: answers HTTP 404; want a redirect to /go/post/",
+		"/go/squatted/: answers HTTP 200 without redirecting; want /go/post/",
+		"/go/temporary/: answers HTTP 302, a temporary redirect; want 301 to /go/post/",
+	}
+	if !slices.Equal(problems, want) {
+		t.Fatalf("verifyRedirects =\n  %s\nwant\n  %s", strings.Join(problems, "\n  "), strings.Join(want, "\n  "))
+	}
+	if stats != (redirectStats{edge: 1, page: 1}) {
+		t.Errorf("stats = %+v, want one edge redirect and one alias page", stats)
+	}
+
+	problems, _, err = verifyRedirects(context.Background(), edge.Client(), edge.URL, inv, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(problems, "/go/hugo-page/: served by Hugo's meta-refresh page, not redirected by the server") {
+		t.Errorf("-edge let the alias page pass: %q", problems)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	root := t.TempDir()
+	content := filepath.Join(root, "content")
+	mustWrite(t, filepath.Join(content, "go", "errgroup.md"), "---\ntitle: Structured concurrency with errgroup\ndraft: true\n---\n")
+	mustWrite(t, filepath.Join(content, "go", "stray.md"), "---\ntitle: Something else entirely\ndraft: true\n---\n")
+	mustWrite(t, filepath.Join(content, "go", "iterators.md"), "---\ntitle: Range over func iterators\ndraft: true\n---\n")
+	mustWrite(t, filepath.Join(content, "go", "released.md"), "---\ntitle: Released already\n---\n")
+	calendar := filepath.Join(root, "calendar.yml")
+	iterators := filepath.ToSlash(filepath.Join(content, "go", "iterators.md"))
+	mustWrite(t, calendar, `ideas:
+  - title: Structured concurrency with errgroup
+    target: 2026-10-01
+    status: drafting
+  - title: Iterators
+    target: 2026-10-20
+    draft: `+iterators+`
+  - title: Released already
+    target: 2026-09-01
+    status: drafting
+  - title: Someday
+  - title: Far off
+    target: 2027-06-01
+  - title: Abandoned
+    target: 2026-01-01
+    status: dropped
+`)
+
+	ideas, err := loadCalendar(calendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, err := collectPosts(content, []string{"go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planned, unplanned := planCalendar(ideas, inv, defaultMinScore)
+	var out strings.Builder
+	today := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	writeCalendar(&out, planned, unplanned, today, 30)
+
+	errgroup := filepath.ToSlash(filepath.Join(content, "go", "errgroup.md"))
+	stray := filepath.ToSlash(filepath.Join(content, "go", "stray.md"))
+	want := "Overdue:\n" +
+		"  2026-10-01  Structured concurrency with errgroup (drafting, " + errgroup + ")\n" +
+		"Due in the next 30 days:\n" +
+		"  2026-10-20  Iterators (idea, " + iterators + ")\n" +
+		"1 later, 1 unscheduled, 2 published or dropped\n" +
+		"Draft not on the calendar:\n" +
+		"  " + stray + "\n" +
+		"warning: \"Structured concurrency with errgroup\" was due 2026-10-01 and isn't published\n" +
+		"warning: \"Released already\" is out as /go/released/; mark it published\n"
+	if out.String() != want {
+		t.Fatalf("writeCalendar =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestLoadCalendarRejectsBadEntries(t *testing.T) {
+	for _, tc := range []struct {
+		name, ideas, want string
+	}{
+		{"no title", "  - target: 2026-10-01\n", "idea 1 has no title"},
+		{"status", "  - title: A\n    status: someday\n", `"A": status "someday"`},
+		{"target", "  - title: A\n    target: next week\n", `"A": target "next week" is not a YYYY-MM-DD date`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "calendar.yml")
+			mustWrite(t, path, "ideas:\n"+tc.ideas)
+			_, err := loadCalendar(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("loadCalendar error = %v, want it to mention %s", err, tc.want)
+			}
+		})
+	}
+
+	ideas, err := loadCalendar(filepath.Join(t.TempDir(), "missing.yml"))
+	if err != nil || ideas != nil {
+		t.Fatalf("loadCalendar(missing) = %v, %v; want no ideas", ideas, err)
+	}
+}
+
+func TestSitemapsSplitAndVerify(t *testing.T) {
+	public := t.TempDir()
+	mustWrite(t, filepath.Join(public, "sitemap.xml"), `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://rednafi.com/</loc><lastmod>2026-03-01T00:00:00+00:00</lastmod></url>
+  <url><loc>https://rednafi.com/go/a/</loc><lastmod>2025-01-01T00:00:00+00:00</lastmod></url>
+  <url><loc>https://rednafi.com/go/b/</loc><lastmod>2026-02-01T00:00:00+00:00</lastmod></url>
+  <url><loc>https://rednafi.com/go/c/</loc><lastmod>2024-01-01T00:00:00+00:00</lastmod></url>
+  <url><loc>https://rednafi.com/til/d/</loc></url>
+</urlset>`)
+	inv := inventory{
+		posts: map[string]string{
+			"/go/a/": "content/go/a.md", "/go/b/": "content/go/b.md", "/go/c/": "content/go/c.md",
+			"/til/d/": "content/shards/d.md", "/go/hidden/": "content/go/hidden.md",
+		},
+		// A permalink pattern serves the shard under /til/.
+		sections: map[string]string{
+			"/go/a/": "go", "/go/b/": "go", "/go/c/": "go", "/til/d/": "shards", "/go/hidden/": "go",
+		},
+		frontmatter: map[string]postFrontmatter{"/go/hidden/": {RobotsNoIndex: true}},
+	}
+
+	children, err := writeSitemaps(public, inv, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, child := range children {
+		files = append(files, fmt.Sprintf("%s:%d", child.file(), len(child.urls)))
+	}
+	if want := "sitemaps/go.xml:2 sitemaps/go-2.xml:1 sitemaps/pages.xml:1 sitemaps/shards.xml:1"; strings.Join(files, " ") != want {
+		t.Fatalf("children = %s, want %s", strings.Join(files, " "), want)
+	}
+	index, err := os.ReadFile(filepath.Join(public, sitemapIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<loc>https://rednafi.com/sitemaps/go.xml</loc>\n    <lastmod>2026-02-01T00:00:00+00:00</lastmod>"; !strings.Contains(string(index), want) {
+		t.Errorf("index lacks %q:\n%s", want, index)
+	}
+
+	site := httptest.NewServer(http.FileServer(http.Dir(public)))
+	defer site.Close()
+	problems, summary, err := verifySitemaps(context.Background(), site.Client(), site.URL, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 || summary != "4 sitemaps and 5 URLs checked" {
+		t.Fatalf("verifySitemaps = %q, %q; want no problems", problems, summary)
+	}
+
+	// A child gone missing, and a post the sitemaps never heard of.
+	if err := os.Remove(filepath.Join(public, "sitemaps", "go-2.xml")); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, filepath.Join(public, "sitemaps", "pages.xml"), `<urlset><url><loc>https://rednafi.com/</loc></url><url><loc>https://rednafi.com/go/a/</loc></url></urlset>`)
+	inv.posts["/go/new/"], inv.sections["/go/new/"] = "content/go/new.md", "go"
+	problems, _, err = verifySitemaps(context.Background(), site.Client(), site.URL, inv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"sitemaps/go-2.xml: answers HTTP 404",
+		"sitemaps/pages.xml: lists /go/a/, which sitemaps/go.xml lists too",
+		"/go/c/: published, but no sitemap lists it",
+		"/go/new/: published, but no sitemap lists it",
+	}
+	if !slices.Equal(problems, want) {
+		t.Fatalf("verifySitemaps =\n  %s\nwant\n  %s", strings.Join(problems, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestSectionSitemap(t *testing.T) {
+	for child, want := range map[string]bool{
+		"sitemaps/go.xml":     true,
+		"sitemaps/go-2.xml":   true,
+		"sitemaps/go-1.xml":   false,
+		"sitemaps/gofmt.xml":  false,
+		"sitemaps/go-x.xml":   false,
+		"sitemaps/pages.xml":  false,
+		"elsewhere/go.xml":    false,
+		"sitemaps/go-10.xml":  true,
+		"sitemaps/go-2-3.xml": false,
+	} {
+		if got := sectionSitemap(child, "go"); got != want {
+			t.Errorf("sectionSitemap(%s, go) = %v, want %v", child, got, want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// redirectStats counts how the aliases were redirected.
+type redirectStats struct {
+	edge, page int
+}
+
+func (s redirectStats) String() string {
+	return fmt.Sprintf("%d alias%s checked: %d redirected by the server, %d by Hugo's meta-refresh page",
+		s.edge+s.page, pluralES(s.edge+s.page), s.edge, s.page)
+}
+
+// verifyRedirects requests every alias in inv from base, the live site,
+// without following redirects, and returns what is wrong with each: an
+// alias must answer a permanent redirect, or Hugo's alias page, to the
+// page that claims it. With edge, Hugo's page is a problem too: the
+// server in front of the site was meant to redirect, and the map it
+// redirects from didn't take effect.
+func verifyRedirects(ctx context.Context, client *http.Client, base string, inv inventory, edge bool) ([]string, redirectStats, error) {
+	noFollow := *client
+	noFollow.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	var problems []string
+	var stats redirectStats
+	for _, alias := range slices.Sorted(maps.Keys(inv.aliases)) {
+		target := inv.aliases[alias]
+		aliasURL := strings.TrimSuffix(base, "/") + alias
+		u, err := url.Parse(aliasURL)
+		if err != nil {
+			return nil, stats, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, aliasURL, nil)
+		if err != nil {
+			return nil, stats, err
+		}
+		req.Header.Set("User-Agent", userAgent)
+		resp, err := noFollow.Do(req)
+		if err != nil {
+			return nil, stats, err
+		}
+		var page []byte
+		if resp.StatusCode == http.StatusOK {
+			// An alias page is a few hundred bytes; a real page squatting
+			// on the alias says so in its head.
+			page, err = io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, stats, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if problem := wrongDestination(u, resp.Header.Get("Location"), target); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: redirects to %s", alias, problem))
+				continue
+			}
+			stats.edge++
+		case http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+			problems = append(problems, fmt.Sprintf("%s: answers HTTP %d, a temporary redirect; want 301 to %s", alias, resp.StatusCode, target))
+		case http.StatusOK:
+			refresh, ok := metaRefresh(string(page))
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: answers HTTP 200 without redirecting; want %s", alias, target))
+				continue
+			}
+			if problem := wrongDestination(u, refresh, target); problem != "" {
+				problems = append(problems, fmt.Sprintf("%s: refreshes to %s", alias, problem))
+				continue
+			}
+			if edge {
+				problems = append(problems, fmt.Sprintf("%s: served by Hugo's meta-refresh page, not redirected by the server", alias))
+				continue
+			}
+			stats.page++
+		default:
+			problems = append(problems, fmt.Sprintf("%s: answers HTTP %d; want a redirect to %s", alias, resp.StatusCode, target))
+		}
+	}
+	return problems, stats, nil
+}
+
+// wrongDestination resolves location against the alias's URL and returns
+// it, for the message, unless it is target on the same site; then "".
+func wrongDestination(aliasURL *url.URL, location, target string) string {
+	dest, err := aliasURL.Parse(location)
+	if err != nil {
+		return fmt.Sprintf("%q, which doesn't parse; want %s", location, target)
+	}
+	site, _ := url.Parse(siteURL)
+	if (dest.Host == aliasURL.Host || dest.Host == site.Host) && dest.Path == target {
+		return ""
+	}
+	return fmt.Sprintf("%s; want %s", dest, target)
+}
+
+var metaTagPattern = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+
+// metaRefresh returns the URL page's <meta http-equiv="refresh"> sends the
+// browser to.
+func metaRefresh(page string) (string, bool) {
+	for _, tag := range metaTagPattern.FindAllString(page, -1) {
+		attrs := tagAttrs(tag)
+		if !strings.EqualFold(attrs["http-equiv"], "refresh") {
+			continue
+		}
+		_, rest, ok := strings.Cut(html.UnescapeString(attrs["content"]), ";")
+		if !ok {
+			return "", false
+		}
+		rest = strings.TrimSpace(rest)
+		if len(rest) < 4 || !strings.EqualFold(rest[:4], "url=") {
+			return "", false
+		}
+		return strings.Trim(strings.TrimSpace(rest[4:]), `'"`), true
+	}
+	return "", false
+}
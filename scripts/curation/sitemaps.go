@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The sitemap protocol's limits on one sitemap file.
+const (
+	maxSitemapURLs  = 50_000
+	maxSitemapBytes = 50 << 20
+)
+
+const (
+	sitemapIndexFile = "sitemap_index.xml"
+	sitemapDir       = "sitemaps"
+	// pagesSitemap holds the URLs that aren't posts: the home page, the
+	// profile page and the other indexable pages.
+	pagesSitemap = "pages"
+	sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod,omitempty"`
+}
+
+type urlset struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr,omitempty"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr,omitempty"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// childSitemap is one file of the split: a section's posts, or a part of
+// them when there are more than a sitemap holds, or pagesSitemap.
+type childSitemap struct {
+	name string
+	urls []sitemapURL
+}
+
+func (c childSitemap) file() string { return sitemapDir + "/" + c.name + ".xml" }
+
+// lastmod is the newest lastmod in c, which is what the index says about
+// it. Hugo writes them all in one layout, so they compare as strings.
+func (c childSitemap) lastmod() string {
+	var newest string
+	for _, u := range c.urls {
+		newest = max(newest, u.Lastmod)
+	}
+	return newest
+}
+
+// splitSitemap splits the URLs of Hugo's sitemap by the section inv says
+// each post belongs to, which a permalink pattern can make differ from the
+// URL's first segment. A section with more than limit posts gets numbered
+// parts: go.xml, go-2.xml and so on.
+func splitSitemap(urls []sitemapURL, inv inventory, limit int) []childSitemap {
+	bySection := map[string][]sitemapURL{}
+	for _, u := range urls {
+		section := pagesSitemap
+		if parsed, err := url.Parse(u.Loc); err == nil {
+			if s, ok := inv.sections[parsed.Path]; ok {
+				section = s
+			}
+		}
+		bySection[section] = append(bySection[section], u)
+	}
+	var children []childSitemap
+	for _, section := range slices.Sorted(maps.Keys(bySection)) {
+		for i, part := range slices.Collect(slices.Chunk(bySection[section], limit)) {
+			name := section
+			if i > 0 {
+				name += "-" + strconv.Itoa(i+1)
+			}
+			children = append(children, childSitemap{name: name, urls: part})
+		}
+	}
+	return children
+}
+
+// writeSitemaps splits public/sitemap.xml into public/sitemaps/ and writes
+// the index of them to public/sitemap_index.xml. Hugo's sitemap.xml stays,
+// so the build serves both.
+func writeSitemaps(public string, inv inventory, limit int) ([]childSitemap, error) {
+	urls, err := readHugoSitemap(public)
+	if err != nil {
+		return nil, err
+	}
+	children := splitSitemap(urls, inv, limit)
+
+	dir := filepath.Join(public, sitemapDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	for _, child := range children {
+		if err := writeXML(filepath.Join(public, filepath.FromSlash(child.file())), urlset{XMLNS: sitemapXMLNS, URLs: child.urls}); err != nil {
+			return nil, err
+		}
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: siteURL + "/" + child.file(), Lastmod: child.lastmod()})
+	}
+	return children, writeXML(filepath.Join(public, sitemapIndexFile), index)
+}
+
+// readHugoSitemap returns the URLs in the sitemap.xml Hugo built in public.
+func readHugoSitemap(public string) ([]sitemapURL, error) {
+	raw, err := os.ReadFile(filepath.Join(public, "sitemap.xml"))
+	if err != nil {
+		return nil, fmt.Errorf("read Hugo's sitemap; build the site first: %w", err)
+	}
+	var hugo urlset
+	if err := xml.Unmarshal(raw, &hugo); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Join(public, "sitemap.xml"), err)
+	}
+	return hugo.URLs, nil
+}
+
+func writeXML(filePath string, v any) error {
+	raw, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, append([]byte(xml.Header), append(raw, '\n')...), 0o644)
+}
+
+// verifySitemaps requests the sitemap index from base, the live site, and
+// every sitemap it lists, and returns what is wrong: a child that doesn't
+// answer, doesn't parse, sits off the site or is over the protocol's
+// limits, a URL two children list, and a published post no child lists or
+// one listed outside its section's sitemaps.
+func verifySitemaps(ctx context.Context, client *http.Client, base string, inv inventory) ([]string, string, error) {
+	base = strings.TrimSuffix(base, "/")
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, "", err
+	}
+	site, _ := url.Parse(siteURL)
+
+	raw, status, err := fetchSitemap(ctx, client, base+"/"+sitemapIndexFile)
+	if err != nil {
+		return nil, "", err
+	}
+	if status != http.StatusOK {
+		return []string{fmt.Sprintf("%s: answers HTTP %d", sitemapIndexFile, status)}, "", nil
+	}
+	var index sitemapIndex
+	if err := xml.Unmarshal(raw, &index); err != nil {
+		return []string{fmt.Sprintf("%s: doesn't parse as a sitemap index: %v", sitemapIndexFile, err)}, "", nil
+	}
+	if len(index.Sitemaps) == 0 {
+		return []string{sitemapIndexFile + ": lists no sitemaps"}, "", nil
+	}
+
+	var problems []string
+	listedIn := map[string]string{}
+	var urls int
+	for _, entry := range index.Sitemaps {
+		loc, err := url.Parse(strings.TrimSpace(entry.Loc))
+		if err != nil || (loc.Host != site.Host && loc.Host != baseURL.Host) {
+			problems = append(problems, fmt.Sprintf("%s: lists %s, which isn't on the site", sitemapIndexFile, entry.Loc))
+			continue
+		}
+		child := strings.TrimPrefix(loc.Path, "/")
+		raw, status, err := fetchSitemap(ctx, client, base+loc.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		var set urlset
+		switch {
+		case status != http.StatusOK:
+			problems = append(problems, fmt.Sprintf("%s: answers HTTP %d", child, status))
+			continue
+		case len(raw) > maxSitemapBytes:
+			problems = append(problems, fmt.Sprintf("%s: over the protocol's %d MB", child, maxSitemapBytes>>20))
+			continue
+		case xml.Unmarshal(raw, &set) != nil:
+			problems = append(problems, fmt.Sprintf("%s: doesn't parse as a sitemap", child))
+			continue
+		case len(set.URLs) == 0:
+			problems = append(problems, fmt.Sprintf("%s: lists no URLs", child))
+		case len(set.URLs) > maxSitemapURLs:
+			problems = append(problems, fmt.Sprintf("%s: lists %d URLs, over the protocol's %d", child, len(set.URLs), maxSitemapURLs))
+		}
+		urls += len(set.URLs)
+		for _, u := range set.URLs {
+			parsed, err := url.Parse(strings.TrimSpace(u.Loc))
+			if err != nil || (parsed.Host != site.Host && parsed.Host != baseURL.Host) {
+				problems = append(problems, fmt.Sprintf("%s: lists %s, which isn't on the site", child, u.Loc))
+				continue
+			}
+			if first, ok := listedIn[parsed.Path]; ok {
+				problems = append(problems, fmt.Sprintf("%s: lists %s, which %s lists too", child, parsed.Path, first))
+				continue
+			}
+			listedIn[parsed.Path] = child
+		}
+	}
+
+	for _, post := range slices.Sorted(maps.Keys(inv.posts)) {
+		if inv.frontmatter[post].RobotsNoIndex {
+			continue
+		}
+		child, ok := listedIn[post]
+		section := inv.sections[post]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: published, but no sitemap lists it", post))
+		case !sectionSitemap(child, section):
+			problems = append(problems, fmt.Sprintf("%s: listed in %s, not under %s/%s.xml", post, child, sitemapDir, section))
+		}
+	}
+	summary := fmt.Sprintf("%d sitemap%s and %d URL%s checked", len(index.Sitemaps), plural(len(index.Sitemaps)), urls, plural(urls))
+	return problems, summary, nil
+}
+
+// sectionSitemap reports whether child is one of section's sitemaps, the
+// first part or a numbered one.
+func sectionSitemap(child, section string) bool {
+	name, ok := strings.CutPrefix(child, sitemapDir+"/"+section)
+	if !ok {
+		return false
+	}
+	name = strings.TrimSuffix(name, ".xml")
+	if name == "" {
+		return true
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "-"))
+	return strings.HasPrefix(name, "-") && err == nil && n > 1
+}
+
+// fetchSitemap returns up to one byte past maxSitemapBytes of rawURL, so
+// an oversized sitemap shows as one.
+func fetchSitemap(ctx context.Context, client *http.Client, rawURL string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapBytes+1))
+	return raw, resp.StatusCode, err
+}
+
+// writeSitemapSummary says what writeSitemaps wrote.
+func writeSitemapSummary(w io.Writer, children []childSitemap) {
+	fmt.Fprintf(w, "%s: %d sitemap%s\n", sitemapIndexFile, len(children), plural(len(children)))
+	for _, child := range children {
+		fmt.Fprintf(w, "  %s  %d URL%s\n", child.file(), len(child.urls), plural(len(child.urls)))
+	}
+}
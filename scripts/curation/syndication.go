@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// devtoAPI serves a published dev.to article, canonical link included, by
+// id.
+const devtoAPI = "https://dev.to/api/articles/"
+
+// userAgent identifies curation's network checks to the sites they ask.
+const userAgent = "rednafi-curation/1.0 (+https://rednafi.com)"
+
+// devtoArticle is the part of dev.to's article JSON the check reads.
+type devtoArticle struct {
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url"`
+}
+
+// checkSyndication asks api about every post whose frontmatter carries a
+// devto id and returns what is wrong with each copy: gone, or no longer
+// claiming the post as canonical, in the API's canonical_url or in the
+// rel=canonical link of the copy's page, which is what search engines
+// read. It also returns how many copies it checked.
+func checkSyndication(ctx context.Context, client *http.Client, api string, inv inventory) ([]string, int, error) {
+	var problems []string
+	checked := 0
+	for _, postURL := range slices.Sorted(maps.Keys(inv.frontmatter)) {
+		id := inv.frontmatter[postURL].DevTo
+		if id == 0 {
+			continue
+		}
+		checked++
+		source := inv.posts[postURL]
+		want := siteURL + postURL
+
+		raw, status, err := get(ctx, client, api+strconv.Itoa(id))
+		if err != nil {
+			return nil, checked, err
+		}
+		if status == http.StatusNotFound {
+			problems = append(problems, fmt.Sprintf("%s: dev.to article %d is gone", source, id))
+			continue
+		}
+		if status != http.StatusOK {
+			return nil, checked, fmt.Errorf("dev.to article %d: HTTP %d", id, status)
+		}
+		var article devtoArticle
+		if err := json.Unmarshal(raw, &article); err != nil {
+			return nil, checked, fmt.Errorf("dev.to article %d: %w", id, err)
+		}
+		if problem := canonicalProblem(article.CanonicalURL, want, article.URL, inv); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: dev.to article %d %s", source, id, problem))
+		}
+
+		page, status, err := get(ctx, client, article.URL)
+		if err != nil {
+			return nil, checked, err
+		}
+		if status != http.StatusOK {
+			problems = append(problems, fmt.Sprintf("%s: %s answers HTTP %d", source, article.URL, status))
+			continue
+		}
+		if problem := canonicalProblem(pageCanonical(string(page)), want, article.URL, inv); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s's rel=canonical %s", source, article.URL, problem))
+		}
+	}
+	return problems, checked, nil
+}
+
+// canonicalProblem says how got, a copy's canonical link, fails to name
+// want, or returns "" when it does. self is the copy's own URL: pointing
+// the canonical link there is how the platforms drop it.
+func canonicalProblem(got, want, self string, inv inventory) string {
+	switch {
+	case got == want:
+		return ""
+	case got == "" || got == self:
+		return "dropped the canonical link to " + want
+	case looseURL(got) == looseURL(want):
+		return fmt.Sprintf("mangled the canonical link to %s into %s", want, got)
+	case strings.HasPrefix(looseURL(got), looseURL(siteURL)) && inv.aliases[normalizeLink(strings.TrimPrefix(got, siteURL))] == strings.TrimPrefix(want, siteURL):
+		return fmt.Sprintf("claims %s, an alias that redirects to %s", got, want)
+	}
+	return fmt.Sprintf("claims %s as canonical instead of %s", got, want)
+}
+
+// looseURL reduces rawURL to what still matters once a platform has
+// rewritten it: no scheme, www, query, fragment or trailing slash, and
+// lowercase.
+func looseURL(rawURL string) string {
+	s := strings.ToLower(rawURL)
+	s, _, _ = strings.Cut(s, "#")
+	s, _, _ = strings.Cut(s, "?")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+	s = strings.TrimPrefix(s, "www.")
+	return strings.TrimSuffix(s, "/")
+}
+
+var (
+	linkTagPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?is)\b([a-z][a-z-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// tagAttrs returns an HTML tag's attributes by lowercased name, values
+// still escaped.
+func tagAttrs(tag string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+	}
+	return attrs
+}
+
+// pageCanonical returns the href of page's <link rel="canonical">, or ""
+// without one.
+func pageCanonical(page string) string {
+	for _, tag := range linkTagPattern.FindAllString(page, -1) {
+		attrs := tagAttrs(tag)
+		if slices.Contains(strings.Fields(strings.ToLower(attrs["rel"])), "canonical") {
+			return html.UnescapeString(strings.TrimSpace(attrs["href"]))
+		}
+	}
+	return ""
+}
+
+// get fetches rawURL and returns its body and status.
+func get(ctx context.Context, client *http.Client, rawURL string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json, text/html;q=0.9")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	return raw, resp.StatusCode, err
+}
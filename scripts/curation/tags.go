@@ -0,0 +1,318 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tagsPath is where Hugo serves the tags taxonomy, and the directory under
+// content/ holding its term pages.
+const tagsPath = "/tags/"
+
+// termPage is a content/tags/<term>/_index.md file. A tombstone is a term
+// page kept on purpose after the last post dropped the tag, so that its
+// URL answers with a pointer elsewhere rather than a 404.
+type termPage struct {
+	Source    string
+	Title     string   `yaml:"title"`
+	Aliases   []string `yaml:"aliases"`
+	Tombstone bool     `yaml:"tombstone"`
+}
+
+// collectTerms records the term pages under root in inv, and their aliases
+// next to the posts' so that links to them count as aliases too.
+func collectTerms(root string, inv *inventory) error {
+	inv.terms = map[string]termPage{}
+	dir := filepath.Join(root, strings.Trim(tagsPath, "/"))
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		filePath := filepath.Join(dir, entry.Name(), "_index.md")
+		raw, err := os.ReadFile(filePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmRaw, _, _ := splitFrontmatter(string(raw))
+		var page termPage
+		if err := yaml.Unmarshal([]byte(fmRaw), &page); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		page.Source = filepath.ToSlash(filePath)
+		termURL := tagsPath + entry.Name() + "/"
+		inv.terms[termURL] = page
+		for _, alias := range page.Aliases {
+			inv.aliases[normalizeLink(alias)] = termURL
+		}
+	}
+	return nil
+}
+
+// liveTerms maps the URL of every tag a published post carries to the tag
+// as the posts spell it.
+func liveTerms(inv inventory) map[string]string {
+	terms := map[string]string{}
+	for _, postURL := range slices.Sorted(maps.Keys(inv.frontmatter)) {
+		for _, tag := range inv.frontmatter[postURL].Tags {
+			termURL := tagsPath + termSlug(tag) + "/"
+			if _, ok := terms[termURL]; !ok {
+				terms[termURL] = tag
+			}
+		}
+	}
+	return terms
+}
+
+// termSlug is the path segment Hugo gives a term: lowercased, spaces
+// turned into hyphens, and everything but letters, digits and a few
+// punctuation marks dropped.
+func termSlug(tag string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(tag)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r), strings.ContainsRune("%._-#+~", r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// deadTag is a tag URL that no published post's tag serves any more, with
+// who still points at it and what to do about it: redirect to the live
+// tag it most resembles, or, when none does, keep a tombstone page.
+type deadTag struct {
+	Path      string
+	Referrers []string
+	Redirect  string
+	Score     float64
+	// Stale is set when a term page for the tag still exists; Hugo keeps
+	// rendering it, empty, until it is deleted or made a tombstone.
+	Stale string
+}
+
+// deadTags finds the tag URLs that would 404 or render empty: those linked
+// from anywhere under root, listed in legacy (server logs, a search
+// console export), or kept alive only by a leftover term page. Tags with
+// a tombstone, or redirected by a live term page's aliases, are fine.
+func deadTags(root string, inv inventory, legacy []string, minScore float64) ([]deadTag, error) {
+	live := liveTerms(inv)
+	referrers := map[string][]string{}
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		for _, link := range internalLinks(string(raw)) {
+			if strings.HasPrefix(link, tagsPath) && link != tagsPath {
+				file := filepath.ToSlash(filePath)
+				if !slices.Contains(referrers[link], file) {
+					referrers[link] = append(referrers[link], file)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range legacy {
+		if link := normalizeLink(l); strings.HasPrefix(link, tagsPath) && link != tagsPath && !strings.Contains(l, "?") {
+			referrers[link] = append(referrers[link], "the legacy list")
+		}
+	}
+	for termURL := range inv.terms {
+		if _, ok := referrers[termURL]; !ok {
+			referrers[termURL] = nil
+		}
+	}
+
+	var dead []deadTag
+	for _, termURL := range slices.Sorted(maps.Keys(referrers)) {
+		if _, ok := live[termURL]; ok {
+			continue
+		}
+		if _, ok := inv.aliases[termURL]; ok {
+			continue
+		}
+		page, hasPage := inv.terms[termURL]
+		if page.Tombstone {
+			continue
+		}
+		d := deadTag{Path: termURL, Referrers: referrers[termURL]}
+		if hasPage {
+			d.Stale = page.Source
+		}
+		d.Redirect, d.Score = closestTerm(termURL, live, minScore)
+		dead = append(dead, d)
+	}
+	return dead, nil
+}
+
+// closestTerm returns the live tag whose slug or spelling termURL
+// resembles most, if it scores at least minScore and clearly beats the
+// runner-up.
+func closestTerm(termURL string, live map[string]string, minScore float64) (string, float64) {
+	key := matchKey(path.Base(termURL))
+	var best, runnerUp float64
+	var target string
+	for _, candidate := range slices.Sorted(maps.Keys(live)) {
+		score := max(similarity(key, matchKey(path.Base(candidate))), similarity(key, matchKey(live[candidate])))
+		switch {
+		case score > best:
+			runnerUp = best
+			best, target = score, candidate
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if best < minScore || best-runnerUp < ambiguityMargin {
+		return "", best
+	}
+	return target, best
+}
+
+// reportDeadTags prints each dead tag with its suggestion and, with apply,
+// carries the suggestions out: an alias on the live tag's term page, which
+// is created if need be, or a tombstone term page. With dryRun it lists the
+// term pages apply would write instead, and writes none.
+func reportDeadTags(w io.Writer, root string, inv inventory, dead []deadTag, apply, dryRun bool) error {
+	if len(dead) == 0 {
+		fmt.Fprintln(w, "no dead tag pages")
+		return nil
+	}
+	live := liveTerms(inv)
+	p := &plan{}
+	for _, d := range dead {
+		action := "keep a tombstone page"
+		if d.Redirect != "" {
+			action = fmt.Sprintf("redirect to %s (%.2f)", d.Redirect, d.Score)
+		}
+		fmt.Fprintf(w, "%s: %s\n", d.Path, action)
+		for _, r := range d.Referrers {
+			fmt.Fprintf(w, "  linked from %s\n", r)
+		}
+		if d.Stale != "" {
+			fmt.Fprintf(w, "  %s still renders it, with no posts\n", d.Stale)
+		}
+		switch {
+		case dryRun:
+			planDeadTag(p, root, d)
+		case apply:
+			if err := applyDeadTag(root, live, d); err != nil {
+				return err
+			}
+		}
+	}
+	if dryRun {
+		return p.print(w)
+	}
+	if !apply {
+		fmt.Fprintf(w, "%d dead tag page%s; -apply adds the redirects and tombstones\n", len(dead), plural(len(dead)))
+	}
+	return nil
+}
+
+// applyDeadTag carries out d's suggestion.
+func applyDeadTag(root string, live map[string]string, d deadTag) error {
+	if d.Redirect == "" {
+		return writeTombstone(root, d.Path)
+	}
+	// The alias page and a leftover term page would both claim the URL.
+	if d.Stale != "" {
+		if err := os.Remove(d.Stale); err != nil {
+			return err
+		}
+		os.Remove(filepath.Dir(d.Stale))
+	}
+	return addTermAlias(root, d.Redirect, live[d.Redirect], d.Path)
+}
+
+// planDeadTag adds the files applyDeadTag would touch for d to p.
+func planDeadTag(p *plan, root string, d deadTag) {
+	if d.Redirect == "" {
+		p.write(termFile(root, d.Path))
+		return
+	}
+	if d.Stale != "" {
+		p.note("%s would be removed, as the alias on %s replaces it", d.Stale, d.Redirect)
+	}
+	p.write(termFile(root, d.Redirect))
+}
+
+// termFile is the term page that serves termURL.
+func termFile(root, termURL string) string {
+	return filepath.Join(root, filepath.FromSlash(strings.Trim(termURL, "/")), "_index.md")
+}
+
+// addTermAlias makes target's term page redirect alias to it, creating the
+// page, titled as the posts spell the tag, when there isn't one.
+func addTermAlias(root, target, title, alias string) error {
+	file := termFile(root, target)
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte("---\ntitle: "+quoteYAML(title)+"\n---\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return addAliases(file, []string{alias})
+}
+
+// writeTombstone writes, or turns an existing term page into, a page that
+// says the tag is retired and points at the tag list and the archive. It
+// is kept out of search engines' indexes.
+func writeTombstone(root, termURL string) error {
+	file := termFile(root, termURL)
+	title := path.Base(strings.TrimSuffix(termURL, "/"))
+	if raw, err := os.ReadFile(file); err == nil {
+		fmRaw, _, _ := splitFrontmatter(string(raw))
+		var page termPage
+		if yaml.Unmarshal([]byte(fmRaw), &page) == nil && page.Title != "" {
+			title = page.Title
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(`---
+title: `+quoteYAML(title)+`
+description: "No posts carry this tag any more. Browse [all tags](/tags/) or the [archive](/archive/)."
+tombstone: true
+robotsNoIndex: true
+---
+`), 0o644)
+}
+
+// quoteYAML quotes s when YAML would read it as anything but a plain
+// string.
+func quoteYAML(s string) string {
+	if strings.ContainsAny(s, ":#'\"{}[],&*!|>%@`") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,293 @@
+// Command describe suggests a description for posts that lack one.
+//
+// The suggestion is the post's first paragraph with Markdown stripped,
+// trimmed to what search engines show in a result snippet: whole sentences
+// when they fit, otherwise cut at a word. Posts that open with a code block
+// or an image have no usable first paragraph; they are listed for a human to
+// describe. By default it only prints; with -fix it writes the suggestions
+// into the frontmatter, unless -dry-run is set too, which names the posts
+// it would write and leaves them alone.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const contentDir = "content"
+
+// maxLength is the longest description a search result shows uncut.
+const maxLength = 160
+
+type siteConfig struct {
+	Params struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+	} `yaml:"params"`
+}
+
+// suggestion is a description proposed for one post, or the reason none
+// could be.
+type suggestion struct {
+	File        string
+	Description string
+	Problem     string
+}
+
+func main() {
+	fix := flag.Bool("fix", false, "write the suggested descriptions into the frontmatter")
+	dryRun := flag.Bool("dry-run", false, "with -fix, print the posts it would write without writing them")
+	flag.Parse()
+
+	sections, err := loadSections("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	suggestions, err := suggest(contentDir, sections)
+	if err != nil {
+		fatal(err)
+	}
+
+	var flagged []suggestion
+	for _, s := range suggestions {
+		if s.Problem != "" {
+			flagged = append(flagged, s)
+			continue
+		}
+		fmt.Printf("%s:\n  %s\n", s.File, s.Description)
+		if *fix && !*dryRun {
+			if err := writeDescription(s.File, s.Description); err != nil {
+				fatal(err)
+			}
+		}
+	}
+	if len(flagged) > 0 {
+		fmt.Printf("%d post%s need a description written by hand:\n", len(flagged), plural(len(flagged)))
+		for _, s := range flagged {
+			fmt.Printf("  %s: %s\n", s.File, s.Problem)
+		}
+	}
+	if n := len(suggestions) - len(flagged); *fix && n > 0 {
+		verb := "wrote"
+		if *dryRun {
+			verb = "would write"
+		}
+		fmt.Printf("%s %d description%s\n", verb, n, plural(n))
+	}
+}
+
+// suggest returns a suggestion for every post under sections whose
+// description is empty.
+func suggest(root string, sections []string) ([]suggestion, error) {
+	var out []suggestion
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		section, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if !slices.Contains(sections, section) {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, body, ok := splitFrontmatter(string(raw))
+		if !ok {
+			return nil
+		}
+		var fm struct {
+			Description string `yaml:"description"`
+		}
+		if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		if strings.TrimSpace(fm.Description) != "" {
+			return nil
+		}
+
+		s := suggestion{File: filepath.ToSlash(filePath)}
+		paragraph, problem := firstParagraph(body)
+		if problem != "" {
+			s.Problem = problem
+		} else {
+			s.Description = trimDescription(stripMarkdown(paragraph))
+		}
+		out = append(out, s)
+		return nil
+	})
+	return out, err
+}
+
+// firstParagraph returns the first block of prose in body, skipping
+// headings. When the body opens with code or an image instead, it returns
+// why no description can be drawn from it.
+func firstParagraph(body string) (paragraph, problem string) {
+	var block []string
+	for line := range strings.SplitSeq(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if len(block) == 0 {
+			switch {
+			case trimmed == "", strings.HasPrefix(trimmed, "#"):
+				continue
+			case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"), strings.HasPrefix(line, "    "), strings.HasPrefix(line, "\t"):
+				return "", "first paragraph is a code block"
+			case strings.HasPrefix(trimmed, "!["), strings.HasPrefix(trimmed, "<img"), strings.HasPrefix(trimmed, "<figure"), strings.HasPrefix(trimmed, "{{< figure"):
+				return "", "first paragraph is an image"
+			case strings.HasPrefix(trimmed, "{{<"), strings.HasPrefix(trimmed, "{{%"):
+				return "", "first paragraph is a shortcode"
+			}
+		}
+		if trimmed == "" {
+			break
+		}
+		block = append(block, strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+	}
+	if len(block) == 0 {
+		return "", "post has no prose"
+	}
+	return strings.Join(block, " "), ""
+}
+
+var (
+	imagePattern    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\](?:\([^)]*\)|\[[^\]]*\])`)
+	footnotePattern = regexp.MustCompile(`\[\^[^\]]+\]`)
+	htmlPattern     = regexp.MustCompile(`<[^>]+>`)
+	emphasisPattern = regexp.MustCompile("\\*\\*|__|[*_`~]")
+)
+
+// stripMarkdown reduces inline Markdown to its plain text.
+func stripMarkdown(s string) string {
+	s = imagePattern.ReplaceAllString(s, "")
+	s = footnotePattern.ReplaceAllString(s, "")
+	s = linkPattern.ReplaceAllString(s, "$1")
+	s = htmlPattern.ReplaceAllString(s, "")
+	s = emphasisPattern.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// trimDescription fits s within maxLength: as many whole sentences as fit,
+// or, when even the first is too long, the words that fit plus an ellipsis.
+func trimDescription(s string) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	cut := -1
+	for i := 0; i < maxLength; i++ {
+		if (s[i] == '.' || s[i] == '!' || s[i] == '?') && i+1 < len(s) && s[i+1] == ' ' {
+			cut = i + 1
+		}
+	}
+	if cut > 0 {
+		return s[:cut]
+	}
+	words := strings.Fields(s)
+	out := ""
+	for _, word := range words {
+		next := strings.TrimSpace(out + " " + word)
+		if len(next)+len("...") > maxLength {
+			break
+		}
+		out = next
+	}
+	return strings.TrimRight(out, ",;:") + "..."
+}
+
+var descriptionPattern = regexp.MustCompile(`(?m)^description:.*\n(?:[ \t]+.*\n)*`)
+
+// writeDescription replaces filePath's description entry with a folded
+// block in the layout scripts/frontmatter produces.
+func writeDescription(filePath, description string) error {
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	fmRaw, body, ok := splitFrontmatter(string(raw))
+	if !ok {
+		return fmt.Errorf("%s: missing YAML frontmatter", filePath)
+	}
+	var block strings.Builder
+	block.WriteString("description: >-\n")
+	for _, line := range wrapWords(description, 88) {
+		block.WriteString("    " + line + "\n")
+	}
+	fmRaw += "\n"
+	if descriptionPattern.MatchString(fmRaw) {
+		fmRaw = descriptionPattern.ReplaceAllLiteralString(fmRaw, block.String())
+	} else {
+		fmRaw += block.String()
+	}
+	return os.WriteFile(filePath, []byte("---\n"+fmRaw+"---\n"+body), 0o644)
+}
+
+func wrapWords(value string, width int) []string {
+	words := strings.Fields(value)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if len(line)+1+len(word) <= width {
+			line += " " + word
+			continue
+		}
+		lines = append(lines, line)
+		line = word
+	}
+	return append(lines, line)
+}
+
+func loadSections(configPath string) ([]string, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	sections := slices.Clone(config.Params.MainSections)
+	if config.Params.NotesSection != "" {
+		sections = append(sections, config.Params.NotesSection)
+	}
+	return sections, nil
+}
+
+func splitFrontmatter(raw string) (frontmatter, body string, ok bool) {
+	const start = "---\n"
+	if !strings.HasPrefix(raw, start) {
+		return "", raw, false
+	}
+	idx := strings.Index(raw[len(start):], "\n---\n")
+	if idx == -1 {
+		return "", raw, false
+	}
+	fmEnd := len(start) + idx
+	return raw[len(start):fmEnd], raw[fmEnd+len("\n---\n"):], true
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "describe:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFirstParagraph(t *testing.T) {
+	for _, tc := range []struct {
+		body, paragraph, problem string
+	}{
+		{"\n## Intro\n\nFirst line\nwraps here.\n\nSecond.\n", "First line wraps here.", ""},
+		{"> Quoted opener\n> continues.\n", "Quoted opener continues.", ""},
+		{"```go\nfunc main() {}\n```\n\nProse.\n", "", "first paragraph is a code block"},
+		{"![diagram](/x.png)\n\nProse.\n", "", "first paragraph is an image"},
+		{"{{< mermaid >}}\ngraph\n{{< /mermaid >}}\n", "", "first paragraph is a shortcode"},
+		{"\n\n", "", "post has no prose"},
+	} {
+		paragraph, problem := firstParagraph(tc.body)
+		if paragraph != tc.paragraph || problem != tc.problem {
+			t.Errorf("firstParagraph(%q) = %q, %q; want %q, %q", tc.body, paragraph, problem, tc.paragraph, tc.problem)
+		}
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	got := stripMarkdown("Use **[errgroup](https://pkg.go.dev/x)** and `ctx`[^1] with _care_ ![img](/a.png) <kbd>now</kbd>.")
+	if want := "Use errgroup and ctx with care now."; got != want {
+		t.Fatalf("stripMarkdown = %q, want %q", got, want)
+	}
+}
+
+func TestTrimDescription(t *testing.T) {
+	short := "Fits as is."
+	if got := trimDescription(short); got != short {
+		t.Errorf("trimDescription(short) = %q", got)
+	}
+
+	sentences := "First sentence is here. " + strings.Repeat("Second sentence goes on and on. ", 6)
+	if got := trimDescription(sentences); len(got) > maxLength || !strings.HasSuffix(got, ".") || strings.HasSuffix(got, "...") {
+		t.Errorf("trimDescription(sentences) = %q; want whole sentences within %d chars", got, maxLength)
+	}
+
+	rambling := strings.Repeat("word ", 60)
+	got := trimDescription(rambling)
+	if len(got) > maxLength || !strings.HasSuffix(got, "word...") {
+		t.Errorf("trimDescription(rambling) = %q; want words cut with an ellipsis", got)
+	}
+}
+
+func TestSuggestAndWrite(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "described.md"), "---\ntitle: A\ndescription: >-\n    Already there.\ntags: [go]\n---\nBody.\n")
+	mustWrite(t, filepath.Join(root, "go", "empty.md"), "---\ntitle: B\ndescription: \"\"\ntags: [go]\n---\nThe *first* paragraph.\n\nMore.\n")
+	mustWrite(t, filepath.Join(root, "go", "code.md"), "---\ntitle: C\ndescription: \"\"\n---\n```sh\nls\n```\n")
+
+	got, err := suggest(root, []string{"go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(got, func(a, b suggestion) int { return strings.Compare(a.File, b.File) })
+	want := []suggestion{
+		{File: filepath.ToSlash(filepath.Join(root, "go", "code.md")), Problem: "first paragraph is a code block"},
+		{File: filepath.ToSlash(filepath.Join(root, "go", "empty.md")), Description: "The first paragraph."},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("suggest =\n  %+v\nwant\n  %+v", got, want)
+	}
+
+	if err := writeDescription(want[1].File, want[1].Description); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(want[1].File)
+	if wantRaw := "---\ntitle: B\ndescription: >-\n    The first paragraph.\ntags: [go]\n---\nThe *first* paragraph.\n\nMore.\n"; string(raw) != wantRaw {
+		t.Fatalf("rewritten post =\n%s\nwant\n%s", raw, wantRaw)
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
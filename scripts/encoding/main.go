@@ -0,0 +1,196 @@
+// Command encoding audits the site's Markdown for invisible characters.
+//
+// Text pasted from other editors drags in byte-order marks, zero-width
+// characters, and non-breaking spaces that render subtly wrong and poison
+// the search index. By default it rewrites every offending file in place:
+// BOMs and zero-width characters are dropped and non-breaking spaces become
+// plain spaces. Invalid UTF-8 can't be repaired mechanically, so it is always
+// reported and always fails. With --check it touches nothing and exits
+// non-zero if any file needs attention; --dry-run lists what the rewrite
+// would change, file by file, and writes nothing.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unicode"
+	"unicode/utf8"
+)
+
+const contentDir = "content"
+
+const bom = '\ufeff'
+
+// invisible lists every character the audit rewrites, with a readable name
+// and its replacement. An empty replacement drops the character.
+var invisible = map[rune]struct{ name, replacement string }{
+	'\u00a0': {"non-breaking space", " "},
+	'\u202f': {"narrow non-breaking space", " "},
+	'\u200b': {"zero-width space", ""},
+	'\u200c': {"zero-width non-joiner", ""},
+	'\u200d': {"zero-width joiner", ""},
+	'\u2060': {"word joiner", ""},
+	bom:      {"byte-order mark", ""},
+}
+
+// finding is one suspicious character, located for a human to find it.
+type finding struct {
+	line    int
+	col     int
+	offset  int
+	what    string
+	fixable bool
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%d:%d (byte %d): %s", f.line, f.col, f.offset, f.what)
+}
+
+// audit returns every invalid byte and invisible character in raw.
+func audit(raw []byte) []finding {
+	var found []finding
+	line, col := 1, 1
+	prev := rune(0)
+	for offset := 0; offset < len(raw); {
+		r, size := utf8.DecodeRune(raw[offset:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			found = append(found, finding{line, col, offset, fmt.Sprintf("invalid UTF-8 byte 0x%02X", raw[offset]), false})
+		case isInvisible(raw, offset, prev, r):
+			found = append(found, finding{line, col, offset, fmt.Sprintf("%s U+%04X", invisible[r].name, r), true})
+		}
+
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+		prev = r
+		offset += size
+	}
+	return found
+}
+
+// fix drops or replaces every fixable character and leaves invalid bytes
+// untouched so audit keeps reporting them.
+func fix(raw []byte) []byte {
+	var b bytes.Buffer
+	b.Grow(len(raw))
+	prev := rune(0)
+	for offset := 0; offset < len(raw); {
+		r, size := utf8.DecodeRune(raw[offset:])
+		if size == 1 && r == utf8.RuneError {
+			b.WriteByte(raw[offset])
+		} else if isInvisible(raw, offset, prev, r) {
+			b.WriteString(invisible[r].replacement)
+		} else {
+			b.WriteRune(r)
+		}
+		prev = r
+		offset += size
+	}
+	return b.Bytes()
+}
+
+// isInvisible reports whether r at offset is a character worth flagging. A
+// zero-width joiner between two pictographs builds a single emoji, so it is
+// left alone.
+func isInvisible(raw []byte, offset int, prev, r rune) bool {
+	if _, ok := invisible[r]; !ok {
+		return false
+	}
+	if r != '\u200d' {
+		return true
+	}
+	next, _ := utf8.DecodeRune(raw[offset+utf8.RuneLen(r):])
+	return !isPictograph(prev) || !isPictograph(next)
+}
+
+func isPictograph(r rune) bool {
+	return unicode.Is(unicode.So, r) || r == '\ufe0f'
+}
+
+// markdownFiles returns every *.md file under dir, recursively.
+func markdownFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".md" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func main() {
+	check := flag.Bool("check", false, "report invisible characters and exit non-zero instead of fixing them")
+	dryRun := flag.Bool("dry-run", false, "print the characters it would fix without rewriting any file")
+	flag.Parse()
+
+	files, err := markdownFiles(contentDir)
+	if err != nil {
+		fatal(err)
+	}
+
+	failed := false
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fatal(err)
+		}
+		found := audit(content)
+		if len(found) == 0 {
+			continue
+		}
+
+		unfixable := false
+		for _, f := range found {
+			if !f.fixable {
+				unfixable = true
+			}
+			if *check || *dryRun || !f.fixable {
+				fmt.Printf("ERROR: %s:%s\n", file, f)
+			}
+		}
+		if unfixable || *check {
+			failed = true
+		}
+		fixed := fix(content)
+		if *check || bytes.Equal(fixed, content) {
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("Would fix invisible characters in: %s\n", file)
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(file, fixed, info.Mode().Perm()); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Fixed invisible characters in: %s\n", file)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	if *check {
+		fmt.Println("All markdown files are clean UTF-8")
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"clean", "plain text\n", nil},
+		{"leading bom", "\ufeff---\n", []string{"1:1 (byte 0): byte-order mark U+FEFF"}},
+		{"nbsp on second line", "a\nb\u00a0c\n", []string{"2:2 (byte 3): non-breaking space U+00A0"}},
+		{"zero-width space", "foo\u200bbar", []string{"1:4 (byte 3): zero-width space U+200B"}},
+		{"invalid byte", "ok\xffok", []string{"1:3 (byte 2): invalid UTF-8 byte 0xFF"}},
+		{"stray joiner", "a\u200db", []string{"1:2 (byte 1): zero-width joiner U+200D"}},
+		{"emoji joiner", "\U0001F469\u200d\U0001F4BB", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			for _, f := range audit([]byte(c.in)) {
+				got = append(got, f.String())
+			}
+			if strings.Join(got, "|") != strings.Join(c.want, "|") {
+				t.Errorf("audit(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFix(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bom", "\ufeff---\n", "---\n"},
+		{"nbsp", "10\u00a0ms", "10 ms"},
+		{"zero-width", "foo\u200b\u2060bar", "foobar"},
+		{"keeps emoji joiner", "\U0001F469\u200d\U0001F4BB", "\U0001F469\u200d\U0001F4BB"},
+		{"keeps invalid bytes", "ok\xff\u00a0", "ok\xff "},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := string(fix([]byte(c.in))); got != c.want {
+				t.Errorf("fix(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"cmp"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// linkBudget is how many outbound links a post may carry before it counts
+// as link-heavy: more than Links distinct external URLs, or more than
+// Density of them per thousand words. Link-heavy posts, round-ups and
+// reading lists mostly, are the ones that rot fastest.
+type linkBudget struct {
+	Links   int
+	Density float64
+}
+
+// minDenseLinks is how many outbound links a post needs before its density
+// counts: a short note citing its one source isn't a link list.
+const minDenseLinks = 5
+
+// over reports whether p spends more than the budget.
+func (b linkBudget) over(p post) bool {
+	return p.Links > b.Links || p.Links >= minDenseLinks && density(p) > b.Density
+}
+
+// density is p's outbound links per thousand words.
+func density(p post) float64 {
+	return float64(p.Links) * 1000 / float64(max(p.Words, 1))
+}
+
+// linkHeavy returns the posts over budget, densest first.
+func linkHeavy(posts []post, budget linkBudget) []post {
+	var heavy []post
+	for _, p := range posts {
+		if budget.over(p) {
+			heavy = append(heavy, p)
+		}
+	}
+	slices.SortFunc(heavy, func(a, b post) int {
+		return cmp.Or(cmp.Compare(density(b), density(a)), cmp.Compare(b.Links, a.Links), strings.Compare(a.File, b.File))
+	})
+	return heavy
+}
+
+var (
+	fencePattern      = regexp.MustCompile("(?ms)^[ \\t]*(```|~~~)[^\\n]*\\n.*?^[ \\t]*(```|~~~)[ \\t]*$")
+	inlineCodePattern = regexp.MustCompile("`[^`]*`")
+	urlPattern        = regexp.MustCompile("https?://[^\\s<>\"'`\\[\\]()]+")
+	markupPattern     = regexp.MustCompile(`<[^>]*>|\{\{[<%].*?[%>]\}\}|\]\([^)]*\)`)
+)
+
+// selfHosts are the site's own hosts; links to them aren't outbound.
+var selfHosts = []string{"rednafi.com", "www.rednafi.com"}
+
+// countLinks returns the distinct external URLs body links to and the
+// words of its prose. Code, fenced or inline, counts as neither: URLs
+// there are examples, not links.
+func countLinks(body string) (links, words int) {
+	text := inlineCodePattern.ReplaceAllString(fencePattern.ReplaceAllString(body, ""), "")
+	seen := map[string]bool{}
+	for _, rawURL := range urlPattern.FindAllString(text, -1) {
+		rawURL = strings.TrimRight(rawURL, ".,;:!?*_~")
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" || slices.Contains(selfHosts, strings.ToLower(u.Hostname())) {
+			continue
+		}
+		seen[rawURL] = true
+	}
+	// A Markdown link's target isn't a word the reader sees, and neither
+	// is a list bullet or a table pipe.
+	for field := range strings.FieldsSeq(markupPattern.ReplaceAllString(text, " ")) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words++
+		}
+	}
+	return len(seen), words
+}
@@ -0,0 +1,351 @@
+// Command freshness lists published posts that haven't been touched in a
+// while, so the oldest widely read ones can be updated or marked outdated
+// with `outdated: true` in their frontmatter, which puts a banner on the
+// page.
+//
+// A post's last touch is its latest git commit, or its publish date when
+// git has no record of it. Posts older than -years are listed, most viewed
+// first when -traffic names a CSV export of page views from Google Analytics
+// (a page path column and a views column), otherwise oldest first. Among
+// equals, link-heavy posts come first: those with more than -max-links
+// distinct outbound links, or more than -max-link-density per thousand
+// words, rot fastest.
+//
+// With -links, it lists every link-heavy post instead, densest first, to
+// decide which posts to prune or to check more often.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const contentDir = "content"
+
+type siteConfig struct {
+	Params struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+	} `yaml:"params"`
+}
+
+// post is one published post and when it was last touched.
+type post struct {
+	File     string
+	Path     string
+	Touched  time.Time
+	Outdated bool
+	Views    int
+	// Links counts the distinct external URLs the post links to; Words,
+	// the words of its prose.
+	Links int
+	Words int
+}
+
+func main() {
+	years := flag.Int("years", 3, "list posts untouched for at least this many years")
+	traffic := flag.String("traffic", "", "CSV of page views per path, exported from Google Analytics")
+	links := flag.Bool("links", false, "list the link-heavy posts instead of the untouched ones")
+	var budget linkBudget
+	flag.IntVar(&budget.Links, "max-links", 40, "outbound links a post may carry before it counts as link-heavy")
+	flag.Float64Var(&budget.Density, "max-link-density", 20, "outbound links per thousand words a post may carry before it counts as link-heavy")
+	flag.Parse()
+
+	sections, err := loadSections("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	touched, err := gitTouched(contentDir)
+	if err != nil {
+		fatal(err)
+	}
+	posts, err := collectPosts(contentDir, sections, touched)
+	if err != nil {
+		fatal(err)
+	}
+	if *traffic != "" {
+		views, err := loadTraffic(*traffic)
+		if err != nil {
+			fatal(err)
+		}
+		for i := range posts {
+			posts[i].Views = views[posts[i].Path]
+		}
+	}
+
+	if *links {
+		heavy := linkHeavy(posts, budget)
+		if len(heavy) == 0 {
+			fmt.Printf("no posts over %d outbound links or %g per thousand words\n", budget.Links, budget.Density)
+			return
+		}
+		fmt.Printf("%d link-heavy post%s:\n", len(heavy), plural(len(heavy)))
+		for _, p := range heavy {
+			fmt.Printf("  %4d links  %5.1f per 1k words  %s  %s\n", p.Links, density(p), p.File, p.Path)
+		}
+		return
+	}
+
+	stale := staleSince(posts, time.Now().AddDate(-*years, 0, 0), budget)
+	if len(stale) == 0 {
+		fmt.Printf("no posts untouched for %d year%s\n", *years, plural(*years))
+		return
+	}
+	fmt.Printf("%d post%s untouched for %d year%s:\n", len(stale), plural(len(stale)), *years, plural(*years))
+	for _, p := range stale {
+		flagged := ""
+		if p.Outdated {
+			flagged = " [outdated]"
+		}
+		if budget.over(p) {
+			flagged += fmt.Sprintf(" [%d links]", p.Links)
+		}
+		views := ""
+		if *traffic != "" {
+			views = fmt.Sprintf("%8d views  ", p.Views)
+		}
+		fmt.Printf("  %s%s  %s  %s%s\n", views, p.Touched.Format("2006-01-02"), p.File, p.Path, flagged)
+	}
+}
+
+// staleSince returns the posts last touched before cutoff, most viewed
+// first, then those over budget, then oldest first.
+func staleSince(posts []post, cutoff time.Time, budget linkBudget) []post {
+	var stale []post
+	for _, p := range posts {
+		if p.Touched.Before(cutoff) {
+			stale = append(stale, p)
+		}
+	}
+	slices.SortFunc(stale, func(a, b post) int {
+		heavy := func(p post) int {
+			if budget.over(p) {
+				return 1
+			}
+			return 0
+		}
+		return cmp.Or(cmp.Compare(b.Views, a.Views), cmp.Compare(heavy(b), heavy(a)), a.Touched.Compare(b.Touched), strings.Compare(a.File, b.File))
+	})
+	return stale
+}
+
+// collectPosts reads every published post under sections. A post's last
+// touch is the later of its publish date and its entry in touched.
+func collectPosts(root string, sections []string, touched map[string]time.Time) ([]post, error) {
+	var posts []post
+	now := time.Now()
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		section, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if !slices.Contains(sections, section) {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, body, _ := splitFrontmatter(string(raw))
+		var fm struct {
+			Date        string `yaml:"date"`
+			Outdated    bool   `yaml:"outdated"`
+			AtprotoPath string `yaml:"atprotoPath"`
+		}
+		if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		date, err := parseDate(fm.Date)
+		if err != nil {
+			return fmt.Errorf("%s: date %q: %w", filePath, fm.Date, err)
+		}
+		if date.After(now) {
+			return nil
+		}
+
+		file := filepath.ToSlash(filePath)
+		last := date
+		if t := touched[file]; t.After(last) {
+			last = t
+		}
+		links, words := countLinks(body)
+		posts = append(posts, post{File: file, Path: fm.AtprotoPath, Touched: last, Outdated: fm.Outdated, Links: links, Words: words})
+		return nil
+	})
+	return posts, err
+}
+
+// gitTouched returns the date of the latest commit to each file under dir,
+// keyed by its slash-separated path from the repository root. Files git
+// doesn't know are absent.
+func gitTouched(dir string) (map[string]time.Time, error) {
+	out, err := exec.Command("git", "log", "--format=%x00%cI", "--name-only", "--", dir).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return parseGitLog(bytes.NewReader(out))
+}
+
+// parseGitLog reads `git log --format=%x00%cI --name-only` output, newest
+// commit first, and keeps the first date seen for each file.
+func parseGitLog(r io.Reader) (map[string]time.Time, error) {
+	touched := map[string]time.Time{}
+	var current time.Time
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if date, ok := strings.CutPrefix(line, "\x00"); ok {
+			t, err := time.Parse(time.RFC3339, date)
+			if err != nil {
+				return nil, fmt.Errorf("git log: commit date %q: %w", date, err)
+			}
+			current = t
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if _, seen := touched[line]; !seen {
+			touched[line] = current
+		}
+	}
+	return touched, scanner.Err()
+}
+
+// loadTraffic reads a page views export: a header row naming a path column
+// ("Page path", "Page path and screen class" or "path") and a views column
+// ("Views" or "Screen page views"), then one row per page. Query strings and
+// a missing trailing slash are folded into the post's path.
+func loadTraffic(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	views, err := parseTraffic(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return views, nil
+}
+
+func parseTraffic(r io.Reader) (map[string]int, error) {
+	reader := csv.NewReader(r)
+	// GA exports open with "#" comment lines and vary the column count.
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	pathCol, viewsCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "page path", "page path and screen class", "path":
+			pathCol = i
+		case "views", "screen page views", "screenpageviews":
+			viewsCol = i
+		}
+	}
+	if pathCol < 0 || viewsCol < 0 {
+		return nil, fmt.Errorf("header %q needs a page path and a views column", strings.Join(header, ","))
+	}
+
+	views := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) <= max(pathCol, viewsCol) {
+			continue
+		}
+		count, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(record[viewsCol]), ",", ""))
+		if err != nil {
+			continue
+		}
+		views[normalizePath(record[pathCol])] += count
+	}
+	return views, nil
+}
+
+func normalizePath(p string) string {
+	p, _, _ = strings.Cut(strings.TrimSpace(p), "?")
+	p, _, _ = strings.Cut(p, "#")
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return p
+}
+
+func loadSections(configPath string) ([]string, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	sections := slices.Clone(config.Params.MainSections)
+	if config.Params.NotesSection != "" {
+		sections = append(sections, config.Params.NotesSection)
+	}
+	return sections, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a YYYY-MM-DD or RFC 3339 date")
+}
+
+func splitFrontmatter(raw string) (frontmatter, body string, ok bool) {
+	const start = "---\n"
+	if !strings.HasPrefix(raw, start) {
+		return "", raw, false
+	}
+	idx := strings.Index(raw[len(start):], "\n---\n")
+	if idx == -1 {
+		return "", raw, false
+	}
+	fmEnd := len(start) + idx
+	return raw[len(start):fmEnd], raw[fmEnd+len("\n---\n"):], true
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "freshness:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGitLog(t *testing.T) {
+	log := "\x002025-03-01T10:00:00Z\n\ncontent/go/a.md\ncontent/go/b.md\n" +
+		"\x002021-06-01T10:00:00Z\n\ncontent/go/a.md\ncontent/python/c.md\n"
+	got, err := parseGitLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"content/go/a.md":     "2025-03-01",
+		"content/go/b.md":     "2025-03-01",
+		"content/python/c.md": "2021-06-01",
+	} {
+		if date := got[file].Format("2006-01-02"); date != want {
+			t.Errorf("%s touched %s, want %s", file, date, want)
+		}
+	}
+}
+
+func TestParseTraffic(t *testing.T) {
+	export := "# Pages and screens\n# 2025-01-01 - 2025-12-31\n" +
+		"Page path and screen class,Views,Users\n" +
+		"/go/gofix/,\"1,204\",900\n" +
+		"/go/gofix/?utm_source=x,6,5\n" +
+		"/python/old,40,30\n" +
+		"Total,1250,935\n"
+	got, err := parseTraffic(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["/go/gofix/"] != 1210 || got["/python/old/"] != 40 {
+		t.Fatalf("parseTraffic = %v", got)
+	}
+
+	if _, err := parseTraffic(strings.NewReader("a,b\n1,2\n")); err == nil {
+		t.Fatal("parseTraffic accepted a header without path and views columns")
+	}
+}
+
+func TestStaleSinceRanksByViews(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "old.md"), "---\ndate: 2019-01-01\natprotoPath: /go/old/\n---\nBody.\n")
+	mustWrite(t, filepath.Join(root, "go", "popular.md"), "---\ndate: 2020-01-01\noutdated: true\natprotoPath: /go/popular/\n---\nBody.\n")
+	mustWrite(t, filepath.Join(root, "go", "edited.md"), "---\ndate: 2018-01-01\natprotoPath: /go/edited/\n---\nBody.\n")
+	mustWrite(t, filepath.Join(root, "go", "recent.md"), "---\ndate: 2025-01-01\natprotoPath: /go/recent/\n---\nBody.\n")
+	mustWrite(t, filepath.Join(root, "notes", "skipped.md"), "---\ndate: 2010-01-01\n---\nBody.\n")
+
+	touched := map[string]time.Time{
+		filepath.ToSlash(filepath.Join(root, "go", "edited.md")): time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	posts, err := collectPosts(root, []string{"go"}, touched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views := map[string]int{"/go/popular/": 500, "/go/old/": 10}
+	for i := range posts {
+		posts[i].Views = views[posts[i].Path]
+	}
+
+	stale := staleSince(posts, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), linkBudget{Links: 40, Density: 20})
+	var got []string
+	for _, p := range stale {
+		got = append(got, p.Path)
+	}
+	if want := "/go/popular/ /go/old/"; strings.Join(got, " ") != want {
+		t.Fatalf("staleSince = %v, want %s", got, want)
+	}
+	if !stale[0].Outdated || stale[1].Outdated {
+		t.Fatalf("outdated flags = %v, %v; want true, false", stale[0].Outdated, stale[1].Outdated)
+	}
+}
+
+func TestCountLinks(t *testing.T) {
+	body := "See [the spec](https://go.dev/ref/spec) and <https://go.dev/blog/>.\n" +
+		"Again: https://go.dev/ref/spec, and [home](https://rednafi.com/go/).\n" +
+		"Run `curl https://example.com/inline` first.\n" +
+		"```sh\ncurl https://example.com/fenced\n```\n"
+	links, words := countLinks(body)
+	if links != 2 {
+		t.Errorf("links = %d, want 2", links)
+	}
+	if words != 10 {
+		t.Errorf("words = %d, want 10", words)
+	}
+}
+
+func TestLinkHeavyRanksDensestFirst(t *testing.T) {
+	root := t.TempDir()
+	list := "---\ndate: 2019-01-01\natprotoPath: /go/list/\n---\n"
+	for i := range 5 {
+		list += fmt.Sprintf("- [Tool %d](https://tool%d.example/)\n", i, i)
+	}
+	essay := "---\ndate: 2019-01-01\natprotoPath: /go/essay/\n---\n" + strings.Repeat("word ", 1000) + "[one](https://one.example/)\n"
+	mustWrite(t, filepath.Join(root, "go", "list.md"), list)
+	mustWrite(t, filepath.Join(root, "go", "essay.md"), essay)
+	mustWrite(t, filepath.Join(root, "go", "old.md"), "---\ndate: 2018-01-01\natprotoPath: /go/old/\n---\nBody.\n")
+
+	posts, err := collectPosts(root, []string{"go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := linkBudget{Links: 40, Density: 20}
+	heavy := linkHeavy(posts, budget)
+	if len(heavy) != 1 || heavy[0].Path != "/go/list/" || heavy[0].Links != 5 {
+		t.Fatalf("linkHeavy = %+v, want only /go/list/ with 5 links", heavy)
+	}
+	if heavy := linkHeavy(posts, linkBudget{Links: 0, Density: 20}); len(heavy) != 2 || heavy[1].Path != "/go/essay/" {
+		t.Fatalf("linkHeavy over a zero link budget = %+v, want /go/list/ then /go/essay/", heavy)
+	}
+
+	// The list is newer than old.md but over budget, so it comes first.
+	var got []string
+	for _, p := range staleSince(posts, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), budget) {
+		got = append(got, p.Path)
+	}
+	if want := "/go/list/ /go/old/ /go/essay/"; strings.Join(got, " ") != want {
+		t.Fatalf("staleSince = %v, want %s", got, want)
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
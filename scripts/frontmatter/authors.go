@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// authorTaxonomy is the Hugo taxonomy a post's author field feeds, so each
+// author gets a page listing their posts at /author/ID/.
+const authorTaxonomy = "author"
+
+// author is an entry of data/authors.yml, the people a post's author field
+// may name.
+type author struct {
+	Name   string `yaml:"name"`
+	Link   string `yaml:"link"`
+	Avatar string `yaml:"avatar"`
+}
+
+// loadAuthors reads the authors registry and checks every entry: a name, a
+// link that is a site path or an https URL, and an avatar that is either an
+// https URL or a file under static/. siteAuthor, params.author, writes the
+// posts without an author field and must be registered too.
+func loadAuthors(registryPath, staticDir, siteAuthor string) (map[string]author, error) {
+	raw, err := os.ReadFile(registryPath)
+	if err != nil {
+		return nil, fmt.Errorf("read authors: %w", err)
+	}
+	var authors map[string]author
+	if err := yaml.Unmarshal(raw, &authors); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", registryPath, err)
+	}
+
+	var problems []string
+	registered := false
+	for _, id := range slices.Sorted(maps.Keys(authors)) {
+		a := authors[id]
+		if id != strings.ToLower(id) || strings.ContainsAny(id, " /") {
+			problems = append(problems, fmt.Sprintf("%s: want a lowercase id without spaces or slashes, as it names the author's page", id))
+		}
+		if strings.TrimSpace(a.Name) == "" {
+			problems = append(problems, id+": missing name")
+		}
+		if a.Name == siteAuthor {
+			registered = true
+		}
+		switch {
+		case a.Link == "":
+			problems = append(problems, id+": missing link")
+		case !strings.HasPrefix(a.Link, "/") && !isHTTPS(a.Link):
+			problems = append(problems, fmt.Sprintf("%s: link %q is neither a site path nor an https URL", id, a.Link))
+		}
+		switch {
+		case a.Avatar == "":
+			problems = append(problems, id+": missing avatar")
+		case isHTTPS(a.Avatar):
+		case !strings.HasPrefix(a.Avatar, "/"):
+			problems = append(problems, fmt.Sprintf("%s: avatar %q is neither a site path nor an https URL", id, a.Avatar))
+		default:
+			if _, err := os.Stat(filepath.Join(staticDir, filepath.FromSlash(a.Avatar))); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: avatar %s is not in %s", id, a.Avatar, staticDir))
+			}
+		}
+	}
+	if siteAuthor != "" && !registered {
+		problems = append(problems, fmt.Sprintf("the site's author, %q, is not registered", siteAuthor))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s:\n  %s", registryPath, strings.Join(problems, "\n  "))
+	}
+	return authors, nil
+}
+
+func isHTTPS(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+// checkAuthor returns the author id a post names, after checking it is
+// registered. A post without one is by the site's author.
+func checkAuthor(filePath string, value *yaml.Node, authors map[string]author) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	id := strings.TrimSpace(scalar(value))
+	if id == "" {
+		return "", fmt.Errorf("%s: author is empty; drop it for the site's author or name one from data/authors.yml", filePath)
+	}
+	if _, ok := authors[id]; !ok {
+		return "", fmt.Errorf("%s: author %q is not in data/authors.yml; want one of %s", filePath, id, strings.Join(slices.Sorted(maps.Keys(authors)), ", "))
+	}
+	return id, nil
+}
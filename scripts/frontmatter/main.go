@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"gopkg.in/yaml.v3"
@@ -28,8 +31,27 @@ var canonicalKeys = []string{
 	"atUri",
 }
 
+// optionalKeys may follow the canonical keys but are only written when set,
+// so the posts that don't need them stay untouched.
+var optionalKeys = []string{
+	// outdated puts a banner on a post that may no longer hold; see
+	// `make freshness`.
+	"outdated",
+	// lint_ignore lists linkcheck rules the post opts out of.
+	"lint_ignore",
+	// devto is the id of the post's cross-posted copy on dev.to, whose
+	// canonical link `curation syndication` keeps an eye on.
+	"devto",
+	// author is the id, in data/authors.yml, of a guest writer; posts
+	// without one are by the site's author.
+	"author",
+}
+
 type siteConfig struct {
-	Params struct {
+	Permalinks map[string]any    `yaml:"permalinks"`
+	Taxonomies map[string]string `yaml:"taxonomies"`
+	Params     struct {
+		Author       string   `yaml:"author"`
 		MainSections []string `yaml:"mainSections"`
 		NotesSection string   `yaml:"notesSection"`
 	} `yaml:"params"`
@@ -38,6 +60,9 @@ type siteConfig struct {
 type publishConfig struct {
 	sections     []string
 	notesSection string
+	permalinks   permalinks
+	// authors are the registered authors a post's author field may name.
+	authors map[string]author
 }
 
 type discussion struct {
@@ -55,12 +80,18 @@ type postFrontmatter struct {
 	Discussions []discussion
 	Mermaid     bool
 	TypeLabel   string
+	Outdated    bool
 	AtprotoPath string
 	AtURI       string
+	LintIgnore  []string
+	DevTo       int
+	Author      string
 }
 
 func main() {
 	check := flag.Bool("check", false, "fail if post frontmatter is not canonical")
+	rename := flag.Bool("rename", false, "rename posts whose hand-edited slug no longer matches the file name")
+	dryRun := flag.Bool("dry-run", false, "print the renames and frontmatter rewrites without touching any file")
 	flag.Parse()
 
 	publishing, err := loadPublishConfig("config.yml")
@@ -68,7 +99,7 @@ func main() {
 		fatal(err)
 	}
 
-	var changed []string
+	var changed, renamed, orphaned []string
 	err = filepath.WalkDir("content", func(filePath string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -76,7 +107,7 @@ func main() {
 		if entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
 			return nil
 		}
-		if !slices.Contains(publishing.sections, sectionFor(filePath)) {
+		if !slices.Contains(publishing.sections, sectionFor(filePath)) || isBundleResource(filePath) {
 			return nil
 		}
 
@@ -86,7 +117,24 @@ func main() {
 		}
 
 		raw := string(rawBytes)
-		next, err := normalizePostFrontmatter(raw, filePath, publishing.notesSection)
+		slug, slugURL, err := orphanedSlug(raw, filePath, publishing)
+		if err != nil {
+			return err
+		}
+		if slug != "" {
+			if *check || !*rename {
+				orphaned = append(orphaned, fmt.Sprintf("%s: slug serves %s, which normalizing would drop", filePath, slugURL))
+				return nil
+			}
+			target, err := renameToSlug(filePath, slug, *dryRun)
+			if err != nil {
+				return err
+			}
+			renamed = append(renamed, filePath+" -> "+target)
+			filePath = target
+		}
+
+		next, err := normalizePostFrontmatter(raw, filePath, publishing)
 		if err != nil {
 			return err
 		}
@@ -95,7 +143,7 @@ func main() {
 		}
 
 		changed = append(changed, filePath)
-		if !*check {
+		if !*check && !*dryRun {
 			return os.WriteFile(filePath, []byte(next), 0o644)
 		}
 		return nil
@@ -104,13 +152,32 @@ func main() {
 		fatal(err)
 	}
 
+	for _, move := range renamed {
+		if *dryRun {
+			fmt.Printf("would rename %s\n", move)
+		} else {
+			fmt.Printf("renamed %s\n", move)
+		}
+	}
+	if len(orphaned) > 0 {
+		fmt.Printf("%d post%s whose slug no longer matches the file name:\n", len(orphaned), plural(len(orphaned)))
+		for _, line := range orphaned {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Println("rename the file with --rename, or add the old URL to aliases")
+		os.Exit(1)
+	}
+
 	if len(changed) == 0 {
 		return
 	}
 
 	verb := "updated"
-	if *check {
+	switch {
+	case *check:
 		verb = "need"
+	case *dryRun:
+		verb = "would get"
 	}
 	fmt.Printf("%d post frontmatter file%s %s normalization\n", len(changed), plural(len(changed)), verb)
 	for _, filePath := range changed {
@@ -121,7 +188,64 @@ func main() {
 	}
 }
 
-func normalizePostFrontmatter(raw, filePath, notesSection string) (string, error) {
+// orphanedSlug returns a post's explicit slug and the URL it currently serves
+// when that slug disagrees with the file name and no alias keeps the URL
+// alive. Normalizing pins the slug to the file name, so such a post would
+// otherwise move to a new URL and leave a 404 behind.
+func orphanedSlug(raw, filePath string, publishing publishConfig) (slug, slugURL string, err error) {
+	fmRaw, _, ok := splitFrontmatter(raw)
+	if !ok {
+		return "", "", fmt.Errorf("%s: missing YAML frontmatter", filePath)
+	}
+	var fm struct {
+		Slug    string   `yaml:"slug"`
+		Date    string   `yaml:"date"`
+		Aliases []string `yaml:"aliases"`
+	}
+	if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+		return "", "", fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+	}
+
+	slug = strings.TrimSpace(fm.Slug)
+	if slug == "" || slug == slugFromFilePath(filePath) {
+		return "", "", nil
+	}
+	slugURL, err = atprotoPathFor(filePath, slug, strings.TrimSpace(fm.Date), publishing)
+	if err != nil {
+		return "", "", err
+	}
+	if slices.Contains(fm.Aliases, slugURL) {
+		return "", "", nil
+	}
+	return slug, slugURL, nil
+}
+
+// renameToSlug moves a post so its file name matches its explicit slug,
+// following the repo's snake_case file naming, and returns the new path. A
+// leaf bundle moves as a whole, resources and all. With dryRun it only
+// checks that the new path is free.
+func renameToSlug(filePath, slug string, dryRun bool) (string, error) {
+	name := strings.ReplaceAll(slugPart(slug), "-", "_")
+	from, target := filePath, filepath.Join(filepath.Dir(filePath), name+".md")
+	if filepath.Base(filePath) == "index.md" {
+		from = filepath.Dir(filePath)
+		target = filepath.Join(filepath.Dir(from), name)
+	}
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("%s: cannot rename to %s: file exists", filePath, target)
+	}
+	if !dryRun {
+		if err := os.Rename(from, target); err != nil {
+			return "", err
+		}
+	}
+	if from != filePath {
+		return filepath.Join(target, "index.md"), nil
+	}
+	return target, nil
+}
+
+func normalizePostFrontmatter(raw, filePath string, publishing publishConfig) (string, error) {
 	fmRaw, body, ok := splitFrontmatter(raw)
 	if !ok {
 		return "", fmt.Errorf("%s: missing YAML frontmatter", filePath)
@@ -140,7 +264,7 @@ func normalizePostFrontmatter(raw, filePath, notesSection string) (string, error
 		return "", err
 	}
 
-	post, err := canonicalPost(filePath, body, notesSection, values)
+	post, err := canonicalPost(filePath, body, publishing, values)
 	if err != nil {
 		return "", err
 	}
@@ -150,7 +274,7 @@ func normalizePostFrontmatter(raw, filePath, notesSection string) (string, error
 
 func frontmatterMap(filePath string, node *yaml.Node) (map[string]*yaml.Node, error) {
 	known := map[string]bool{}
-	for _, key := range canonicalKeys {
+	for _, key := range slices.Concat(canonicalKeys, optionalKeys) {
 		known[key] = true
 	}
 
@@ -165,7 +289,7 @@ func frontmatterMap(filePath string, node *yaml.Node) (map[string]*yaml.Node, er
 	return values, nil
 }
 
-func canonicalPost(filePath, body, notesSection string, values map[string]*yaml.Node) (postFrontmatter, error) {
+func canonicalPost(filePath, body string, publishing publishConfig, values map[string]*yaml.Node) (postFrontmatter, error) {
 	required := []string{"title", "slug", "date", "description", "tags"}
 	for _, key := range required {
 		if values[key] == nil {
@@ -178,7 +302,12 @@ func canonicalPost(filePath, body, notesSection string, values map[string]*yaml.
 		return postFrontmatter{}, fmt.Errorf("%s: slug cannot be empty", filePath)
 	}
 
-	atprotoPath, err := atprotoPathFor(filePath, slug, notesSection)
+	date := strings.TrimSpace(scalar(values["date"]))
+	if err := checkDatePath(filePath, date); err != nil {
+		return postFrontmatter{}, err
+	}
+
+	atprotoPath, err := atprotoPathFor(filePath, slug, date, publishing)
 	if err != nil {
 		return postFrontmatter{}, err
 	}
@@ -201,18 +330,46 @@ func canonicalPost(filePath, body, notesSection string, values map[string]*yaml.
 		return postFrontmatter{}, err
 	}
 
+	lintIgnore, err := optionalStringSeq(filePath, "lint_ignore", values["lint_ignore"])
+	if err != nil {
+		return postFrontmatter{}, err
+	}
+
+	devTo := 0
+	if value := strings.TrimSpace(scalar(values["devto"])); value != "" {
+		if devTo, err = strconv.Atoi(value); err != nil || devTo <= 0 {
+			return postFrontmatter{}, fmt.Errorf("%s: devto must be a dev.to article id, got %q", filePath, value)
+		}
+	}
+
+	author, err := checkAuthor(filePath, values["author"], publishing.authors)
+	if err != nil {
+		return postFrontmatter{}, err
+	}
+
+	outdated := false
+	if value := strings.TrimSpace(scalar(values["outdated"])); value != "" {
+		if outdated, err = strconv.ParseBool(value); err != nil {
+			return postFrontmatter{}, fmt.Errorf("%s: outdated must be true or false, got %q", filePath, value)
+		}
+	}
+
 	return postFrontmatter{
 		Title:       strings.TrimSpace(scalar(values["title"])),
 		Slug:        slug,
-		Date:        strings.TrimSpace(scalar(values["date"])),
+		Date:        date,
 		Description: strings.TrimSpace(scalar(values["description"])),
 		Tags:        tags,
 		Aliases:     aliases,
 		Discussions: discussions,
 		Mermaid:     containsMermaid(body),
 		TypeLabel:   strings.TrimSpace(scalar(values["type_label"])),
+		Outdated:    outdated,
 		AtprotoPath: atprotoPath,
 		AtURI:       strings.TrimSpace(scalar(values["atUri"])),
+		LintIgnore:  lintIgnore,
+		DevTo:       devTo,
+		Author:      author,
 	}, nil
 }
 
@@ -234,6 +391,18 @@ func renderFrontmatter(post postFrontmatter) string {
 	writeKeyValue(&b, "type_label", quoted(post.TypeLabel))
 	writeKeyValue(&b, "atprotoPath", post.AtprotoPath)
 	writeKeyValue(&b, "atUri", quoted(post.AtURI))
+	if post.Outdated {
+		writeKeyValue(&b, "outdated", "true")
+	}
+	if len(post.LintIgnore) > 0 {
+		writeStringSeq(&b, "lint_ignore", post.LintIgnore)
+	}
+	if post.DevTo != 0 {
+		writeKeyValue(&b, "devto", strconv.Itoa(post.DevTo))
+	}
+	if post.Author != "" {
+		writeKeyValue(&b, "author", plainOrQuoted(post.Author))
+	}
 	return b.String()
 }
 
@@ -347,23 +516,96 @@ func optionalDiscussions(filePath string, node *yaml.Node) ([]discussion, error)
 	return values, nil
 }
 
-func atprotoPathFor(filePath, slug, notesSection string) (string, error) {
+// atprotoPathFor is the URL path Hugo serves a post from, following the
+// permalink pattern of its section when config.yml sets one.
+func atprotoPathFor(filePath, slug, date string, publishing publishConfig) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(filePath), "content/"), "/")
+	if parts[0] == publishing.notesSection && len(parts) < 4 {
+		return "", fmt.Errorf("%s: notes posts must live under content/%s/YYYY/MM/", filePath, publishing.notesSection)
+	}
+	return publishing.permalinks.path(filePath, slug, date)
+}
+
+var datedFileName = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[-_]`)
+
+// checkDatePath verifies that dates embedded in a post's path agree with its
+// frontmatter date: year and month directories (content/shards/2026/03/) and
+// date-prefixed file names (2019-07-04-title.md) left over from imports.
+func checkDatePath(filePath, date string) error {
+	published, err := parseDate(date)
+	if err != nil {
+		return fmt.Errorf("%s: date %q: %w", filePath, date, err)
+	}
+
 	parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(filePath), "content/"), "/")
-	section := parts[0]
+	dirs := parts[1 : len(parts)-1]
+	for i, dir := range dirs {
+		if !isYearDir(dir) {
+			continue
+		}
+		want := published.Format("2006")
+		got := dir
+		if i+1 < len(dirs) && len(dirs[i+1]) == 2 {
+			want = published.Format("2006/01")
+			got = dir + "/" + dirs[i+1]
+		}
+		if got != want {
+			return fmt.Errorf("%s: date %s disagrees with %s in the path", filePath, date, got)
+		}
+	}
 
-	if section == notesSection {
-		if len(parts) < 4 {
-			return "", fmt.Errorf("%s: notes posts must live under content/%s/YYYY/MM/", filePath, notesSection)
+	if m := datedFileName.FindStringSubmatch(postName(filePath)); m != nil {
+		if m[1] != published.Format("2006-01-02") {
+			return fmt.Errorf("%s: date %s disagrees with %s in the file name", filePath, date, m[1])
 		}
-		return fmt.Sprintf("/%s/%s/%s/%s/", notesSection, parts[1], parts[2], slug), nil
 	}
+	return nil
+}
 
-	return fmt.Sprintf("/%s/%s/", section, slug), nil
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not a YYYY-MM-DD or RFC 3339 date")
+}
+
+func isYearDir(dir string) bool {
+	if len(dir) != 4 {
+		return false
+	}
+	_, err := strconv.Atoi(dir)
+	return err == nil
 }
 
 func slugFromFilePath(filePath string) string {
-	base := filepath.Base(filepath.ToSlash(filePath))
-	return slugPart(strings.TrimSuffix(base, filepath.Ext(base)))
+	return slugPart(postName(filePath))
+}
+
+// postName is what a post's URL derives from: its file name, or for a leaf
+// bundle's index.md, the bundle's directory name.
+func postName(filePath string) string {
+	filePath = filepath.ToSlash(filePath)
+	if path.Base(filePath) == "index.md" {
+		return path.Base(path.Dir(filePath))
+	}
+	return strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+}
+
+// isBundleResource reports whether filePath sits inside a leaf bundle
+// without being its index.md. Hugo treats such Markdown as a resource of
+// the bundle's post, not as a post of its own.
+func isBundleResource(filePath string) bool {
+	if filepath.Base(filePath) == "index.md" {
+		return false
+	}
+	for dir := filepath.Dir(filePath); filepath.Base(dir) != "content" && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "index.md")); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func sectionFor(filePath string) string {
@@ -387,7 +629,11 @@ func loadPublishConfig(configPath string) (publishConfig, error) {
 		return publishConfig{}, fmt.Errorf("parse %s: %w", configPath, err)
 	}
 
-	publishing := publishConfig{notesSection: config.Params.NotesSection}
+	patterns, err := parsePermalinks(config.Permalinks)
+	if err != nil {
+		return publishConfig{}, fmt.Errorf("%s: %w", configPath, err)
+	}
+	publishing := publishConfig{notesSection: config.Params.NotesSection, permalinks: patterns}
 	for _, section := range config.Params.MainSections {
 		if section != "" {
 			publishing.sections = append(publishing.sections, section)
@@ -401,6 +647,16 @@ func loadPublishConfig(configPath string) (publishConfig, error) {
 		return publishConfig{}, fmt.Errorf("%s did not define params.mainSections or params.notesSection", configPath)
 	}
 
+	// Without the taxonomy, a post's author would render no page of theirs.
+	if config.Taxonomies[authorTaxonomy] != authorTaxonomy {
+		return publishConfig{}, fmt.Errorf("%s: taxonomies.%s must be %q, so each author gets a page", configPath, authorTaxonomy, authorTaxonomy)
+	}
+	root := filepath.Dir(configPath)
+	publishing.authors, err = loadAuthors(filepath.Join(root, "data", "authors.yml"), filepath.Join(root, "static"), config.Params.Author)
+	if err != nil {
+		return publishConfig{}, err
+	}
+
 	return publishing, nil
 }
 
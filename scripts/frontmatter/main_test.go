@@ -45,7 +45,7 @@ func TestPostFrontmatterConformsToCanonicalFormat(t *testing.T) {
 		}
 
 		raw := string(rawBytes)
-		normalized, err := normalizePostFrontmatter(raw, rel, publishing.notesSection)
+		normalized, err := normalizePostFrontmatter(raw, rel, publishing)
 		if err != nil {
 			violations = append(violations, fmt.Sprintf("%s: %v", rel, err))
 			return nil
@@ -59,6 +59,7 @@ func TestPostFrontmatterConformsToCanonicalFormat(t *testing.T) {
 			violations = append(violations, fmt.Sprintf("%s: %v", rel, err))
 			return nil
 		}
+		keys = slices.DeleteFunc(keys, func(key string) bool { return slices.Contains(optionalKeys, key) })
 		if !slices.Equal(keys, canonicalKeys) {
 			violations = append(violations, fmt.Sprintf("%s: frontmatter keys are %v, want %v", rel, keys, canonicalKeys))
 		}
@@ -91,7 +92,7 @@ atUri: ""
 Body.
 `
 
-	next, err := normalizePostFrontmatter(raw, "content/go/request_coalescing.md", "shards")
+	next, err := normalizePostFrontmatter(raw, "content/go/request_coalescing.md", publishConfig{notesSection: "shards"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -123,12 +124,237 @@ atUri: ""
 Body.
 `
 
-	_, err := normalizePostFrontmatter(raw, "content/go/old.md", "shards")
+	_, err := normalizePostFrontmatter(raw, "content/go/old.md", publishConfig{notesSection: "shards"})
 	if err == nil || !strings.Contains(err.Error(), `unknown frontmatter key "images"`) {
 		t.Fatalf("expected images to be rejected, got %v", err)
 	}
 }
 
+func TestNormalizePostFrontmatterKeepsLintIgnoreOnlyWhenSet(t *testing.T) {
+	raw := `---
+title: "Old"
+slug: old
+date: 2026-06-30
+description: >-
+    A short description.
+tags:
+    - Go
+aliases: []
+discussions: []
+mermaid: false
+type_label: ""
+atprotoPath: /go/old/
+atUri: ""
+lint_ignore: [http]
+---
+Body.
+`
+
+	next, err := normalizePostFrontmatter(raw, "content/go/old.md", publishConfig{notesSection: "shards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.Split(next, "\n---\n")[0], "atUri: \"\"\nlint_ignore:\n    - http") {
+		t.Fatalf("lint_ignore was not kept after the canonical keys:\n%s", next)
+	}
+
+	next, err = normalizePostFrontmatter(strings.Replace(raw, "lint_ignore: [http]\n", "", 1), "content/go/old.md", publishConfig{notesSection: "shards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(next, "lint_ignore") {
+		t.Fatalf("normalizing added an empty lint_ignore:\n%s", next)
+	}
+}
+
+func TestNormalizePostFrontmatterWritesOutdatedOnlyWhenTrue(t *testing.T) {
+	raw := `---
+title: "Old"
+slug: old
+date: 2026-06-30
+description: >-
+    A short description.
+tags:
+    - Go
+aliases: []
+discussions: []
+mermaid: false
+type_label: ""
+outdated: true
+atprotoPath: /go/old/
+atUri: ""
+---
+Body.
+`
+
+	next, err := normalizePostFrontmatter(raw, "content/go/old.md", publishConfig{notesSection: "shards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.Split(next, "\n---\n")[0], "atUri: \"\"\noutdated: true") {
+		t.Fatalf("outdated was not kept after the canonical keys:\n%s", next)
+	}
+
+	next, err = normalizePostFrontmatter(strings.Replace(raw, "outdated: true", "outdated: false", 1), "content/go/old.md", publishConfig{notesSection: "shards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(next, "outdated") {
+		t.Fatalf("normalizing kept outdated: false:\n%s", next)
+	}
+}
+
+func TestNormalizePostFrontmatterKeepsDevToID(t *testing.T) {
+	raw := `---
+title: "Old"
+slug: old
+date: 2026-06-30
+description: >-
+    A short description.
+tags:
+    - Go
+devto: 1873456
+---
+Body.
+`
+
+	next, err := normalizePostFrontmatter(raw, "content/go/old.md", publishConfig{notesSection: "shards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.Split(next, "\n---\n")[0], "atUri: \"\"\ndevto: 1873456") {
+		t.Fatalf("devto was not kept after the canonical keys:\n%s", next)
+	}
+
+	_, err = normalizePostFrontmatter(strings.Replace(raw, "1873456", "my-post-4k2p", 1), "content/go/old.md", publishConfig{notesSection: "shards"})
+	if err == nil || !strings.Contains(err.Error(), "devto must be a dev.to article id") {
+		t.Fatalf("expected a slug for devto to be rejected, got %v", err)
+	}
+}
+
+func TestOrphanedSlugFlagsHandEditedSlugWithoutAlias(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		filePath string
+		fm       string
+		want     string
+	}{
+		{"matches file name", "content/go/request_coalescing.md", "slug: request-coalescing\naliases: []\n", ""},
+		{"hand-edited slug", "content/go/request_coalescing.md", "slug: singleflight\naliases: []\n", "/go/singleflight/"},
+		{"alias keeps old URL", "content/go/request_coalescing.md", "slug: singleflight\naliases:\n    - /go/singleflight/\n", ""},
+		{"notes post", "content/shards/2026/03/dynamo.md", "slug: dynamodb\naliases: []\n", "/shards/2026/03/dynamodb/"},
+		{"bundle matches directory", "content/go/request_coalescing/index.md", "slug: request-coalescing\naliases: []\n", ""},
+		{"hand-edited bundle slug", "content/go/request_coalescing/index.md", "slug: singleflight\naliases: []\n", "/go/singleflight/"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, got, err := orphanedSlug("---\n"+tc.fm+"---\nBody.\n", tc.filePath, publishConfig{notesSection: "shards"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("orphanedSlug URL = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenameToSlugUsesSnakeCaseFileName(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "request_coalescing.md")
+	if err := os.WriteFile(old, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := renameToSlug(old, "go-singleflight", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "go_singleflight.md"); got != want {
+		t.Fatalf("renameToSlug = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("old file still exists: %v", err)
+	}
+}
+
+func TestRenameToSlugDryRunMovesNothing(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "request_coalescing.md")
+	if err := os.WriteFile(old, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := renameToSlug(old, "go-singleflight", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "go_singleflight.md"); got != want {
+		t.Fatalf("renameToSlug = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Fatalf("dry run moved the post: %v", err)
+	}
+	if _, err := os.Stat(got); !os.IsNotExist(err) {
+		t.Fatalf("dry run created %s: %v", got, err)
+	}
+}
+
+func TestRenameToSlugMovesWholeBundle(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "request_coalescing", "index.md")
+	for _, name := range []string{old, filepath.Join(dir, "request_coalescing", "flow.png")} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := renameToSlug(old, "go-singleflight", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "go_singleflight", "index.md"); got != want {
+		t.Fatalf("renameToSlug = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go_singleflight", "flow.png")); err != nil {
+		t.Fatalf("bundle resource didn't move: %v", err)
+	}
+	if !isBundleResource(filepath.Join(dir, "go_singleflight", "notes", "aside.md")) || isBundleResource(got) {
+		t.Fatal("isBundleResource misjudges the bundle")
+	}
+}
+
+func TestCheckDatePathReportsDrift(t *testing.T) {
+	for _, tc := range []struct {
+		filePath string
+		date     string
+		wantErr  string
+	}{
+		{"content/shards/2026/03/dynamo.md", "2026-03-14", ""},
+		{"content/shards/2026/03/dynamo.md", "2026-03-14T23:30:00-05:00", ""},
+		{"content/shards/2026/03/dynamo.md", "2026-04-01", "disagrees with 2026/03 in the path"},
+		{"content/go/request_coalescing.md", "2026-06-27T00:00:00Z", ""},
+		{"content/go/2019-07-04-old_post.md", "2019-07-04", ""},
+		{"content/go/2019-07-04-old_post.md", "2019-07-05", "disagrees with 2019-07-04 in the file name"},
+		{"content/go/old_post.md", "July 4, 2019", "not a YYYY-MM-DD or RFC 3339 date"},
+	} {
+		t.Run(tc.filePath+"@"+tc.date, func(t *testing.T) {
+			err := checkDatePath(tc.filePath, tc.date)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("checkDatePath error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
 func frontmatterKeys(raw string) ([]string, error) {
 	fmRaw, _, ok := splitFrontmatter(raw)
 	if !ok {
@@ -169,3 +395,120 @@ func repoRoot(t *testing.T) string {
 		dir = parent
 	}
 }
+
+func TestPermalinkPatternsOverrideSectionPaths(t *testing.T) {
+	var raw map[string]any
+	if err := yaml.Unmarshal([]byte("page:\n  go: /:section/:year/:slug/\n  misc: /:filename/\n"), &raw); err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := parsePermalinks(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishing := publishConfig{notesSection: "shards", permalinks: patterns}
+	for _, tc := range []struct {
+		filePath, slug, date, want string
+	}{
+		{"content/go/request_coalescing.md", "request-coalescing", "2026-03-01", "/go/2026/request-coalescing/"},
+		{"content/misc/old_notes/index.md", "old-notes", "2019-07-04", "/old_notes/"},
+		{"content/shards/2026/03/dynamo.md", "dynamo", "2026-03-05", "/shards/2026/03/dynamo/"},
+		{"content/python/nested/gil.md", "gil", "2026-03-05", "/python/nested/gil/"},
+	} {
+		got, err := atprotoPathFor(tc.filePath, tc.slug, tc.date, publishing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: path = %q, want %q", tc.filePath, got, tc.want)
+		}
+	}
+
+	flat, err := parsePermalinks(map[string]any{"go": "/:slug/:uniqueid/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := flat.path("content/go/gil.md", "gil", "2026-03-05"); err == nil || !strings.Contains(err.Error(), ":uniqueid") {
+		t.Fatalf("err = %v, want the unsupported token", err)
+	}
+}
+
+func TestNormalizePostFrontmatterChecksAuthor(t *testing.T) {
+	raw := `---
+title: "Old"
+slug: old
+date: 2026-06-30
+description: >-
+    A short description.
+tags:
+    - Go
+author: guest
+---
+Body.
+`
+	publishing := publishConfig{notesSection: "shards", authors: map[string]author{"guest": {Name: "A Guest"}}}
+	next, err := normalizePostFrontmatter(raw, "content/go/old.md", publishing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(strings.Split(next, "\n---\n")[0], "atUri: \"\"\nauthor: guest") {
+		t.Fatalf("author was not kept after the canonical keys:\n%s", next)
+	}
+
+	for value, want := range map[string]string{
+		`""`:     "author is empty",
+		"nobody": `author "nobody" is not in data/authors.yml; want one of guest`,
+	} {
+		_, err := normalizePostFrontmatter(strings.Replace(raw, "author: guest", "author: "+value, 1), "content/go/old.md", publishing)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("author: %s: got %v, want %q", value, err, want)
+		}
+	}
+}
+
+func TestLoadAuthorsChecksEveryEntry(t *testing.T) {
+	dir := t.TempDir()
+	static := filepath.Join(dir, "static")
+	if err := os.MkdirAll(filepath.Join(static, "avatars"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(static, "avatars", "guest.png"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	registry := filepath.Join(dir, "authors.yml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(registry, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`
+owner: {name: Site Owner, link: /about/, avatar: "https://blob.example.com/owner.jpg"}
+guest: {name: A Guest, link: "https://guest.example.com", avatar: /avatars/guest.png}
+`)
+	authors, err := loadAuthors(registry, static, "Site Owner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 2 || authors["guest"].Name != "A Guest" {
+		t.Fatalf("authors = %+v", authors)
+	}
+
+	write(`
+Guest: {name: A Guest, link: "http://guest.example.com", avatar: /avatars/missing.png}
+nameless: {link: /about/}
+`)
+	_, err = loadAuthors(registry, static, "Site Owner")
+	for _, want := range []string{
+		"Guest: want a lowercase id",
+		`Guest: link "http://guest.example.com" is neither a site path nor an https URL`,
+		"Guest: avatar /avatars/missing.png is not in",
+		"nameless: missing name",
+		"nameless: missing avatar",
+		`the site's author, "Site Owner", is not registered`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("loadAuthors error = %v, want it to mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// permalinks maps a section to the Hugo permalink pattern its pages use,
+// from the permalinks block of config.yml. A section without one is served
+// from its directory path: content/shards/2026/03/foo.md is
+// /shards/2026/03/foo/.
+type permalinks map[string]string
+
+// parsePermalinks reads Hugo's permalinks block in either of its forms:
+// page patterns under a page key, or the older flat section-to-pattern
+// map.
+func parsePermalinks(raw map[string]any) (permalinks, error) {
+	patterns := permalinks{}
+	if pages, ok := raw["page"].(map[string]any); ok {
+		raw = pages
+	}
+	for section, v := range raw {
+		switch pattern := v.(type) {
+		case string:
+			patterns[section] = pattern
+		case map[string]any:
+			// section, term and taxonomy patterns don't move posts.
+		default:
+			return nil, fmt.Errorf("permalinks.%s: want a pattern, got %v", section, v)
+		}
+	}
+	return patterns, nil
+}
+
+var permalinkToken = regexp.MustCompile(`:[a-z]+`)
+
+// path returns the URL Hugo serves the post at filePath from, given its
+// slug and its YYYY-MM-DD date.
+func (p permalinks) path(filePath, slug, date string) (string, error) {
+	rel := strings.TrimPrefix(filepath.ToSlash(filePath), "content/")
+	if path.Base(rel) == "index.md" {
+		rel = path.Dir(rel)
+	}
+	dir := path.Dir(rel)
+	pattern, ok := p[sectionFor(filePath)]
+	if !ok {
+		return "/" + dir + "/" + slug + "/", nil
+	}
+
+	var unknown string
+	expanded := permalinkToken.ReplaceAllStringFunc(pattern, func(token string) string {
+		switch token {
+		case ":year", ":month", ":day":
+			if len(date) < len("2006-01-02") {
+				unknown = token + " needs a date"
+				return ""
+			}
+			return map[string]string{":year": date[:4], ":month": date[5:7], ":day": date[8:10]}[token]
+		case ":section":
+			return sectionFor(filePath)
+		case ":sections":
+			return dir
+		case ":slug", ":slugorfilename", ":slugorcontentbasename":
+			return slug
+		case ":filename", ":contentbasename":
+			return path.Base(rel)
+		}
+		unknown = "unsupported token " + token
+		return ""
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("%s: permalink %q: %s", filePath, pattern, unknown)
+	}
+	return "/" + strings.TrimLeft(expanded, "/"), nil
+}
@@ -0,0 +1,171 @@
+// Command hugoversion checks the installed Hugo against the version range
+// in config.yml's module.hugoVersion before anything builds or serves the
+// site. An older Hugo otherwise fails deep in a template with an error that
+// says nothing about versions, or worse, builds a subtly different site.
+//
+// min and max are inclusive; either may be empty. extended: true also
+// requires the extended edition.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type siteConfig struct {
+	Module struct {
+		HugoVersion requirement `yaml:"hugoVersion"`
+	} `yaml:"module"`
+}
+
+// requirement is module.hugoVersion.
+type requirement struct {
+	Min      string `yaml:"min"`
+	Max      string `yaml:"max"`
+	Extended bool   `yaml:"extended"`
+}
+
+// installed is what `hugo version` reports.
+type installed struct {
+	Version  version
+	Extended bool
+}
+
+type version [3]int
+
+func main() {
+	req, err := loadRequirement("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	out, err := exec.Command("hugo", "version").Output()
+	if err != nil {
+		fatal(fmt.Errorf("run `hugo version`: %w; install Hugo %s", err, describe(req)))
+	}
+	got, err := parseInstalled(string(out))
+	if err != nil {
+		fatal(err)
+	}
+	if err := check(req, got); err != nil {
+		fatal(err)
+	}
+}
+
+func loadRequirement(configPath string) (requirement, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return requirement{}, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return requirement{}, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	req := config.Module.HugoVersion
+	for _, v := range []string{req.Min, req.Max} {
+		if v == "" {
+			continue
+		}
+		if _, err := parseVersion(v); err != nil {
+			return requirement{}, fmt.Errorf("%s: module.hugoVersion: %w", configPath, err)
+		}
+	}
+	return req, nil
+}
+
+var installedPattern = regexp.MustCompile(`\bv(\d+\.\d+\.\d+)\S*`)
+
+// parseInstalled reads output like
+// "hugo v0.164.0-1a2b3c+extended linux/amd64 BuildDate=...".
+func parseInstalled(out string) (installed, error) {
+	m := installedPattern.FindStringSubmatch(out)
+	if m == nil {
+		return installed{}, fmt.Errorf("no version in `hugo version` output %q", strings.TrimSpace(out))
+	}
+	v, err := parseVersion(m[1])
+	if err != nil {
+		return installed{}, err
+	}
+	return installed{Version: v, Extended: strings.Contains(m[0], "+extended")}, nil
+}
+
+// check returns an error saying what to install when got falls outside req.
+func check(req requirement, got installed) error {
+	problem := ""
+	switch {
+	case req.Min != "" && got.Version.less(mustParse(req.Min)):
+		problem = "is older than the minimum " + req.Min
+	case req.Max != "" && mustParse(req.Max).less(got.Version):
+		problem = "is newer than the maximum " + req.Max
+	case req.Extended && !got.Extended:
+		problem = "is not the extended edition"
+	default:
+		return nil
+	}
+	return fmt.Errorf("installed Hugo %s %s; install Hugo %s (config.yml module.hugoVersion)", got.Version, problem, describe(req))
+}
+
+func describe(req requirement) string {
+	var parts []string
+	if req.Min != "" {
+		parts = append(parts, ">= "+req.Min)
+	}
+	if req.Max != "" {
+		parts = append(parts, "<= "+req.Max)
+	}
+	if req.Extended {
+		parts = append(parts, "extended")
+	}
+	if len(parts) == 0 {
+		return "(any version)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseVersion(s string) (version, error) {
+	var v version
+	fields := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(fields) < 2 || len(fields) > 3 {
+		return v, fmt.Errorf("version %q is not MAJOR.MINOR[.PATCH]", s)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("version %q is not MAJOR.MINOR[.PATCH]", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// mustParse parses a version loadRequirement already validated.
+func mustParse(s string) version {
+	v, err := parseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func (v version) less(w version) bool {
+	for i := range v {
+		if v[i] != w[i] {
+			return v[i] < w[i]
+		}
+	}
+	return false
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "hugoversion:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseInstalled(t *testing.T) {
+	for _, tc := range []struct {
+		out      string
+		version  string
+		extended bool
+	}{
+		{"hugo v0.164.0-3c1b0d4e+extended linux/amd64 BuildDate=2026-06-01T10:00:00Z VendorInfo=gohugoio\n", "0.164.0", true},
+		{"hugo v0.152.2-DEV darwin/arm64 BuildDate=unknown\n", "0.152.2", false},
+	} {
+		got, err := parseInstalled(tc.out)
+		if err != nil {
+			t.Fatalf("parseInstalled(%q): %v", tc.out, err)
+		}
+		if got.Version.String() != tc.version || got.Extended != tc.extended {
+			t.Errorf("parseInstalled(%q) = %s extended=%v, want %s extended=%v", tc.out, got.Version, got.Extended, tc.version, tc.extended)
+		}
+	}
+	if _, err := parseInstalled("command not found"); err == nil {
+		t.Error("parseInstalled accepted output without a version")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	v := func(s string) version { return mustParse(s) }
+	for _, tc := range []struct {
+		req     requirement
+		got     installed
+		problem string
+	}{
+		{requirement{Min: "0.164.0"}, installed{Version: v("0.164.0")}, ""},
+		{requirement{Min: "0.164"}, installed{Version: v("0.170.1")}, ""},
+		{requirement{Min: "0.164.0"}, installed{Version: v("0.163.9")}, "older than the minimum 0.164.0"},
+		{requirement{Max: "0.170.0"}, installed{Version: v("0.171.0")}, "newer than the maximum 0.170.0"},
+		{requirement{Extended: true}, installed{Version: v("0.164.0")}, "not the extended edition"},
+	} {
+		err := check(tc.req, tc.got)
+		switch {
+		case tc.problem == "" && err != nil:
+			t.Errorf("check(%+v, %s) = %v, want nil", tc.req, tc.got.Version, err)
+		case tc.problem != "" && (err == nil || !strings.Contains(err.Error(), tc.problem)):
+			t.Errorf("check(%+v, %s) = %v, want %q", tc.req, tc.got.Version, err, tc.problem)
+		}
+	}
+}
+
+func TestRepoConfigDeclaresMinimum(t *testing.T) {
+	req, err := loadRequirement(filepath.Join("..", "..", "config.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Min == "" {
+		t.Fatal("config.yml sets no module.hugoVersion.min")
+	}
+}
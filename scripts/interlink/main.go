@@ -0,0 +1,416 @@
+// Command interlink suggests older posts worth linking to from new or
+// changed ones. Every published post is scored against the changed post by
+// TF-IDF cosine similarity over its prose; the closest ones it doesn't link
+// yet are reported along with phrases in the changed post that could carry
+// the link: words from the older post's title or its most distinctive terms.
+//
+// Pass post files as arguments, or none to check the posts changed against
+// -base, including untracked ones. It only reports; nothing is rewritten.
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentDir = "content"
+	siteURL    = "https://rednafi.com"
+)
+
+type siteConfig struct {
+	Params struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+	} `yaml:"params"`
+}
+
+// doc is one published post as the corpus sees it.
+type doc struct {
+	File  string
+	Title string
+	Path  string
+	Raw   string
+	// Lines is the body with code, links and markup blanked out, one entry
+	// per line of the file so matches keep their line numbers.
+	Lines   []string
+	Weights map[string]float64
+}
+
+// suggestion is an older post to link from a changed one.
+type suggestion struct {
+	Target  *doc
+	Score   float64
+	Anchors []anchor
+}
+
+// anchor is a phrase in the changed post that could carry the link.
+type anchor struct {
+	Line   int
+	Phrase string
+}
+
+func main() {
+	base := flag.String("base", "HEAD", "git ref to find changed posts against when no files are given")
+	top := flag.Int("top", 5, "suggestions per post")
+	minScore := flag.Float64("min-score", 0.1, "lowest similarity worth suggesting")
+	flag.Parse()
+
+	sections, err := loadSections("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	corpus, err := loadCorpus(contentDir, sections)
+	if err != nil {
+		fatal(err)
+	}
+	files := flag.Args()
+	if len(files) == 0 {
+		if files, err = changedPosts(*base); err != nil {
+			fatal(err)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf("no posts changed against %s\n", *base)
+		return
+	}
+
+	weigh(corpus)
+	for _, file := range files {
+		file = filepath.ToSlash(filepath.Clean(file))
+		i := slices.IndexFunc(corpus, func(d *doc) bool { return d.File == file })
+		if i < 0 {
+			fmt.Printf("%s: not a published post, skipped\n", file)
+			continue
+		}
+		report(corpus[i], suggest(corpus[i], corpus, *top, *minScore))
+	}
+}
+
+func report(source *doc, suggestions []suggestion) {
+	fmt.Printf("%s\n", source.File)
+	if len(suggestions) == 0 {
+		fmt.Println("  no related posts left to link")
+		return
+	}
+	for _, s := range suggestions {
+		fmt.Printf("  %.2f  %s  %s\n", s.Score, s.Target.Path, s.Target.Title)
+		if len(s.Anchors) == 0 {
+			fmt.Println("        no anchor phrase found; consider a sentence pointing to it")
+		}
+		for _, a := range s.Anchors {
+			fmt.Printf("        line %d: %q\n", a.Line, a.Phrase)
+		}
+	}
+}
+
+// suggest ranks the posts in corpus by similarity to source and returns the
+// top ones at or above minScore that source doesn't already link to.
+func suggest(source *doc, corpus []*doc, top int, minScore float64) []suggestion {
+	var out []suggestion
+	for _, target := range corpus {
+		if target == source || links(source, target) {
+			continue
+		}
+		score := cosine(source.Weights, target.Weights)
+		if score < minScore {
+			continue
+		}
+		out = append(out, suggestion{Target: target, Score: score})
+	}
+	slices.SortFunc(out, func(a, b suggestion) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Target.File, b.Target.File))
+	})
+	out = out[:min(top, len(out))]
+	for i := range out {
+		out[i].Anchors = anchors(source, out[i].Target)
+	}
+	return out
+}
+
+// links reports whether source already links to target, by site-relative
+// path or absolute URL.
+func links(source, target *doc) bool {
+	if target.Path == "" {
+		return false
+	}
+	return strings.Contains(source.Raw, "("+target.Path) || strings.Contains(source.Raw, siteURL+target.Path)
+}
+
+// maxAnchors caps the phrases reported per suggestion.
+const maxAnchors = 3
+
+// anchors finds phrases in source's prose taken from target's title or
+// its most distinctive terms, longest phrases first.
+func anchors(source, target *doc) []anchor {
+	var out []anchor
+	seen := map[string]bool{}
+	for _, phrase := range candidatePhrases(target) {
+		pattern := regexp.MustCompile(`(?i)\b` + strings.Join(quoteAll(strings.Fields(phrase)), `\s+`) + `\b`)
+		for i, line := range source.Lines {
+			m := pattern.FindString(line)
+			if m == "" || seen[strings.ToLower(m)] {
+				continue
+			}
+			seen[strings.ToLower(m)] = true
+			out = append(out, anchor{Line: i + 1, Phrase: m})
+			break
+		}
+		if len(out) == maxAnchors {
+			break
+		}
+	}
+	return out
+}
+
+// candidatePhrases returns the runs of one to three title words that
+// neither start nor end with a stopword, then target's top terms.
+func candidatePhrases(target *doc) []string {
+	words := strings.FieldsFunc(strings.ToLower(target.Title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	var phrases []string
+	for n := 3; n >= 1; n-- {
+		for i := 0; i+n <= len(words); i++ {
+			run := words[i : i+n]
+			if stopwords[run[0]] || stopwords[run[n-1]] || (n == 1 && len(run[0]) < 4) {
+				continue
+			}
+			phrases = append(phrases, strings.Join(run, " "))
+		}
+	}
+	for _, term := range topTerms(target.Weights, 5) {
+		if !slices.Contains(phrases, term) {
+			phrases = append(phrases, term)
+		}
+	}
+	return phrases
+}
+
+func topTerms(weights map[string]float64, n int) []string {
+	terms := make([]string, 0, len(weights))
+	for term := range weights {
+		terms = append(terms, term)
+	}
+	slices.SortFunc(terms, func(a, b string) int {
+		return cmp.Or(cmp.Compare(weights[b], weights[a]), strings.Compare(a, b))
+	})
+	return terms[:min(n, len(terms))]
+}
+
+func quoteAll(words []string) []string {
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	return words
+}
+
+// weigh sets each document's TF-IDF vector, normalized to unit length:
+// term frequency damped by log, times the log inverse document frequency.
+func weigh(corpus []*doc) {
+	counts := make([]map[string]int, len(corpus))
+	df := map[string]int{}
+	for i, d := range corpus {
+		counts[i] = termCounts(strings.Join(d.Lines, "\n"))
+		for term := range counts[i] {
+			df[term]++
+		}
+	}
+	n := float64(len(corpus))
+	for i, d := range corpus {
+		d.Weights = map[string]float64{}
+		var norm float64
+		for term, count := range counts[i] {
+			w := (1 + math.Log(float64(count))) * math.Log(n/float64(df[term]))
+			if w <= 0 {
+				continue
+			}
+			d.Weights[term] = w
+			norm += w * w
+		}
+		norm = math.Sqrt(norm)
+		for term := range d.Weights {
+			d.Weights[term] /= norm
+		}
+	}
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot float64
+	for term, w := range a {
+		dot += w * b[term]
+	}
+	return dot
+}
+
+func termCounts(text string) map[string]int {
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) < 3 || stopwords[word] || isNumber(word) {
+			continue
+		}
+		counts[word]++
+	}
+	return counts
+}
+
+func isNumber(word string) bool {
+	return strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
+
+var stopwords = func() map[string]bool {
+	m := map[string]bool{}
+	for w := range strings.FieldsSeq(`a an and are as at be but by can do does for from has have how i if in
+		into is it its it's just like may more my no not of on or our so than that the their them then there
+		these they this to too up us use was we what when where which while who why will with without you your
+		all also any been both can't could did don't each get got here i'm let's many most much must one only
+		other out over own same should some such very via were would yet about after again against because
+		before being between during few further once under until`) {
+		m[w] = true
+	}
+	return m
+}()
+
+var (
+	fencePattern  = regexp.MustCompile("^[ \\t]*(```|~~~)")
+	maskPattern   = regexp.MustCompile("`[^`]*`|!?\\[[^\\]]*\\]\\([^)]*\\)|<[^>]*>|\\{\\{[<%].*?[%>]\\}\\}|https?://\\S+")
+	headingPrefix = regexp.MustCompile(`^#+\s`)
+)
+
+// proseLines returns raw's lines with the frontmatter, fenced code,
+// inline code, links, HTML and shortcodes blanked out. Existing link text is
+// dropped too, since it can't carry a second link.
+func proseLines(raw string) []string {
+	lines := strings.Split(raw, "\n")
+	out := make([]string, len(lines))
+	inFrontmatter, inFence := len(lines) > 0 && lines[0] == "---", false
+	for i, line := range lines {
+		switch {
+		case inFrontmatter:
+			if i > 0 && line == "---" {
+				inFrontmatter = false
+			}
+		case fencePattern.MatchString(line):
+			inFence = !inFence
+		case inFence, headingPrefix.MatchString(line):
+		default:
+			out[i] = maskPattern.ReplaceAllString(line, " ")
+		}
+	}
+	return out
+}
+
+// loadCorpus reads every published post under sections.
+func loadCorpus(root string, sections []string) ([]*doc, error) {
+	var corpus []*doc
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		section, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if !slices.Contains(sections, section) {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, _, _ := splitFrontmatter(string(raw))
+		var fm struct {
+			Title       string `yaml:"title"`
+			Draft       bool   `yaml:"draft"`
+			AtprotoPath string `yaml:"atprotoPath"`
+		}
+		if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		if fm.Draft {
+			return nil
+		}
+		corpus = append(corpus, &doc{
+			File:  filepath.ToSlash(filePath),
+			Title: fm.Title,
+			Path:  fm.AtprotoPath,
+			Raw:   string(raw),
+			Lines: proseLines(string(raw)),
+		})
+		return nil
+	})
+	return corpus, err
+}
+
+// changedPosts lists the posts under content/ that differ from base or
+// aren't tracked yet.
+func changedPosts(base string) ([]string, error) {
+	var files []string
+	for _, args := range [][]string{
+		{"diff", "--name-only", "--diff-filter=AM", base, "--", contentDir},
+		{"ls-files", "--others", "--exclude-standard", "--", contentDir},
+	} {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		for line := range strings.SplitSeq(strings.TrimSpace(string(out)), "\n") {
+			if strings.HasSuffix(line, ".md") && filepath.Base(line) != "_index.md" {
+				files = append(files, line)
+			}
+		}
+	}
+	return files, nil
+}
+
+func loadSections(configPath string) ([]string, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	sections := slices.Clone(config.Params.MainSections)
+	if config.Params.NotesSection != "" {
+		sections = append(sections, config.Params.NotesSection)
+	}
+	return sections, nil
+}
+
+func splitFrontmatter(raw string) (frontmatter, body string, ok bool) {
+	const start = "---\n"
+	if !strings.HasPrefix(raw, start) {
+		return "", raw, false
+	}
+	idx := strings.Index(raw[len(start):], "\n---\n")
+	if idx == -1 {
+		return "", raw, false
+	}
+	fmEnd := len(start) + idx
+	return raw[len(start):fmEnd], raw[fmEnd+len("\n---\n"):], true
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "interlink:", err)
+	os.Exit(1)
+}
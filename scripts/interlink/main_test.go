@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProseLinesBlanksNonProse(t *testing.T) {
+	raw := "---\ntitle: x\n---\n## Heading words\nUse `errgroup` with [context](/go/ctx/) today.\n```go\nfunc retry() {}\n```\nPlain retry.\n"
+	got := proseLines(raw)
+	if len(got) != strings.Count(raw, "\n")+1 {
+		t.Fatalf("proseLines kept %d lines, want one per line of the file", len(got))
+	}
+	text := strings.Join(got, "|")
+	for _, gone := range []string{"title", "Heading", "errgroup", "context", "func"} {
+		if strings.Contains(text, gone) {
+			t.Errorf("proseLines kept %q: %q", gone, text)
+		}
+	}
+	if !strings.Contains(got[4], "today") || got[8] != "Plain retry." {
+		t.Errorf("proseLines dropped prose: %q", text)
+	}
+}
+
+func TestSuggestRanksUnlinkedRelatedPosts(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "new.md"), post("Draining queues", "/go/new/",
+		"Backpressure keeps a worker pool honest. When the queue fills, the worker pool must shed load or block.\nSee [limits](/go/linked/) too.\n"))
+	mustWrite(t, filepath.Join(root, "go", "pool.md"), post("Worker pool patterns", "/go/pool/",
+		"A worker pool bounds concurrency. Each worker pulls from a queue.\n"))
+	mustWrite(t, filepath.Join(root, "go", "linked.md"), post("Worker limits", "/go/linked/",
+		"A worker pool with a queue and backpressure limits.\n"))
+	mustWrite(t, filepath.Join(root, "go", "css.md"), post("Styling tables", "/go/css/",
+		"Borders and padding in stylesheets.\n"))
+	mustWrite(t, filepath.Join(root, "go", "draft.md"), "---\ntitle: Worker pool queue\ndraft: true\n---\nWorker pool queue backpressure.\n")
+
+	corpus, err := loadCorpus(root, []string{"go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	weigh(corpus)
+	source := corpus[slices.IndexFunc(corpus, func(d *doc) bool { return d.Path == "/go/new/" })]
+
+	got := suggest(source, corpus, 5, 0.01)
+	if len(got) != 1 || got[0].Target.Path != "/go/pool/" {
+		t.Fatalf("suggest = %+v; want only /go/pool/ (linked, unrelated and draft posts left out)", got)
+	}
+	if want := (anchor{Line: 5, Phrase: "worker pool"}); len(got[0].Anchors) == 0 || got[0].Anchors[0] != want {
+		t.Fatalf("anchors = %+v; want %+v first", got[0].Anchors, want)
+	}
+}
+
+func post(title, path, body string) string {
+	return "---\ntitle: " + title + "\natprotoPath: " + path + "\n---\n" + body
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
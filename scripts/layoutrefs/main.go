@@ -0,0 +1,132 @@
+// Command layoutrefs checks that every partial and template a layout calls
+// actually exists, and that every partial is called from somewhere.
+//
+// A renamed partial otherwise fails only at build time, or worse, at render
+// time on the rarely built page that uses it. Calls with a computed name
+// can't be resolved statically and are skipped.
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const layoutsDir = "layouts"
+
+var (
+	partialCallPattern  = regexp.MustCompile(`\b(?:partial|partialCached)\s+"([^"]+)"`)
+	templateCallPattern = regexp.MustCompile(`\btemplate\s+"([^"]+)"`)
+	definePattern       = regexp.MustCompile(`\bdefine\s+"([^"]+)"`)
+)
+
+func main() {
+	problems, err := checkLayouts(layoutsDir)
+	if err != nil {
+		fatal(err)
+	}
+	if len(problems) > 0 {
+		fatal(fmt.Errorf("layout references are broken:\n  %s", strings.Join(problems, "\n  ")))
+	}
+}
+
+// checkLayouts returns every call to a missing partial or template under
+// root, followed by every partial nothing calls.
+func checkLayouts(root string) ([]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(raw)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defined := map[string]bool{}
+	for _, body := range files {
+		for _, m := range definePattern.FindAllStringSubmatch(body, -1) {
+			defined[m[1]] = true
+		}
+	}
+
+	var problems []string
+	called := map[string]bool{}
+	for _, rel := range sortedKeys(files) {
+		body := files[rel]
+		for _, m := range partialCallPattern.FindAllStringSubmatchIndex(body, -1) {
+			name := body[m[2]:m[3]]
+			target, ok := resolvePartial(name, files)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s:%d: partial %q does not exist", path.Join(layoutsDir, rel), lineAt(body, m[0]), name))
+				continue
+			}
+			called[target] = true
+		}
+		for _, m := range templateCallPattern.FindAllStringSubmatchIndex(body, -1) {
+			name := body[m[2]:m[3]]
+			if strings.HasPrefix(name, "_internal/") || defined[name] {
+				continue
+			}
+			if _, ok := files[name]; ok {
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("%s:%d: template %q is neither defined nor a layout file", path.Join(layoutsDir, rel), lineAt(body, m[0]), name))
+		}
+	}
+
+	for _, rel := range sortedKeys(files) {
+		if strings.HasPrefix(rel, "partials/") && !called[rel] {
+			problems = append(problems, fmt.Sprintf("%s: partial is never called", path.Join(layoutsDir, rel)))
+		}
+	}
+	return problems, nil
+}
+
+// resolvePartial maps a partial call to its file the way Hugo does: the name
+// is relative to partials/, and a name without an extension means .html.
+func resolvePartial(name string, files map[string]string) (string, bool) {
+	candidates := []string{"partials/" + name}
+	if path.Ext(name) == "" {
+		candidates = append(candidates, "partials/"+name+".html")
+	}
+	for _, candidate := range candidates {
+		if _, ok := files[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func lineAt(body string, offset int) int {
+	return strings.Count(body[:offset], "\n") + 1
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "layoutrefs:", err)
+	os.Exit(1)
+}
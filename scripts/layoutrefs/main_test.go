@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckLayouts(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "_default", "baseof.html"), `{{ partial "head.html" . }}
+{{ partialCached "footer" . }}
+{{ template "_internal/opengraph.html" . }}
+{{ template "main" . }}
+{{ partial "gone.html" . }}
+{{ template "missing" . }}
+{{ partial (printf "%s.html" .Type) . }}`)
+	mustWrite(t, filepath.Join(root, "_default", "single.html"), `{{ define "main" }}{{ partial "head.html" . }}{{ end }}`)
+	mustWrite(t, filepath.Join(root, "partials", "head.html"), `<meta>`)
+	mustWrite(t, filepath.Join(root, "partials", "footer.html"), `<footer>`)
+	mustWrite(t, filepath.Join(root, "partials", "orphan.html"), `<p>`)
+
+	got, err := checkLayouts(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`layouts/_default/baseof.html:5: partial "gone.html" does not exist`,
+		`layouts/_default/baseof.html:6: template "missing" is neither defined nor a layout file`,
+		`layouts/partials/orphan.html: partial is never called`,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("checkLayouts =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
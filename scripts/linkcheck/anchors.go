@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// anchorLimit caps how much of a page is searched for a fragment's target.
+// Anchors sit anywhere in a page, so this is far above bodyLimit.
+const anchorLimit = 4 << 20
+
+// anchorCheck flags links whose #fragment names no element on the page,
+// usually because the heading it pointed at was renamed.
+type anchorCheck struct{}
+
+func (anchorCheck) need(u *url.URL) int64 {
+	if !checksFragment(u.Fragment) {
+		return 0
+	}
+	// GitHub draws line anchors with JavaScript; the code check covers
+	// them.
+	if _, ok := parseCodeRef(u); ok {
+		return 0
+	}
+	return anchorLimit
+}
+
+func (anchorCheck) inspect(u *url.URL, r fetchResult) (string, string, bool) {
+	if hasAnchor(r.body, u.Fragment) {
+		return "", "", false
+	}
+	// The target may sit past what was read; don't guess.
+	if int64(len(r.body)) >= anchorLimit {
+		return "", "", false
+	}
+	return ruleAnchor, fmt.Sprintf("no element with id %q", u.Fragment), true
+}
+
+// checksFragment reports whether fragment should name an element. Client
+// routes (#/path, #!path) and text fragments (#:~:text=) don't.
+func checksFragment(fragment string) bool {
+	if fragment == "" || strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "!") || strings.HasPrefix(fragment, ":~:") {
+		return false
+	}
+	return fragment != "top"
+}
+
+// hasAnchor reports whether body has an element whose id or name is
+// fragment. GitHub prefixes rendered README ids with user-content- and
+// resolves the bare fragment with JavaScript, so that form counts too.
+func hasAnchor(body []byte, fragment string) bool {
+	name := regexp.QuoteMeta(fragment)
+	pattern := regexp.MustCompile(`(?i:\b(?:id|name))\s*=\s*(?:"(?:user-content-)?` + name + `"|'(?:user-content-)?` + name + `'|(?:user-content-)?` + name + `[\s/>])`)
+	return pattern.Match(body) || bytes.Contains(body, []byte(`id="`+url.PathEscape(fragment)+`"`))
+}
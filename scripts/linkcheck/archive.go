@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// waybackAPI is the Wayback Machine's availability API, which answers with
+// the snapshot of a URL closest to a timestamp.
+const waybackAPI = "https://archive.org/wayback/available"
+
+// wayback finds archived copies of dead links and, for -snapshots, asks
+// for new ones at saveEndpoint.
+type wayback struct {
+	client       *http.Client
+	endpoint     string
+	saveEndpoint string
+}
+
+// closest returns the snapshot of rawURL nearest to timestamp (YYYYMMDD,
+// or empty for the latest) that the archive captured with a 200, or ""
+// when there is none.
+func (w *wayback) closest(ctx context.Context, rawURL, timestamp string) (string, error) {
+	q := url.Values{"url": {rawURL}}
+	if timestamp != "" {
+		q.Set("timestamp", timestamp)
+	}
+	req, err := newRequest(ctx, http.MethodGet, w.endpoint+"?"+q.Encode())
+	if err != nil {
+		return "", err
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("wayback: HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Snapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+				Status    string `json:"status"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("wayback: %w", err)
+	}
+	snap := body.Snapshots.Closest
+	if !snap.Available || snap.Status != "200" || snap.URL == "" {
+		return "", nil
+	}
+	// The API hands out http:// snapshot URLs; the archive serves them all
+	// over https.
+	if rest, ok := strings.CutPrefix(snap.URL, "http://"); ok {
+		return "https://" + rest, nil
+	}
+	return snap.URL, nil
+}
+
+// archive looks up a snapshot for every failing finding's URL, taken as
+// close as possible to the date of the first post citing it, and records
+// it on the findings. dates maps a file to its post's YYYYMMDD date.
+func (w *wayback) archive(ctx context.Context, log *slog.Logger, findings []finding, dates map[string]string, workers int) {
+	firstCited := map[string]string{}
+	var urls []string
+	for _, f := range findings {
+		if !f.failed() {
+			continue
+		}
+		if _, seen := firstCited[f.Link.URL]; !seen {
+			urls = append(urls, f.Link.URL)
+		}
+		if d := dates[f.Link.File]; d != "" && (firstCited[f.Link.URL] == "" || d < firstCited[f.Link.URL]) {
+			firstCited[f.Link.URL] = d
+		}
+	}
+
+	var mu sync.Mutex
+	snapshots := map[string]string{}
+	forEach(ctx, urls, workers, func(ctx context.Context, rawURL string) error {
+		snap, err := w.closest(ctx, rawURL, firstCited[rawURL])
+		if err != nil {
+			log.Warn("wayback lookup failed", "url", rawURL, "err", err)
+			return nil
+		}
+		mu.Lock()
+		snapshots[rawURL] = snap
+		mu.Unlock()
+		return nil
+	})
+
+	for i, f := range findings {
+		if f.failed() {
+			findings[i].Archive = snapshots[f.Link.URL]
+		}
+	}
+}
+
+// collectDates reads each Markdown file's date under dir as YYYYMMDD, keyed
+// by path as collectLinks reports it.
+func collectDates(dir string) (map[string]string, error) {
+	dates := map[string]string{}
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, ok := frontmatter(string(raw))
+		if !ok {
+			return nil
+		}
+		var fm struct {
+			Date string `yaml:"date"`
+		}
+		if yaml.Unmarshal([]byte(fmRaw), &fm) != nil || len(fm.Date) < len("2006-01-02") {
+			return nil
+		}
+		dates[filepath.ToSlash(filePath)] = strings.ReplaceAll(fm.Date[:len("2006-01-02")], "-", "")
+		return nil
+	})
+	return dates, err
+}
+
+// archivedLinks maps each failing URL that has a snapshot to it.
+func archivedLinks(findings []finding) map[string]string {
+	archived := map[string]string{}
+	for _, f := range findings {
+		if f.failed() && f.Archive != "" {
+			archived[f.Link.URL] = f.Archive
+		}
+	}
+	return archived
+}
+
+// annotateArchived points each dead URL on line at its snapshot and says
+// so: [text](dead) becomes [text (archived)](snapshot), and any other
+// occurrence becomes "snapshot (archived)".
+func annotateArchived(line string, archived map[string]string) string {
+	for dead, snap := range archived {
+		link := regexp.MustCompile(`\[([^\]]*)\]\(\s*<?` + regexp.QuoteMeta(dead) + `>?((?:\s+"[^"]*")?\s*)\)`)
+		line = link.ReplaceAllStringFunc(line, func(m string) string {
+			sub := link.FindStringSubmatch(m)
+			return "[" + sub[1] + " (archived)](" + snap + sub[2] + ")"
+		})
+	}
+	return urlPattern.ReplaceAllStringFunc(line, func(match string) string {
+		rawURL := trimURL(match)
+		if snap, ok := archived[rawURL]; ok {
+			return snap + " (archived)" + match[len(rawURL):]
+		}
+		return match
+	})
+}
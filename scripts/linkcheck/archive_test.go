@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWaybackArchivesDeadLinks(t *testing.T) {
+	asked := map[string]string{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("url")
+		asked[target] = r.URL.Query().Get("timestamp")
+		closest := map[string]any{"available": true, "status": "200", "url": "http://web.archive.org/web/20150101000000/" + target}
+		if target == "https://never.example/" {
+			closest = map[string]any{"available": true, "status": "404", "url": "http://web.archive.org/web/2015/" + target}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"archived_snapshots": map[string]any{"closest": closest}})
+	}))
+	defer api.Close()
+
+	findings := []finding{
+		newFinding(link{URL: "https://gone.example/post", File: "new.md", Line: 1}, ruleHTTP, "HTTP 404"),
+		newFinding(link{URL: "https://gone.example/post", File: "old.md", Line: 9}, ruleHTTP, "HTTP 404"),
+		newFinding(link{URL: "https://never.example/", File: "new.md", Line: 2}, ruleNXDomain, "host does not resolve"),
+		newFinding(link{URL: "https://odd.example/", File: "new.md", Line: 3}, ruleSuspect, "looks parked"),
+	}
+	dates := map[string]string{"new.md": "20240301", "old.md": "20150620"}
+	wb := &wayback{client: api.Client(), endpoint: api.URL}
+	wb.archive(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), findings, dates, 2)
+
+	want := "https://web.archive.org/web/20150101000000/https://gone.example/post"
+	if findings[0].Archive != want || findings[1].Archive != want {
+		t.Fatalf("archives = %q, %q, want %q", findings[0].Archive, findings[1].Archive, want)
+	}
+	if asked["https://gone.example/post"] != "20150620" {
+		t.Fatalf("asked for the snapshot nearest %q, want the first citing post's date", asked["https://gone.example/post"])
+	}
+	if findings[2].Archive != "" {
+		t.Fatalf("a snapshot of an error page was used: %q", findings[2].Archive)
+	}
+	if _, ok := asked["https://odd.example/"]; ok {
+		t.Fatal("looked up a link that isn't dead")
+	}
+	if got := findings[0].String(); got != "new.md:1:0: https://gone.example/post: HTTP 404 (archived: "+want+")" {
+		t.Fatalf("String = %q", got)
+	}
+}
+
+func TestAnnotateArchived(t *testing.T) {
+	snap := "https://web.archive.org/web/2015/https://gone.example/a"
+	archived := map[string]string{"https://gone.example/a": snap}
+	for in, want := range map[string]string{
+		"See [the post](https://gone.example/a).":         "See [the post (archived)](" + snap + ").",
+		`See [the post](https://gone.example/a "Title").`: "See [the post (archived)](" + snap + ` "Title").`,
+		"[^1]: https://gone.example/a":                    "[^1]: " + snap + " (archived)",
+		"See https://gone.example/ab too.":                "See https://gone.example/ab too.",
+	} {
+		if got := annotateArchived(in, archived); got != want {
+			t.Errorf("annotateArchived(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCollectDates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "post.md"), []byte("---\ntitle: A\ndate: 2019-04-07\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dates, err := collectDates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dates[filepath.ToSlash(filepath.Join(dir, "post.md"))]; got != "20190407" {
+		t.Fatalf("date = %q, want 20190407", got)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// artifactBodyLimit is how much of a page's body is read, and so saved,
+// while -artifacts is on: the same as the anchor check reads, so turning
+// it on doesn't change what the anchor check sees.
+const artifactBodyLimit = anchorLimit
+
+// artifactStore saves the response behind each body-check failure under
+// dir, so a failure seen only in CI can be read from the run's artifacts
+// instead of reproduced. A nil artifactStore means -artifacts is off.
+type artifactStore struct {
+	dir string
+
+	mu    sync.Mutex
+	byURL map[string]string
+}
+
+func newArtifactStore(dir string) *artifactStore {
+	return &artifactStore{dir: dir, byURL: map[string]string{}}
+}
+
+// save writes r, the response rawURL got, as an HTTP message: the request
+// line, the status line and headers of the final response, and the body
+// as read. It returns the file's path.
+func (a *artifactStore) save(rawURL string, r fetchResult) (string, error) {
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	path := filepath.Join(a.dir, hex.EncodeToString(sum[:8])+".http")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "GET %s\n", rawURL)
+	if r.finalURL != rawURL {
+		fmt.Fprintf(&b, "# redirected to %s\n", r.finalURL)
+	}
+	fmt.Fprintf(&b, "\nHTTP/1.1 %d %s\n", r.status, http.StatusText(r.status))
+	for _, name := range slices.Sorted(maps.Keys(r.header)) {
+		for _, value := range r.header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+	b.Write(r.body)
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.byURL[rawURL] = path
+	return path, nil
+}
+
+// get returns the saved response of rawURL, or "" without one.
+func (a *artifactStore) get(rawURL string) string {
+	if a == nil {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.byURL[rawURL]
+}
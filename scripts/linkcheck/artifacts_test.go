@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rednafi.com/internal/webtest"
+)
+
+func TestArtifactsKeepBodyCheckFailures(t *testing.T) {
+	page := webtest.Page("Docs", `<h2 id="install">Install</h2>`+strings.Repeat("<p>Long page.</p>", 5000)+"<p>the end</p>")
+	site := webtest.Site(t, map[string]string{
+		"/docs/": page,
+		"/gone/": webtest.Page("Page not found", "<p>Nothing here.</p>"),
+	})
+	dir := filepath.Join(t.TempDir(), "artifacts")
+	c := &checker{client: site.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, artifacts: newArtifactStore(dir)}
+	links := []link{
+		{URL: site.URL + "/docs/#install", File: "a.md", Line: 1},
+		{URL: site.URL + "/docs/#usage", File: "a.md", Line: 2},
+		{URL: site.URL + "/gone/", File: "a.md", Line: 3},
+		{URL: site.URL + "/missing/", File: "a.md", Line: 4},
+	}
+	findings := c.sweep(context.Background(), links, 2)
+
+	saved := map[string]string{}
+	for _, f := range findings {
+		saved[f.Link.URL] = f.Artifact
+	}
+	if len(findings) != 3 || saved[links[1].URL] == "" || saved[links[2].URL] == "" || saved[links[3].URL] != "" {
+		t.Fatalf("findings = %v; want the missing anchor and the soft 404 saved, and not the plain 404", findings)
+	}
+
+	raw, err := os.ReadFile(saved[links[1].URL])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(raw)
+	if !strings.HasPrefix(got, "GET "+links[1].URL+"\n\nHTTP/1.1 200 OK\n") || !strings.Contains(got, "\nContent-Type: text/html; charset=utf-8\n") || !strings.HasSuffix(got, page) {
+		t.Fatalf("saved response =\n%.300s\n...", got)
+	}
+	if !strings.Contains(findings[0].String(), "(response saved in "+saved[links[1].URL]+")") {
+		t.Errorf("text report line %q doesn't name the file", findings[0])
+	}
+	rows := reportRows(links, findings, baseline{}, nil, nil)
+	if rows[2].Artifact != saved[links[2].URL] {
+		t.Errorf("json row = %+v, want the artifact", rows[2])
+	}
+}
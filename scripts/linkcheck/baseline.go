@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+const defaultBaseline = "linkcheck.baseline"
+
+// baseline is the set of URLs whose findings were reviewed and accepted,
+// one per line in a committed file. Their findings are left out of the
+// report and don't fail the sweep.
+type baseline map[string]bool
+
+// loadBaseline reads path, treating a missing file as empty. Blank lines
+// and # comments are ignored.
+func loadBaseline(path string) (baseline, error) {
+	b := baseline{}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			b[line] = true
+		}
+	}
+	return b, scanner.Err()
+}
+
+// save writes the baseline sorted, so diffs stay small.
+func (b baseline) save(path string) error {
+	var out strings.Builder
+	out.WriteString("# External links whose findings were reviewed and accepted.\n")
+	out.WriteString("# Managed by `make linkcheck args=-tui`; one URL per line.\n")
+	for _, u := range slices.Sorted(maps.Keys(b)) {
+		out.WriteString(u + "\n")
+	}
+	return os.WriteFile(path, []byte(out.String()), 0o644)
+}
+
+// filter splits findings into those still to report and the number the
+// baseline hides.
+func (b baseline) filter(findings []finding) ([]finding, int) {
+	var kept []finding
+	for _, f := range findings {
+		if !b[f.Link.URL] {
+			kept = append(kept, f)
+		}
+	}
+	return kept, len(findings) - len(kept)
+}
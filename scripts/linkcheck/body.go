@@ -0,0 +1,66 @@
+package main
+
+import "net/url"
+
+// bodyCheck inspects the body of a live HTML response. A link gets one
+// request however many body checks are registered: the fetch reads as much
+// of the body as the hungriest check wants for that URL, and every check
+// reads the same bytes.
+type bodyCheck interface {
+	// need is how many body bytes the check wants for u; zero means it has
+	// nothing to say about u.
+	need(u *url.URL) int64
+	// inspect returns the finding the body gives away, if any.
+	inspect(u *url.URL, r fetchResult) (class, reason string, ok bool)
+}
+
+// defaultBodyChecks run in order on every live page; the first to flag it
+// decides the finding.
+var defaultBodyChecks = []bodyCheck{soft404Check{}, anchorCheck{}}
+
+// bodyChecks returns the checks registered on c.
+func (c *checker) bodyChecks() []bodyCheck {
+	if c.pageChecks == nil {
+		return defaultBodyChecks
+	}
+	return c.pageChecks
+}
+
+// bodyNeed is the most body any of checks wants for u.
+func bodyNeed(checks []bodyCheck, u *url.URL) int64 {
+	var limit int64
+	for _, check := range checks {
+		limit = max(limit, check.need(u))
+	}
+	return limit
+}
+
+// inspectBody hands r to each check that wants u's body and returns the
+// first finding.
+func inspectBody(checks []bodyCheck, u *url.URL, r fetchResult) (class, reason string, ok bool) {
+	if !r.html || r.status < 200 || r.status >= 300 {
+		return "", "", false
+	}
+	for _, check := range checks {
+		if check.need(u) == 0 {
+			continue
+		}
+		if class, reason, ok := check.inspect(u, r); ok {
+			return class, reason, true
+		}
+	}
+	return "", "", false
+}
+
+// soft404Check runs the soft-404 heuristics in soft404.go.
+type soft404Check struct{}
+
+func (soft404Check) need(*url.URL) int64 { return bodyLimit }
+
+func (soft404Check) inspect(_ *url.URL, r fetchResult) (string, string, bool) {
+	if int64(len(r.body)) > bodyLimit {
+		r.body = r.body[:bodyLimit]
+	}
+	reason, ok := suspect(r)
+	return ruleSuspect, reason, ok
+}
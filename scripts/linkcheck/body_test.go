@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// recordingCheck is a body check that records the bodies it was shown.
+type recordingCheck struct {
+	limit int64
+	seen  *[]int
+}
+
+func (c recordingCheck) need(*url.URL) int64 { return c.limit }
+
+func (c recordingCheck) inspect(_ *url.URL, r fetchResult) (string, string, bool) {
+	*c.seen = append(*c.seen, len(r.body))
+	return "", "", false
+}
+
+func TestBodyChecksShareOneRequest(t *testing.T) {
+	var requests atomic.Int32
+	page := "<html><body>" + strings.Repeat("x", 1000) + "</body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	var small, large []int
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, pageChecks: []bodyCheck{
+		recordingCheck{limit: 100, seen: &small},
+		recordingCheck{limit: 500, seen: &large},
+	}}
+	if r := c.check(context.Background(), server.URL+"/post", validators{}); r.class != "" {
+		t.Fatalf("check = %+v, want a live link", r)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("%d requests, want 1", n)
+	}
+	// Both checks see the same bytes, read up to the larger need.
+	if !slices.Equal(small, []int{500}) || !slices.Equal(large, []int{500}) {
+		t.Fatalf("checks saw bodies of %v and %v bytes, want 500 each", small, large)
+	}
+}
+
+func TestAnchorCheck(t *testing.T) {
+	article := "<html><head><title>Errors</title></head><body><h2 id=\"wrapping\">Wrapping</h2><a name='legacy'></a>" +
+		"<h2 id=\"user-content-install\">Install</h2><p>" + strings.Repeat("Words about errors. ", 30) + "</p></body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(article))
+	}))
+	defer server.Close()
+
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true}
+	for fragment, want := range map[string]string{
+		"":          "",
+		"wrapping":  "",
+		"legacy":    "",
+		"install":   "",
+		"/settings": "",
+		"renamed":   `no element with id "renamed"`,
+		"wrap":      `no element with id "wrap"`,
+	} {
+		rawURL := server.URL + "/post"
+		if fragment != "" {
+			rawURL += "#" + fragment
+		}
+		r := c.check(context.Background(), rawURL, validators{})
+		if r.reason != want || (want != "" && r.class != ruleAnchor) {
+			t.Errorf("#%s: check = %q %q, want %q", fragment, r.class, r.reason, want)
+		}
+	}
+	if newFinding(link{}, ruleAnchor, "").failed() {
+		t.Fatal("a missing anchor fails the sweep; it should only be flagged")
+	}
+}
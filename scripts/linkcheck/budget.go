@@ -0,0 +1,149 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// prioritize orders links for a sweep that may not finish: URLs that
+// failed or were throttled last time first, since they are the likeliest
+// to be broken now, then URLs cited by link-heavy posts, which rot
+// fastest, then URLs cited by the newest posts, which readers are
+// likeliest to follow. Among equals, links keep their order. dates maps a
+// file to its post date as collectDates returns it; heavy holds the files
+// linkHeavy returns.
+func prioritize(links []link, cache *resultCache, dates map[string]string, heavy map[string]bool) []link {
+	type rank struct {
+		failure int
+		heavy   bool
+		date    string
+	}
+	ranks := map[string]rank{}
+	for _, l := range links {
+		r, ok := ranks[l.URL]
+		if !ok {
+			entry, _ := cache.fresh(l.URL)
+			switch {
+			case entry.Class != "":
+				r.failure = 2
+			case len(entry.Throttles) > 0:
+				r.failure = 1
+			}
+		}
+		r.heavy = r.heavy || heavy[l.File]
+		r.date = max(r.date, dates[l.File])
+		ranks[l.URL] = r
+	}
+	out := slices.Clone(links)
+	slices.SortStableFunc(out, func(a, b link) int {
+		ra, rb := ranks[a.URL], ranks[b.URL]
+		return cmp.Or(cmp.Compare(rb.failure, ra.failure), compareBool(rb.heavy, ra.heavy), cmp.Compare(rb.date, ra.date))
+	})
+	return out
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// A post is link-heavy, and its links checked early, when it links to
+// more than heavyLinks distinct URLs, or to at least minDenseLinks and
+// more than heavyDensity per thousand words of prose: the defaults of
+// freshness's -max-links and -max-link-density.
+const (
+	heavyLinks    = 40
+	heavyDensity  = 20
+	minDenseLinks = 5
+)
+
+// linkHeavy returns the link-heavy files among those links come from.
+func linkHeavy(links []link) (map[string]bool, error) {
+	distinct := map[string]map[string]bool{}
+	for _, l := range links {
+		if distinct[l.File] == nil {
+			distinct[l.File] = map[string]bool{}
+		}
+		distinct[l.File][l.URL] = true
+	}
+	heavy := map[string]bool{}
+	for file, urls := range distinct {
+		n := len(urls)
+		if n < minDenseLinks {
+			continue
+		}
+		if n <= heavyLinks {
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if float64(n)*1000/float64(max(proseWords(raw), 1)) <= heavyDensity {
+				continue
+			}
+		}
+		heavy[file] = true
+	}
+	return heavy, nil
+}
+
+var linkTargetPattern = regexp.MustCompile(`\]\([^)]*\)`)
+
+// proseWords counts the words of a Markdown file's body outside code,
+// fenced or inline, and link targets: fields holding a letter or digit, so
+// list bullets and table pipes don't count.
+func proseWords(raw []byte) int {
+	body := string(raw)
+	if fm, ok := frontmatter(body); ok {
+		body = body[len("---\n")+len(fm)+len("\n---\n"):]
+	}
+	var words int
+	var fence []byte
+	for line := range strings.SplitSeq(body, "\n") {
+		if fence != nil {
+			if isFenceClose([]byte(line), fence) {
+				fence = nil
+			}
+			continue
+		}
+		if fence = fenceOpen([]byte(line)); fence != nil {
+			continue
+		}
+		text := linkTargetPattern.ReplaceAllString(inlineCodePattern.ReplaceAllString(line, " "), " ")
+		for field := range strings.FieldsSeq(text) {
+			if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+				words++
+			}
+		}
+	}
+	return words
+}
+
+// outOfTime reports whether the sweep should stop sending rawURL out: the
+// -max-duration budget is spent and rawURL has no fresh cached result,
+// which would cost nothing to report.
+func (c *checker) outOfTime(rawURL string) bool {
+	if c.stopAt.IsZero() || time.Now().Before(c.stopAt) {
+		return false
+	}
+	_, fresh := c.cache.fresh(rawURL)
+	return !fresh
+}
+
+// coverage summarizes a sweep cut short by -max-duration, given how many
+// unique URLs it had and how many it didn't reach.
+func coverage(budget time.Duration, unique, unchecked int) string {
+	checked := unique - unchecked
+	return fmt.Sprintf("the %s budget ran out: checked %d of %d unique URLs (%d%%); the rest are listed as unchecked\n",
+		budget, checked, unique, checked*100/max(unique, 1))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrioritizePutsLikelyFailuresAndNewPostsFirst(t *testing.T) {
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.store("https://failed.example/", ruleHTTP, "HTTP 404", validators{}, nil, 0)
+	cache.recordThrottles("https://throttled.example/", []throttle{{Status: http.StatusTooManyRequests}})
+	links := []link{
+		{URL: "https://old.example/", File: "content/go/old.md"},
+		{URL: "https://new.example/", File: "content/go/new.md"},
+		{URL: "https://throttled.example/", File: "content/go/old.md"},
+		{URL: "https://old.example/", File: "content/go/newer.md"},
+		{URL: "https://failed.example/", File: "content/go/old.md"},
+	}
+	dates := map[string]string{"content/go/old.md": "20190101", "content/go/new.md": "20250101", "content/go/newer.md": "20260101"}
+
+	var got []string
+	for _, l := range prioritize(links, cache, dates, nil) {
+		got = append(got, strings.TrimPrefix(l.URL, "https://")+" "+l.File)
+	}
+	want := []string{
+		"failed.example/ content/go/old.md",
+		"throttled.example/ content/go/old.md",
+		// Cited by the newest post, so it outranks new.example.
+		"old.example/ content/go/old.md",
+		"old.example/ content/go/newer.md",
+		"new.example/ content/go/new.md",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("prioritize =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestPrioritizePutsLinkHeavyPostsBeforeNewOnes(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "list.md")
+	essay := filepath.Join(dir, "essay.md")
+	var links []link
+	listBody := "---\ndate: 2019-01-01\n---\n"
+	essayBody := "---\ndate: 2026-01-01\n---\n" + strings.Repeat("word ", 1000) + "\n"
+	for i := range 6 {
+		u := fmt.Sprintf("https://tool%d.example/", i)
+		listBody += "- [Tool](" + u + ")\n"
+		essayBody += "[Tool](" + u + ")\n"
+		links = append(links, link{URL: u, File: filepath.ToSlash(list)}, link{URL: u, File: filepath.ToSlash(essay)})
+	}
+	for file, body := range map[string]string{list: listBody, essay: essayBody} {
+		if err := os.WriteFile(file, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links = append(links,
+		link{URL: "https://new.example/", File: filepath.ToSlash(essay)},
+		link{URL: "https://listed.example/", File: filepath.ToSlash(list)},
+	)
+
+	heavy, err := linkHeavy(links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !heavy[filepath.ToSlash(list)] || heavy[filepath.ToSlash(essay)] {
+		t.Fatalf("linkHeavy = %v, want only %s", heavy, list)
+	}
+
+	cache, err := loadCache(filepath.Join(dir, "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dates := map[string]string{filepath.ToSlash(list): "20190101", filepath.ToSlash(essay): "20260101"}
+	ordered := prioritize(links, cache, dates, heavy)
+	if last := ordered[len(ordered)-1].URL; last != "https://new.example/" {
+		t.Fatalf("last link = %s, want the one only the new essay cites", last)
+	}
+}
+
+func TestSweepStopsWhenTheBudgetRunsOut(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.store(server.URL+"/cached", ruleHTTP, "HTTP 410", validators{}, nil, 0)
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, cache: cache, stopAt: time.Now().Add(-time.Second)}
+	links := []link{
+		{URL: server.URL + "/cached", File: "a.md", Line: 1},
+		{URL: server.URL + "/fresh", File: "a.md", Line: 2},
+		{URL: server.URL + "/fresh", File: "b.md", Line: 1},
+	}
+
+	findings := c.sweep(context.Background(), links, 2)
+	if hits != 0 {
+		t.Fatalf("%d requests went out after the budget ran out", hits)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Link.File+" "+f.Rule)
+	}
+	if want := "a.md http, a.md unchecked, b.md unchecked"; strings.Join(got, ", ") != want {
+		t.Fatalf("findings = %s, want %s", strings.Join(got, ", "), want)
+	}
+	if n := c.unchecked.Load(); n != 1 {
+		t.Fatalf("unchecked = %d, want 1", n)
+	}
+	if got := coverage(10*time.Minute, 2, 1); !strings.Contains(got, "checked 1 of 2 unique URLs (50%)") {
+		t.Fatalf("coverage = %q", got)
+	}
+}
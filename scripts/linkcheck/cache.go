@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultCache = ".cache/linkcheck.json"
+
+// Default cache lifetimes. Live links rarely die within a week; failures get
+// rechecked sooner because many are transient.
+const (
+	defaultOKTTL     = 7 * 24 * time.Hour
+	defaultFailedTTL = 24 * time.Hour
+)
+
+// maxThrottles caps the throttling history kept per URL.
+const maxThrottles = 20
+
+// cacheEntry is one URL's last check result. An empty Class means alive;
+// live links keep the server's validators for conditional rechecks.
+// Throttles is the URL's history of rate-limit answers, newest last, kept
+// across checks. Headers holds the response headers captured by the run
+// that checked it, if it ran with -capture-headers, and Latency how long
+// the URL took to answer then.
+type cacheEntry struct {
+	CheckedAt time.Time         `json:"checkedAt"`
+	Class     string            `json:"class,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	Throttles []throttle        `json:"throttles,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Latency   time.Duration     `json:"latency,omitempty"`
+	validators
+}
+
+// resultCache persists check results between runs so a sweep only
+// re-verifies links whose result has expired.
+type resultCache struct {
+	okTTL, failedTTL time.Duration
+	now              func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// loadCache reads the cache at path, treating a missing file as empty.
+func loadCache(path string, okTTL, failedTTL time.Duration) (*resultCache, error) {
+	c := &resultCache{okTTL: okTTL, failedTTL: failedTTL, now: time.Now, entries: map[string]cacheEntry{}}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &c.entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// fresh returns rawURL's cached result and whether it is still fresh. An
+// expired entry is returned too, for its validators.
+func (c *resultCache) fresh(rawURL string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[rawURL]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	ttl := c.okTTL
+	if entry.Class != "" {
+		ttl = c.failedTTL
+	}
+	return entry, c.now().Sub(entry.CheckedAt) < ttl
+}
+
+func (c *resultCache) store(rawURL, class, reason string, v validators, headers map[string]string, latency time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev := c.entries[rawURL]
+	c.entries[rawURL] = cacheEntry{CheckedAt: c.now(), Class: class, Reason: reason, Throttles: prev.Throttles, Headers: headers, Latency: latency, validators: v}
+}
+
+// expire makes rawURL's cached result stale, so the next sweep checks it
+// again, and keeps the rest of its entry.
+func (c *resultCache) expire(rawURL string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[rawURL]; ok {
+		entry.CheckedAt = time.Time{}
+		c.entries[rawURL] = entry
+	}
+}
+
+// recordThrottles appends events to rawURL's throttling history without
+// touching its result, so a link that stays throttled still leaves a trail.
+func (c *resultCache) recordThrottles(rawURL string, events []throttle) {
+	if c == nil || len(events) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.entries[rawURL]
+	entry.Throttles = append(entry.Throttles, events...)
+	if n := len(entry.Throttles); n > maxThrottles {
+		entry.Throttles = entry.Throttles[n-maxThrottles:]
+	}
+	c.entries[rawURL] = entry
+}
+
+// prune drops entries for URLs no longer linked from content.
+func (c *resultCache) prune(keep []string) {
+	if c == nil {
+		return
+	}
+	live := map[string]bool{}
+	for _, u := range keep {
+		live[u] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for u := range c.entries {
+		if !live[u] {
+			delete(c.entries, u)
+		}
+	}
+}
+
+func (c *resultCache) save(path string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	raw, err := json.MarshalIndent(c.entries, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultCacheExpiresByOutcome(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), 7*24*time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.now = func() time.Time { return now }
+	cache.store("https://alive.example/", "", "", validators{}, nil, 0)
+	cache.store("https://dead.example/", ruleHTTP, "HTTP 404", validators{}, nil, 0)
+
+	now = now.Add(2 * 24 * time.Hour)
+	if _, ok := cache.fresh("https://alive.example/"); !ok {
+		t.Error("a live result should stay fresh for a week")
+	}
+	if _, ok := cache.fresh("https://dead.example/"); ok {
+		t.Error("a failed result should expire after a day")
+	}
+	if _, ok := cache.fresh("https://unknown.example/"); ok {
+		t.Error("an unknown URL is never fresh")
+	}
+}
+
+func TestResultCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".cache", "linkcheck.json")
+	cache, err := loadCache(path, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.store("https://keep.example/", ruleHTTP, "HTTP 410", validators{}, nil, 0)
+	cache.store("https://gone.example/", "", "", validators{ETag: `"v1"`}, nil, 0)
+	cache.prune([]string{"https://keep.example/"})
+	if err := cache.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := loadCache(path, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := reloaded.fresh("https://keep.example/"); !ok || entry.Reason != "HTTP 410" {
+		t.Errorf("reloaded entry = %+v, %t", entry, ok)
+	}
+	if _, ok := reloaded.entries["https://gone.example/"]; ok {
+		t.Error("pruned URL survived the round trip")
+	}
+}
+
+func TestSweepServesFreshResultsFromCache(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			hits++
+		}
+	}))
+	defer server.Close()
+
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(server.Client()), cache: cache}
+	links := []link{{URL: server.URL + "/page", File: "a.md", Line: 1}}
+
+	c.sweep(context.Background(), links, 1)
+	c.sweep(context.Background(), links, 1)
+	if hits != 1 {
+		t.Errorf("page fetched %d times across two sweeps, want 1", hits)
+	}
+	if len(stale(links, cache)) != 0 {
+		t.Error("a freshly checked link should not be stale")
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Where the code check reads a file's lines and a branch's history.
+const (
+	githubRaw = "https://raw.githubusercontent.com"
+	githubAPI = "https://api.github.com"
+)
+
+// githubTokenEnv names the variable holding a GitHub token for the code
+// check's history lookups, which GitHub limits to 60 an hour without one.
+const githubTokenEnv = "GITHUB_TOKEN"
+
+// codeRef is a link to lines of a file in a GitHub repository, the way
+// posts cite code: github.com/OWNER/REPO/blob/REF/PATH#L10-L20.
+type codeRef struct {
+	repo, ref, path string
+	start, end      int
+}
+
+var lineFragmentPattern = regexp.MustCompile(`^L(\d+)(?:C\d+)?(?:-L(\d+)(?:C\d+)?)?$`)
+
+// commitPattern matches a full commit hash, the one ref that never moves.
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// parseCodeRef reports whether u links to lines of code on GitHub. A ref
+// with a slash in it can't be told from the path, so it is read as a ref
+// of one segment, which holds for the branches, tags and commits posts
+// link to.
+func parseCodeRef(u *url.URL) (codeRef, bool) {
+	if !strings.EqualFold(u.Hostname(), "github.com") {
+		return codeRef{}, false
+	}
+	m := lineFragmentPattern.FindStringSubmatch(u.Fragment)
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 5)
+	if m == nil || len(parts) < 5 || parts[2] != "blob" || parts[4] == "" {
+		return codeRef{}, false
+	}
+	ref := codeRef{repo: parts[0] + "/" + parts[1], ref: parts[3], path: parts[4]}
+	ref.start, _ = strconv.Atoi(m[1])
+	ref.end = ref.start
+	if m[2] != "" {
+		ref.end, _ = strconv.Atoi(m[2])
+	}
+	if ref.end < ref.start {
+		ref.start, ref.end = ref.end, ref.start
+	}
+	return ref, ref.start > 0
+}
+
+// pinned reports whether r's ref is a commit, so its lines never change.
+func (r codeRef) pinned() bool { return commitPattern.MatchString(r.ref) }
+
+func (r codeRef) lines() string {
+	if r.start == r.end {
+		return fmt.Sprintf("line %d", r.start)
+	}
+	return fmt.Sprintf("lines %d-%d", r.start, r.end)
+}
+
+// at returns the link to r's lines at another ref.
+func (r codeRef) at(ref string) string {
+	fragment := fmt.Sprintf("L%d", r.start)
+	if r.end != r.start {
+		fragment += fmt.Sprintf("-L%d", r.end)
+	}
+	return fmt.Sprintf("https://github.com/%s/blob/%s/%s#%s", r.repo, ref, r.path, fragment)
+}
+
+// codeLines checks links to lines of code on GitHub against the file they
+// cite: the file at the linked ref must still hold the lines, and a link
+// to a branch must cite the same code it did when the oldest post citing
+// it was published, or it points at whatever moved into its place.
+type codeLines struct {
+	raw, api string
+	token    string
+	// published maps a URL to the date, YYYYMMDD, of the oldest post
+	// citing it, as collectDates reports dates.
+	published map[string]string
+}
+
+// newCodeLines sets up the code check for links, dated by the posts they
+// come from.
+func newCodeLines(links []link, dates map[string]string, token string) *codeLines {
+	published := map[string]string{}
+	for _, l := range links {
+		date := dates[l.File]
+		if date == "" {
+			continue
+		}
+		if prev, ok := published[l.URL]; !ok || date < prev {
+			published[l.URL] = date
+		}
+	}
+	return &codeLines{raw: githubRaw, api: githubAPI, token: token, published: published}
+}
+
+// check returns what is wrong with the code rawURL cites, if anything. It
+// runs after the GitHub page itself answered, so a deleted file or a
+// vanished ref is already an HTTP failure by then.
+func (cl *codeLines) check(ctx context.Context, client *http.Client, log *slog.Logger, rawURL string, ref codeRef) (class, reason string, ok bool) {
+	current, found, err := cl.file(ctx, client, ref.repo, ref.ref, ref.path)
+	if err != nil || !found {
+		// The page was there a moment ago; don't fail the link on the raw
+		// file's account.
+		log.Warn("code not read", "repo", ref.repo, "ref", ref.ref, "path", ref.path, "found", found, "err", err)
+		return "", "", false
+	}
+	if ref.end > len(current) {
+		return ruleCode, fmt.Sprintf("cites %s, but %s has %d %s now", ref.lines(), path.Base(ref.path), len(current), plural(len(current), "line")), true
+	}
+	date := cl.published[rawURL]
+	if ref.pinned() || date == "" {
+		return "", "", false
+	}
+
+	// A branch moves: compare the lines with the file as the branch had it
+	// when the post went out.
+	then, err := cl.commitAt(ctx, client, ref, date)
+	if err != nil {
+		log.Warn("branch history not read", "repo", ref.repo, "ref", ref.ref, "path", ref.path, "err", err)
+		return "", "", false
+	}
+	if then == "" {
+		return "", "", false
+	}
+	old, found, err := cl.file(ctx, client, ref.repo, then, ref.path)
+	if err != nil || !found || ref.end > len(old) {
+		return "", "", false
+	}
+	if strings.Join(old[ref.start-1:ref.end], "\n") == strings.Join(current[ref.start-1:ref.end], "\n") {
+		return "", "", false
+	}
+	return ruleCode, fmt.Sprintf("%s of %s changed on %s since the post; the code it cited is at %s", ref.lines(), path.Base(ref.path), ref.ref, ref.at(then)), true
+}
+
+// file returns the lines of path in repo at ref, and false when there is
+// no such file.
+func (cl *codeLines) file(ctx context.Context, client *http.Client, repo, ref, filePath string) ([]string, bool, error) {
+	req, err := newRequest(ctx, http.MethodGet, cl.rawURL(repo, ref, filePath))
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, anchorLimit))
+	if err != nil {
+		return nil, false, err
+	}
+	return strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n"), true, nil
+}
+
+// rawURL is where the file's content is served.
+func (cl *codeLines) rawURL(repo, ref, filePath string) string {
+	return cl.raw + "/" + repo + "/" + ref + "/" + filePath
+}
+
+// commitAt returns the last commit on ref's branch to touch its file by
+// the end of date, or "" when the file didn't exist yet.
+func (cl *codeLines) commitAt(ctx context.Context, client *http.Client, ref codeRef, date string) (string, error) {
+	day, err := time.Parse("20060102", date)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"sha":      {ref.ref},
+		"path":     {ref.path},
+		"until":    {day.Add(24*time.Hour - time.Second).Format(time.RFC3339)},
+		"per_page": {"1"},
+	}
+	req, err := newRequest(ctx, http.MethodGet, cl.api+"/repos/"+ref.repo+"/commits?"+query.Encode())
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if cl.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", nil
+	}
+	return commits[0].SHA, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseCodeRef(t *testing.T) {
+	for _, tc := range []struct {
+		link string
+		want codeRef
+		ok   bool
+	}{
+		{"https://github.com/golang/go/blob/go1.24.2/src/syscall/syscall_unix.go#L120", codeRef{"golang/go", "go1.24.2", "src/syscall/syscall_unix.go", 120, 120}, true},
+		{"https://github.com/rednafi/eon/blob/857935f9fe411dce7a5b306d5b898397fdac87e5/cmd/eon/script_test.go#L18-L92", codeRef{"rednafi/eon", "857935f9fe411dce7a5b306d5b898397fdac87e5", "cmd/eon/script_test.go", 18, 92}, true},
+		{"https://github.com/o/r/blob/main/a.go#L9C2-L4C10", codeRef{"o/r", "main", "a.go", 4, 9}, true},
+		{"https://github.com/uber-go/guide/blob/master/style.md#verify-interface-compliance", codeRef{}, false},
+		{"https://github.com/o/r/tree/main/cmd#L3", codeRef{}, false},
+		{"https://gitlab.com/o/r/blob/main/a.go#L3", codeRef{}, false},
+		{"https://github.com/o/r/blob/main/a.go#L0", codeRef{}, false},
+	} {
+		u, err := url.Parse(tc.link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := parseCodeRef(u)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("parseCodeRef(%s) = %+v, %v; want %+v, %v", tc.link, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCodeLinesFlagsMovedCode(t *testing.T) {
+	const (
+		pinned = "0123456789abcdef0123456789abcdef01234567"
+		then   = "89abcdef0123456789abcdef0123456789abcdef"
+	)
+	files := map[string]string{
+		"/o/r/" + pinned + "/a.go": "one\ntwo\nthree\nfour\nfive\n",
+		"/o/r/main/a.go":           "one\ntwo\nTHREE\nfour\nfive\nsix\n",
+		"/o/r/" + then + "/a.go":   "one\ntwo\nthree\nfour\n",
+	}
+	var until string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/o/r/commits" {
+			until = r.URL.Query().Get("until")
+			w.Write([]byte(`[{"sha": "` + then + `"}]`))
+			return
+		}
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	links := []link{
+		{URL: "https://github.com/o/r/blob/" + pinned + "/a.go#L2-L3", File: "content/go/a.md"},
+		{URL: "https://github.com/o/r/blob/" + pinned + "/a.go#L4-L9", File: "content/go/a.md"},
+		{URL: "https://github.com/o/r/blob/main/a.go#L1-L2", File: "content/go/a.md"},
+		{URL: "https://github.com/o/r/blob/main/a.go#L3", File: "content/go/b.md"},
+		{URL: "https://github.com/o/r/blob/main/a.go#L3", File: "content/go/a.md"},
+		{URL: "https://github.com/o/r/blob/main/a.go#L4", File: "content/go/undated.md"},
+	}
+	dates := map[string]string{"content/go/a.md": "20240301", "content/go/b.md": "20250101"}
+	cl := newCodeLines(links, dates, "")
+	cl.raw, cl.api = server.URL, server.URL
+
+	want := map[string]string{
+		links[1].URL: "cites lines 4-9, but a.go has 5 lines now",
+		links[3].URL: "line 3 of a.go changed on main since the post; the code it cited is at https://github.com/o/r/blob/" + then + "/a.go#L3",
+	}
+	for _, l := range links {
+		u, _ := url.Parse(l.URL)
+		ref, _ := parseCodeRef(u)
+		class, reason, ok := cl.check(context.Background(), server.Client(), discard, l.URL, ref)
+		if reason != want[l.URL] || ok != (want[l.URL] != "") || (ok && class != ruleCode) {
+			t.Errorf("%s: check = %q, %q; want %q", l.URL, class, reason, want[l.URL])
+		}
+	}
+	// The oldest post citing a link dates it.
+	if !strings.HasPrefix(until, "2024-03-01T23:59:59") {
+		t.Errorf("history asked until %q, want the end of 2024-03-01", until)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// worktree checks ref out into a temporary git worktree and returns its
+// directory and the func that removes it.
+func worktree(ref string) (string, func(), error) {
+	parent, err := os.MkdirTemp("", "linkcheck-")
+	if err != nil {
+		return "", nil, err
+	}
+	dir := filepath.Join(parent, "tree")
+	if out, err := exec.Command("git", "worktree", "add", "--detach", "--quiet", dir, ref).CombinedOutput(); err != nil {
+		os.RemoveAll(parent)
+		return "", nil, fmt.Errorf("git worktree add %s: %v: %s", ref, err, strings.TrimSpace(string(out)))
+	}
+	cleanup := func() {
+		exec.Command("git", "worktree", "remove", "--force", dir).Run()
+		os.RemoveAll(parent)
+	}
+	return dir, cleanup, nil
+}
+
+// linksAt returns the external links in ref's content, with file paths as
+// they are in the repository.
+func linksAt(ref string) ([]link, func(), error) {
+	dir, cleanup, err := worktree(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	links, err := collectLinks(filepath.Join(dir, contentDir))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	prefix := filepath.ToSlash(dir) + "/"
+	for i := range links {
+		links[i].File = strings.TrimPrefix(links[i].File, prefix)
+	}
+	return links, cleanup, nil
+}
+
+// compareRefs checks out base and head and returns head's findings that
+// base doesn't have.
+func (c *checker) compareRefs(ctx context.Context, base, head string, workers, dnsWorkers int) ([]finding, error) {
+	baseLinks, cleanupBase, err := linksAt(base)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupBase()
+	headLinks, cleanupHead, err := linksAt(head)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupHead()
+
+	c.resolveAll(ctx, hostsOf(append(slices.Clone(baseLinks), headLinks...)), dnsWorkers)
+	return c.compare(ctx, baseLinks, headLinks, workers), nil
+}
+
+// compare sweeps the links of base and head together, so a URL cited on
+// both sides is checked once, and returns head's findings that base doesn't
+// have.
+func (c *checker) compare(ctx context.Context, baseLinks, headLinks []link, workers int) []finding {
+	// Most links are identical on both sides, so base's are tagged to tell
+	// the two apart after the sweep.
+	const baseTag = "\x00base:"
+	baseLinks = slices.Clone(baseLinks)
+	for i := range baseLinks {
+		baseLinks[i].File = baseTag + baseLinks[i].File
+	}
+	var baseFindings, headFindings []finding
+	for _, f := range c.sweep(ctx, append(baseLinks, headLinks...), workers) {
+		if file, ok := strings.CutPrefix(f.Link.File, baseTag); ok {
+			f.Link.File = file
+			baseFindings = append(baseFindings, f)
+		} else {
+			headFindings = append(headFindings, f)
+		}
+	}
+	return newFindings(baseFindings, headFindings)
+}
+
+// newFindings returns the findings in head that base doesn't share. A
+// finding is matched by URL and rule, not position: editing a post shifts
+// its lines without introducing anything.
+func newFindings(base, head []finding) []finding {
+	type key struct{ url, class string }
+	existing := map[key]bool{}
+	for _, f := range base {
+		existing[key{f.Link.URL, f.Rule}] = true
+	}
+	var added []finding
+	for _, f := range head {
+		if !existing[key{f.Link.URL, f.Rule}] {
+			added = append(added, f)
+		}
+	}
+	return added
+}
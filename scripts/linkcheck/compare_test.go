@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestCompareReportsOnlyNewFindings(t *testing.T) {
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/old-dead", "/new-dead":
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	base := []link{
+		{URL: server.URL + "/old-dead", File: "content/go/a.md", Line: 3},
+		{URL: server.URL + "/ok", File: "content/go/a.md", Line: 5},
+	}
+	head := []link{
+		// An edit above the old dead link shifted it down two lines.
+		{URL: server.URL + "/old-dead", File: "content/go/a.md", Line: 5},
+		{URL: server.URL + "/ok", File: "content/go/a.md", Line: 7},
+		{URL: server.URL + "/new-dead", File: "content/go/b.md", Line: 1},
+	}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(server.Client())}
+	got := c.compare(context.Background(), base, head, 2)
+
+	want := []finding{newFinding(head[2], ruleHTTP, "HTTP 404")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("compare =\n  %v\nwant\n  %v", got, want)
+	}
+	if hits["/old-dead"] != 1 || hits["/ok"] != 1 {
+		t.Errorf("shared URLs fetched %v, want once each", hits)
+	}
+	if base[0].File != "content/go/a.md" {
+		t.Error("compare modified the caller's links")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfig = "linkcheck.yml"
+
+// config is linkcheck.yml. Every field is optional.
+type config struct {
+	// Skip lists domains whose links are reported as skipped, not checked.
+	Skip []string `yaml:"skip"`
+	// Force lists domains that are always checked, overriding Skip and
+	// robots.txt.
+	Force []string `yaml:"force"`
+	// Cache sets how long results stay fresh: OK for live links, Failed for
+	// everything else. Zero means the default.
+	Cache struct {
+		OK     time.Duration `yaml:"ok"`
+		Failed time.Duration `yaml:"failed"`
+	} `yaml:"cache"`
+	// Limits caps concurrency and request rate per domain.
+	Limits limitsConfig `yaml:"limits"`
+	// UserAgent replaces the default User-Agent. It must carry a contact
+	// URL so site owners can reach whoever runs the sweep.
+	UserAgent string `yaml:"userAgent"`
+	// Headers maps a domain to extra request headers for it and its
+	// subdomains, for sites that turn away requests without browser-like
+	// Accept headers.
+	Headers map[string]map[string]string `yaml:"headers"`
+	// Tracking lists the query parameters reported on outbound links and
+	// stripped by -fix; a trailing * matches by prefix. Empty means
+	// defaultTracking.
+	Tracking []string `yaml:"tracking"`
+	// Daemon schedules check groups for -daemon mode.
+	Daemon daemonConfig `yaml:"daemon"`
+	// Email is where `report digest -mail` sends the nightly digest.
+	Email emailConfig `yaml:"email"`
+}
+
+// loadConfig reads path, treating a missing file as an empty config.
+func loadConfig(path string) (config, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config{}, nil
+	}
+	if err != nil {
+		return config{}, err
+	}
+	var c config
+	if err := yaml.Unmarshal(raw, &c); err != nil {
+		return config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if c.UserAgent != "" && !strings.Contains(c.UserAgent, "://") {
+		return config{}, fmt.Errorf("%s: userAgent %q needs a contact URL", path, c.UserAgent)
+	}
+	return c, nil
+}
+
+// matchesDomain reports whether host is one of domains or a subdomain of one.
+func matchesDomain(host string, domains []string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
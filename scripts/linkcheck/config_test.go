@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "linkcheck.yml")
+	if err := os.WriteFile(path, []byte("skip:\n  - twitter.com\nforce:\n  - github.com\ncache:\n  ok: 72h\n  failed: 6h\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got.Skip, []string{"twitter.com"}) || !slices.Equal(got.Force, []string{"github.com"}) {
+		t.Fatalf("loadConfig = %+v", got)
+	}
+	if got.Cache.OK != 72*time.Hour || got.Cache.Failed != 6*time.Hour {
+		t.Fatalf("cache TTLs = %s, %s; want 72h, 6h", got.Cache.OK, got.Cache.Failed)
+	}
+
+	missing, err := loadConfig(filepath.Join(t.TempDir(), "absent.yml"))
+	if err != nil || len(missing.Skip) != 0 {
+		t.Fatalf("missing config = %+v, %v; want empty config", missing, err)
+	}
+}
+
+func TestMatchesDomain(t *testing.T) {
+	domains := []string{"twitter.com"}
+	for host, want := range map[string]bool{
+		"twitter.com":        true,
+		"mobile.twitter.com": true,
+		"TWITTER.com":        true,
+		"nottwitter.com":     false,
+		"twitter.com.evil":   false,
+	} {
+		if got := matchesDomain(host, domains); got != want {
+			t.Errorf("matchesDomain(%q) = %t, want %t", host, got, want)
+		}
+	}
+}
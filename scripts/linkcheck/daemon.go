@@ -0,0 +1,455 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for daemon settings linkcheck.yml leaves out.
+const (
+	defaultListen      = "127.0.0.1:8087"
+	defaultDaemonState = ".cache/linkcheck-daemon.json"
+)
+
+// Check kinds a daemon group can run.
+const (
+	checkUptime = "uptime"
+	checkLinks  = "links"
+)
+
+// certMargin is how close to its certificate's expiry a live site's uptime
+// check starts failing.
+const certMargin = 14 * 24 * time.Hour
+
+// maxStateFailures caps the failures kept per group in the daemon state.
+const maxStateFailures = 50
+
+// daemonConfig is the daemon section of linkcheck.yml.
+type daemonConfig struct {
+	// Listen is the address of the status endpoint.
+	Listen string `yaml:"listen"`
+	// State is where group results persist between runs and restarts.
+	State  string        `yaml:"state"`
+	Groups []groupConfig `yaml:"groups"`
+}
+
+// groupConfig is one scheduled check group. An uptime group fetches URLs
+// and fails on any error or status of 400 and up; a links group sweeps
+// every external link in content/ as a normal run would.
+//
+// Against the live site, an uptime group also fails on a URL that isn't
+// served over TLS, whose certificate expires within certMargin, or whose
+// response lacks one of Headers. A group whose URLs all point at a local
+// server (hugo server, say) skips those checks; see uptime.
+type groupConfig struct {
+	Name     string   `yaml:"name"`
+	Check    string   `yaml:"check"`
+	Schedule string   `yaml:"schedule"`
+	URLs     []string `yaml:"urls"`
+	// Headers are response headers every URL must carry in production.
+	Headers []string `yaml:"headers"`
+}
+
+// groupState is what the daemon knows about a group, served on /status and
+// saved to the state file.
+type groupState struct {
+	Name     string        `json:"name"`
+	Check    string        `json:"check"`
+	Schedule string        `json:"schedule"`
+	LastRun  time.Time     `json:"lastRun,omitzero"`
+	NextRun  time.Time     `json:"nextRun,omitzero"`
+	Duration time.Duration `json:"duration"`
+	OK       bool          `json:"ok"`
+	Summary  string        `json:"summary,omitempty"`
+	Failures []string      `json:"failures,omitempty"`
+	// Streak counts consecutive failed runs.
+	Streak int `json:"streak,omitempty"`
+}
+
+// daemon runs check groups on their schedules and serves their state.
+type daemon struct {
+	checker      *checker
+	groups       []groupConfig
+	schedules    map[string]schedule
+	statePath    string
+	cachePath    string
+	baselinePath string
+	workers      int
+	dnsWorkers   int
+	now          func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*groupState
+}
+
+// validate checks the daemon section and parses each group's schedule.
+func (dc daemonConfig) validate() (map[string]schedule, error) {
+	if len(dc.Groups) == 0 {
+		return nil, errors.New("daemon: no groups configured")
+	}
+	schedules := map[string]schedule{}
+	for _, g := range dc.Groups {
+		if g.Name == "" {
+			return nil, errors.New("daemon: group without a name")
+		}
+		if _, dup := schedules[g.Name]; dup {
+			return nil, fmt.Errorf("daemon: group %q defined twice", g.Name)
+		}
+		switch g.Check {
+		case checkUptime:
+			if len(g.URLs) == 0 {
+				return nil, fmt.Errorf("daemon: uptime group %q has no urls", g.Name)
+			}
+		case checkLinks:
+		default:
+			return nil, fmt.Errorf("daemon: group %q: check must be %s or %s, got %q", g.Name, checkUptime, checkLinks, g.Check)
+		}
+		s, err := parseSchedule(g.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("daemon: group %q: %w", g.Name, err)
+		}
+		if s.next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("daemon: group %q: schedule %q never runs", g.Name, g.Schedule)
+		}
+		schedules[g.Name] = s
+	}
+	return schedules, nil
+}
+
+func newDaemon(c *checker, dc daemonConfig, cachePath, baselinePath string, workers, dnsWorkers int) (*daemon, error) {
+	schedules, err := dc.validate()
+	if err != nil {
+		return nil, err
+	}
+	d := &daemon{
+		checker:      c,
+		groups:       dc.Groups,
+		schedules:    schedules,
+		statePath:    cmp.Or(dc.State, defaultDaemonState),
+		cachePath:    cachePath,
+		baselinePath: baselinePath,
+		workers:      workers,
+		dnsWorkers:   dnsWorkers,
+		now:          time.Now,
+		states:       map[string]*groupState{},
+	}
+	saved, err := loadDaemonState(d.statePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range dc.Groups {
+		s := &groupState{Name: g.Name, Check: g.Check, Schedule: g.Schedule}
+		if prev, ok := saved[g.Name]; ok && prev.Check == g.Check && prev.Schedule == g.Schedule {
+			s = prev
+		}
+		d.states[g.Name] = s
+	}
+	return d, nil
+}
+
+// run starts every group's loop and the status server, and blocks until
+// ctx is done.
+func (d *daemon) run(ctx context.Context, listen string) error {
+	server := &http.Server{Addr: listen, Handler: d.handler()}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+	d.checker.logger().Info("daemon started", "groups", len(d.groups), "status", "http://"+listen+"/status")
+
+	var wg sync.WaitGroup
+	for _, g := range d.groups {
+		wg.Go(func() { d.loop(ctx, g) })
+	}
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		return err
+	}
+	wg.Wait()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// loop runs g whenever its schedule comes due. A group whose last run is
+// missing, or whose next run passed while the daemon was down, runs at
+// once.
+func (d *daemon) loop(ctx context.Context, g groupConfig) {
+	for {
+		d.mu.Lock()
+		state := d.states[g.Name]
+		next := d.now()
+		if !state.LastRun.IsZero() {
+			next = d.schedules[g.Name].next(state.LastRun)
+		}
+		state.NextRun = next
+		d.mu.Unlock()
+
+		timer := time.NewTimer(max(next.Sub(d.now()), 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		d.runGroup(ctx, g)
+	}
+}
+
+// runGroup runs g once and records the outcome.
+func (d *daemon) runGroup(ctx context.Context, g groupConfig) {
+	ctx, root := d.checker.tracer.start(ctx, "daemon "+g.Name, "linkcheck.group", g.Name, "linkcheck.check", g.Check)
+	defer func() {
+		root.finish()
+		if err := d.checker.tracer.flush(context.Background()); err != nil {
+			d.checker.logger().Warn("traces not exported", "err", err)
+		}
+	}()
+	start := d.now()
+	var failures []string
+	var summary string
+	var err error
+	switch g.Check {
+	case checkUptime:
+		failures, summary = d.uptime(ctx, g)
+	case checkLinks:
+		failures, summary, err = d.links(ctx)
+	}
+	if err != nil {
+		failures, summary = []string{err.Error()}, "run failed"
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	d.mu.Lock()
+	state := d.states[g.Name]
+	state.LastRun = start
+	state.Duration = d.now().Sub(start).Round(time.Millisecond)
+	state.OK = len(failures) == 0
+	state.Summary = summary
+	state.Failures = failures[:min(len(failures), maxStateFailures)]
+	if state.OK {
+		state.Streak = 0
+	} else {
+		state.Streak++
+	}
+	state.NextRun = d.schedules[g.Name].next(start)
+	if !state.OK {
+		root.fail(errors.New(summary))
+	}
+	saveErr := d.saveLocked()
+	d.mu.Unlock()
+
+	log := d.checker.logger().With("group", g.Name, "check", g.Check)
+	if state.OK {
+		log.Info("group passed", "summary", summary, "duration", state.Duration)
+	} else {
+		log.Warn("group failed", "summary", summary, "duration", state.Duration, "streak", state.Streak)
+	}
+	if saveErr != nil {
+		log.Error("save daemon state", "err", saveErr)
+	}
+}
+
+// uptime probes each of g's URLs, up to d.workers at a time, and returns
+// what failed. When every URL is on the local machine it probes one at a
+// time instead, doesn't wait out throttling, and skips the production
+// checks: a dev server rebuilds under load, never throttles, and serves
+// plain HTTP without the host's headers, so one config works against both.
+func (d *daemon) uptime(ctx context.Context, g groupConfig) (failures []string, summary string) {
+	var down int
+	for _, p := range d.probeGroup(ctx, g) {
+		if len(p) > 0 {
+			down++
+		}
+		failures = append(failures, p...)
+	}
+	return failures, fmt.Sprintf("%d/%d up", len(g.URLs)-down, len(g.URLs))
+}
+
+// probeGroup probes g's URLs and returns what is wrong with each, in the
+// order of g.URLs.
+func (d *daemon) probeGroup(ctx context.Context, g groupConfig) [][]string {
+	local := !slices.ContainsFunc(g.URLs, func(u string) bool { return !isLocalURL(u) })
+	workers := min(max(d.workers, 1), len(g.URLs))
+	if local {
+		workers = 1
+		d.checker.logger().Debug("uptime group is local; probing gently", "group", g.Name)
+	}
+
+	problems := make([][]string, len(g.URLs))
+	indices := make([]int, len(g.URLs))
+	for i := range indices {
+		indices[i] = i
+	}
+	forEach(ctx, indices, workers, func(ctx context.Context, i int) error {
+		problems[i] = d.probe(ctx, g.URLs[i], g.Headers, local)
+		return nil
+	})
+	return problems
+}
+
+// probe fetches rawURL and lists what is wrong with it. A local URL only
+// has to answer below 400; anything else also waits out throttling within
+// the checker's retry budget and has to pass the production checks.
+func (d *daemon) probe(ctx context.Context, rawURL string, headers []string, local bool) []string {
+	c := d.checker
+	log := c.logger().With("url", rawURL)
+	budget := c.retryBudget
+	if local {
+		budget = 0
+	}
+	start := d.now()
+	r, _, err := fetchWithRetry(ctx, log, c.client, rawURL, validators{}, bodyLimit, budget)
+	elapsed := d.now().Sub(start).Round(time.Millisecond)
+	log.Debug("uptime probe", "status", r.status, "duration", elapsed, "err", err)
+	switch {
+	case err != nil:
+		return []string{fmt.Sprintf("%s: %v", rawURL, err)}
+	case r.status >= 400:
+		return []string{fmt.Sprintf("%s: HTTP %d after %s", rawURL, r.status, elapsed)}
+	case local:
+		return nil
+	}
+
+	var problems []string
+	switch left := r.certExpiry.Sub(d.now()); {
+	case r.certExpiry.IsZero():
+		problems = append(problems, fmt.Sprintf("%s: not served over TLS", rawURL))
+	case left < certMargin:
+		problems = append(problems, fmt.Sprintf("%s: TLS certificate expires %s", rawURL, r.certExpiry.Format(time.DateOnly)))
+	}
+	for _, name := range headers {
+		if r.header.Get(name) == "" {
+			problems = append(problems, fmt.Sprintf("%s: no %s header", rawURL, name))
+		}
+	}
+	return problems
+}
+
+// isLocalURL reports whether rawURL points at this machine: localhost, a
+// .localhost name, or a loopback address.
+func isLocalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// links sweeps content/ like a normal run, with the result cache and the
+// baseline, and returns the failing findings.
+func (d *daemon) links(ctx context.Context) (failures []string, summary string, err error) {
+	c := d.checker
+	links, err := collectLinks(contentDir)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := loadBaseline(d.baselinePath)
+	if err != nil {
+		return nil, "", err
+	}
+	ig, err := collectIgnores(contentDir)
+	if err != nil {
+		return nil, "", err
+	}
+	// Addresses change over a daemon's lifetime; resolve afresh each sweep.
+	c.resolver.reset()
+	c.resolveAll(ctx, hostsOf(stale(links, c.cache)), d.dnsWorkers)
+	findings, _ := b.filter(c.sweep(ctx, links, d.workers))
+	findings, _ = ig.filter(findings)
+	c.cache.prune(uniqueURLs(links))
+	if err := c.cache.save(d.cachePath); err != nil {
+		return nil, "", err
+	}
+	for _, f := range findings {
+		if f.failed() {
+			failures = append(failures, f.String())
+		}
+	}
+	return failures, fmt.Sprintf("%d links, %d failing, %d to review", len(uniqueURLs(links)), len(failures), len(findings)-len(failures)), nil
+}
+
+// handler serves /status, every group's state as JSON, and /healthz, which
+// answers 503 while any group's last run failed.
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(d.snapshot())
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		var failing []string
+		for _, s := range d.snapshot() {
+			if !s.LastRun.IsZero() && !s.OK {
+				failing = append(failing, s.Name)
+			}
+		}
+		if len(failing) > 0 {
+			http.Error(w, "failing: "+strings.Join(failing, ", "), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// snapshot copies the group states in config order.
+func (d *daemon) snapshot() []groupState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]groupState, 0, len(d.groups))
+	for _, g := range d.groups {
+		s := *d.states[g.Name]
+		s.Failures = slices.Clone(s.Failures)
+		out = append(out, s)
+	}
+	return out
+}
+
+// saveLocked writes every group's state; d.mu must be held.
+func (d *daemon) saveLocked() error {
+	raw, err := json.MarshalIndent(d.states, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.statePath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(d.statePath, append(raw, '\n'), 0o644)
+}
+
+// loadDaemonState reads the state file, treating a missing file as empty.
+func loadDaemonState(path string) (map[string]*groupState, error) {
+	states := map[string]*groupState{}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &states); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return states, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemonConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config daemonConfig
+		err    string
+	}{
+		{"no groups", daemonConfig{}, "no groups"},
+		{"unknown check", daemonConfig{Groups: []groupConfig{{Name: "a", Check: "ping", Schedule: "@hourly"}}}, "check must be"},
+		{"uptime without urls", daemonConfig{Groups: []groupConfig{{Name: "a", Check: checkUptime, Schedule: "@hourly"}}}, "no urls"},
+		{"bad schedule", daemonConfig{Groups: []groupConfig{{Name: "a", Check: checkLinks, Schedule: "nightly"}}}, "five cron fields"},
+		{"never runs", daemonConfig{Groups: []groupConfig{{Name: "a", Check: checkLinks, Schedule: "0 0 31 2 *"}}}, "never runs"},
+		{"duplicate", daemonConfig{Groups: []groupConfig{{Name: "a", Check: checkLinks, Schedule: "@daily"}, {Name: "a", Check: checkLinks, Schedule: "@daily"}}}, "twice"},
+	} {
+		if _, err := tc.config.validate(); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: validate = %v, want an error containing %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestDaemonRunsUptimeGroupAndServesStatus(t *testing.T) {
+	down := true
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/feed" && down {
+			http.Error(w, "oops", http.StatusBadGateway)
+		}
+	}))
+	defer site.Close()
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	c := &checker{client: site.Client(), resolver: newDNSCache(netLookup)}
+	dc := daemonConfig{State: statePath, Groups: []groupConfig{
+		{Name: "uptime", Check: checkUptime, Schedule: "@every 5m", URLs: []string{site.URL + "/", site.URL + "/feed"}},
+	}}
+	d, err := newDaemon(c, dc, "", "", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := httptest.NewServer(d.handler())
+	defer status.Close()
+
+	if code := get(t, status.URL+"/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz before any run = %d, want 200", code)
+	}
+
+	d.runGroup(context.Background(), dc.Groups[0])
+	if code := get(t, status.URL+"/healthz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("healthz with a failing group = %d, want 503", code)
+	}
+	resp, err := http.Get(status.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []groupState
+	json.NewDecoder(resp.Body).Decode(&states)
+	resp.Body.Close()
+	if len(states) != 1 || states[0].OK || states[0].Summary != "1/2 up" || states[0].Streak != 1 || !strings.Contains(strings.Join(states[0].Failures, ""), "HTTP 502") {
+		t.Fatalf("status = %+v", states)
+	}
+	if want := states[0].LastRun.Add(5 * time.Minute); !states[0].NextRun.Equal(want) {
+		t.Fatalf("next run = %s, want %s", states[0].NextRun, want)
+	}
+
+	// State survives a restart, and a recovered run clears the streak.
+	restarted, err := newDaemon(c, dc, "", "", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := restarted.snapshot()[0]; s.Streak != 1 || s.LastRun.IsZero() {
+		t.Fatalf("state after restart = %+v", s)
+	}
+	down = false
+	restarted.runGroup(context.Background(), dc.Groups[0])
+	if s := restarted.snapshot()[0]; !s.OK || s.Streak != 0 || len(s.Failures) != 0 {
+		t.Fatalf("state after recovery = %+v", s)
+	}
+}
+
+func TestUptimeRunsProductionChecksOnlyAgainstTheLiveSite(t *testing.T) {
+	site := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer site.Close()
+	// example.com is on the test server's certificate; dial the server for it.
+	transport := site.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return new(net.Dialer).DialContext(ctx, network, site.Listener.Addr().String())
+	}
+	c := &checker{client: &http.Client{Transport: transport}, resolver: newDNSCache(netLookup)}
+	d := &daemon{checker: c, workers: 4, now: time.Now}
+	headers := []string{"X-Content-Type-Options"}
+
+	live := groupConfig{Name: "live", URLs: []string{"https://example.com/", "https://example.com/feed"}, Headers: headers}
+	failures, summary := d.uptime(context.Background(), live)
+	if summary != "0/2 up" || len(failures) != 2 || !strings.Contains(failures[0], "no X-Content-Type-Options header") {
+		t.Fatalf("live uptime = %q, %q", summary, failures)
+	}
+
+	d.now = func() time.Time { return time.Date(2084, 1, 20, 0, 0, 0, 0, time.UTC) }
+	live.Headers = nil
+	failures, _ = d.uptime(context.Background(), live)
+	if len(failures) != 2 || !strings.Contains(failures[0], "TLS certificate expires 2084-") {
+		t.Fatalf("live uptime near certificate expiry = %q", failures)
+	}
+
+	// hugo server: plain HTTP, none of the host's headers.
+	dev := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer dev.Close()
+	c.client = dev.Client()
+	local := groupConfig{Name: "local", URLs: []string{dev.URL + "/", strings.Replace(dev.URL, "127.0.0.1", "localhost", 1) + "/feed"}, Headers: headers}
+	if failures, summary := d.uptime(context.Background(), local); summary != "2/2 up" || len(failures) != 0 {
+		t.Fatalf("local uptime = %q, %q; want production checks skipped", summary, failures)
+	}
+}
+
+func TestIsLocalURL(t *testing.T) {
+	for rawURL, want := range map[string]bool{
+		"http://localhost:1313/":     true,
+		"http://blog.localhost/":     true,
+		"http://127.0.0.1:1313/":     true,
+		"http://[::1]:1313/":         true,
+		"https://rednafi.com/":       false,
+		"http://192.168.1.5:1313/":   false,
+		"https://localhost.example/": false,
+	} {
+		if got := isLocalURL(rawURL); got != want {
+			t.Errorf("isLocalURL(%q) = %t, want %t", rawURL, got, want)
+		}
+	}
+}
+
+func get(t *testing.T, url string) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// smtpPasswordEnv holds the SMTP password, which never goes in
+// linkcheck.yml: the file is committed.
+const smtpPasswordEnv = "LINKCHECK_SMTP_PASSWORD"
+
+// emailConfig is where `report digest -mail` sends the digest.
+type emailConfig struct {
+	To   []string `yaml:"to"`
+	From string   `yaml:"from"`
+	// SMTP is the submission server as host:port. The connection is
+	// upgraded with STARTTLS when the server offers it, and the password
+	// is only ever sent over TLS or to localhost.
+	SMTP     string `yaml:"smtp"`
+	Username string `yaml:"username"`
+}
+
+// sendFunc is smtp.SendMail's signature, so tests can catch the message.
+type sendFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// digest summarizes a series of nightly -format=json reports, oldest
+// first: what broke and what was fixed since the one before last, and how
+// the count of broken links moved across all of them. uptime, when there
+// is a history, adds each URL's uptime over the window.
+func digest(reports [][]reportRow, names []string, uptime *uptimeHistory) (subject, body string) {
+	d := diffReports(reports[len(reports)-2], reports[len(reports)-1])
+	subject = fmt.Sprintf("linkcheck: %d newly broken, %d fixed, %d still broken", len(d.NewlyBroken), len(d.NewlyFixed), len(d.StillBroken))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Since %s:\n", names[len(names)-2])
+	for _, g := range []struct {
+		change string
+		rows   []reportRow
+	}{
+		{"newly broken", d.NewlyBroken},
+		{"fixed", d.NewlyFixed},
+	} {
+		fmt.Fprintf(&b, "\n%d %s\n", len(g.rows), g.change)
+		for _, row := range g.rows {
+			fmt.Fprintf(&b, "  %s:%d:%d: %s: %s\n", row.File, row.Line, row.Column, row.URL, row.Message)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d still broken\n", len(d.StillBroken))
+
+	if len(reports) > 2 {
+		counts := make([]string, len(reports))
+		for i, rows := range reports {
+			broken := 0
+			for _, row := range rows {
+				if row.Severity == severityError {
+					broken++
+				}
+			}
+			counts[i] = fmt.Sprint(broken)
+		}
+		fmt.Fprintf(&b, "\nBroken links over the last %d runs, oldest first: %s\n", len(reports), strings.Join(counts, ", "))
+	}
+
+	if uptime != nil && len(uptime.URLs) > 0 {
+		fmt.Fprintf(&b, "\nUptime over the last %d days:\n", int(uptimeWindow.Hours()/24))
+		for _, u := range uptime.URLs {
+			state := "up now"
+			if !u.LastOK {
+				state = "down now: " + strings.Join(u.LastProblems, "; ")
+			}
+			fmt.Fprintf(&b, "  %s %.2f%% (%s)\n", u.URL, u.Percent, state)
+		}
+	}
+	return subject, b.String()
+}
+
+// mail sends body to the configured addresses through cfg's server.
+func (cfg emailConfig) mail(send sendFunc, subject, body string, now time.Time) error {
+	if len(cfg.To) == 0 || cfg.From == "" || cfg.SMTP == "" {
+		return errors.New("email in linkcheck.yml needs to, from and smtp to send the digest")
+	}
+	host, _, err := net.SplitHostPort(cfg.SMTP)
+	if err != nil {
+		return fmt.Errorf("email: smtp %q: %w", cfg.SMTP, err)
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		password := os.Getenv(smtpPasswordEnv)
+		if password == "" {
+			return fmt.Errorf("email: set $%s for %s", smtpPasswordEnv, cfg.Username)
+		}
+		auth = smtp.PlainAuth("", cfg.Username, password, host)
+	}
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return send(cfg.SMTP, auth, cfg.From, cfg.To, []byte(msg.String()))
+}
+
+// runReportDigest implements `linkcheck report digest REPORT...`: it
+// prints the digest of the saved reports and the uptime history and, with
+// mail, sends it too.
+func runReportDigest(w io.Writer, args []string, uptimePath string, cfg emailConfig, mail bool) error {
+	if len(args) < 2 {
+		return errors.New("usage: linkcheck [-mail] report digest OLDEST.json ... NEWEST.json")
+	}
+	reports := make([][]reportRow, len(args))
+	for i, path := range args {
+		rows, err := loadReport(path)
+		if err != nil {
+			return err
+		}
+		reports[i] = rows
+	}
+	uptime, err := loadUptimeHistory(uptimePath)
+	if err != nil {
+		return err
+	}
+	subject, body := digest(reports, args, uptime)
+	fmt.Fprintf(w, "%s\n\n%s", subject, body)
+	if !mail {
+		return nil
+	}
+	if err := cfg.mail(smtp.SendMail, subject, body, time.Now()); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nmailed to %s\n", strings.Join(cfg.To, ", "))
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigest(t *testing.T) {
+	row := func(url, rule string) reportRow {
+		return reportRow{URL: url, File: "content/go/a.md", Line: 3, Column: 5, Rule: rule, Severity: severityOf(rule), Message: rule}
+	}
+	reports := [][]reportRow{
+		{row("https://still.example/", ruleHTTP)},
+		{row("https://still.example/", ruleHTTP), row("https://fixed.example/", ruleHTTP)},
+		{row("https://still.example/", ruleHTTP), row("https://fixed.example/", ruleOK), row("https://new.example/", ruleHTTP)},
+	}
+	uptime := &uptimeHistory{URLs: []urlUptime{
+		{URL: "https://rednafi.com/", Percent: 100, LastOK: true},
+		{URL: "https://rednafi.com/index.xml", Percent: 99.5, LastProblems: []string{"HTTP 503"}},
+	}}
+	subject, body := digest(reports, []string{"mon.json", "tue.json", "wed.json"}, uptime)
+
+	if want := "linkcheck: 1 newly broken, 1 fixed, 1 still broken"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	for _, want := range []string{
+		"Since tue.json:",
+		"1 newly broken\n  content/go/a.md:3:5: https://new.example/: http\n",
+		"1 fixed\n  content/go/a.md:3:5: https://fixed.example/",
+		"Broken links over the last 3 runs, oldest first: 1, 2, 2\n",
+		"  https://rednafi.com/ 100.00% (up now)\n",
+		"  https://rednafi.com/index.xml 99.50% (down now: HTTP 503)\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("digest lacks %q:\n%s", want, body)
+		}
+	}
+
+	_, body = digest(reports[1:], []string{"tue.json", "wed.json"}, nil)
+	if strings.Contains(body, "over the last") || strings.Contains(body, "Uptime") {
+		t.Errorf("two reports and no history should leave out the trend and uptime:\n%s", body)
+	}
+}
+
+func TestMailDigest(t *testing.T) {
+	var addr, from string
+	var to []string
+	var msg []byte
+	var auth smtp.Auth
+	send := func(a string, au smtp.Auth, f string, t []string, m []byte) error {
+		addr, auth, from, to, msg = a, au, f, t, m
+		return nil
+	}
+	cfg := emailConfig{To: []string{"me@example.com"}, From: "linkcheck@example.com", SMTP: "smtp.example.com:587", Username: "linkcheck"}
+	now := time.Date(2026, 10, 15, 3, 30, 0, 0, time.UTC)
+
+	t.Setenv(smtpPasswordEnv, "")
+	if err := cfg.mail(send, "subject", "body\n", now); err == nil || !strings.Contains(err.Error(), smtpPasswordEnv) {
+		t.Fatalf("mail without a password = %v, want an error naming $%s", err, smtpPasswordEnv)
+	}
+
+	t.Setenv(smtpPasswordEnv, "secret")
+	if err := cfg.mail(send, "linkcheck: 1 newly broken", "line one\nline two\n", now); err != nil {
+		t.Fatal(err)
+	}
+	if addr != cfg.SMTP || from != cfg.From || strings.Join(to, ",") != "me@example.com" || auth == nil {
+		t.Errorf("sent to %s from %s for %v with auth %v", addr, from, to, auth)
+	}
+	want := "From: linkcheck@example.com\r\n" +
+		"To: me@example.com\r\n" +
+		"Subject: linkcheck: 1 newly broken\r\n" +
+		"Date: Thu, 15 Oct 2026 03:30:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" +
+		"line one\r\nline two\r\n"
+	if string(msg) != want {
+		t.Errorf("message =\n%q\nwant\n%q", msg, want)
+	}
+
+	if err := (emailConfig{To: []string{"me@example.com"}}).mail(send, "s", "b", now); err == nil {
+		t.Error("mail without from and smtp succeeded")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const dnsTimeout = 5 * time.Second
+
+// lookupFunc resolves a hostname to its addresses.
+type lookupFunc func(ctx context.Context, host string) ([]string, error)
+
+func netLookup(ctx context.Context, host string) ([]string, error) {
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+type dnsResult struct {
+	addrs []string
+	err   error
+}
+
+// dnsCache resolves each hostname once and shares the answer between the
+// up-front resolution pass and every connection the sweep dials.
+type dnsCache struct {
+	lookup lookupFunc
+	dialer net.Dialer
+
+	mu      sync.Mutex
+	results map[string]dnsResult
+}
+
+func newDNSCache(lookup lookupFunc) *dnsCache {
+	return &dnsCache{lookup: lookup, results: map[string]dnsResult{}}
+}
+
+// resolveAll looks up hosts with a pool of workers and caches the results.
+func (c *dnsCache) resolveAll(ctx context.Context, hosts []string, workers int) {
+	forEach(ctx, hosts, workers, func(ctx context.Context, host string) error {
+		c.resolve(ctx, host)
+		return nil
+	})
+}
+
+// resolve returns the cached result for host, looking it up on a miss.
+// Redirects can lead to hosts the up-front pass never saw.
+func (c *dnsCache) resolve(ctx context.Context, host string) dnsResult {
+	c.mu.Lock()
+	r, ok := c.results[host]
+	c.mu.Unlock()
+	if ok {
+		return r
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+	addrs, err := c.lookup(lookupCtx, host)
+	r = dnsResult{addrs: addrs, err: err}
+
+	c.mu.Lock()
+	c.results[host] = r
+	c.mu.Unlock()
+	return r
+}
+
+// reset forgets every cached answer, so the next lookups go to DNS again.
+func (c *dnsCache) reset() {
+	c.mu.Lock()
+	c.results = map[string]dnsResult{}
+	c.mu.Unlock()
+}
+
+// notFound reports whether host was resolved and definitively doesn't exist,
+// as opposed to a lookup that timed out or hit a flaky resolver.
+func (c *dnsCache) notFound(host string) bool {
+	c.mu.Lock()
+	r, ok := c.results[host]
+	c.mu.Unlock()
+	var dnsErr *net.DNSError
+	return ok && errors.As(r.err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// dialContext dials addr through the cache instead of resolving per
+// connection, trying each cached address in turn.
+func (c *dnsCache) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, addr)
+	}
+
+	r := c.resolve(ctx, host)
+	if r.err != nil {
+		return nil, r.err
+	}
+	var lastErr error
+	for _, ip := range r.addrs {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, lastErr
+}
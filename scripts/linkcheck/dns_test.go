@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestResolveAllLooksUpEachHostOnce(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	cache := newDNSCache(func(ctx context.Context, host string) ([]string, error) {
+		mu.Lock()
+		calls[host]++
+		mu.Unlock()
+		if host == "gone.example" {
+			return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		}
+		if host == "flaky.example" {
+			return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+		}
+		return []string{"127.0.0.1"}, nil
+	})
+
+	cache.resolveAll(context.Background(), []string{"live.example", "gone.example", "flaky.example"}, 4)
+	cache.resolve(context.Background(), "live.example")
+
+	for host, n := range calls {
+		if n != 1 {
+			t.Errorf("%s looked up %d times, want 1", host, n)
+		}
+	}
+	if !cache.notFound("gone.example") {
+		t.Error("gone.example should be NXDOMAIN")
+	}
+	if cache.notFound("flaky.example") {
+		t.Error("a timed-out lookup is not NXDOMAIN")
+	}
+	if cache.notFound("live.example") {
+		t.Error("live.example resolved")
+	}
+}
+
+func TestDialContextUsesCachedAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	cache := newDNSCache(func(ctx context.Context, host string) ([]string, error) {
+		return nil, errors.New("unexpected lookup")
+	})
+	cache.results["cached.example"] = dnsResult{addrs: []string{"127.0.0.1"}}
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	conn, err := cache.dialContext(context.Background(), "tcp", net.JoinHostPort("cached.example", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
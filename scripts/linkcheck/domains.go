@@ -0,0 +1,239 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// latencyLog collects how long every URL a sweep checked, or took from the
+// cache, took to answer. A nil latencyLog records nothing.
+type latencyLog struct {
+	mu    sync.Mutex
+	byURL map[string]time.Duration
+}
+
+func newLatencyLog() *latencyLog {
+	return &latencyLog{byURL: map[string]time.Duration{}}
+}
+
+func (l *latencyLog) record(rawURL string, d time.Duration) {
+	if l == nil || d == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byURL[rawURL] = d
+}
+
+func (l *latencyLog) get(rawURL string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.byURL[rawURL]
+}
+
+// degradingLatency is how much slower a domain has to get, from the oldest
+// report to the newest, to count as degrading.
+const degradingLatency = 1.5
+
+// domainPoint is a domain's health in one report. FailureRate is the
+// share of its distinct URLs that failed, and AvgLatencyMS the mean
+// latency of those measured.
+type domainPoint struct {
+	Report       string  `json:"report"`
+	Links        int     `json:"links"`
+	URLs         int     `json:"urls"`
+	Failed       int     `json:"failed"`
+	FailureRate  float64 `json:"failureRate"`
+	AvgLatencyMS int64   `json:"avgLatencyMs,omitempty"`
+}
+
+// domainHealth is a registrable domain's health in the newest report, with
+// its history across all of them, oldest first. A domain is degrading when
+// more of its URLs fail than in the oldest report it appears in, or it
+// answers degradingLatency times as slowly.
+type domainHealth struct {
+	Domain string `json:"domain"`
+	domainPoint
+	Degrading bool          `json:"degrading"`
+	History   []domainPoint `json:"history"`
+}
+
+// domainHealths rolls reports, oldest first, up by registrable domain, the
+// most cited domains first. Domains the newest report no longer cites are
+// left out.
+func domainHealths(reports [][]reportRow, names []string) []domainHealth {
+	histories := map[string][]domainPoint{}
+	for i, rows := range reports {
+		// failed and latencies are keyed by URL: a URL cited twice counts
+		// once towards the failure rate.
+		type tally struct {
+			links     int
+			failed    map[string]bool
+			latencies map[string]int64
+		}
+		tallies := map[string]*tally{}
+		for _, row := range rows {
+			u, err := url.Parse(row.URL)
+			if err != nil || u.Hostname() == "" {
+				continue
+			}
+			domain := registrableDomain(u.Hostname())
+			t := tallies[domain]
+			if t == nil {
+				t = &tally{failed: map[string]bool{}, latencies: map[string]int64{}}
+				tallies[domain] = t
+			}
+			t.links++
+			t.failed[row.URL] = t.failed[row.URL] || row.Severity == severityError
+			if row.LatencyMS > 0 {
+				t.latencies[row.URL] = row.LatencyMS
+			}
+		}
+		for domain, t := range tallies {
+			p := domainPoint{Report: names[i], Links: t.links, URLs: len(t.failed)}
+			for _, failed := range t.failed {
+				if failed {
+					p.Failed++
+				}
+			}
+			p.FailureRate = float64(p.Failed) / float64(p.URLs)
+			var total int64
+			for _, ms := range t.latencies {
+				total += ms
+			}
+			if len(t.latencies) > 0 {
+				p.AvgLatencyMS = total / int64(len(t.latencies))
+			}
+			histories[domain] = append(histories[domain], p)
+		}
+	}
+
+	newest := names[len(names)-1]
+	var healths []domainHealth
+	for domain, history := range histories {
+		last := history[len(history)-1]
+		if last.Report != newest {
+			continue
+		}
+		first := history[0]
+		h := domainHealth{Domain: domain, domainPoint: last, History: history}
+		h.Degrading = len(history) > 1 && (last.FailureRate > first.FailureRate ||
+			first.AvgLatencyMS > 0 && float64(last.AvgLatencyMS) >= degradingLatency*float64(first.AvgLatencyMS))
+		healths = append(healths, h)
+	}
+	slices.SortFunc(healths, func(a, b domainHealth) int {
+		return cmp.Or(cmp.Compare(b.Links, a.Links), strings.Compare(a.Domain, b.Domain))
+	})
+	return healths
+}
+
+// runReportDomains implements `linkcheck report domains REPORT...`: it
+// rolls the saved reports, oldest first, up by domain and writes the
+// result as text, json or a standalone html page.
+func runReportDomains(w io.Writer, format string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: linkcheck [-format text|json|html] report domains OLDEST.json ... NEWEST.json")
+	}
+	reports := make([][]reportRow, len(args))
+	for i, path := range args {
+		rows, err := loadReport(path)
+		if err != nil {
+			return err
+		}
+		reports[i] = rows
+	}
+	healths := domainHealths(reports, args)
+	switch format {
+	case "text":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DOMAIN\tLINKS\tURLS\tFAILING\tLATENCY\tTREND")
+		for _, h := range healths {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d (%s)\t%s\t%s\n", h.Domain, h.Links, h.URLs, h.Failed, percent(h.FailureRate), latencyText(h.AvgLatencyMS), trend(h))
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Reports []string       `json:"reports"`
+			Domains []domainHealth `json:"domains"`
+		}{args, healths})
+	case "html":
+		return domainsPage.Execute(w, struct {
+			Reports []string
+			Domains []domainHealth
+		}{args, healths})
+	}
+	return fmt.Errorf("unknown domains format %q; want text, json or html", format)
+}
+
+func percent(rate float64) string {
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
+
+func latencyText(ms int64) string {
+	if ms == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// trend is how h's failure rate and latency moved across its history, or
+// "" when there's one report.
+func trend(h domainHealth) string {
+	if len(h.History) < 2 {
+		return ""
+	}
+	var rates, latencies []string
+	for _, p := range h.History {
+		rates = append(rates, percent(p.FailureRate))
+		latencies = append(latencies, latencyText(p.AvgLatencyMS))
+	}
+	s := "failing " + strings.Join(rates, " → ") + ", latency " + strings.Join(latencies, " → ")
+	if h.Degrading {
+		s = "degrading: " + s
+	}
+	return s
+}
+
+var domainsPage = template.Must(template.New("domains").Funcs(template.FuncMap{
+	"percent": percent,
+	"latency": latencyText,
+	"trend":   trend,
+	"last":    func(s []string) string { return s[len(s)-1] },
+}).Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Link health by domain</title>
+<style>
+body { font: 14px/1.4 system-ui, sans-serif; margin: 2rem; }
+table { border-collapse: collapse; }
+th, td { padding: .3rem .8rem; text-align: left; border-bottom: 1px solid #ddd; }
+td.n { text-align: right; font-variant-numeric: tabular-nums; }
+tr.degrading { background: #fff3cd; }
+</style>
+</head>
+<body>
+<h1>Link health by domain</h1>
+<p>{{len .Domains}} domains cited in {{last .Reports}}{{if gt (len .Reports) 1}}, compared across {{len .Reports}} reports{{end}}. Degrading domains are highlighted.</p>
+<table>
+<tr><th>Domain</th><th>Links</th><th>URLs</th><th>Failing</th><th>Latency</th><th>Trend</th></tr>
+{{range .Domains}}<tr{{if .Degrading}} class="degrading"{{end}}><td>{{.Domain}}</td><td class="n">{{.Links}}</td><td class="n">{{.URLs}}</td><td class="n">{{.Failed}} ({{percent .FailureRate}})</td><td class="n">{{latency .AvgLatencyMS}}</td><td>{{trend .}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rednafi.com/internal/webtest"
+)
+
+func TestDomainHealthsRollUpAndSpotDegrading(t *testing.T) {
+	ok := func(rawURL string, ms int64) reportRow {
+		return reportRow{URL: rawURL, File: "a.md", Rule: ruleOK, LatencyMS: ms}
+	}
+	broken := func(rawURL string) reportRow {
+		return reportRow{URL: rawURL, File: "b.md", Rule: ruleHTTP, Severity: severityError}
+	}
+	old := []reportRow{
+		ok("https://docs.python.org/3/a", 100),
+		ok("https://docs.python.org/3/b", 100),
+		ok("https://pkg.go.dev/fmt", 200),
+		ok("https://gone.example/", 50),
+	}
+	current := []reportRow{
+		ok("https://docs.python.org/3/a", 100),
+		broken("https://www.python.org/b"),
+		broken("https://www.python.org/b"),
+		ok("https://pkg.go.dev/fmt", 300),
+		ok("https://go.dev/blog/", 300),
+		ok("https://new.example/", 10),
+	}
+	healths := domainHealths([][]reportRow{old, current}, []string{"old.json", "new.json"})
+
+	byDomain := map[string]domainHealth{}
+	var order []string
+	for _, h := range healths {
+		byDomain[h.Domain] = h
+		order = append(order, h.Domain)
+	}
+	if want := "python.org go.dev new.example"; strings.Join(order, " ") != want {
+		t.Fatalf("domains = %q, want %s, most cited first and gone.example left out", order, want)
+	}
+	python := byDomain["python.org"]
+	if python.Links != 3 || python.URLs != 2 || python.Failed != 1 || python.FailureRate != 0.5 || python.AvgLatencyMS != 100 || !python.Degrading {
+		t.Errorf("python.org = %+v, want 3 links to 2 URLs, one failing, and degrading", python)
+	}
+	if gd := byDomain["go.dev"]; gd.AvgLatencyMS != 300 || !gd.Degrading || len(gd.History) != 2 {
+		t.Errorf("go.dev = %+v, want 300ms, up from 200ms, so degrading", gd)
+	}
+	if fresh := byDomain["new.example"]; fresh.Degrading || len(fresh.History) != 1 {
+		t.Errorf("new.example = %+v, want one report of history and not degrading", fresh)
+	}
+	if got, want := trend(python), "degrading: failing 0% → 50%, latency 100ms → 100ms"; got != want {
+		t.Errorf("trend = %q, want %q", got, want)
+	}
+}
+
+func TestRunReportDomainsWritesEachFormat(t *testing.T) {
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.json")
+	raw, err := json.Marshal([]reportRow{{URL: "https://a.example/<b>", File: "a.md", Rule: ruleHTTP, Severity: severityError}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(report, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for format, want := range map[string]string{
+		"text": "a.example  1      1     1 (100%)  -",
+		"json": `"failureRate": 1`,
+		"html": "<td>a.example</td>",
+	} {
+		var out strings.Builder
+		if err := runReportDomains(&out, format, []string{report}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%s:\n%s\nwant it to contain %q", format, out.String(), want)
+		}
+	}
+	if err := runReportDomains(&strings.Builder{}, "csv", []string{report}); err == nil {
+		t.Error("runReportDomains accepted csv")
+	}
+}
+
+func TestLatencyReachesReportsFromTheCache(t *testing.T) {
+	site := webtest.Slow(t, 20*time.Millisecond)
+	links := []link{{URL: site.URL + "/slow", File: "a.md", Line: 1}}
+	cache := &resultCache{okTTL: time.Hour, failedTTL: time.Hour, now: time.Now, entries: map[string]cacheEntry{}}
+	c := &checker{client: site.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, cache: cache, latencies: newLatencyLog()}
+	c.sweep(context.Background(), links, 1)
+	if rows := reportRows(links, nil, baseline{}, nil, c.latencies); rows[0].LatencyMS < 20 {
+		t.Fatalf("latency = %dms, want at least the server's 20ms delay", rows[0].LatencyMS)
+	}
+
+	// The next run takes the result from the cache, latency included.
+	again := &checker{client: site.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, cache: cache, latencies: newLatencyLog()}
+	again.sweep(context.Background(), links, 1)
+	if rows := reportRows(links, nil, baseline{}, nil, again.latencies); rows[0].LatencyMS < 20 {
+		t.Fatalf("cached latency = %dms, want the first run's", rows[0].LatencyMS)
+	}
+	if site.Hits("/slow") != 1 {
+		t.Fatalf("hits = %d, want the second run served from the cache", site.Hits("/slow"))
+	}
+}
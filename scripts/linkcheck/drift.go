@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// registrableDomain returns the part of host a single owner registers,
+// one label past its public suffix: go.dev for pkg.go.dev, bbc.co.uk for
+// www.bbc.co.uk, and alice.github.io for alice.github.io, as the list
+// counts hosting domains like github.io as suffixes. A host that is a
+// suffix itself, or an IP address, is returned as it is.
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// drifted reports whether finalURL, where rawURL's redirects ended, sits
+// on a different registrable domain: a sign the site was sold, merged or
+// squatted, and the content may not be what the post meant to cite.
+func drifted(rawURL, finalURL string) bool {
+	from, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	to, err := url.Parse(finalURL)
+	if err != nil || to.Host == "" {
+		return false
+	}
+	return registrableDomain(from.Hostname()) != registrableDomain(to.Hostname())
+}
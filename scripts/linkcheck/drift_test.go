@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	for host, want := range map[string]string{
+		"go.dev":               "go.dev",
+		"pkg.go.dev":           "go.dev",
+		"www.bbc.co.uk":        "bbc.co.uk",
+		"alice.github.io":      "alice.github.io",
+		"docs.alice.github.io": "alice.github.io",
+		"github.io":            "github.io",
+		"www.news24.co.za":     "news24.co.za",
+		"blog.example.com.mx":  "example.com.mx",
+		"shop.example.com.tr":  "example.com.tr",
+		"Example.COM.":         "example.com",
+		"127.0.0.1":            "127.0.0.1",
+		"localhost":            "localhost",
+	} {
+		if got := registrableDomain(host); got != want {
+			t.Errorf("registrableDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestSweepReportsCrossDomainRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, port, _ := net.SplitHostPort(r.Host)
+		switch r.Host {
+		case "old.example:" + port:
+			http.Redirect(w, r, "http://buyer.example:"+port+"/landing", http.StatusMovedPermanently)
+		case "blog.old.example:" + port:
+			http.Redirect(w, r, "http://www.old.example:"+port+"/post", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver := newDNSCache(func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	})
+	client := &http.Client{Transport: &http.Transport{DialContext: resolver.dialContext}}
+	c := &checker{client: client, resolver: resolver, robots: newRobotsCache(client)}
+	links := []link{
+		{URL: "http://old.example:" + port + "/post", File: "a.md", Line: 1},
+		{URL: "http://blog.old.example:" + port + "/post", File: "a.md", Line: 2},
+	}
+	got := c.sweep(context.Background(), links, 2)
+	if len(got) != 1 || got[0].Link != links[0] || got[0].Rule != ruleDrift || got[0].failed() {
+		t.Fatalf("sweep = %v; want only the cross-domain redirect, reported as drift", got)
+	}
+	if want := "redirects to http://buyer.example:" + port + "/landing"; got[0].Message != want {
+		t.Errorf("reason = %q, want %q", got[0].Message, want)
+	}
+}
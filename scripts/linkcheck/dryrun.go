@@ -0,0 +1,231 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// plan is what -dry-run prints instead of running: every request the run
+// would send, every file it would write and every outside service it would
+// talk to. Work that depends on answers a dry run never gets, like the
+// Wayback lookups for links that turn out dead, is described in notes
+// rather than listed.
+type plan struct {
+	requests []string
+	writes   []string
+	services []string
+	notes    []string
+}
+
+func (p *plan) request(format string, args ...any) {
+	p.requests = append(p.requests, fmt.Sprintf(format, args...))
+}
+
+func (p *plan) write(file string) {
+	if !slices.Contains(p.writes, file) {
+		p.writes = append(p.writes, file)
+	}
+}
+
+func (p *plan) service(format string, args ...any) {
+	p.services = append(p.services, fmt.Sprintf(format, args...))
+}
+
+func (p *plan) note(format string, args ...any) {
+	p.notes = append(p.notes, fmt.Sprintf(format, args...))
+}
+
+// fetches plans the requests checking rawURLs takes: one robots.txt per
+// origin, unless robots are ignored or the domain is forced, then a GET per
+// URL, with "GET" replaced by verb. Skip-listed URLs take none.
+func (p *plan) fetches(c *checker, rawURLs []string, verb string) {
+	seen := map[string]bool{}
+	skipped := 0
+	for _, rawURL := range rawURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		forced := matchesDomain(u.Hostname(), c.config.Force)
+		if !forced && matchesDomain(u.Hostname(), c.config.Skip) {
+			skipped++
+			continue
+		}
+		if origin := u.Scheme + "://" + u.Host; !forced && !c.ignoreRobots && !seen[origin] {
+			seen[origin] = true
+			p.request("GET %s/robots.txt", origin)
+		}
+		p.request("%s %s", verb, rawURL)
+	}
+	if hosts := hostsFor(rawURLs); len(hosts) > 0 {
+		p.service("DNS: resolve %d %s", len(hosts), plural(len(hosts), "host"))
+	}
+	if skipped > 0 {
+		p.note("%d %s on the skip list in linkcheck.yml would be reported without a request", skipped, plural(skipped, "URL"))
+	}
+}
+
+// sweep plans a sweep of links, after sharding and -max-duration ordering.
+// Links with a fresh cached result cost nothing.
+func (p *plan) sweep(c *checker, links []link, cachePath string, wayback, waybackFix bool) {
+	pending := uniqueURLs(stale(links, c.cache))
+	p.fetches(c, pending, "GET")
+	p.note("throttled answers are retried within -retry-budget, so a URL can take more than one request")
+	if cached := len(uniqueURLs(links)) - len(pending); cached > 0 {
+		p.note("%d %s with a fresh cached result would need no request", cached, plural(cached, "URL"))
+	}
+	if c.code != nil {
+		branches := 0
+		for _, rawURL := range pending {
+			u, err := url.Parse(rawURL)
+			if err != nil {
+				continue
+			}
+			if ref, ok := parseCodeRef(u); ok {
+				p.request("GET %s, the code %s cites", c.code.rawURL(ref.repo, ref.ref, ref.path), rawURL)
+				if !ref.pinned() && c.code.published[rawURL] != "" {
+					branches++
+				}
+			}
+		}
+		if branches > 0 {
+			p.service("GitHub commits API (%s), once per link to lines on a branch, then a GET of the file as of the citing post", c.code.api)
+			if c.code.token == "" {
+				p.note("without $%s, GitHub allows 60 history lookups an hour", githubTokenEnv)
+			}
+		}
+	}
+	if !c.stopAt.IsZero() {
+		p.note("-max-duration stops sending requests when the budget runs out, in the order listed")
+	}
+	if c.cache != nil {
+		p.write(cachePath)
+	}
+	if c.artifacts != nil {
+		p.write(filepath.Join(c.artifacts.dir, "*.http"))
+		p.note("a response is saved only for a link that fails a body check")
+	}
+	if wayback || waybackFix {
+		p.service("Wayback Machine availability API (%s), once per dead link", waybackAPI)
+	}
+	if waybackFix {
+		p.note("posts citing a dead link with a snapshot would be rewritten; which ones depends on the sweep")
+	}
+}
+
+// fix plans -fix: tracking parameters are stripped without a request, so
+// those files are known; the https and redirect rewrites depend on the
+// answers.
+func (p *plan) fix(c *checker, links []link) {
+	p.fetches(c, uniqueURLs(links), "GET, one redirect hop at a time,")
+	for _, rawURL := range uniqueURLs(links) {
+		if secure, ok := strings.CutPrefix(rawURL, "http://"); ok {
+			p.request("GET %s and https://%s, to compare them", rawURL, secure)
+		}
+	}
+	stripped := trackingRewrites(links, c.tracking())
+	candidates := map[string]bool{}
+	for _, l := range links {
+		if _, ok := stripped[l.URL]; ok {
+			p.write(l.File)
+		}
+		candidates[l.File] = true
+	}
+	if n := len(candidates) - len(p.writes); n > 0 {
+		p.note("up to %d more %s could be rewritten, depending on which links upgrade to https or redirect permanently", n, plural(n, "post"))
+	}
+}
+
+// uptime plans -uptime: one GET per URL of each uptime group.
+func (p *plan) uptime(groups []groupConfig, historyPath string) {
+	for _, g := range groups {
+		if g.Check != checkUptime {
+			continue
+		}
+		for _, u := range g.URLs {
+			p.request("GET %s (uptime group %s)", u, g.Name)
+		}
+	}
+	p.write(historyPath)
+}
+
+// daemon plans -daemon, which runs until stopped: what it would serve and
+// which groups it would run how often.
+func (p *plan) daemon(cfg daemonConfig, listen, cachePath string) {
+	p.write(cmp.Or(cfg.State, defaultDaemonState))
+	p.service("HTTP server on %s answering /status and /healthz", listen)
+	for _, g := range cfg.Groups {
+		target := fmt.Sprintf("%d %s", len(g.URLs), plural(len(g.URLs), "URL"))
+		if g.Check == checkLinks {
+			target = "every external link in content/"
+		}
+		p.note("group %s (%s) runs %q against %s", g.Name, g.Check, g.Schedule, target)
+	}
+	p.write(cachePath)
+}
+
+// snapshots plans -snapshots: a GET per due snapshot to verify it, and
+// for those that are dead or missing, lookups and captures that depend on
+// the answers.
+func (p *plan) snapshots(m snapshotMap, due []string, mapPath string) {
+	missing := 0
+	for _, rawURL := range due {
+		if snap := m[rawURL].Snapshot; snap != "" {
+			p.request("GET %s", snap)
+			continue
+		}
+		missing++
+	}
+	if len(due) > 0 {
+		p.service("Wayback Machine availability API (%s), once per dead or missing snapshot", waybackAPI)
+		p.service("Save Page Now (%s), for links the archive has no capture of", waybackSave)
+	}
+	if missing > 0 {
+		p.note("%d %s without a snapshot would be looked up, then archived if the archive has none", missing, plural(missing, "link"))
+	}
+	if n := len(m) - len(due); n > 0 {
+		p.note("%d %s verified recently or past -snapshot-batch would wait for a later run", n, plural(n, "link"))
+	}
+	p.note("posts citing a snapshot that turns out dead would be rewritten to its replacement")
+	p.write(mapPath)
+}
+
+// print writes the plan to w, one section per kind of work.
+func (p *plan) print(w io.Writer) error {
+	var b strings.Builder
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"requests", p.requests},
+		{"files written", p.writes},
+		{"outside services", p.services},
+		{"notes", p.notes},
+	} {
+		if len(section.lines) == 0 {
+			if section.title != "notes" {
+				fmt.Fprintf(&b, "%s: none\n", section.title)
+			}
+			continue
+		}
+		fmt.Fprintf(&b, "%s (%d):\n", section.title, len(section.lines))
+		for _, line := range section.lines {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func hostsFor(rawURLs []string) []string {
+	links := make([]link, len(rawURLs))
+	for i, u := range rawURLs {
+		links[i] = link{URL: u}
+	}
+	return hostsOf(links)
+}
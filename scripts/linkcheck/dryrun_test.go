@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanSweepListsOnlyTheRequestsItWouldSend(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	cache, err := loadCache(cachePath, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.store("https://fresh.example/", "", "", validators{}, nil, 0)
+	c := &checker{config: config{Skip: []string{"skipped.example"}, Force: []string{"forced.example"}}, cache: cache}
+	links := []link{
+		{URL: "https://a.example/one", File: "a.md"},
+		{URL: "https://a.example/two", File: "a.md"},
+		{URL: "https://a.example/one", File: "b.md"},
+		{URL: "https://fresh.example/", File: "a.md"},
+		{URL: "https://skipped.example/", File: "a.md"},
+		{URL: "https://forced.example/", File: "b.md"},
+	}
+
+	p := &plan{}
+	p.sweep(c, links, cachePath, true, false)
+	var out strings.Builder
+	if err := p.print(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `requests (4):
+  GET https://a.example/robots.txt
+  GET https://a.example/one
+  GET https://a.example/two
+  GET https://forced.example/
+files written (1):
+  ` + cachePath + `
+outside services (2):
+  DNS: resolve 3 hosts
+  Wayback Machine availability API (` + waybackAPI + `), once per dead link
+notes (3):
+  1 URL on the skip list in linkcheck.yml would be reported without a request
+  throttled answers are retried within -retry-budget, so a URL can take more than one request
+  1 URL with a fresh cached result would need no request
+`
+	if out.String() != want {
+		t.Fatalf("plan =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPlanFixNamesTheFilesItKnowsItWillRewrite(t *testing.T) {
+	c := &checker{ignoreRobots: true}
+	links := []link{
+		{URL: "http://a.example/", File: "a.md"},
+		{URL: "https://b.example/?utm_source=feed", File: "b.md"},
+		{URL: "https://c.example/", File: "c.md"},
+	}
+	p := &plan{}
+	p.fix(c, links)
+	if got := strings.Join(p.writes, " "); got != "b.md" {
+		t.Errorf("writes = %q, want b.md", got)
+	}
+	if !strings.Contains(strings.Join(p.requests, "\n"), "GET http://a.example/ and https://a.example/, to compare them") {
+		t.Errorf("requests lack the https probe:\n%s", strings.Join(p.requests, "\n"))
+	}
+	if len(p.notes) != 1 || !strings.Contains(p.notes[0], "up to 2 more posts") {
+		t.Errorf("notes = %q", p.notes)
+	}
+}
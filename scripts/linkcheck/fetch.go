@@ -0,0 +1,173 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validators are the cache validators a server sent for a URL, replayed as
+// If-None-Match and If-Modified-Since on the next check.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// fetchResult is the final response to a check after redirects.
+type fetchResult struct {
+	status     int
+	retryAfter string
+	validators validators
+	// finalURL is where redirects, if any, ended up.
+	finalURL string
+	// body is the start of an HTML response's body; html reports whether
+	// the response was HTML at all.
+	body []byte
+	html bool
+	// header is the final response's header.
+	header http.Header
+	// certExpiry is when the final response's TLS certificate expires, or
+	// zero when it didn't come over TLS.
+	certExpiry time.Time
+	// elapsed is how long the final request took, body included.
+	elapsed time.Duration
+}
+
+// throttle is one 429 or 503 answer that carried a Retry-After.
+type throttle struct {
+	At     time.Time     `json:"at"`
+	Status int           `json:"status"`
+	Wait   time.Duration `json:"wait"`
+}
+
+// bodyLimit caps how much of a page the soft-404 heuristics read: enough for
+// the title, the first heading, and a parking page's boilerplate.
+const bodyLimit = 64 << 10
+
+// fetch GETs rawURL, conditionally when prev holds validators, so an
+// unchanged page answers 304 without a body. A 304 counts as alive. Up to
+// limit bytes of an HTML body are kept for the body checks; the rest is
+// never downloaded.
+func fetch(ctx context.Context, client *http.Client, rawURL string, prev validators, limit int64) (fetchResult, error) {
+	req, err := newRequest(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return fetchResult{}, err
+	}
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fetchResult{}, err
+	}
+	defer resp.Body.Close()
+
+	r := fetchResult{status: resp.StatusCode, retryAfter: resp.Header.Get("Retry-After"), finalURL: resp.Request.URL.String(), header: resp.Header}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		r.certExpiry = resp.TLS.PeerCertificates[0].NotAfter
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/html" {
+		r.body, err = io.ReadAll(io.LimitReader(resp.Body, limit))
+		if err != nil {
+			return fetchResult{}, err
+		}
+		r.html = true
+	}
+	r.elapsed = time.Since(start)
+
+	r.validators = validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if resp.StatusCode == http.StatusNotModified {
+		// A 304 may omit the validators; the old ones still hold.
+		r.validators.ETag = cmp.Or(r.validators.ETag, prev.ETag)
+		r.validators.LastModified = cmp.Or(r.validators.LastModified, prev.LastModified)
+	}
+	return r, nil
+}
+
+// fetchWithRetry fetches rawURL and, while the server answers 429 or 503
+// with a Retry-After the remaining budget covers, waits as asked and tries
+// again. It returns the last result along with every throttle it hit. Each
+// attempt is logged to log at debug level, each throttle at info.
+func fetchWithRetry(ctx context.Context, log *slog.Logger, client *http.Client, rawURL string, prev validators, limit int64, budget time.Duration) (fetchResult, []throttle, error) {
+	var throttles []throttle
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		r, err := fetch(ctx, client, rawURL, prev, limit)
+		duration := time.Since(start)
+		if err != nil {
+			log.Debug("fetch failed", "attempt", attempt, "duration", duration, "err", err)
+			return r, throttles, err
+		}
+		log.Debug("fetched", "attempt", attempt, "duration", duration, "status", r.status)
+		now := time.Now()
+		wait, ok := retryAfter(r, now)
+		if !ok {
+			return r, throttles, nil
+		}
+		throttles = append(throttles, throttle{At: now.UTC(), Status: r.status, Wait: wait})
+		if wait > budget {
+			log.Info("throttled beyond retry budget", "attempt", attempt, "status", r.status, "retry_after", wait, "budget", budget)
+			return r, throttles, nil
+		}
+		log.Info("throttled, waiting", "attempt", attempt, "status", r.status, "retry_after", wait)
+		budget -= wait
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return r, throttles, ctx.Err()
+		}
+	}
+}
+
+// retried counts the throttles fetchWithRetry waited out and tried again
+// after: the ones whose wait the budget still covered.
+func retried(throttles []throttle, budget time.Duration) int {
+	n := 0
+	for _, t := range throttles {
+		if t.Wait > budget {
+			break
+		}
+		budget -= t.Wait
+		n++
+	}
+	return n
+}
+
+// retryAfter returns how long r asks the client to back off, if r is a 429
+// or 503 with a Retry-After in either delay-seconds or HTTP-date form.
+func retryAfter(r fetchResult, now time.Time) (time.Duration, bool) {
+	if r.status != http.StatusTooManyRequests && r.status != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(r.retryAfter)
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// newRequest builds a request that identifies the sweep.
+func newRequest(ctx context.Context, method, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	return req, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpiredLinksRevalidateConditionally(t *testing.T) {
+	const etag = `"abc123"`
+	var conditional, full int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Mon, 05 Jan 2026 10:00:00 GMT")
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.now = func() time.Time { return now }
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(server.Client()), cache: cache}
+	links := []link{{URL: server.URL + "/page", File: "a.md", Line: 1}}
+
+	c.sweep(context.Background(), links, 1)
+	now = now.Add(2 * time.Hour)
+	if got := c.sweep(context.Background(), links, 1); len(got) != 0 {
+		t.Fatalf("a 304 should count as alive, got %v", got)
+	}
+	if full != 1 || conditional != 1 {
+		t.Fatalf("full fetches = %d, conditional = %d; want 1 and 1", full, conditional)
+	}
+
+	entry, _ := cache.fresh(links[0].URL)
+	if entry.ETag != etag || entry.LastModified == "" {
+		t.Errorf("validators lost across a 304: %+v", entry.validators)
+	}
+}
+
+func TestThrottledLinksRetryWithinBudget(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+		case "/busy":
+			hits++
+			if hits == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		case "/overloaded":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(server.Client()), cache: cache, retryBudget: time.Minute}
+	links := []link{
+		{URL: server.URL + "/busy", File: "a.md", Line: 1},
+		{URL: server.URL + "/overloaded", File: "a.md", Line: 2},
+	}
+
+	got := c.sweep(context.Background(), links, 1)
+	if len(got) != 1 || got[0].Link != links[1] || got[0].Rule != ruleThrottled || got[0].failed() {
+		t.Fatalf("sweep = %v; want only /overloaded, reported as throttled", got)
+	}
+	if hits != 2 {
+		t.Errorf("/busy fetched %d times, want a retry after the 429", hits)
+	}
+
+	busy, fresh := cache.fresh(links[0].URL)
+	if !fresh || busy.Class != "" || len(busy.Throttles) != 1 || busy.Throttles[0].Status != http.StatusTooManyRequests {
+		t.Errorf("/busy cache entry = %+v; want alive with one 429 in its history", busy)
+	}
+	overloaded, fresh := cache.fresh(links[1].URL)
+	if fresh || len(overloaded.Throttles) != 1 || overloaded.Throttles[0].Wait != time.Hour {
+		t.Errorf("/overloaded cache entry = %+v; want uncached with one 1h throttle", overloaded)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		status int
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{http.StatusTooManyRequests, "120", 2 * time.Minute, true},
+		{http.StatusServiceUnavailable, "Sat, 10 Jan 2026 12:00:30 GMT", 30 * time.Second, true},
+		{http.StatusServiceUnavailable, "Sat, 10 Jan 2026 11:00:00 GMT", 0, true},
+		{http.StatusTooManyRequests, "", 0, false},
+		{http.StatusTooManyRequests, "soon", 0, false},
+		{http.StatusNotFound, "120", 0, false},
+	} {
+		got, ok := retryAfter(fetchResult{status: tc.status, retryAfter: tc.header}, now)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%d, %q) = %s, %t; want %s, %t", tc.status, tc.header, got, ok, tc.want, tc.ok)
+		}
+	}
+}
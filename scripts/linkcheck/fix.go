@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// httpsUpgrades probes the https:// equivalent of every http:// link and
+// returns the ones that serve the same resource, mapped to their https URL.
+// Hosts that don't resolve, are on the skip list, or disallow the probe in
+// robots.txt are left alone.
+func (c *checker) httpsUpgrades(ctx context.Context, links []link, workers int) map[string]string {
+	var mu sync.Mutex
+	upgrades := map[string]string{}
+	insecure := slices.DeleteFunc(uniqueURLs(links), func(rawURL string) bool { return !strings.HasPrefix(rawURL, "http://") })
+	forEach(ctx, insecure, workers, func(ctx context.Context, rawURL string) error {
+		if secure, ok := c.httpsEquivalent(ctx, rawURL); ok {
+			mu.Lock()
+			upgrades[rawURL] = secure
+			mu.Unlock()
+		}
+		return nil
+	})
+	return upgrades
+}
+
+// httpsEquivalent fetches rawURL over both schemes and returns the https
+// URL when it serves the same resource.
+func (c *checker) httpsEquivalent(ctx context.Context, rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := u.Hostname()
+	if c.resolver.notFound(host) || (!matchesDomain(host, c.config.Force) && matchesDomain(host, c.config.Skip)) {
+		return "", false
+	}
+	secure := *u
+	secure.Scheme = "https"
+	if !c.ignoreRobots && !c.robots.allowed(ctx, &secure) {
+		return "", false
+	}
+
+	plain, err := fetch(ctx, c.client, rawURL, validators{}, bodyLimit)
+	if err != nil {
+		return "", false
+	}
+	tls, err := fetch(ctx, c.client, secure.String(), validators{}, bodyLimit)
+	if err != nil || !sameResource(plain, tls) {
+		return "", false
+	}
+	return secure.String(), true
+}
+
+// sameResource reports whether the https response serves what the http one
+// does: both end up at the same URL after redirects, or both answer 2xx with
+// the same body or, for pages with dynamic markup, the same title.
+func sameResource(plain, tls fetchResult) bool {
+	if tls.status < 200 || tls.status >= 300 {
+		return false
+	}
+	if plain.finalURL == tls.finalURL {
+		return true
+	}
+	if plain.status < 200 || plain.status >= 300 || plain.html != tls.html {
+		return false
+	}
+	if bytes.Equal(plain.body, tls.body) {
+		return true
+	}
+	plainTitle, tlsTitle := pageTitle(plain.body), pageTitle(tls.body)
+	return plainTitle != "" && plainTitle == tlsTitle
+}
+
+func pageTitle(body []byte) string {
+	m := titlePattern.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(string(m[1])), " ")
+}
+
+// rewriteLinks replaces each URL in rewrites on the lines where links found
+// it and returns a diff of the changed lines. It writes the files back only
+// when write is set.
+func rewriteLinks(links []link, rewrites map[string]string, write bool) (string, error) {
+	return editLinks(links, rewrites, func(line string) string { return replaceURLs(line, rewrites) }, write)
+}
+
+// editLinks runs edit over each line where links found a URL in targets
+// and returns a diff of the changed lines, writing the files back, with
+// their permissions kept, only when write is set. A file edit leaves as it
+// was is neither in the diff nor written.
+func editLinks(links []link, targets map[string]string, edit func(string) string, write bool) (string, error) {
+	byFile := map[string][]link{}
+	for _, l := range links {
+		if _, ok := targets[l.URL]; ok {
+			byFile[l.File] = append(byFile[l.File], l)
+		}
+	}
+
+	var diff strings.Builder
+	for _, file := range slices.Sorted(maps.Keys(byFile)) {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		lines := strings.Split(string(raw), "\n")
+		var hunks strings.Builder
+		done := map[int]bool{}
+		for _, l := range byFile[file] {
+			if done[l.Line] || l.Line > len(lines) {
+				continue
+			}
+			done[l.Line] = true
+			old := lines[l.Line-1]
+			if lines[l.Line-1] = edit(old); lines[l.Line-1] == old {
+				continue
+			}
+			fmt.Fprintf(&hunks, "@@ -%d +%d @@\n-%s\n+%s\n", l.Line, l.Line, old, lines[l.Line-1])
+		}
+		if hunks.Len() == 0 {
+			continue
+		}
+		fmt.Fprintf(&diff, "--- a/%s\n+++ b/%s\n%s", file, file, hunks.String())
+		if write {
+			if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+				return "", err
+			}
+		}
+	}
+	return diff.String(), nil
+}
+
+// replaceURLs swaps every whole URL on line that has a rewrite, leaving URLs
+// that merely start with one untouched.
+func replaceURLs(line string, rewrites map[string]string) string {
+	return urlPattern.ReplaceAllStringFunc(line, func(match string) string {
+		rawURL := trimURL(match)
+		if to, ok := rewrites[rawURL]; ok {
+			return to + match[len(rawURL):]
+		}
+		return match
+	})
+}
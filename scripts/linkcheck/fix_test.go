@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTTPSUpgrades(t *testing.T) {
+	page := func(title string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>" + title + "</title></head><body>" + strings.Repeat("x", 300) + "</body></html>"))
+		}
+	}
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			page("Same post")(w, r)
+		case "/other":
+			page("Plain version")(w, r)
+		}
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			page("Same post")(w, r)
+		case "/other":
+			page("Parked")(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer secure.Close()
+
+	// Route both schemes of one hostname to the two test servers.
+	_, plainPort, _ := net.SplitHostPort(plain.Listener.Addr().String())
+	_, securePort, _ := net.SplitHostPort(secure.Listener.Addr().String())
+	transport := secure.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		port := plainPort
+		if strings.HasSuffix(addr, ":443") {
+			port = securePort
+		}
+		return (&net.Dialer{}).DialContext(ctx, network, net.JoinHostPort("127.0.0.1", port))
+	}
+	client := &http.Client{Transport: transport}
+	c := &checker{client: client, resolver: newDNSCache(netLookup), robots: newRobotsCache(client), ignoreRobots: true}
+
+	links := []link{
+		{URL: "http://example.com/same", File: "a.md", Line: 1},
+		{URL: "http://example.com/other", File: "a.md", Line: 2},
+		{URL: "http://example.com/missing", File: "a.md", Line: 3},
+		{URL: "https://example.com/already", File: "a.md", Line: 4},
+	}
+	got := c.httpsUpgrades(context.Background(), links, 2)
+	if len(got) != 1 || got["http://example.com/same"] != "https://example.com/same" {
+		t.Fatalf("httpsUpgrades = %v; want only /same upgraded", got)
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "post.md")
+	src := "See [docs](http://example.com/a) and http://example.com/ab.\nUntouched http://example.com/a/b\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := extractLinks(file, []byte(src))
+	rewrites := map[string]string{"http://example.com/a": "https://example.com/a"}
+
+	diff, err := rewriteLinks(links, rewrites, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- a/" + file + "\n+++ b/" + file + "\n@@ -1 +1 @@\n" +
+		"-See [docs](http://example.com/a) and http://example.com/ab.\n" +
+		"+See [docs](https://example.com/a) and http://example.com/ab.\n"
+	if diff != want {
+		t.Fatalf("diff =\n%s\nwant\n%s", diff, want)
+	}
+	if raw, _ := os.ReadFile(file); string(raw) != src {
+		t.Fatal("a dry run wrote the file")
+	}
+
+	if _, err := rewriteLinks(links, rewrites, true); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(file)
+	if got := string(raw); got != strings.Replace(src, "(http://example.com/a)", "(https://example.com/a)", 1) {
+		t.Fatalf("rewritten file =\n%s", got)
+	}
+}
+
+func TestEditLinksKeepsModesAndSkipsUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private.md")
+	same := filepath.Join(dir, "same.md")
+	for file, src := range map[string]string{
+		private: "Read http://example.com/a\n",
+		same:    "Already https://example.com/b\n",
+	} {
+		if err := os.WriteFile(file, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var links []link
+	for _, file := range []string{private, same} {
+		raw, _ := os.ReadFile(file)
+		links = append(links, extractLinks(file, raw)...)
+	}
+	targets := map[string]string{"http://example.com/a": "", "https://example.com/b": ""}
+	edit := func(line string) string { return strings.ReplaceAll(line, "http://", "https://") }
+
+	diff, err := editLinks(links, targets, edit, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(diff, same) {
+		t.Errorf("diff names a file the edit left alone:\n%s", diff)
+	}
+	info, err := os.Stat(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0o600 {
+		t.Errorf("rewritten file's mode = %v, want 0600", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// capturedHeaders are the response headers -capture-headers records for
+// every checked URL, so checks that only need headers can read them off the
+// sweep's one request instead of making their own.
+var capturedHeaders = []string{"Content-Type", "Cache-Control", "Content-Length", "Server"}
+
+// captureHeaders picks capturedHeaders out of r's response.
+func captureHeaders(r fetchResult) map[string]string {
+	headers := map[string]string{}
+	for _, name := range capturedHeaders {
+		if value := r.header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+	return headers
+}
+
+// headerLog collects the captured headers of every URL a sweep checked or
+// took from the cache. A nil headerLog means capture is off.
+type headerLog struct {
+	mu    sync.Mutex
+	byURL map[string]map[string]string
+}
+
+func newHeaderLog() *headerLog {
+	return &headerLog{byURL: map[string]map[string]string{}}
+}
+
+func (h *headerLog) record(rawURL string, headers map[string]string) {
+	if h == nil || headers == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.byURL[rawURL] = headers
+}
+
+func (h *headerLog) get(rawURL string) map[string]string {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.byURL[rawURL]
+}
+
+// headerTransport sets the configured User-Agent and the extra headers
+// linkcheck.yml lists for a request's domain. Headers for a domain also
+// apply to its subdomains; where two entries cover a host, the more
+// specific one wins.
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   map[string]map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	domains := t.domainsFor(req.URL.Hostname())
+	if t.userAgent == "" && len(domains) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	for _, domain := range domains {
+		for name, value := range t.headers[domain] {
+			req.Header.Set(name, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// domainsFor returns the configured domains covering host, least specific
+// first, so applying them in order lets the closest match override.
+func (t *headerTransport) domainsFor(host string) []string {
+	var domains []string
+	for domain := range t.headers {
+		if matchesDomain(host, []string{domain}) {
+			domains = append(domains, domain)
+		}
+	}
+	slices.SortFunc(domains, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+	})
+	return domains
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeaderTransportAppliesDomainHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	transport := &headerTransport{
+		base:      http.DefaultTransport,
+		userAgent: "custom-bot/2.0 (+https://example.com/bot)",
+		headers: map[string]map[string]string{
+			"127.0.0.1":     {"Accept": "text/html", "Accept-Language": "en"},
+			"other.example": {"X-Nope": "1"},
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if ua := got.Get("User-Agent"); ua != transport.userAgent {
+		t.Errorf("User-Agent = %q, want %q", ua, transport.userAgent)
+	}
+	if got.Get("Accept") != "text/html" || got.Get("Accept-Language") != "en" {
+		t.Errorf("domain headers missing: %v", got)
+	}
+	if got.Get("X-Nope") != "" {
+		t.Error("another domain's headers leaked into the request")
+	}
+	if req.Header.Get("Accept") != "" {
+		t.Error("RoundTrip modified the caller's request")
+	}
+}
+
+func TestHeaderTransportPrefersMostSpecificDomain(t *testing.T) {
+	transport := &headerTransport{headers: map[string]map[string]string{
+		"example.com":      {"Accept": "*/*"},
+		"docs.example.com": {"Accept": "text/html"},
+	}}
+	got := transport.domainsFor("api.docs.example.com")
+	if strings.Join(got, ",") != "example.com,docs.example.com" {
+		t.Fatalf("domainsFor = %v; want the closest match applied last", got)
+	}
+}
+
+func TestLoadConfigRequiresContactURLInUserAgent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "linkcheck.yml")
+	if err := os.WriteFile(path, []byte("userAgent: anonymous-bot/1.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Fatal("a User-Agent without a contact URL should be rejected")
+	}
+}
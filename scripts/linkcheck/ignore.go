@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rules lists every rule id, for validating lint_ignore.
+var rules = []string{ruleNXDomain, ruleHTTP, ruleRobots, ruleSkipped, ruleThrottled, ruleSuspect, ruleDrift, ruleAnchor, ruleTracking, ruleCode, ruleUnchecked}
+
+// ignores maps a Markdown file to the rules its lint_ignore frontmatter
+// opts it out of, for posts kept as history whose dead links are the point.
+// Unlike the baseline, which accepts one URL everywhere, it silences whole
+// rules in one post.
+type ignores map[string][]string
+
+// collectIgnores reads lint_ignore from every Markdown file under dir,
+// keyed by path as collectLinks reports it. An unknown rule is an error, so
+// a typo can't silently leave a post unchecked.
+func collectIgnores(dir string) (ignores, error) {
+	ig := ignores{}
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, ok := frontmatter(string(raw))
+		if !ok {
+			return nil
+		}
+		var fm struct {
+			LintIgnore []string `yaml:"lint_ignore"`
+		}
+		if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		for _, rule := range fm.LintIgnore {
+			if !slices.Contains(rules, rule) {
+				return fmt.Errorf("%s: lint_ignore: unknown rule %q; want one of %s", filePath, rule, strings.Join(rules, ", "))
+			}
+		}
+		if len(fm.LintIgnore) > 0 {
+			ig[filepath.ToSlash(filePath)] = fm.LintIgnore
+		}
+		return nil
+	})
+	return ig, err
+}
+
+// suppresses reports whether f's post opted out of f's rule.
+func (ig ignores) suppresses(f finding) bool {
+	return slices.Contains(ig[f.Link.File], f.Rule)
+}
+
+// filter splits findings into those still to report and those lint_ignore
+// suppresses.
+func (ig ignores) filter(findings []finding) (kept, suppressed []finding) {
+	for _, f := range findings {
+		if ig.suppresses(f) {
+			suppressed = append(suppressed, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, suppressed
+}
+
+// suppressedReport lists the suppressed findings, or with top > 0 only
+// counts them, so an opt-out stays visible. It is empty when nothing was
+// suppressed.
+func suppressedReport(suppressed []finding, st style, top int) string {
+	if len(suppressed) == 0 {
+		return ""
+	}
+	title := st.paint(fmt.Sprintf("suppressed by lint_ignore (%d)", len(suppressed)), ansiDim)
+	if top > 0 {
+		return title + "\n"
+	}
+	lines := make([]string, len(suppressed))
+	for i, f := range suppressed {
+		lines[i] = fmt.Sprintf("%s [%s]", f, f.Rule)
+	}
+	return fmt.Sprintf("%s:\n  %s\n", title, strings.Join(lines, "\n  "))
+}
+
+// withoutOccurrences drops the link occurrences behind findings from links.
+func withoutOccurrences(links []link, findings []finding) []link {
+	drop := map[link]bool{}
+	for _, f := range findings {
+		drop[f.Link] = true
+	}
+	return slices.DeleteFunc(slices.Clone(links), func(l link) bool { return drop[l] })
+}
+
+// frontmatter returns the YAML between a Markdown file's leading ---
+// lines.
+func frontmatter(raw string) (string, bool) {
+	rest, ok := strings.CutPrefix(raw, "---\n")
+	if !ok {
+		return "", false
+	}
+	fm, _, ok := strings.Cut(rest, "\n---\n")
+	return fm, ok
+}
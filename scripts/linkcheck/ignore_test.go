@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectIgnores(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old.md", "---\ntitle: Old\nlint_ignore:\n    - http\n    - nxdomain\n---\nSee https://gone.example/.\n")
+	write("new.md", "---\ntitle: New\n---\nSee https://gone.example/.\n")
+	write("notes.md", "No frontmatter at all.\n")
+
+	ig, err := collectIgnores(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, fresh := filepath.ToSlash(filepath.Join(dir, "old.md")), filepath.ToSlash(filepath.Join(dir, "new.md"))
+	if len(ig) != 1 || strings.Join(ig[old], ",") != "http,nxdomain" {
+		t.Fatalf("ignores = %v", ig)
+	}
+
+	findings := []finding{
+		newFinding(link{URL: "https://gone.example/", File: old, Line: 5}, ruleHTTP, "HTTP 404"),
+		newFinding(link{URL: "https://gone.example/#top", File: old, Line: 6}, ruleAnchor, "no #top"),
+		newFinding(link{URL: "https://gone.example/", File: fresh, Line: 4}, ruleHTTP, "HTTP 404"),
+	}
+	kept, suppressed := ig.filter(findings)
+	if len(kept) != 2 || len(suppressed) != 1 || suppressed[0] != findings[0] {
+		t.Fatalf("kept %v, suppressed %v", kept, suppressed)
+	}
+	if got := suppressedReport(suppressed, style{}, 0); got != "suppressed by lint_ignore (1):\n  "+old+":5:0: https://gone.example/: HTTP 404 [http]\n" {
+		t.Fatalf("report = %q", got)
+	}
+	if got := suppressedReport(suppressed, style{}, 3); got != "suppressed by lint_ignore (1)\n" {
+		t.Fatalf("report with -top = %q", got)
+	}
+	if rest := withoutOccurrences([]link{findings[0].Link, findings[2].Link}, suppressed); len(rest) != 1 || rest[0] != findings[2].Link {
+		t.Fatalf("withoutOccurrences = %v", rest)
+	}
+
+	write("typo.md", "---\nlint_ignore: [htp]\n---\n")
+	if _, err := collectIgnores(dir); err == nil || !strings.Contains(err.Error(), `unknown rule "htp"`) {
+		t.Fatalf("err = %v, want the unknown rule", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"rednafi.com/internal/webtest"
+)
+
+// sweepReasons sweeps links with c and returns each finding as
+// "path rule: message", with the server's address trimmed off.
+func sweepReasons(t *testing.T, c *checker, links []link) []string {
+	t.Helper()
+	var got []string
+	for _, f := range c.sweep(context.Background(), links, 4) {
+		_, p, _ := strings.Cut(strings.TrimPrefix(f.Link.URL, "http://"), "/")
+		got = append(got, "/"+p+" "+f.Rule+": "+f.Message)
+	}
+	return got
+}
+
+func TestSweepAgainstFlakyUpstreams(t *testing.T) {
+	busy := webtest.Flaky(t, 1, http.StatusTooManyRequests, "0")
+	overloaded := webtest.Flaky(t, 5, http.StatusServiceUnavailable, "3600")
+	broken := webtest.Flaky(t, 1, http.StatusInternalServerError, "")
+	c := &checker{client: http.DefaultClient, resolver: newDNSCache(netLookup), ignoreRobots: true, retryBudget: time.Minute}
+
+	got := sweepReasons(t, c, []link{
+		{URL: busy.URL + "/busy", File: "a.md", Line: 1},
+		{URL: overloaded.URL + "/overloaded", File: "a.md", Line: 2},
+		{URL: broken.URL + "/broken", File: "a.md", Line: 3},
+	})
+	want := []string{
+		"/overloaded throttled: HTTP 503, Retry-After 1h0m0s exceeds the 1m0s retry budget",
+		"/broken http: HTTP 500",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("findings =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+	if n := busy.Hits("/busy"); n != 2 {
+		t.Errorf("the 429 was tried %d times, want a retry once Retry-After passed", n)
+	}
+	if n := overloaded.Hits("/overloaded"); n != 1 {
+		t.Errorf("the 503 was tried %d times, want no retry past the budget", n)
+	}
+	if n := broken.Hits("/broken"); n != 1 {
+		t.Errorf("the 500 was tried %d times, want no retry without Retry-After", n)
+	}
+}
+
+func TestSweepGivesUpOnSlowResponders(t *testing.T) {
+	slow := webtest.Slow(t, time.Minute)
+	client := &http.Client{Transport: &limitedTransport{
+		base:    http.DefaultTransport,
+		limiter: newHostLimiter(limitsConfig{}),
+		timeout: 50 * time.Millisecond,
+	}}
+	c := &checker{client: client, resolver: newDNSCache(netLookup), ignoreRobots: true}
+
+	start := time.Now()
+	got := sweepReasons(t, c, []link{{URL: slow.URL + "/slow", File: "a.md", Line: 1}})
+	if len(got) != 1 || !strings.Contains(got[0], "/slow http: ") || !strings.Contains(got[0], "deadline exceeded") {
+		t.Fatalf("findings = %q, want a timeout", got)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("the sweep waited %s for a slow responder", elapsed)
+	}
+}
+
+func TestRedirectMaze(t *testing.T) {
+	maze := webtest.RedirectMaze(t)
+	c := &checker{client: maze.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true}
+	var links []link
+	for i, p := range []string{"/hops/3", "/hops/12", "/temporary/2", "/loop/a"} {
+		links = append(links, link{URL: maze.URL + p, File: "a.md", Line: i + 1})
+	}
+
+	got := sweepReasons(t, c, links)
+	if len(got) != 2 || !strings.HasPrefix(got[0], "/hops/12 http: ") || !strings.HasPrefix(got[1], "/loop/a http: ") {
+		t.Fatalf("findings = %q, want the chain too long to follow and the loop", got)
+	}
+
+	targets := c.permanentRedirects(context.Background(), links, 4)
+	if len(targets) != 1 || targets[maze.URL+"/hops/3"] != maze.URL+"/hops/0" {
+		t.Fatalf("permanentRedirects = %v, want only /hops/3 rewritten to /hops/0", targets)
+	}
+	if n := maze.Hits("/loop/a"); n > 2*maxRedirects {
+		t.Errorf("the loop was followed %d times round", n)
+	}
+}
+
+func TestSweepAgainstTheSite(t *testing.T) {
+	site := webtest.Site(t, map[string]string{
+		"/go/retry/": webtest.Page("Retry", `<h2 id="backoff">Backoff</h2>`),
+		"/404.html":  webtest.Page("Page not found", ""),
+	})
+	c := &checker{client: site.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(site.Client())}
+
+	got := sweepReasons(t, c, []link{
+		{URL: site.URL + "/go/retry/#backoff", File: "a.md", Line: 1},
+		{URL: site.URL + "/go/retry#backoff", File: "a.md", Line: 2},
+		{URL: site.URL + "/go/retry/#jitter", File: "a.md", Line: 3},
+		{URL: site.URL + "/go/gone/", File: "a.md", Line: 4},
+	})
+	want := []string{
+		`/go/retry/#jitter anchor: no element with id "jitter"`,
+		"/go/gone/ http: HTTP 404",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("findings =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+	if site.Hits("/robots.txt") != 1 {
+		t.Errorf("robots.txt fetched %d times, want once", site.Hits("/robots.txt"))
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default per-host politeness when linkcheck.yml sets none.
+const defaultHostConcurrency = 4
+
+// domainLimit caps requests to one domain: at most Concurrency in flight, and
+// successive requests at least Interval apart.
+type domainLimit struct {
+	Concurrency int           `yaml:"concurrency"`
+	Interval    time.Duration `yaml:"interval"`
+}
+
+// limitsConfig is the limits block of linkcheck.yml. The top-level values
+// apply to each host on its own; Domains overrides them for a domain and its
+// subdomains, which then share one budget.
+type limitsConfig struct {
+	domainLimit `yaml:",inline"`
+	Domains     map[string]domainLimit `yaml:"domains"`
+}
+
+// hostLimiter hands out request slots per host, layered under the sweep's
+// global worker pool so a heavily cited domain doesn't absorb every worker's
+// burst at once.
+type hostLimiter struct {
+	config limitsConfig
+
+	mu    sync.Mutex
+	slots map[string]*slot
+}
+
+type slot struct {
+	sem      chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newHostLimiter(config limitsConfig) *hostLimiter {
+	return &hostLimiter{config: config, slots: map[string]*slot{}}
+}
+
+// slotFor returns the shared slot for host: that of the most specific
+// configured domain covering it, or its own with the default limits.
+func (l *hostLimiter) slotFor(host string) *slot {
+	key, limit := strings.ToLower(host), l.config.domainLimit
+	matched := ""
+	for domain, domainLimit := range l.config.Domains {
+		if len(domain) > len(matched) && matchesDomain(host, []string{domain}) {
+			matched, key, limit = domain, domain, domainLimit
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.slots[key]
+	if !ok {
+		concurrency := cmp.Or(limit.Concurrency, defaultHostConcurrency)
+		s = &slot{sem: make(chan struct{}, concurrency), interval: limit.Interval}
+		l.slots[key] = s
+	}
+	return s
+}
+
+// acquire blocks until host has a free slot and its interval has passed
+// since the last request, and returns the func that frees the slot.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (release func(), err error) {
+	s := l.slotFor(host)
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	wait := time.Until(s.next)
+	s.next = time.Now().Add(max(wait, 0) + s.interval)
+	s.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			<-s.sem
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-s.sem }, nil
+}
+
+// limitedTransport applies a hostLimiter to every request, including
+// robots.txt fetches and redirect hops. A request holds its host's slot
+// until its body is closed, so the limit covers reading the body too, not
+// just waiting for the headers. The timeout starts once a slot is free, so
+// time spent queued behind a slow domain's limit doesn't count.
+// sent counts the requests that got a slot and went out; received counts
+// the bytes downloaded for them, when base dials through countingDial.
+type limitedTransport struct {
+	base     http.RoundTripper
+	limiter  *hostLimiter
+	timeout  time.Duration
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	t.sent.Add(1)
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, cancel: cancel, release: release}
+	return resp, nil
+}
+
+// releaseOnClose frees a request's host slot and its timeout once its body
+// is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	cancel  context.CancelFunc
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseOnClose) Close() error {
+	defer b.once.Do(func() {
+		b.cancel()
+		b.release()
+	})
+	return b.ReadCloser.Close()
+}
+
+// countingDial wraps dial so that every byte read off the connections it
+// opens is added to received: headers, TLS records, and bodies whether
+// linkcheck reads them or closes them unread.
+func countingDial(dial func(ctx context.Context, network, addr string) (net.Conn, error), received *atomic.Int64) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, received: received}, nil
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	received *atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rednafi.com/internal/webtest"
+)
+
+func TestHostLimiterCapsConcurrency(t *testing.T) {
+	l := newHostLimiter(limitsConfig{domainLimit: domainLimit{Concurrency: 2}})
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			release, err := l.acquire(context.Background(), "example.com")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			release()
+		})
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", got)
+	}
+}
+
+func TestHostLimiterSpacesRequests(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	l := newHostLimiter(limitsConfig{Domains: map[string]domainLimit{
+		"github.com": {Concurrency: 4, Interval: interval},
+	}})
+
+	start := time.Now()
+	for _, host := range []string{"github.com", "gist.github.com", "api.github.com"} {
+		release, err := l.acquire(context.Background(), host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	// Subdomains share github.com's budget, so three requests take at least
+	// two intervals.
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Fatalf("three requests took %s, want at least %s", elapsed, 2*interval)
+	}
+
+	start = time.Now()
+	for range 3 {
+		release, err := l.acquire(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed >= interval {
+		t.Fatalf("unconfigured host was throttled for %s", elapsed)
+	}
+}
+
+func TestHostLimiterHonorsCancellation(t *testing.T) {
+	l := newHostLimiter(limitsConfig{domainLimit: domainLimit{Concurrency: 1}})
+	release, err := l.acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "example.com"); err == nil {
+		t.Fatal("acquire on a full slot should fail once the context is done")
+	}
+}
+
+func TestLimitedTransportHoldsTheSlotUntilTheBodyIsClosed(t *testing.T) {
+	site := webtest.Site(t, map[string]string{"/": webtest.Page("home", "")})
+	limited := &limitedTransport{base: http.DefaultTransport, limiter: newHostLimiter(limitsConfig{domainLimit: domainLimit{Concurrency: 1}}), timeout: time.Second}
+	client := &http.Client{Transport: limited}
+
+	first, err := client.Get(site.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, site.URL+"/", nil)
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("a second request got the host's only slot while the first body was still open")
+	}
+
+	first.Body.Close()
+	first.Body.Close()
+	second, err := client.Get(site.URL + "/")
+	if err != nil {
+		t.Fatalf("the slot wasn't freed when the body was closed: %v", err)
+	}
+	second.Body.Close()
+}
+
+func TestLimitedTransportCountsRequests(t *testing.T) {
+	site := webtest.Site(t, map[string]string{"/": webtest.Page("home", "")})
+	limited := &limitedTransport{base: http.DefaultTransport, limiter: newHostLimiter(limitsConfig{}), timeout: time.Second}
+	client := &http.Client{Transport: limited}
+	for range 3 {
+		resp, err := client.Get(site.URL + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := limited.sent.Load(); n != 3 {
+		t.Fatalf("sent = %d, want 3", n)
+	}
+}
+
+func TestCountingDialCountsUnreadResponses(t *testing.T) {
+	site := webtest.Site(t, map[string]string{"/": webtest.Page("home", "")})
+	var received atomic.Int64
+	client := &http.Client{Transport: &http.Transport{DialContext: countingDial(new(net.Dialer).DialContext, &received)}}
+	resp, err := client.Get(site.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := received.Load(); n < int64(len("HTTP/1.1 200 OK\r\n")) {
+		t.Fatalf("received = %d, want the response counted though its body went unread", n)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	urlPattern        = regexp.MustCompile("https?://[^\\s<>\"'`\\[\\]]+")
+	inlineCodePattern = regexp.MustCompile("`[^`]*`")
+)
+
+// collectLinks returns every external link in the Markdown files under dir,
+// in file order.
+func collectLinks(dir string) ([]link, error) {
+	var links []link
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		links = append(links, extractLinks(filePath, raw)...)
+		return nil
+	})
+	return links, err
+}
+
+// extractLinks finds the external URLs in a Markdown file, skipping fenced
+// code blocks and inline code, where URLs are examples rather than links.
+func extractLinks(filePath string, raw []byte) []link {
+	var links []link
+	var fence []byte
+	for i, line := range bytes.Split(raw, []byte("\n")) {
+		if fence != nil {
+			if isFenceClose(line, fence) {
+				fence = nil
+			}
+			continue
+		}
+		if fence = fenceOpen(line); fence != nil {
+			continue
+		}
+
+		// Blank out inline code rather than cutting it, so columns still
+		// match the file.
+		text := inlineCodePattern.ReplaceAllStringFunc(string(line), func(code string) string {
+			return strings.Repeat(" ", len(code))
+		})
+		for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+			rawURL := trimURL(text[loc[0]:loc[1]])
+			u, err := url.Parse(rawURL)
+			if err != nil || u.Host == "" || slices.Contains(selfHosts, strings.ToLower(u.Hostname())) {
+				continue
+			}
+			links = append(links, link{URL: rawURL, File: filepath.ToSlash(filePath), Line: i + 1, Column: loc[0] + 1})
+		}
+	}
+	return links
+}
+
+// trimURL drops the punctuation that ends a sentence or wraps a link in
+// Markdown, keeping closing parentheses that balance one inside the URL.
+func trimURL(s string) string {
+	for {
+		trimmed := strings.TrimRight(s, ".,;:!?*_~")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == s {
+			return s
+		}
+		s = trimmed
+	}
+}
+
+// hostsOf returns the unique hostnames across links.
+func hostsOf(links []link) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, l := range links {
+		u, err := url.Parse(l.URL)
+		if err != nil || seen[u.Hostname()] {
+			continue
+		}
+		seen[u.Hostname()] = true
+		hosts = append(hosts, u.Hostname())
+	}
+	return hosts
+}
+
+// fenceOpen returns the backtick or tilde run that opens a fenced code block
+// on line, or nil if the line doesn't open one.
+func fenceOpen(line []byte) []byte {
+	trimmed := bytes.TrimLeft(line, " ")
+	for _, marker := range []byte("`~") {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == marker {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return nil
+}
+
+// isFenceClose reports whether line closes a block opened by fence: the same
+// marker, at least as long, and nothing else on the line.
+func isFenceClose(line, fence []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) >= len(fence) && len(bytes.Trim(trimmed, string(fence[:1]))) == 0
+}
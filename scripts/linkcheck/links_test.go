@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	raw := "See [Go](https://go.dev/doc/) and <https://example.com/a>.\n" +
+		"Inline `https://example.com/code` is skipped, as is [home](https://rednafi.com/go/).\n" +
+		"```sh\n" +
+		"curl https://example.com/fenced\n" +
+		"```\n" +
+		"[ref]:\n" +
+		"    https://en.wikipedia.org/wiki/Go_(programming_language)\n" +
+		"(https://example.com/wrapped).\n"
+
+	got := extractLinks("content/go/post.md", []byte(raw))
+	want := []link{
+		{URL: "https://go.dev/doc/", File: "content/go/post.md", Line: 1, Column: 10},
+		{URL: "https://example.com/a", File: "content/go/post.md", Line: 1, Column: 36},
+		{URL: "https://en.wikipedia.org/wiki/Go_(programming_language)", File: "content/go/post.md", Line: 7, Column: 5},
+		{URL: "https://example.com/wrapped", File: "content/go/post.md", Line: 8, Column: 2},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("extractLinks =\n  %v\nwant\n  %v", got, want)
+	}
+}
+
+func TestHostsOfDeduplicates(t *testing.T) {
+	links := []link{
+		{URL: "https://go.dev/a"},
+		{URL: "https://example.com/"},
+		{URL: "https://go.dev/b"},
+	}
+	got := hostsOf(links)
+	want := []string{"go.dev", "example.com"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("hostsOf = %q, want %q", got, want)
+	}
+}
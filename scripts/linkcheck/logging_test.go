@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerRejectsUnknownOptions(t *testing.T) {
+	if _, err := newLogger(nil, "xml", "info"); err == nil {
+		t.Error("newLogger accepted -log-format=xml")
+	}
+	if _, err := newLogger(nil, "text", "loud"); err == nil {
+		t.Error("newLogger accepted -log-level=loud")
+	}
+}
+
+func TestCheckLogsRequestAttributesAsJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	logger, err := newLogger(&out, "json", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, log: logger}
+	c.check(context.Background(), server.URL+"/gone", validators{})
+
+	var messages []string
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", scanner.Text(), err)
+		}
+		if record["url"] != server.URL+"/gone" {
+			t.Errorf("log line %q lacks the url", scanner.Text())
+		}
+		if _, ok := record["duration"]; !ok {
+			t.Errorf("log line %q lacks the duration", scanner.Text())
+		}
+		messages = append(messages, record["msg"].(string))
+		if record["msg"] == "fetched" && (record["attempt"] != 1.0 || record["status"] != 404.0) {
+			t.Errorf("fetched line = %v, want attempt 1 and status 404", record)
+		}
+	}
+	if strings.Join(messages, ",") != "fetched,checked" {
+		t.Fatalf("messages = %v, want fetched then checked", messages)
+	}
+}
@@ -0,0 +1,717 @@
+// Command linkcheck sweeps the external links in content/ and reports the
+// dead ones.
+//
+// It behaves like a polite crawler: each unique URL is checked once however
+// many posts cite it, each host's robots.txt is honored, and requests to a
+// host are capped by the limits in linkcheck.yml. Results are cached in
+// .cache/linkcheck.json, so a nightly run only rechecks the links whose
+// result expired. Besides dead links and hosts that don't resolve, the sweep
+// flags soft 404s, parked domains, missing anchors, moved GitHub line links,
+// redirects to another domain and tracking parameters. Findings listed in
+// linkcheck.baseline, or under a post's lint_ignore, don't fail it.
+//
+// A few flags swap the report for another mode:
+//
+//	-fix          rewrite http://, permanently redirected and tracked links
+//	-wayback-fix  point dead links at their Wayback Machine snapshots
+//	-snapshots    keep the snapshots in linkcheck.snapshots.json verified
+//	-compare ref  report only the findings -head adds to ref
+//	-tui          step through the findings interactively
+//	-daemon       run the check groups in linkcheck.yml on their schedules
+//	-uptime       probe the uptime groups once and append to data/uptime.json
+//
+// `linkcheck report diff|merge|digest|domains REPORT...` works on saved
+// -format=json reports without checking anything, and -dry-run prints what
+// any run would request, write and call instead of doing it. Run with -h
+// for the rest of the flags.
+//
+// The sweep hits other people's servers, so it isn't part of `make lint`; run
+// it with `make linkcheck`.
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const contentDir = "content"
+
+// selfHosts are the site's own hosts. Internal links are covered by the
+// rendered-site tests, not by this sweep.
+var selfHosts = []string{"rednafi.com", "www.rednafi.com"}
+
+// link is one external URL occurrence in a Markdown file. Line and Column
+// are 1-based; Column counts bytes, like Go's own diagnostics.
+type link struct {
+	URL    string
+	File   string
+	Line   int
+	Column int
+}
+
+// defaultUserAgent identifies the sweep to the sites it checks, with a
+// contact URL. userAgent in linkcheck.yml replaces it.
+const defaultUserAgent = robotsToken + "/1.0 (+https://rednafi.com)"
+
+// Rules, the ids of the checks behind findings, each reported in its own
+// group. Skipped links aren't failures, but they aren't passes either.
+const (
+	ruleNXDomain  = "nxdomain"
+	ruleHTTP      = "http"
+	ruleRobots    = "robots"
+	ruleSkipped   = "skipped"
+	ruleThrottled = "throttled"
+	ruleSuspect   = "suspect"
+	ruleDrift     = "drift"
+	ruleAnchor    = "anchor"
+	ruleTracking  = "tracking"
+	ruleCode      = "code"
+	ruleUnchecked = "unchecked"
+)
+
+// severity ranks findings. Only errors fail the sweep; warnings are
+// heuristics and transient trouble that need a human to confirm, and info
+// marks links that weren't checked at all.
+type severity string
+
+const (
+	severityError   severity = "error"
+	severityWarning severity = "warning"
+	severityInfo    severity = "info"
+)
+
+// severityOf is the severity of a rule's findings.
+func severityOf(rule string) severity {
+	switch rule {
+	case ruleNXDomain, ruleHTTP:
+		return severityError
+	case ruleSuspect, ruleDrift, ruleAnchor, ruleCode, ruleThrottled, ruleTracking:
+		return severityWarning
+	}
+	return severityInfo
+}
+
+// finding is one problem at one link occurrence: which rule flagged it, how
+// bad it is, and where the link sits in content/. Every check produces
+// findings and every reporter, from the text report to the daemon's status,
+// renders them.
+type finding struct {
+	Link     link
+	Rule     string
+	Severity severity
+	Message  string
+	// Archive is a Wayback Machine snapshot of a dead link, when -wayback
+	// found one.
+	Archive string
+	// Artifact is the file -artifacts saved the failing response in.
+	Artifact string
+}
+
+func newFinding(l link, rule, message string) finding {
+	return finding{Link: l, Rule: rule, Severity: severityOf(rule), Message: message}
+}
+
+// failed reports whether f fails the sweep.
+func (f finding) failed() bool {
+	return f.Severity == severityError
+}
+
+// String renders f as file:line:column: url: message.
+func (f finding) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s: %s", f.Link.File, f.Link.Line, f.Link.Column, f.Link.URL, f.Message)
+	if f.Archive != "" {
+		s += " (archived: " + f.Archive + ")"
+	}
+	if f.Artifact != "" {
+		s += " (response saved in " + f.Artifact + ")"
+	}
+	return s
+}
+
+// result is the outcome of checking one URL: the rule it broke, as class,
+// and why. An empty class means alive.
+type result struct {
+	class, reason string
+	validators    validators
+	throttles     []throttle
+	// headers are the captured response headers, nil unless the checker
+	// captures them.
+	headers map[string]string
+	// latency is how long the link took to answer; zero when it wasn't
+	// fetched.
+	latency time.Duration
+}
+
+// checker holds what every link check shares: the HTTP client, the DNS and
+// robots.txt caches, and the options.
+type checker struct {
+	client       *http.Client
+	resolver     *dnsCache
+	robots       *robotsCache
+	ignoreRobots bool
+	config       config
+	cache        *resultCache
+	retryBudget  time.Duration
+	log          *slog.Logger
+	tracer       *tracer
+	// headers collects response headers when -capture-headers is set.
+	headers *headerLog
+	// latencies collects how long each URL took to answer; nil skips it.
+	latencies *latencyLog
+	// artifacts saves the responses that fail a body check when
+	// -artifacts is set.
+	artifacts *artifactStore
+	// code checks links to lines of code on GitHub; nil skips them.
+	code *codeLines
+	// pageChecks inspect each live page's body; nil means
+	// defaultBodyChecks.
+	pageChecks []bodyCheck
+	// stopAt, when set, is when a sweep stops sending out URLs that need
+	// a request; see -max-duration. unchecked counts the URLs it held back.
+	stopAt    time.Time
+	unchecked atomic.Int64
+	// retries counts the throttled requests retried, and cacheHits the
+	// links a fresh cache entry answered; see usage.
+	retries   atomic.Int64
+	cacheHits atomic.Int64
+}
+
+// logger returns c's logger, or one that discards everything.
+func (c *checker) logger() *slog.Logger {
+	if c.log == nil {
+		return discard
+	}
+	return c.log
+}
+
+// options are the command line flags.
+type options struct {
+	workers, dnsWorkers       int
+	timeout, retryBudget      time.Duration
+	ignoreRobots, noCache     bool
+	configPath, cachePath     string
+	fix, preview, dryRun      bool
+	waybackLookup, waybackFix bool
+	snapshots                 bool
+	snapshotMapPath           string
+	snapshotAge               time.Duration
+	snapshotBatch             int
+	tui                       bool
+	baselinePath              string
+	compareBase, compareHead  string
+	daemonMode, uptimeMode    bool
+	uptimeHistory             string
+	mail                      bool
+	logFormat, logLevel       string
+	ascii                     bool
+	top                       int
+	format                    string
+	artifactsDir              string
+	captureHeaders, stream    bool
+	maxDuration               time.Duration
+	shard                     shard
+	rerunFailed, otlpEndpoint string
+}
+
+func parseFlags() (options, error) {
+	var o options
+	flag.IntVar(&o.workers, "workers", 16, "concurrent HTTP checks")
+	flag.IntVar(&o.dnsWorkers, "dns-workers", 32, "concurrent DNS lookups")
+	flag.DurationVar(&o.timeout, "timeout", 15*time.Second, "per-request timeout")
+	flag.BoolVar(&o.ignoreRobots, "ignore-robots", false, "check links even where robots.txt disallows it")
+	flag.StringVar(&o.configPath, "config", defaultConfig, "skip and force lists, cache lifetimes")
+	flag.StringVar(&o.cachePath, "cache", defaultCache, "result cache file")
+	flag.BoolVar(&o.noCache, "no-cache", false, "ignore cached results and recheck every link")
+	flag.DurationVar(&o.retryBudget, "retry-budget", time.Minute, "longest total Retry-After wait per link")
+	flag.BoolVar(&o.fix, "fix", false, "rewrite http:// links to https://, permanently redirected links to their target, and strip tracking parameters")
+	flag.BoolVar(&o.preview, "preview", false, "with -fix, -wayback-fix or -snapshots, print the diff without rewriting posts")
+	flag.BoolVar(&o.dryRun, "dry-run", false, "print the requests, file writes and outside services the run would use, and do none of it")
+	flag.BoolVar(&o.waybackLookup, "wayback", false, "look up a Wayback Machine snapshot for each dead link and include it in the report")
+	flag.BoolVar(&o.waybackFix, "wayback-fix", false, "like -wayback, and rewrite dead links to their snapshot, marked (archived)")
+	flag.BoolVar(&o.snapshots, "snapshots", false, "verify the Wayback Machine snapshots in -snapshot-map, replace dead ones and archive links that have none")
+	flag.StringVar(&o.snapshotMapPath, "snapshot-map", defaultSnapshots, "every outbound link's Wayback Machine snapshot")
+	flag.DurationVar(&o.snapshotAge, "snapshot-age", defaultSnapshotAge, "with -snapshots, how long a verified snapshot goes before it is verified again")
+	flag.IntVar(&o.snapshotBatch, "snapshot-batch", 50, "with -snapshots, the most links handled per run; 0 means no limit")
+	flag.BoolVar(&o.tui, "tui", false, "step through the findings interactively after the sweep")
+	flag.StringVar(&o.baselinePath, "baseline", defaultBaseline, "URLs whose findings were reviewed and accepted")
+	flag.StringVar(&o.compareBase, "compare", "", "report only findings that `ref` doesn't already have")
+	flag.StringVar(&o.compareHead, "head", "HEAD", "with -compare, the ref to check against the base")
+	flag.BoolVar(&o.daemonMode, "daemon", false, "run the check groups in linkcheck.yml on their schedules and serve their status")
+	flag.BoolVar(&o.uptimeMode, "uptime", false, "probe the uptime groups in linkcheck.yml once and append the results to -uptime-history")
+	flag.StringVar(&o.uptimeHistory, "uptime-history", defaultUptimeHistory, "uptime history the status page renders from")
+	flag.BoolVar(&o.mail, "mail", false, "with report digest, also mail the digest to the email addresses in linkcheck.yml")
+	flag.StringVar(&o.logFormat, "log-format", "text", "diagnostics format on stderr: text or json")
+	flag.StringVar(&o.logLevel, "log-level", "info", "lowest diagnostics level logged: debug, info, warn or error")
+	flag.BoolVar(&o.ascii, "ascii", false, "draw the report with ASCII characters only")
+	flag.IntVar(&o.top, "top", 0, "print only the `N` largest section and rule failure counts instead of every finding")
+	flag.StringVar(&o.format, "format", "text", "report format on stdout: text, json or csv; report domains takes text, json or html")
+	flag.StringVar(&o.artifactsDir, "artifacts", "", "save the full response of every link that fails a body check (soft 404, missing anchor) under this directory and name the file in the report")
+	flag.BoolVar(&o.captureHeaders, "capture-headers", false, "record each link's Content-Type, Cache-Control, Content-Length and Server in the json and csv reports")
+	flag.BoolVar(&o.stream, "stream", false, "print each finding as soon as its link is checked instead of a grouped report at the end")
+	flag.DurationVar(&o.maxDuration, "max-duration", 0, "stop sending out requests after this long, checking the likeliest broken links first; 0 means no limit")
+	shardFlag := flag.String("shard", "", "check only slice `i/n` of the unique URLs, for splitting a sweep across parallel jobs")
+	flag.StringVar(&o.rerunFailed, "rerun-failed", "", "re-check only the URLs that failed in this saved -format=json `report`, bypassing the cache for them")
+	flag.StringVar(&o.otlpEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "OTLP/HTTP collector to send traces to, such as http://localhost:4318")
+	flag.Parse()
+
+	var err error
+	o.shard, err = parseShard(*shardFlag)
+	return o, err
+}
+
+// validate rejects flags that pick more than one mode, or an option the
+// mode they pick has no use for.
+func (o options) validate() error {
+	switch {
+	case !slices.Contains(reportFormats, o.format):
+		return fmt.Errorf("unknown -format %q; want text, json or csv", o.format)
+	case o.stream && (o.format != "text" || o.tui || o.waybackLookup || o.waybackFix):
+		return errors.New("-stream prints text findings as they come; it can't be combined with -format, -tui or -wayback")
+	case o.uptimeMode && o.daemonMode:
+		return errors.New("-uptime probes once and -daemon on a schedule; pick one")
+	case o.shard.count > 0 && (o.fix || o.waybackFix || o.tui || o.compareBase != "" || o.daemonMode || o.uptimeMode):
+		return errors.New("-shard splits a sweep; it can't be combined with -fix, -wayback-fix, -tui, -compare, -daemon or -uptime")
+	case o.maxDuration > 0 && (o.fix || o.compareBase != "" || o.daemonMode || o.uptimeMode):
+		return errors.New("-max-duration budgets a sweep; it can't be combined with -fix, -compare, -daemon or -uptime")
+	case o.rerunFailed != "" && (o.fix || o.compareBase != "" || o.daemonMode || o.uptimeMode):
+		return errors.New("-rerun-failed re-checks a sweep's failures; it can't be combined with -fix, -compare, -daemon or -uptime")
+	case o.snapshots && (o.rerunFailed != "" || o.fix || o.waybackLookup || o.waybackFix || o.tui || o.compareBase != "" || o.daemonMode || o.uptimeMode || o.stream || o.shard.count > 0 || o.maxDuration > 0):
+		return errors.New("-snapshots doesn't sweep; it can't be combined with the sweep's modes or options")
+	case o.dryRun && o.tui:
+		return errors.New("-tui is interactive; run -dry-run without it")
+	}
+	return nil
+}
+
+// printPlan prints p to stdout, with the trace export the run would add.
+func (o options) printPlan(p *plan) error {
+	if o.otlpEndpoint != "" {
+		p.service("OTLP/HTTP trace export to %s", o.otlpEndpoint)
+	}
+	return p.print(os.Stdout)
+}
+
+func main() {
+	opts, shardErr := parseFlags()
+	started := time.Now()
+	logger, err := newLogger(os.Stderr, opts.logFormat, opts.logLevel)
+	if err != nil {
+		fatal(err)
+	}
+	slog.SetDefault(logger)
+	if err := cmp.Or(shardErr, opts.validate()); err != nil {
+		fatal(err)
+	}
+	if flag.NArg() > 0 {
+		failed, err := runReport(opts, flag.Args())
+		if err != nil {
+			fatal(err)
+		}
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// An interrupt cancels the checks in flight rather than killing them
+	// mid-write, so a one-off run still saves what it checked.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// A one-off run is a single trace; the daemon starts one per group run.
+	tr := newTracer(opts.otlpEndpoint, "linkcheck")
+	var root *span
+	if !opts.daemonMode {
+		ctx, root = tr.start(ctx, "linkcheck")
+	}
+	r, err := newRun(ctx, opts, started, tr, logger)
+	if err != nil {
+		fatal(err)
+	}
+	failed, err := r.mode()(ctx)
+	// A dry run calls no outside service, the trace collector included.
+	if !opts.dryRun && !opts.daemonMode {
+		r.endTrace(ctx, root)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runReport runs one of the report commands on saved reports, and reports
+// whether the run should fail.
+func runReport(o options, args []string) (bool, error) {
+	unexpected := fmt.Errorf("unexpected arguments %q; the commands are `report diff OLD NEW`, `report merge SHARD...`, `report digest REPORT...` and `report domains REPORT...`", args)
+	if len(args) < 2 || args[0] != "report" {
+		return false, unexpected
+	}
+	if o.dryRun {
+		p := &plan{}
+		p.note("reads %s and writes the %s to stdout", strings.Join(args[2:], ", "), strings.Join(args[:2], " "))
+		if o.mail && args[1] == "digest" {
+			cfg, err := loadConfig(o.configPath)
+			if err != nil {
+				return false, err
+			}
+			p.service("SMTP: mail the digest to %s through %s", strings.Join(cfg.Email.To, ", "), cfg.Email.SMTP)
+		}
+		return false, o.printPlan(p)
+	}
+	switch args[1] {
+	case "merge":
+		return false, runReportMerge(os.Stdout, o.format, args[2:])
+	case "diff":
+		return runReportDiff(os.Stdout, o.format, args[2:])
+	case "domains":
+		return false, runReportDomains(os.Stdout, o.format, args[2:])
+	case "digest":
+		cfg, err := loadConfig(o.configPath)
+		if err != nil {
+			return false, err
+		}
+		return false, runReportDigest(os.Stdout, args[2:], o.uptimeHistory, cfg.Email, o.mail)
+	}
+	return false, unexpected
+}
+
+// resolveAll resolves hosts up front under its own span.
+func (c *checker) resolveAll(ctx context.Context, hosts []string, workers int) {
+	ctx, s := startSpan(ctx, "resolve hosts", "hosts", len(hosts))
+	defer s.finish()
+	c.resolver.resolveAll(ctx, hosts, workers)
+}
+
+// sweep checks each unique URL once with a pool of workers and returns a
+// finding for every occurrence of a URL that failed or was skipped, sorted
+// by file and line. Links whose host is NXDOMAIN fail immediately without
+// an HTTP request.
+func (c *checker) sweep(ctx context.Context, links []link, workers int) []finding {
+	var findings []finding
+	for f := range c.stream(ctx, links, workers) {
+		findings = append(findings, f)
+	}
+	slices.SortFunc(findings, func(a, b finding) int {
+		return cmp.Or(strings.Compare(a.Link.File, b.Link.File), cmp.Compare(a.Link.Line, b.Link.Line), strings.Compare(a.Link.URL, b.Link.URL))
+	})
+	return findings
+}
+
+// stream does sweep's work but sends each finding as soon as its URL has
+// been checked, in no particular order, and closes the channel when the
+// sweep is done. A reader that handles findings one at a time holds none
+// of them, however many links there are. The reader must drain the
+// channel; cancel ctx to cut the sweep short, which stops it sending URLs
+// out and drops the checks in flight rather than reporting them.
+func (c *checker) stream(ctx context.Context, links []link, workers int) <-chan finding {
+	occurrences := map[string][]link{}
+	for _, l := range links {
+		occurrences[l.URL] = append(occurrences[l.URL], l)
+	}
+	results := make(chan finding, max(workers, 1))
+
+	go func() {
+		ctx, s := startSpan(ctx, "sweep", "links", len(links), "workers", workers)
+		start := time.Now()
+		var found atomic.Int64
+		send := func(f finding) {
+			results <- f
+			found.Add(1)
+		}
+		// Tracking parameters take no request to spot.
+		for _, l := range links {
+			if params := trackingIn(l.URL, c.tracking()); len(params) > 0 {
+				send(newFinding(l, ruleTracking, "tracking parameters "+strings.Join(params, ", ")+"; -fix strips them"))
+			}
+		}
+		err := forEach(ctx, uniqueURLs(links), workers, func(ctx context.Context, rawURL string) error {
+			if c.outOfTime(rawURL) {
+				c.unchecked.Add(1)
+				for _, l := range occurrences[rawURL] {
+					send(newFinding(l, ruleUnchecked, "not checked before the -max-duration budget ran out"))
+				}
+				return nil
+			}
+			class, reason := c.cachedCheck(ctx, rawURL)
+			if err := ctx.Err(); err != nil {
+				// A check cut short says nothing about the link.
+				return err
+			}
+			if class == "" {
+				return nil
+			}
+			for _, l := range occurrences[rawURL] {
+				f := newFinding(l, class, reason)
+				f.Artifact = c.artifacts.get(rawURL)
+				send(f)
+			}
+			return nil
+		})
+		if err != nil {
+			c.logger().Warn("sweep cut short", "err", err)
+		}
+		c.logger().Info("sweep done", "links", len(links), "unique", len(occurrences), "findings", found.Load(), "duration", time.Since(start).Round(time.Millisecond))
+		s.finish()
+		close(results)
+	}()
+	return results
+}
+
+// check returns rawURL's result. prev holds validators from an earlier
+// check, which turn the request into a conditional one; a live link returns
+// the validators to keep for next time.
+func (c *checker) check(ctx context.Context, rawURL string, prev validators) result {
+	ctx, s := startSpan(ctx, "check", "url.full", rawURL)
+	defer s.finish()
+	log := c.logger().With("url", rawURL)
+	start := time.Now()
+	r := c.classify(ctx, log, rawURL, prev)
+	s.set("linkcheck.class", cmp.Or(r.class, "ok"))
+	if r.class != "" {
+		s.set("linkcheck.reason", r.reason)
+	}
+	log.Debug("checked", "class", cmp.Or(r.class, "ok"), "reason", r.reason, "duration", time.Since(start).Round(time.Millisecond))
+	return r
+}
+
+// classify does check's work, logging each request to log.
+func (c *checker) classify(ctx context.Context, log *slog.Logger, rawURL string, prev validators) result {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return result{class: ruleHTTP, reason: err.Error()}
+	}
+	host := u.Hostname()
+	if c.resolver.notFound(host) {
+		return result{class: ruleNXDomain, reason: "host does not resolve"}
+	}
+	forced := matchesDomain(host, c.config.Force)
+	if !forced && matchesDomain(host, c.config.Skip) {
+		return result{class: ruleSkipped, reason: "domain is on the skip list"}
+	}
+	if !forced && !c.ignoreRobots && !c.robots.allowed(ctx, u) {
+		return result{class: ruleRobots, reason: "disallowed by robots.txt"}
+	}
+
+	checks := c.bodyChecks()
+	limit := bodyNeed(checks, u)
+	if c.artifacts != nil && limit > 0 {
+		limit = max(limit, artifactBodyLimit)
+	}
+	r, throttles, err := fetchWithRetry(ctx, log, c.client, rawURL, prev, limit, c.retryBudget)
+	c.retries.Add(int64(retried(throttles, c.retryBudget)))
+	if err != nil {
+		return result{class: ruleHTTP, reason: err.Error(), throttles: throttles}
+	}
+	res := result{throttles: throttles, latency: r.elapsed}
+	if c.headers != nil {
+		res.headers = captureHeaders(r)
+	}
+	if _, ok := retryAfter(r, time.Now()); ok {
+		last := throttles[len(throttles)-1]
+		res.class = ruleThrottled
+		res.reason = fmt.Sprintf("HTTP %d, Retry-After %s exceeds the %s retry budget", r.status, last.Wait, c.retryBudget)
+	} else if r.status >= 400 {
+		res.class, res.reason = ruleHTTP, fmt.Sprintf("HTTP %d", r.status)
+	} else if class, reason, ok := inspectBody(checks, u, r); ok {
+		res.class, res.reason = class, reason
+		if c.artifacts != nil {
+			if _, err := c.artifacts.save(rawURL, r); err != nil {
+				log.Warn("response not saved", "err", err)
+			}
+		}
+	} else if drifted(rawURL, r.finalURL) {
+		res.class, res.reason = ruleDrift, "redirects to "+r.finalURL
+	} else if ref, ok := parseCodeRef(u); ok && c.code != nil && r.status != http.StatusNotModified {
+		res.class, res.reason, _ = c.code.check(ctx, c.client, log, rawURL, ref)
+		if res.class == "" {
+			res.validators = r.validators
+		}
+	} else {
+		res.validators = r.validators
+	}
+	return res
+}
+
+// cachedCheck returns rawURL's fresh cached result, or checks it and caches
+// the outcome. An expired entry still lends its validators, so the recheck
+// can be answered with a cheap 304. Skips and throttles aren't cached; the
+// lists and robots.txt may change, and a throttled link deserves another
+// try on the next run. Throttling is recorded in the link's history either
+// way. A check cancelled with ctx is neither: the caller learns of it from
+// ctx.
+func (c *checker) cachedCheck(ctx context.Context, rawURL string) (class, reason string) {
+	entry, fresh := c.cache.fresh(rawURL)
+	if fresh {
+		c.cacheHits.Add(1)
+		c.headers.record(rawURL, entry.Headers)
+		c.latencies.record(rawURL, entry.Latency)
+		return entry.Class, entry.Reason
+	}
+	r := c.check(ctx, rawURL, entry.validators)
+	if ctx.Err() != nil {
+		// Cancelled mid-request: nothing worth remembering.
+		return "", ""
+	}
+	if r.class == "" && r.headers != nil {
+		// A 304 leaves most headers out; the ones cached with the page
+		// still describe it.
+		for name, value := range entry.Headers {
+			if _, ok := r.headers[name]; !ok {
+				r.headers[name] = value
+			}
+		}
+	}
+	c.headers.record(rawURL, r.headers)
+	c.latencies.record(rawURL, r.latency)
+	c.remember(rawURL, r)
+	return r.class, r.reason
+}
+
+// remember caches r unless it is a skip or a throttle, and records any
+// throttling in rawURL's history.
+func (c *checker) remember(rawURL string, r result) {
+	c.cache.recordThrottles(rawURL, r.throttles)
+	if r.class != ruleSkipped && r.class != ruleRobots && r.class != ruleThrottled {
+		c.cache.store(rawURL, r.class, r.reason, r.validators, r.headers, r.latency)
+	}
+}
+
+// stale returns the links without a fresh cached result.
+func stale(links []link, cache *resultCache) []link {
+	var out []link
+	for _, l := range links {
+		if _, ok := cache.fresh(l.URL); !ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// uniqueURLs returns each distinct URL in links once, in first-seen order.
+func uniqueURLs(links []link) []string {
+	seen := map[string]bool{}
+	var urls []string
+	for _, l := range links {
+		if !seen[l.URL] {
+			seen[l.URL] = true
+			urls = append(urls, l.URL)
+		}
+	}
+	return urls
+}
+
+// reportGroup is a rule's section of the report.
+type reportGroup struct{ rule, title string }
+
+// reportGroups orders rules for display, NXDOMAIN first: a dead domain needs
+// a different fix than a dead page. Skips come last.
+var reportGroups = []reportGroup{
+	{ruleNXDomain, "hosts that no longer resolve"},
+	{ruleHTTP, "broken links"},
+	{ruleSuspect, "live but probably dead"},
+	{ruleDrift, "redirected to another domain"},
+	{ruleAnchor, "missing anchors"},
+	{ruleCode, "cited code that moved"},
+	{ruleTracking, "tracking parameters"},
+	{ruleThrottled, "still rate limited after retrying"},
+	{ruleUnchecked, "not reached within -max-duration"},
+	{ruleRobots, "skipped by robots.txt"},
+	{ruleSkipped, "skipped by linkcheck.yml"},
+}
+
+// textReport is the report on stdout: every finding grouped by rule, then
+// the failures counted by section and rule. With top > 0 it is only the top
+// counts, for runs with too many failures to read line by line.
+func textReport(findings []finding, st style, top int) string {
+	if top > 0 {
+		return summary(findings, top, st)
+	}
+	return report(findings, st) + summary(findings, 0, st)
+}
+
+// streamReport prints each finding from findings to w as it arrives,
+// leaving out baselined URLs and what lint_ignore suppresses, and keeps
+// only the counts: the findings per rule, whether any failed, and how many
+// the baseline hid. The few suppressed findings are returned for the
+// summary.
+func streamReport(w io.Writer, findings <-chan finding, b baseline, ig ignores) (counts map[string]int, failed bool, hidden int, suppressed []finding) {
+	counts = map[string]int{}
+	for f := range findings {
+		if b[f.Link.URL] {
+			hidden++
+			continue
+		}
+		if ig.suppresses(f) {
+			suppressed = append(suppressed, f)
+			continue
+		}
+		fmt.Fprintf(w, "%s [%s]\n", f, f.Rule)
+		counts[f.Rule]++
+		failed = failed || f.failed()
+	}
+	return counts, failed, hidden, suppressed
+}
+
+// report renders findings grouped by rule, with group titles colored by
+// severity when st allows: red for errors, yellow for warnings, dim for
+// info.
+func report(findings []finding, st style) string {
+	var b strings.Builder
+	for _, group := range reportGroups {
+		var lines []string
+		for _, f := range findings {
+			if f.Rule == group.rule {
+				lines = append(lines, f.String())
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n  %s\n", st.paint(fmt.Sprintf("%s (%d)", group.title, len(lines)), groupColor(group.rule)...), strings.Join(lines, "\n  "))
+	}
+	return b.String()
+}
+
+func groupColor(rule string) []string {
+	switch severityOf(rule) {
+	case severityError:
+		return []string{ansiBold, ansiRed}
+	case severityWarning:
+		return []string{ansiYellow}
+	}
+	return []string{ansiDim}
+}
+
+func fatal(err error) {
+	slog.Error("linkcheck failed", "err", err)
+	os.Exit(1)
+}
+
+// interrupted ends a run whose sweep ctx cut short, after saving the links
+// checked so far so the next run doesn't request them again. A partial
+// sweep's summary would read as a clean bill for the links it never got
+// to, so the run ends without one.
+func interrupted(ctx context.Context, c *checker, cachePath string) {
+	if ctx.Err() == nil {
+		return
+	}
+	if err := c.cache.save(cachePath); err != nil {
+		fatal(err)
+	}
+	fatal(errors.New("interrupted; the links checked so far are cached"))
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSweepClassifiesFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone":
+			http.NotFound(w, r)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}
+	}))
+	defer server.Close()
+
+	resolver := newDNSCache(func(ctx context.Context, host string) ([]string, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	})
+	resolver.resolveAll(context.Background(), []string{"gone.example"}, 1)
+
+	links := []link{
+		{URL: server.URL + "/ok", File: "a.md", Line: 1},
+		{URL: server.URL + "/gone", File: "a.md", Line: 2},
+		{URL: server.URL + "/no-head", File: "a.md", Line: 3},
+		{URL: "https://gone.example/page", File: "b.md", Line: 1},
+	}
+	client := &http.Client{Transport: &http.Transport{DialContext: resolver.dialContext}}
+	c := &checker{client: client, resolver: resolver, robots: newRobotsCache(client)}
+	got := c.sweep(context.Background(), links, 2)
+
+	want := []finding{
+		newFinding(links[1], ruleHTTP, "HTTP 404"),
+		newFinding(links[3], ruleNXDomain, "host does not resolve"),
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("sweep =\n  %v\nwant\n  %v", got, want)
+	}
+}
+
+func TestSweepSkipsRobotsDisallowedLinks(t *testing.T) {
+	requested := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		requested[r.URL.Path] = true
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	links := []link{{URL: server.URL + "/private/page", File: "a.md", Line: 1}}
+	resolver := newDNSCache(netLookup)
+
+	c := &checker{client: server.Client(), resolver: resolver, robots: newRobotsCache(server.Client())}
+	got := c.sweep(context.Background(), links, 1)
+	want := []finding{newFinding(links[0], ruleRobots, "disallowed by robots.txt")}
+	if !slices.Equal(got, want) || requested["/private/page"] {
+		t.Fatalf("sweep = %v, want %v without fetching the page", got, want)
+	}
+	if slices.ContainsFunc(got, finding.failed) {
+		t.Fatal("robots skips should not fail the sweep")
+	}
+
+	c.ignoreRobots = true
+	got = c.sweep(context.Background(), links, 1)
+	want = []finding{newFinding(links[0], ruleHTTP, "HTTP 404")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("sweep with -ignore-robots = %v, want %v", got, want)
+	}
+}
+
+func TestSweepChecksEachURLOnceAndHonorsDomainLists(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.Host+r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /\n"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver := newDNSCache(func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	})
+	client := &http.Client{Transport: &http.Transport{DialContext: resolver.dialContext}}
+	c := &checker{
+		client:   client,
+		resolver: resolver,
+		robots:   newRobotsCache(client),
+		config:   config{Skip: []string{"blocked.example"}, Force: []string{"forced.example"}},
+	}
+
+	blocked := "http://www.blocked.example:" + port + "/post"
+	forced := "http://forced.example:" + port + "/dead"
+	links := []link{
+		{URL: blocked, File: "a.md", Line: 1},
+		{URL: forced, File: "a.md", Line: 2},
+		{URL: forced, File: "b.md", Line: 7},
+	}
+	got := c.sweep(context.Background(), links, 4)
+	want := []finding{
+		newFinding(links[0], ruleSkipped, "domain is on the skip list"),
+		newFinding(links[1], ruleHTTP, "HTTP 404"),
+		newFinding(links[2], ruleHTTP, "HTTP 404"),
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("sweep =\n  %v\nwant\n  %v", got, want)
+	}
+	if n := hits["forced.example:"+port+"/dead"]; n != 1 {
+		t.Errorf("duplicate URL fetched %d times, want 1", n)
+	}
+	if n := hits["www.blocked.example:"+port+"/post"]; n != 0 {
+		t.Errorf("skipped URL fetched %d times, want 0", n)
+	}
+}
+
+func TestStreamReportPrintsFindingsAsTheyArrive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	links := []link{
+		{URL: server.URL + "/ok", File: "a.md", Line: 1},
+		{URL: server.URL + "/gone", File: "a.md", Line: 2, Column: 5},
+		{URL: server.URL + "/accepted", File: "b.md", Line: 1},
+	}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true}
+	var out strings.Builder
+	counts, failed, hidden, _ := streamReport(&out, c.stream(context.Background(), links, 2), baseline{links[2].URL: true}, nil)
+
+	want := "a.md:2:5: " + server.URL + "/gone: HTTP 404 [http]\n"
+	if out.String() != want || !failed || hidden != 1 || counts[ruleHTTP] != 1 {
+		t.Fatalf("streamReport printed %q (failed %t, hidden %d, counts %v), want %q", out.String(), failed, hidden, counts, want)
+	}
+}
+
+func TestValidateRejectsFlagsThatDontCombine(t *testing.T) {
+	sharded := shard{index: 1, count: 2}
+	for _, tc := range []struct {
+		name string
+		o    options
+		want string
+	}{
+		{"sweep", options{format: "text"}, ""},
+		{"sharded json sweep", options{format: "json", shard: sharded}, ""},
+		{"unknown format", options{format: "yaml"}, "unknown -format"},
+		{"stream as json", options{format: "json", stream: true}, "-stream"},
+		{"uptime and daemon", options{format: "text", uptimeMode: true, daemonMode: true}, "pick one"},
+		{"sharded fix", options{format: "text", fix: true, shard: sharded}, "-shard"},
+		{"budgeted compare", options{format: "text", compareBase: "main", maxDuration: time.Minute}, "-max-duration"},
+		{"snapshots with -stream", options{format: "text", snapshots: true, stream: true}, "-snapshots"},
+		{"dry-run tui", options{format: "text", dryRun: true, tui: true}, "-tui"},
+	} {
+		err := tc.o.validate()
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%s: validate() = %v, want an error mentioning %q", tc.name, err, tc.want)
+		}
+	}
+}
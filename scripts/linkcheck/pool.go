@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// forEach runs fn on every item, at most workers at a time, handing items
+// out as workers free up, so a run holds no more than workers goroutines
+// however many items there are. The first error fn returns cancels ctx for
+// the calls in flight, no further items start, and forEach returns that
+// error; a cancelled ctx stops it the same way and returns ctx's error.
+func forEach[T any](ctx context.Context, items []T, workers int, fn func(context.Context, T) error) error {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(workers, 1))
+	for _, item := range items {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error { return fn(gctx, item) })
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
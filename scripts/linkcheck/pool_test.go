@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachBoundsConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	var done atomic.Int32
+	items := make([]int, 50)
+	err := forEach(context.Background(), items, 4, func(ctx context.Context, _ int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		done.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEach: %v", err)
+	}
+	if got := done.Load(); got != int32(len(items)) {
+		t.Errorf("ran %d items, want %d", got, len(items))
+	}
+	if got := peak.Load(); got > 4 {
+		t.Errorf("%d items ran at once, want at most 4", got)
+	}
+}
+
+func TestForEachStopsAtTheFirstError(t *testing.T) {
+	boom := errors.New("boom")
+	var started atomic.Int32
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	err := forEach(context.Background(), items, 1, func(ctx context.Context, i int) error {
+		started.Add(1)
+		if i == 2 {
+			return boom
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	if got := started.Load(); got >= int32(len(items)) {
+		t.Errorf("started all %d items after an error", got)
+	}
+}
+
+func TestForEachStartsNothingOnceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var started atomic.Int32
+	err := forEach(ctx, []string{"a", "b", "c"}, 2, func(ctx context.Context, _ string) error {
+		started.Add(1)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if got := started.Load(); got != 0 {
+		t.Errorf("started %d items on a cancelled context", got)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxRedirects bounds a redirect chain the fixer is willing to follow.
+const maxRedirects = 10
+
+// trackingParams are query parameters that tag a visit rather than name a
+// resource. A redirect target carrying any of them isn't canonical.
+var trackingParams = []string{"fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_ga", "ref", "ref_src", "si"}
+
+// permanentRedirects returns, for each link whose redirect chain is made
+// only of permanent hops (301 and 308) ending at a live page, the URL the
+// chain ends at. Targets with tracking parameters, on a different
+// registrable domain, or at a site's root when the link wasn't are left
+// out: those need a human to decide.
+func (c *checker) permanentRedirects(ctx context.Context, links []link, workers int) map[string]string {
+	var mu sync.Mutex
+	targets := map[string]string{}
+	forEach(ctx, uniqueURLs(links), workers, func(ctx context.Context, rawURL string) error {
+		if target, ok := c.permanentTarget(ctx, rawURL); ok {
+			mu.Lock()
+			targets[rawURL] = target
+			mu.Unlock()
+		}
+		return nil
+	})
+	return targets
+}
+
+// permanentTarget follows rawURL's redirects one hop at a time and returns
+// where they end if every hop was permanent and the target is canonical.
+func (c *checker) permanentTarget(ctx context.Context, rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := u.Hostname()
+	if c.resolver.notFound(host) || (!matchesDomain(host, c.config.Force) && matchesDomain(host, c.config.Skip)) {
+		return "", false
+	}
+	if !c.ignoreRobots && !c.robots.allowed(ctx, u) {
+		return "", false
+	}
+
+	client := *c.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	current := u
+	for hops := 0; ; hops++ {
+		if hops > maxRedirects {
+			return "", false
+		}
+		status, location, err := hop(ctx, &client, current.String())
+		if err != nil {
+			return "", false
+		}
+		switch status {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			next, err := current.Parse(location)
+			if err != nil {
+				return "", false
+			}
+			current = next
+			continue
+		}
+		if hops == 0 || status < 200 || status >= 300 {
+			return "", false
+		}
+		break
+	}
+
+	target := current.String()
+	if target == rawURL || hasTrackingParams(current) || drifted(rawURL, target) {
+		return "", false
+	}
+	if strings.Trim(current.Path, "/") == "" && strings.Trim(u.Path, "/") != "" {
+		return "", false
+	}
+	return target, true
+}
+
+// hop requests rawURL without following redirects and returns the status
+// and Location header.
+func hop(ctx context.Context, client *http.Client, rawURL string) (status int, location string, err error) {
+	req, err := newRequest(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, bodyLimit))
+	location = resp.Header.Get("Location")
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 && location == "" {
+		return 0, "", errors.New("redirect without a Location")
+	}
+	return resp.StatusCode, location, nil
+}
+
+func hasTrackingParams(u *url.URL) bool {
+	for name := range u.Query() {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "utm_") || slices.Contains(trackingParams, name) {
+			return true
+		}
+	}
+	return false
+}
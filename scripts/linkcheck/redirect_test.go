@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPermanentRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+		case "/older":
+			http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+		case "/temporary":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/mixed":
+			http.Redirect(w, r, "/temporary", http.StatusMovedPermanently)
+		case "/tracked":
+			http.Redirect(w, r, "/new?utm_source=feed", http.StatusMovedPermanently)
+		case "/retired":
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		case "/sold":
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://buyer.example:"+port+"/new", http.StatusMovedPermanently)
+		case "/to-dead":
+			http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+		case "/gone":
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	resolver := newDNSCache(func(ctx context.Context, host string) ([]string, error) {
+		return []string{"127.0.0.1"}, nil
+	})
+	client := &http.Client{Transport: &http.Transport{DialContext: resolver.dialContext}}
+	c := &checker{client: client, resolver: resolver, robots: newRobotsCache(client)}
+
+	base := "http://blog.example:" + port
+	var links []link
+	for i, path := range []string{"/old", "/temporary", "/mixed", "/tracked", "/retired", "/sold", "/to-dead", "/new"} {
+		links = append(links, link{URL: base + path, File: "a.md", Line: i + 1})
+	}
+	got := c.permanentRedirects(context.Background(), links, 4)
+	if len(got) != 1 || got[base+"/old"] != base+"/new" {
+		t.Fatalf("permanentRedirects = %v; want only /old rewritten to /new", got)
+	}
+}
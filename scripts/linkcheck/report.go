@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// reportFormats are the values -format accepts.
+var reportFormats = []string{"text", "json", "csv"}
+
+// ruleOK marks a live link in the json and csv reports.
+const ruleOK = "ok"
+
+// reportRow is one link occurrence in the json and csv reports.
+type reportRow struct {
+	URL      string            `json:"url"`
+	File     string            `json:"file"`
+	Line     int               `json:"line"`
+	Column   int               `json:"column"`
+	Rule     string            `json:"rule"`
+	Severity severity          `json:"severity,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Archive  string            `json:"archive,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Artifact string            `json:"artifact,omitempty"`
+	// LatencyMS is how long the URL took to answer, in milliseconds, when
+	// the run or the cache measured it.
+	LatencyMS int64 `json:"latencyMs,omitempty"`
+}
+
+// reportRows lists every link occurrence in links, with its finding's rule,
+// severity and message or just the rule ok, and the URL's captured headers
+// and latency. Baselined URLs are left out, as in the text report.
+func reportRows(links []link, findings []finding, b baseline, headers *headerLog, latencies *latencyLog) []reportRow {
+	byLink := map[link]finding{}
+	for _, f := range findings {
+		// A link can draw more than one finding; a failure outranks the
+		// rest.
+		if prev, ok := byLink[f.Link]; !ok || !prev.failed() {
+			byLink[f.Link] = f
+		}
+	}
+	rows := []reportRow{}
+	for _, l := range links {
+		if b[l.URL] {
+			continue
+		}
+		row := reportRow{URL: l.URL, File: l.File, Line: l.Line, Column: l.Column, Rule: ruleOK, Headers: headers.get(l.URL), LatencyMS: latencies.get(l.URL).Milliseconds()}
+		if f, ok := byLink[l]; ok {
+			row.Rule, row.Severity, row.Message, row.Archive, row.Artifact = f.Rule, f.Severity, f.Message, f.Archive, f.Artifact
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// jsonReport is the json report: what the run cost, when one made it, and
+// a row per link occurrence.
+type jsonReport struct {
+	Usage *usage      `json:"usage,omitempty"`
+	Links []reportRow `json:"links"`
+}
+
+// writeReport writes rows as json or csv. The csv gets a column per
+// captured header when withHeaders is set; only the json has room for
+// use, the run's usage.
+func writeReport(w io.Writer, format string, rows []reportRow, withHeaders bool, use *usage) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonReport{Usage: use, Links: rows})
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"url", "file", "line", "column", "rule", "severity", "message", "archive"}
+		if withHeaders {
+			for _, name := range capturedHeaders {
+				header = append(header, strings.ToLower(name))
+			}
+		}
+		cw.Write(header)
+		for _, row := range rows {
+			record := []string{row.URL, row.File, strconv.Itoa(row.Line), strconv.Itoa(row.Column), row.Rule, string(row.Severity), row.Message, row.Archive}
+			if withHeaders {
+				for _, name := range capturedHeaders {
+					record = append(record, row.Headers[name])
+				}
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureHeadersReachesReports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	links := []link{
+		{URL: server.URL + "/ok", File: "a.md", Line: 1},
+		{URL: server.URL + "/gone", File: "a.md", Line: 2},
+		{URL: server.URL + "/accepted", File: "b.md", Line: 1},
+	}
+	cache := &resultCache{okTTL: time.Hour, failedTTL: time.Hour, now: time.Now, entries: map[string]cacheEntry{}}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, cache: cache, headers: newHeaderLog()}
+	findings := c.sweep(context.Background(), links, 2)
+
+	rows := reportRows(links, findings, baseline{links[2].URL: true}, c.headers, nil)
+	if len(rows) != 2 || rows[0].Rule != ruleOK || rows[1].Rule != ruleHTTP {
+		t.Fatalf("rows = %+v, want the live link, the 404, and no baselined link", rows)
+	}
+	if got := rows[0].Headers; got["Content-Type"] != "text/plain" || got["Cache-Control"] != "max-age=60" || got["Content-Length"] != "5" || got["Server"] != "test" {
+		t.Fatalf("captured headers = %v", got)
+	}
+
+	// A conditional recheck answers 304 without the content headers; the
+	// cached ones fill in.
+	cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	c.headers = newHeaderLog()
+	c.sweep(context.Background(), links[:1], 1)
+	if got := c.headers.get(links[0].URL); got["Content-Type"] != "text/plain" || got["Server"] != "test" {
+		t.Fatalf("headers after 304 = %v", got)
+	}
+
+	var out strings.Builder
+	if err := writeReport(&out, "csv", rows, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "url,file,line,column,rule,severity,message,archive,content-type,cache-control,content-length,server\n" +
+		server.URL + "/ok,a.md,1,0,ok,,,,text/plain,max-age=60,5,test\n" +
+		server.URL + "/gone,a.md,2,0,http,error,HTTP 404,,text/plain,max-age=60,5,test\n"
+	if out.String() != wantCSV {
+		t.Fatalf("csv =\n%s\nwant\n%s", out.String(), wantCSV)
+	}
+
+	out.Reset()
+	if err := writeReport(&out, "json", rows, true, &usage{Requests: 3, Bytes: 15}); err != nil {
+		t.Fatal(err)
+	}
+	var report jsonReport
+	err := json.Unmarshal([]byte(out.String()), &report)
+	decoded := report.Links
+	if err != nil || report.Usage == nil || report.Usage.Requests != 3 || decoded[1].Message != "HTTP 404" || decoded[1].Severity != severityError || decoded[1].Headers["Server"] != "test" {
+		t.Fatalf("json = %s (%v)", out.String(), err)
+	}
+}
+
+func TestHeadersStayOffByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test")
+	}))
+	defer server.Close()
+
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true}
+	if r := c.check(context.Background(), server.URL, validators{}); r.headers != nil {
+		t.Fatalf("headers captured without -capture-headers: %v", r.headers)
+	}
+}
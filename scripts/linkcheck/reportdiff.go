@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// reportDiff is what changed between two saved -format=json reports. Only
+// failures count as broken; a row is matched by URL and rule, not position,
+// so edits that shift lines don't show up as changes.
+type reportDiff struct {
+	NewlyBroken []reportRow `json:"newlyBroken"`
+	NewlyFixed  []reportRow `json:"newlyFixed"`
+	StillBroken []reportRow `json:"stillBroken"`
+}
+
+// runReportDiff implements `linkcheck report diff OLD NEW`. It reports
+// whether anything newly broke.
+func runReportDiff(w io.Writer, format string, args []string) (bool, error) {
+	if len(args) != 2 {
+		return false, fmt.Errorf("usage: linkcheck [-format text|json|csv] report diff OLD.json NEW.json")
+	}
+	old, err := loadReport(args[0])
+	if err != nil {
+		return false, err
+	}
+	current, err := loadReport(args[1])
+	if err != nil {
+		return false, err
+	}
+	d := diffReports(old, current)
+	return len(d.NewlyBroken) > 0, d.write(w, format)
+}
+
+func loadReport(path string) ([]reportRow, error) {
+	r, err := readReport(path)
+	return r.Links, err
+}
+
+// readReport reads a -format=json report, including one saved before
+// reports carried their usage, when they were a bare array of rows.
+func readReport(path string) (jsonReport, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return jsonReport{}, err
+	}
+	var r jsonReport
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		err = json.Unmarshal(raw, &r.Links)
+	} else {
+		err = json.Unmarshal(raw, &r)
+	}
+	if err != nil {
+		return jsonReport{}, fmt.Errorf("parse %s: %w; want a linkcheck -format=json report", path, err)
+	}
+	return r, nil
+}
+
+func diffReports(old, current []reportRow) reportDiff {
+	type key struct{ url, rule string }
+	broken := func(rows []reportRow) map[key]bool {
+		set := map[key]bool{}
+		for _, row := range rows {
+			if row.Severity == severityError {
+				set[key{row.URL, row.Rule}] = true
+			}
+		}
+		return set
+	}
+	wasBroken, isBroken := broken(old), broken(current)
+
+	d := reportDiff{NewlyBroken: []reportRow{}, NewlyFixed: []reportRow{}, StillBroken: []reportRow{}}
+	for _, row := range current {
+		switch k := (key{row.URL, row.Rule}); {
+		case !isBroken[k]:
+		case wasBroken[k]:
+			d.StillBroken = append(d.StillBroken, row)
+		default:
+			d.NewlyBroken = append(d.NewlyBroken, row)
+		}
+	}
+	for _, row := range old {
+		if k := (key{row.URL, row.Rule}); wasBroken[k] && !isBroken[k] {
+			d.NewlyFixed = append(d.NewlyFixed, row)
+		}
+	}
+	return d
+}
+
+func (d reportDiff) write(w io.Writer, format string) error {
+	groups := []struct {
+		change string
+		rows   []reportRow
+	}{
+		{"newly broken", d.NewlyBroken},
+		{"newly fixed", d.NewlyFixed},
+		{"still broken", d.StillBroken},
+	}
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"change", "url", "file", "line", "column", "rule", "message"})
+		for _, g := range groups {
+			for _, row := range g.rows {
+				cw.Write([]string{g.change, row.URL, row.File, strconv.Itoa(row.Line), strconv.Itoa(row.Column), row.Rule, row.Message})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	var b strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&b, "%s (%d)\n", g.change, len(g.rows))
+		for _, row := range g.rows {
+			fmt.Fprintf(&b, "  %s:%d:%d: %s: %s\n", row.File, row.Line, row.Column, row.URL, row.Message)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffReportsMatchesByURLAndRule(t *testing.T) {
+	row := func(url, file string, line int, rule string) reportRow {
+		return reportRow{URL: url, File: file, Line: line, Rule: rule, Severity: severityOf(rule), Message: rule}
+	}
+	old := []reportRow{
+		row("https://fixed.example/", "a.md", 1, ruleHTTP),
+		row("https://still.example/", "a.md", 2, ruleHTTP),
+		row("https://warned.example/", "a.md", 3, ruleSuspect),
+	}
+	current := []reportRow{
+		row("https://still.example/", "a.md", 9, ruleHTTP),
+		row("https://fixed.example/", "a.md", 1, ruleOK),
+		row("https://new.example/", "b.md", 1, ruleNXDomain),
+		row("https://warned.example/", "a.md", 3, ruleHTTP),
+	}
+	d := diffReports(old, current)
+	urls := func(rows []reportRow) string {
+		var out []string
+		for _, r := range rows {
+			out = append(out, r.URL)
+		}
+		return strings.Join(out, " ")
+	}
+	if got := urls(d.NewlyBroken); got != "https://new.example/ https://warned.example/" {
+		t.Errorf("newly broken = %q", got)
+	}
+	if got := urls(d.NewlyFixed); got != "https://fixed.example/" {
+		t.Errorf("newly fixed = %q", got)
+	}
+	if got := urls(d.StillBroken); got != "https://still.example/" || d.StillBroken[0].Line != 9 {
+		t.Errorf("still broken = %+v, want the new report's row", d.StillBroken)
+	}
+}
+
+func TestRunReportDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	old := `[{"url":"https://a.example/","file":"a.md","line":1,"rule":"http","severity":"error","message":"HTTP 404"}]`
+	if err := os.WriteFile(oldPath, []byte(old), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newPath, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	regressed, err := runReportDiff(&out, "json", []string{oldPath, newPath})
+	if err != nil || regressed {
+		t.Fatalf("regressed = %t, err = %v", regressed, err)
+	}
+	var d reportDiff
+	if err := json.Unmarshal([]byte(out.String()), &d); err != nil || len(d.NewlyFixed) != 1 || d.NewlyBroken == nil {
+		t.Fatalf("json = %s (%v)", out.String(), err)
+	}
+
+	out.Reset()
+	regressed, err = runReportDiff(&out, "text", []string{newPath, oldPath})
+	if err != nil || !regressed || !strings.Contains(out.String(), "newly broken (1)\n  a.md:1:0: https://a.example/: HTTP 404\n") {
+		t.Fatalf("regressed = %t, err = %v, text = %q", regressed, err, out.String())
+	}
+
+	os.WriteFile(newPath, []byte("not json"), 0o644)
+	if _, err := runReportDiff(&out, "text", []string{oldPath, newPath}); err == nil {
+		t.Fatal("a malformed report diffed cleanly")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// failedURLs returns the URLs that failed in a saved report.
+func failedURLs(rows []reportRow) map[string]bool {
+	failed := map[string]bool{}
+	for _, row := range rows {
+		if row.Severity == severityError {
+			failed[row.URL] = true
+		}
+	}
+	return failed
+}
+
+// rerunLinks keeps the links to the URLs in failed, for -rerun-failed, and
+// returns the failed URLs no post cites anymore, sorted: those were fixed
+// by editing the post, and there is nothing to re-check.
+func rerunLinks(links []link, failed map[string]bool) (kept []link, gone []string) {
+	cited := map[string]bool{}
+	for _, l := range links {
+		if failed[l.URL] {
+			kept = append(kept, l)
+			cited[l.URL] = true
+		}
+	}
+	for rawURL := range failed {
+		if !cited[rawURL] {
+			gone = append(gone, rawURL)
+		}
+	}
+	slices.Sort(gone)
+	return kept, gone
+}
+
+// rerunSummary says what -rerun-failed took from report: how many of its
+// failed URLs are re-checked and which are no longer cited.
+func rerunSummary(report string, rechecked int, gone []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "re-checking %d %s that failed in %s\n", rechecked, plural(rechecked, "URL"), report)
+	if len(gone) > 0 {
+		fmt.Fprintf(&b, "%d no longer cited: %s\n", len(gone), strings.Join(gone, ", "))
+	}
+	return b.String()
+}
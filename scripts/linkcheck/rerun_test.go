@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"rednafi.com/internal/webtest"
+)
+
+func TestRerunFailedRechecksOnlyTheFailures(t *testing.T) {
+	site := webtest.Site(t, map[string]string{
+		"/fixed/": webtest.Page("Fixed", "<p>Back up.</p>"),
+		"/fine/":  webtest.Page("Fine", "<p>Always up.</p>"),
+	})
+	links := []link{
+		{URL: site.URL + "/fixed/", File: "a.md", Line: 1},
+		{URL: site.URL + "/fine/", File: "a.md", Line: 2},
+		{URL: site.URL + "/still-broken/", File: "b.md", Line: 1},
+		{URL: site.URL + "/fixed/", File: "b.md", Line: 2},
+	}
+	previous := []reportRow{
+		{URL: site.URL + "/fixed/", Rule: ruleHTTP, Severity: severityError},
+		{URL: site.URL + "/fine/", Rule: ruleOK},
+		{URL: site.URL + "/still-broken/", Rule: ruleHTTP, Severity: severityError},
+		{URL: site.URL + "/edited-out/", Rule: ruleHTTP, Severity: severityError},
+		{URL: site.URL + "/slow/", Rule: ruleSuspect, Severity: severityWarning},
+	}
+
+	kept, gone := rerunLinks(links, failedURLs(previous))
+	if len(kept) != 3 || slices.ContainsFunc(kept, func(l link) bool { return strings.HasSuffix(l.URL, "/fine/") }) {
+		t.Fatalf("kept = %v, want every occurrence of the two failed URLs still cited", kept)
+	}
+	if want := []string{site.URL + "/edited-out/"}; !slices.Equal(gone, want) {
+		t.Fatalf("gone = %q, want %q", gone, want)
+	}
+
+	// The failures are cached and fresh; the rerun has to look past that.
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range previous[:3] {
+		cache.store(row.URL, row.Rule, "HTTP 404", validators{}, nil, 0)
+	}
+	for _, rawURL := range uniqueURLs(kept) {
+		cache.expire(rawURL)
+	}
+	c := &checker{client: site.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true, cache: cache}
+	findings := c.sweep(context.Background(), kept, 2)
+	if len(findings) != 1 || !strings.HasSuffix(findings[0].Link.URL, "/still-broken/") {
+		t.Fatalf("findings = %v, want only /still-broken/", findings)
+	}
+	if site.Hits("/fine/") != 0 || site.Hits("/fixed/") != 1 {
+		t.Fatalf("hits: /fine/ %d, /fixed/ %d; want only the failures re-checked, once per URL", site.Hits("/fine/"), site.Hits("/fixed/"))
+	}
+	if entry, fresh := cache.fresh(site.URL + "/fixed/"); !fresh || entry.Class != "" {
+		t.Fatalf("cache after rerun = %+v, fresh %v; want /fixed/ cached alive", entry, fresh)
+	}
+
+	want := "re-checking 2 URLs that failed in report.json\n1 no longer cited: " + site.URL + "/edited-out/\n"
+	if got := rerunSummary("report.json", 2, gone); got != want {
+		t.Fatalf("rerunSummary = %q, want %q", got, want)
+	}
+}
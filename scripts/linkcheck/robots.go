@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// robotsToken is the product token robots.txt groups are matched against.
+const robotsToken = "rednafi-linkcheck"
+
+// robotsRule is one Allow or Disallow line. Its pattern length decides
+// precedence; re is the compiled pattern.
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+func newRobotsRule(allow bool, pattern string) robotsRule {
+	return robotsRule{allow: allow, pattern: pattern, re: robotsPattern(pattern)}
+}
+
+// robotsPattern compiles a robots.txt path pattern, where * matches any run
+// of characters and a trailing $ anchors the end.
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// robotsPolicy is the rule group that applies to this crawler on one host.
+type robotsPolicy struct {
+	rules []robotsRule
+}
+
+// allowAll is the policy for hosts without a usable robots.txt.
+var allowAll = robotsPolicy{}
+
+// disallowAll is the policy for hosts whose robots.txt errors, which RFC 9309
+// treats as a request to stay away entirely.
+var disallowAll = robotsPolicy{rules: []robotsRule{newRobotsRule(false, "/")}}
+
+// allowed applies the most specific matching rule to path, with Allow
+// winning ties, per RFC 9309.
+func (p robotsPolicy) allowed(path string) bool {
+	best, allow := -1, true
+	for _, rule := range p.rules {
+		if rule.pattern == "" || !rule.re.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allow = len(rule.pattern), rule.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots returns the rules of the group addressed to token, falling
+// back to the * group. Consecutive user-agent lines share one group.
+func parseRobots(r io.Reader, token string) robotsPolicy {
+	token = strings.ToLower(token)
+	var specific, wildcard []robotsRule
+	var agents []string
+	var inRules, hasSpecific bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			rule := newRobotsRule(key == "allow", value)
+			for _, agent := range agents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case agent != "" && strings.Contains(token, agent):
+					hasSpecific = true
+					specific = append(specific, rule)
+				}
+			}
+		}
+	}
+	if hasSpecific {
+		return robotsPolicy{rules: specific}
+	}
+	return robotsPolicy{rules: wildcard}
+}
+
+// robotsCache fetches each host's robots.txt once and shares the policy
+// between every link to that host.
+type robotsCache struct {
+	client *http.Client
+
+	mu       sync.Mutex
+	policies map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	once   sync.Once
+	policy robotsPolicy
+}
+
+func newRobotsCache(client *http.Client) *robotsCache {
+	return &robotsCache{client: client, policies: map[string]*robotsEntry{}}
+}
+
+// allowed reports whether the crawler may fetch u.
+func (c *robotsCache) allowed(ctx context.Context, u *url.URL) bool {
+	origin := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	entry, ok := c.policies[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		c.policies[origin] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() { entry.policy = c.fetch(ctx, origin) })
+	return entry.policy.allowed(u.RequestURI())
+}
+
+// fetch downloads origin's robots.txt. A missing file allows everything and
+// a server error disallows everything. An unreachable host allows
+// everything, so the link itself gets checked and reported as dead.
+func (c *robotsCache) fetch(ctx context.Context, origin string) robotsPolicy {
+	req, err := newRequest(ctx, http.MethodGet, origin+"/robots.txt")
+	if err != nil {
+		return allowAll
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return allowAll
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return disallowAll
+	case resp.StatusCode >= 400:
+		return allowAll
+	}
+	return parseRobots(io.LimitReader(resp.Body, 500<<10), robotsToken)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseRobotsPicksMostSpecificGroup(t *testing.T) {
+	robots := `
+User-agent: *
+Disallow: /
+
+User-agent: Googlebot
+User-agent: rednafi-linkcheck
+Disallow: /private/
+Allow: /private/public-*
+Disallow: /*.pdf$
+`
+	policy := parseRobots(strings.NewReader(robots), robotsToken)
+	for path, want := range map[string]bool{
+		"/":                     true,
+		"/blog/post":            true,
+		"/private/notes":        false,
+		"/private/public-notes": true,
+		"/files/paper.pdf":      false,
+		"/files/paper.pdf?v=2":  true,
+	} {
+		if got := policy.allowed(path); got != want {
+			t.Errorf("allowed(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestParseRobotsFallsBackToWildcard(t *testing.T) {
+	policy := parseRobots(strings.NewReader("User-agent: *\nDisallow: /search\n"), robotsToken)
+	if policy.allowed("/search?q=go") {
+		t.Error("wildcard group should apply when no group names the crawler")
+	}
+	if !policy.allowed("/about") {
+		t.Error("/about is not disallowed")
+	}
+}
+
+func TestRobotsCacheFetchesOncePerHost(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches++
+			if got := r.Header.Get("User-Agent"); got != defaultUserAgent {
+				t.Errorf("User-Agent = %q, want %q", got, defaultUserAgent)
+			}
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		}
+	}))
+	defer server.Close()
+
+	cache := newRobotsCache(server.Client())
+	for path, want := range map[string]bool{"/ok": true, "/private/x": false, "/also-ok": true} {
+		u, _ := url.Parse(server.URL + path)
+		if got := cache.allowed(context.Background(), u); got != want {
+			t.Errorf("allowed(%s) = %t, want %t", path, got, want)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", fetches)
+	}
+}
+
+func TestRobotsCacheServerErrorDisallows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL + "/page")
+	if newRobotsCache(server.Client()).allowed(context.Background(), u) {
+		t.Error("a 5xx robots.txt should disallow the host")
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// run is one invocation past its flags: the config, the checker and the
+// links every mode starts from.
+type run struct {
+	opts     options
+	started  time.Time
+	log      *slog.Logger
+	style    style
+	cfg      config
+	tracer   *tracer
+	limited  *limitedTransport
+	client   *http.Client
+	resolver *dnsCache
+	checker  *checker
+	links    []link
+	ignores  ignores
+}
+
+func newRun(ctx context.Context, opts options, started time.Time, tr *tracer, logger *slog.Logger) (*run, error) {
+	cfg, err := loadConfig(opts.configPath)
+	if err != nil {
+		return nil, err
+	}
+	_, collectSpan := startSpan(ctx, "collect links")
+	links, err := collectLinks(contentDir)
+	if err != nil {
+		return nil, err
+	}
+	collectSpan.set("links", len(links))
+	collectSpan.finish()
+	ig, err := collectIgnores(contentDir)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := newDNSCache(netLookup)
+	limited := &limitedTransport{
+		limiter: newHostLimiter(cfg.Limits),
+		timeout: opts.timeout,
+	}
+	limited.base = &http.Transport{DialContext: countingDial(resolver.dialContext, &limited.received), ForceAttemptHTTP2: true}
+	client := &http.Client{
+		Transport: &tracingTransport{
+			base: &headerTransport{
+				base:      limited,
+				userAgent: cfg.UserAgent,
+				headers:   cfg.Headers,
+			},
+		},
+	}
+	c := &checker{client: client, resolver: resolver, robots: newRobotsCache(client), ignoreRobots: opts.ignoreRobots, config: cfg, retryBudget: opts.retryBudget, log: logger, tracer: tr, latencies: newLatencyLog()}
+	if opts.captureHeaders {
+		c.headers = newHeaderLog()
+	}
+	if opts.artifactsDir != "" {
+		c.artifacts = newArtifactStore(opts.artifactsDir)
+	}
+	return &run{
+		opts:     opts,
+		started:  started,
+		log:      logger,
+		style:    detectStyle(os.Stdout, os.Getenv, opts.ascii),
+		cfg:      cfg,
+		tracer:   tr,
+		limited:  limited,
+		client:   client,
+		resolver: resolver,
+		checker:  c,
+		links:    links,
+		ignores:  ig,
+	}, nil
+}
+
+// mode returns the mode the flags pick, the sweep unless another is set.
+// Each reports whether the run should fail.
+func (r *run) mode() func(context.Context) (bool, error) {
+	switch {
+	case r.opts.uptimeMode:
+		return r.uptime
+	case r.opts.snapshots:
+		return r.snapshots
+	case r.opts.fix:
+		return r.fix
+	case r.opts.daemonMode:
+		return r.daemon
+	case r.opts.compareBase != "":
+		return r.compare
+	}
+	return r.sweep
+}
+
+// endTrace finishes the run's trace and exports it.
+func (r *run) endTrace(ctx context.Context, root *span) {
+	root.finish()
+	if err := r.tracer.flush(ctx); err != nil {
+		r.log.Warn("traces not exported", "err", err)
+	}
+}
+
+// openCache loads the result cache, unless -no-cache leaves the run
+// without one.
+func (r *run) openCache() error {
+	if r.opts.noCache {
+		return nil
+	}
+	var err error
+	r.checker.cache, err = loadCache(r.opts.cachePath, cmp.Or(r.cfg.Cache.OK, defaultOKTTL), cmp.Or(r.cfg.Cache.Failed, defaultFailedTTL))
+	return err
+}
+
+// uptime probes the uptime groups in linkcheck.yml once and appends the run
+// to -uptime-history, which the site's /status/ page renders from. It fails
+// while anything is down, so a cron job mails about it:
+//
+//	*/5 * * * * cd ~/rednafi.com && make linkcheck args=-uptime
+func (r *run) uptime(ctx context.Context) (bool, error) {
+	if r.opts.dryRun {
+		p := &plan{}
+		p.uptime(r.cfg.Daemon.Groups, r.opts.uptimeHistory)
+		return false, r.opts.printPlan(p)
+	}
+	d := &daemon{checker: r.checker, groups: r.cfg.Daemon.Groups, workers: r.opts.workers, now: time.Now}
+	return d.uptimeOnce(ctx, os.Stdout, r.opts.uptimeHistory)
+}
+
+// snapshots keeps -snapshot-map, the Wayback Machine snapshot of every
+// outbound link, verified: links without one get the archive's latest
+// capture or a new one, and snapshots older than -snapshot-age are checked
+// again, at most -snapshot-batch a run. A dead snapshot is replaced in the
+// map and in any post pointing at it.
+func (r *run) snapshots(ctx context.Context) (bool, error) {
+	m, err := loadSnapshots(r.opts.snapshotMapPath)
+	if err != nil {
+		return false, err
+	}
+	m.track(r.links)
+	due := m.due(time.Now(), r.opts.snapshotAge, r.opts.snapshotBatch)
+	if r.opts.dryRun {
+		p := &plan{}
+		p.snapshots(m, due, r.opts.snapshotMapPath)
+		return false, r.opts.printPlan(p)
+	}
+	wb := &wayback{client: r.client, endpoint: waybackAPI, saveEndpoint: waybackSave}
+	replaced, stats := wb.refresh(ctx, r.log, m, due, time.Now().UTC())
+	if err := m.save(r.opts.snapshotMapPath); err != nil {
+		return false, err
+	}
+	diff, err := rewriteLinks(r.links, replaced, !r.opts.preview)
+	if err != nil {
+		return false, err
+	}
+	fmt.Print(diff)
+	fmt.Printf("%d of %d links due: %s\n", len(due), len(m), stats)
+	return false, nil
+}
+
+// fix rewrites links in place and prints the diff: http:// links the
+// https:// URL serves as well, permanent redirects to their live target,
+// and tracking parameters dropped.
+func (r *run) fix(ctx context.Context) (bool, error) {
+	c := r.checker
+	if r.opts.dryRun {
+		p := &plan{}
+		p.fix(c, r.links)
+		return false, r.opts.printPlan(p)
+	}
+	c.resolveAll(ctx, hostsOf(r.links), r.opts.dnsWorkers)
+	upgrades := c.httpsUpgrades(ctx, r.links, r.opts.workers)
+	moved := c.permanentRedirects(ctx, r.links, r.opts.workers)
+	if ctx.Err() != nil {
+		return false, errors.New("interrupted; no links rewritten")
+	}
+	stripped := trackingRewrites(r.links, c.tracking())
+	rewrites := maps.Clone(upgrades)
+	// A permanent redirect names the canonical URL outright, so it wins
+	// over a scheme swap.
+	maps.Copy(rewrites, moved)
+	for from, to := range stripped {
+		rewrites[from] = stripTracking(cmp.Or(rewrites[from], to), c.tracking())
+	}
+	diff, err := rewriteLinks(r.links, rewrites, !r.opts.preview)
+	if err != nil {
+		return false, err
+	}
+	fmt.Print(diff)
+	fmt.Printf("%d links rewritten: %d upgraded to https://, %d permanently redirected, %d stripped of tracking parameters\n", len(rewrites), len(upgrades), len(moved), len(stripped))
+	return false, nil
+}
+
+// daemon keeps running the check groups in linkcheck.yml on their
+// schedules and serves their status until it is interrupted.
+func (r *run) daemon(ctx context.Context) (bool, error) {
+	if err := r.openCache(); err != nil {
+		return false, err
+	}
+	listen := cmp.Or(r.cfg.Daemon.Listen, defaultListen)
+	if r.opts.dryRun {
+		p := &plan{}
+		p.daemon(r.cfg.Daemon, listen, r.opts.cachePath)
+		return false, r.opts.printPlan(p)
+	}
+	d, err := newDaemon(r.checker, r.cfg.Daemon, r.opts.cachePath, r.opts.baselinePath, r.opts.workers, r.opts.dnsWorkers)
+	if err != nil {
+		return false, err
+	}
+	return false, d.run(ctx, listen)
+}
+
+// compare sweeps -compare and -head in temporary worktrees and reports only
+// the findings head adds, so a PR check doesn't re-report links already
+// broken on its base.
+func (r *run) compare(ctx context.Context) (bool, error) {
+	if err := r.openCache(); err != nil {
+		return false, err
+	}
+	o := r.opts
+	if o.dryRun {
+		p := &plan{}
+		p.service("git: check out %s and %s into temporary worktrees", o.compareBase, o.compareHead)
+		p.note("both trees' links are swept as in a normal run; which requests that takes depends on what they cite")
+		p.write(o.cachePath)
+		return false, o.printPlan(p)
+	}
+	added, err := r.checker.compareRefs(ctx, o.compareBase, o.compareHead, o.workers, o.dnsWorkers)
+	if err != nil {
+		return false, err
+	}
+	if err := r.checker.cache.save(o.cachePath); err != nil {
+		return false, err
+	}
+	added, suppressed := r.ignores.filter(added)
+	fmt.Printf("%d findings in %s that %s doesn't have\n", len(added), o.compareHead, o.compareBase)
+	fmt.Print(runUsage(r.limited, r.checker))
+	fmt.Print(textReport(added, r.style, o.top))
+	fmt.Print(suppressedReport(suppressed, r.style, o.top))
+	return slices.ContainsFunc(added, finding.failed), nil
+}
+
+// scope is what a sweep covers: all the links, the ones it checks after
+// -rerun-failed and -shard, and of those the ones the cache can't answer.
+type scope struct {
+	all, links, pending []link
+	dates               map[string]string
+	rerun               string
+}
+
+// sweep checks the links and reports the findings, as they come with
+// -stream or grouped at the end.
+func (r *run) sweep(ctx context.Context) (bool, error) {
+	if err := r.openCache(); err != nil {
+		return false, err
+	}
+	c, o := r.checker, r.opts
+	// The cache outlives the shard: prune against every link, so entries
+	// other shards own survive.
+	s := scope{all: r.links, links: r.links}
+	if o.rerunFailed != "" {
+		rows, err := loadReport(o.rerunFailed)
+		if err != nil {
+			return false, err
+		}
+		var gone []string
+		s.links, gone = rerunLinks(s.links, failedURLs(rows))
+		for _, rawURL := range uniqueURLs(s.links) {
+			c.cache.expire(rawURL)
+		}
+		s.rerun = rerunSummary(o.rerunFailed, len(uniqueURLs(s.links)), gone)
+	}
+	s.links = o.shard.links(s.links)
+	var err error
+	s.dates, err = collectDates(contentDir)
+	if err != nil {
+		return false, err
+	}
+	c.code = newCodeLines(s.all, s.dates, os.Getenv(githubTokenEnv))
+	if o.maxDuration > 0 {
+		heavy, err := linkHeavy(s.all)
+		if err != nil {
+			return false, err
+		}
+		s.links = prioritize(s.links, c.cache, s.dates, heavy)
+		c.stopAt = r.started.Add(o.maxDuration)
+	}
+	s.pending = stale(s.links, c.cache)
+	if o.dryRun {
+		p := &plan{}
+		p.sweep(c, s.links, o.cachePath, o.waybackLookup, o.waybackFix)
+		return false, o.printPlan(p)
+	}
+	c.resolveAll(ctx, hostsOf(s.pending), o.dnsWorkers)
+
+	b, err := loadBaseline(o.baselinePath)
+	if err != nil {
+		return false, err
+	}
+	if o.stream {
+		return r.streamFindings(ctx, s, b)
+	}
+	return r.reportFindings(ctx, s, b)
+}
+
+// streamFindings prints each finding the moment its link is checked,
+// then the totals.
+func (r *run) streamFindings(ctx context.Context, s scope, b baseline) (bool, error) {
+	c, o := r.checker, r.opts
+	fmt.Print(s.rerun)
+	counts, failed, hidden, suppressed := streamReport(os.Stdout, c.stream(ctx, s.links, o.workers), b, r.ignores)
+	interrupted(ctx, c, o.cachePath)
+	c.cache.prune(uniqueURLs(s.all))
+	if err := c.cache.save(o.cachePath); err != nil {
+		return false, err
+	}
+	unique := len(uniqueURLs(s.links))
+	fmt.Printf("checked %d external links (%d unique, %d from cache) on %d hosts, %d findings baselined\n",
+		len(s.links), unique, unique-len(uniqueURLs(s.pending)), len(r.resolver.results), hidden)
+	if len(counts) > 0 {
+		fmt.Printf("findings by rule: %s\n", totals(counts))
+	}
+	fmt.Print(runUsage(r.limited, c))
+	if n := c.unchecked.Load(); n > 0 {
+		fmt.Print(coverage(o.maxDuration, unique, int(n)))
+	}
+	fmt.Print(suppressedReport(suppressed, r.style, o.top))
+	return failed, nil
+}
+
+// reportFindings sweeps the links, archives or triages the findings when
+// asked to, and writes the report in -format.
+func (r *run) reportFindings(ctx context.Context, s scope, b baseline) (bool, error) {
+	c, o := r.checker, r.opts
+	findings, hidden := b.filter(c.sweep(ctx, s.links, o.workers))
+	interrupted(ctx, c, o.cachePath)
+	findings, suppressed := r.ignores.filter(findings)
+	if o.waybackLookup || o.waybackFix {
+		wb := &wayback{client: r.client, endpoint: waybackAPI}
+		wb.archive(ctx, r.log, findings, s.dates, 2)
+	}
+	if o.waybackFix {
+		if err := r.archiveDeadLinks(s.links, findings); err != nil {
+			return false, err
+		}
+	}
+	if o.tui {
+		session := newTriage(c, findings, b, o.baselinePath, os.Stdin, os.Stdout)
+		session.style = r.style
+		if err := session.run(ctx); err != nil {
+			return false, err
+		}
+	}
+	c.cache.prune(uniqueURLs(s.all))
+	if err := c.cache.save(o.cachePath); err != nil {
+		return false, err
+	}
+	if o.tui {
+		return false, nil
+	}
+
+	unique := len(uniqueURLs(s.links))
+	if o.format == "text" {
+		if o.shard.count > 0 {
+			fmt.Printf("shard %s of %d unique URLs\n", o.shard, len(uniqueURLs(s.all)))
+		}
+		fmt.Print(s.rerun)
+		fmt.Printf("checked %d external links (%d unique, %d from cache) on %d hosts, %d findings baselined\n",
+			len(s.links), unique, unique-len(uniqueURLs(s.pending)), len(r.resolver.results), hidden)
+		if n := c.unchecked.Load(); n > 0 {
+			fmt.Print(coverage(o.maxDuration, unique, int(n)))
+		}
+		fmt.Print(runUsage(r.limited, c))
+		fmt.Print(textReport(findings, r.style, o.top))
+		fmt.Print(suppressedReport(suppressed, r.style, o.top))
+	} else {
+		if s.rerun != "" {
+			r.log.Info(strings.TrimSpace(strings.ReplaceAll(s.rerun, "\n", "; ")))
+		}
+		if n := c.unchecked.Load(); n > 0 {
+			r.log.Warn("sweep cut short", "budget", o.maxDuration, "unique", unique, "unchecked", n)
+		}
+		use := runUsage(r.limited, c)
+		r.log.Info("checked external links", "links", len(s.links), "unique", unique, "cached", unique-len(uniqueURLs(s.pending)), "hosts", len(r.resolver.results), "baselined", hidden, "suppressed", len(suppressed),
+			"requests", use.Requests, "bytes", use.Bytes, "retries", use.Retries, "cache_hits", use.CacheHits)
+		rows := reportRows(withoutOccurrences(s.links, suppressed), findings, b, c.headers, c.latencies)
+		if err := writeReport(os.Stdout, o.format, rows, c.headers != nil, &use); err != nil {
+			return false, err
+		}
+	}
+	return slices.ContainsFunc(findings, finding.failed), nil
+}
+
+// archiveDeadLinks points the dead links among findings at their Wayback
+// Machine snapshots, marked (archived), and records the snapshots in
+// -snapshot-map. With -preview it only prints the diff.
+func (r *run) archiveDeadLinks(links []link, findings []finding) error {
+	archived := archivedLinks(findings)
+	diff, err := editLinks(links, archived, func(line string) string { return annotateArchived(line, archived) }, !r.opts.preview)
+	if err != nil {
+		return err
+	}
+	// Keep stdout parseable for the json and csv reports.
+	out := io.Writer(os.Stdout)
+	if r.opts.format != "text" {
+		out = os.Stderr
+	}
+	fmt.Fprint(out, diff)
+	fmt.Fprintf(out, "%d dead links pointed at their Wayback Machine snapshot\n", len(archived))
+	if r.opts.preview {
+		return nil
+	}
+	m, err := loadSnapshots(r.opts.snapshotMapPath)
+	if err != nil {
+		return err
+	}
+	m.record(archived, time.Now().UTC())
+	return m.save(r.opts.snapshotMapPath)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule decides when a daemon check group runs next.
+type schedule interface {
+	next(after time.Time) time.Time
+}
+
+// every runs at a fixed interval.
+type every time.Duration
+
+func (e every) next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// cronSchedule is a five-field crontab line: minute, hour, day of month,
+// month, day of week. Each field is a set of allowed values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow []bool
+	// domStar and dowStar record an unrestricted day field. As in cron, when
+	// both day fields are restricted a day matching either one runs.
+	domStar, dowStar bool
+}
+
+// parseSchedule accepts "@every <duration>", "@hourly", "@daily", or a
+// five-field crontab expression such as "0 3 * * *".
+func parseSchedule(s string) (schedule, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "@every "):
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(s, "@every ")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("schedule %q: want a positive duration after @every", s)
+		}
+		return every(d), nil
+	case s == "@hourly":
+		s = "0 * * * *"
+	case s == "@daily", s == "@nightly":
+		s = "0 0 * * *"
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q: want @every <duration> or five cron fields", s)
+	}
+	var c cronSchedule
+	var err error
+	for i, f := range []struct {
+		set      *[]bool
+		min, max int
+	}{{&c.minute, 0, 59}, {&c.hour, 0, 23}, {&c.dom, 1, 31}, {&c.month, 1, 12}, {&c.dow, 0, 6}} {
+		if *f.set, err = parseField(fields[i], f.min, f.max); err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", s, err)
+		}
+	}
+	c.domStar, c.dowStar = fields[2] == "*", fields[4] == "*"
+	return c, nil
+}
+
+// parseField expands one cron field: *, a value, a range a-b, any of those
+// with a /step, or a comma-separated list of them.
+func parseField(field string, lo, hi int) ([]bool, error) {
+	set := make([]bool, hi+1)
+	for part := range strings.SplitSeq(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("bad step in %q", part)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rangePart != "*" {
+			a, b, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return nil, fmt.Errorf("bad value in %q", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return nil, fmt.Errorf("bad range in %q", part)
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// next returns the first whole minute after after that the expression
+// matches. Every valid expression matches within a few years, so the search
+// is bounded.
+func (c cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); t = t.Add(time.Minute) {
+		if c.month[t.Month()] && c.dayMatches(t) && c.hour[t.Hour()] && c.minute[t.Minute()] {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[t.Weekday()]
+	if !c.domStar && !c.dowStar {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2026, 3, 4, 10, 17, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		expr, want string
+	}{
+		{"@every 5m", "2026-03-04 10:22:30"},
+		{"@hourly", "2026-03-04 11:00:00"},
+		{"@daily", "2026-03-05 00:00:00"},
+		{"0 3 * * *", "2026-03-05 03:00:00"},
+		{"*/15 * * * *", "2026-03-04 10:30:00"},
+		{"20-40/10 10 * * *", "2026-03-04 10:20:00"},
+		{"0 9 * * 1-5", "2026-03-05 09:00:00"},
+		{"0 9 * * 0,6", "2026-03-07 09:00:00"},
+		// Both day fields restricted: either one matching is enough.
+		{"0 0 1 * 5", "2026-03-06 00:00:00"},
+		{"0 0 29 2 *", "2028-02-29 00:00:00"},
+	} {
+		s, err := parseSchedule(tc.expr)
+		if err != nil {
+			t.Fatalf("parseSchedule(%q): %v", tc.expr, err)
+		}
+		if got := s.next(from).Format(time.DateTime); got != tc.want {
+			t.Errorf("%q.next = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseScheduleRejectsBadExpressions(t *testing.T) {
+	for _, expr := range []string{"", "@every", "@every -5m", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// shard is one of count slices of the sweep, numbered from 1. The zero
+// shard is the whole sweep.
+type shard struct {
+	index, count int
+}
+
+// parseShard reads a -shard value like 2/4. An empty value is no sharding.
+func parseShard(s string) (shard, error) {
+	if s == "" {
+		return shard{}, nil
+	}
+	i, n, ok := strings.Cut(s, "/")
+	index, err1 := strconv.Atoi(i)
+	count, err2 := strconv.Atoi(n)
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 1 || index > count {
+		return shard{}, fmt.Errorf("-shard %q: want i/n with 1 <= i <= n", s)
+	}
+	return shard{index: index, count: count}, nil
+}
+
+func (s shard) String() string {
+	return fmt.Sprintf("%d/%d", s.index, s.count)
+}
+
+// owns reports whether rawURL falls in the shard. The split hashes the URL,
+// so it depends on nothing but the URL and the shard count: every job of a
+// CI matrix agrees on it without coordinating, and every occurrence of a
+// URL lands in the same shard, which checks it once.
+func (s shard) owns(rawURL string) bool {
+	if s.count <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(rawURL))
+	return int(h.Sum32()%uint32(s.count)) == s.index-1
+}
+
+// links returns the occurrences in all whose URL the shard owns.
+func (s shard) links(all []link) []link {
+	if s.count <= 1 {
+		return all
+	}
+	var out []link
+	for _, l := range all {
+		if s.owns(l.URL) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// runReportMerge implements `linkcheck report merge SHARD.json...`: it
+// joins the -format=json reports of a sharded sweep into the report one
+// unsharded run would have written, ready for `report diff`. A link
+// occurrence in two inputs means the shards didn't split the same content
+// or count, and is an error rather than a silently doubled row.
+func runReportMerge(w io.Writer, format string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: linkcheck [-format json|csv] report merge SHARD.json...")
+	}
+	if format == "text" {
+		format = "json"
+	}
+	type occurrence struct {
+		url, file    string
+		line, column int
+	}
+	seen := map[occurrence]string{}
+	rows := []reportRow{}
+	// The merged report's usage is the shards' together, if they have one.
+	var total *usage
+	for _, path := range args {
+		shard, err := readReport(path)
+		if err != nil {
+			return err
+		}
+		if shard.Usage != nil {
+			sum := *shard.Usage
+			if total != nil {
+				sum = sum.add(*total)
+			}
+			total = &sum
+		}
+		for _, row := range shard.Links {
+			o := occurrence{row.URL, row.File, row.Line, row.Column}
+			if prev, ok := seen[o]; ok {
+				return fmt.Errorf("%s and %s both report %s at %s:%d:%d; were they sharded with the same -shard count?", prev, path, row.URL, row.File, row.Line, row.Column)
+			}
+			seen[o] = path
+			rows = append(rows, row)
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b reportRow) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	withHeaders := slices.ContainsFunc(rows, func(row reportRow) bool { return row.Headers != nil })
+	return writeReport(w, format, rows, withHeaders, total)
+}
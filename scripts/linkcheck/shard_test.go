@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseShard(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want shard
+		err  bool
+	}{
+		{"", shard{}, false},
+		{"1/1", shard{1, 1}, false},
+		{"3/4", shard{3, 4}, false},
+		{"0/4", shard{}, true},
+		{"5/4", shard{}, true},
+		{"2", shard{}, true},
+		{"a/b", shard{}, true},
+	} {
+		got, err := parseShard(tc.in)
+		if got != tc.want || (err != nil) != tc.err {
+			t.Errorf("parseShard(%q) = %v, %v; want %v, error %t", tc.in, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestShardsPartitionTheURLs(t *testing.T) {
+	var all []link
+	for i := range 200 {
+		u := fmt.Sprintf("https://example%d.com/page", i%50)
+		all = append(all, link{URL: u, File: "a.md", Line: i + 1})
+	}
+	owners := map[string]int{}
+	total := 0
+	for i := 1; i <= 4; i++ {
+		part := shard{i, 4}.links(all)
+		if len(part) == 0 {
+			t.Errorf("shard %d/4 is empty", i)
+		}
+		total += len(part)
+		for _, l := range part {
+			if prev, ok := owners[l.URL]; ok && prev != i {
+				t.Fatalf("%s is in shards %d and %d", l.URL, prev, i)
+			}
+			owners[l.URL] = i
+		}
+	}
+	if total != len(all) || len(owners) != 50 {
+		t.Fatalf("shards hold %d occurrences of %d URLs, want %d of 50", total, len(owners), len(all))
+	}
+	if got := (shard{}).links(all); len(got) != len(all) {
+		t.Fatalf("the zero shard holds %d links, want all %d", len(got), len(all))
+	}
+}
+
+func TestRunReportMerge(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, rows string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	one := write("shard-1.json", `{"usage":{"requests":5,"bytes":100,"retries":0,"cacheHits":1},"links":[{"url":"https://b.example/","file":"b.md","line":4,"rule":"ok"},{"url":"https://a.example/","file":"a.md","line":9,"rule":"http","severity":"error","message":"HTTP 404"}]}`)
+	two := write("shard-2.json", `{"usage":{"requests":2,"bytes":50,"retries":1,"cacheHits":0},"links":[{"url":"https://c.example/","file":"a.md","line":2,"rule":"ok"}]}`)
+
+	var out strings.Builder
+	if err := runReportMerge(&out, "text", []string{one, two}); err != nil {
+		t.Fatal(err)
+	}
+	var merged jsonReport
+	if err := json.Unmarshal([]byte(out.String()), &merged); err != nil {
+		t.Fatalf("merged report isn't json: %v\n%s", err, out.String())
+	}
+	if want := (usage{Requests: 7, Bytes: 150, Retries: 1, CacheHits: 1}); merged.Usage == nil || *merged.Usage != want {
+		t.Fatalf("merged usage = %+v, want %+v", merged.Usage, want)
+	}
+	var got []string
+	for _, row := range merged.Links {
+		got = append(got, fmt.Sprintf("%s:%d %s", row.File, row.Line, row.Rule))
+	}
+	if want := "a.md:2 ok, a.md:9 http, b.md:4 ok"; strings.Join(got, ", ") != want {
+		t.Fatalf("merged rows = %s, want %s", strings.Join(got, ", "), want)
+	}
+
+	// A report saved before reports carried their usage is a bare array.
+	overlap := write("overlap.json", `[{"url":"https://c.example/","file":"a.md","line":2,"rule":"ok"}]`)
+	if err := runReportMerge(&out, "json", []string{two, overlap}); err == nil || !strings.Contains(err.Error(), "same -shard count") {
+		t.Fatalf("err = %v, want the overlapping shards reported", err)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultSnapshots is the snapshot map. It is committed, unlike the cache:
+// it is the backup of every page the posts cite, and has to outlive the
+// machine that built it.
+const defaultSnapshots = "linkcheck.snapshots.json"
+
+// waybackSave is Save Page Now, which captures a URL when asked.
+const waybackSave = "https://web.archive.org/save/"
+
+// defaultSnapshotAge is how long a verified snapshot goes unchecked.
+const defaultSnapshotAge = 30 * 24 * time.Hour
+
+// snapshotEntry is one outbound link's Wayback Machine snapshot and when
+// it was last seen to resolve. Snapshot is empty until one is found.
+type snapshotEntry struct {
+	Snapshot string    `json:"snapshot,omitempty"`
+	Verified time.Time `json:"verified,omitzero"`
+}
+
+// snapshotMap maps every outbound link, by the URL the posts cite or, for
+// a link already rewritten to its snapshot, the URL the snapshot captured,
+// to its snapshot.
+type snapshotMap map[string]snapshotEntry
+
+func loadSnapshots(path string) (snapshotMap, error) {
+	m := snapshotMap{}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func (m snapshotMap) save(path string) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// snapshotPattern matches a Wayback Machine snapshot URL and captures the
+// URL it archived. The timestamp may carry a flag like id_ or im_.
+var snapshotPattern = regexp.MustCompile(`^https?://web\.archive\.org/web/\d+[a-z_]*/(.+)$`)
+
+// archivedURL returns the URL snap captured, if snap is a snapshot.
+func archivedURL(snap string) (string, bool) {
+	m := snapshotPattern.FindStringSubmatch(snap)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// track adds the links the map doesn't know yet. A link that already
+// points at a snapshot, as -wayback-fix leaves dead links, is entered
+// under the URL it captured, with the snapshot the post cites. Entries for
+// links the posts no longer cite are kept: the backup is of every link the
+// site ever had.
+func (m snapshotMap) track(links []link) {
+	for _, rawURL := range uniqueURLs(links) {
+		if captured, ok := archivedURL(rawURL); ok {
+			entry := m[captured]
+			entry.Snapshot = cmp.Or(entry.Snapshot, rawURL)
+			m[captured] = entry
+			continue
+		}
+		if _, ok := m[rawURL]; !ok {
+			m[rawURL] = snapshotEntry{}
+		}
+	}
+}
+
+// due returns up to batch URLs whose snapshot needs work: those without
+// one first, then those verified longest ago, as long as that is more
+// than maxAge before now.
+func (m snapshotMap) due(now time.Time, maxAge time.Duration, batch int) []string {
+	var urls []string
+	for rawURL, entry := range m {
+		if entry.Snapshot == "" || now.Sub(entry.Verified) > maxAge {
+			urls = append(urls, rawURL)
+		}
+	}
+	slices.SortFunc(urls, func(a, b string) int {
+		ea, eb := m[a], m[b]
+		return cmp.Or(
+			cmp.Compare(min(len(ea.Snapshot), 1), min(len(eb.Snapshot), 1)),
+			ea.Verified.Compare(eb.Verified),
+			strings.Compare(a, b),
+		)
+	})
+	if batch > 0 && len(urls) > batch {
+		urls = urls[:batch]
+	}
+	return urls
+}
+
+// snapshotStats counts what a refresh did.
+type snapshotStats struct {
+	verified, replaced, archived, missing int
+}
+
+func (s snapshotStats) String() string {
+	return fmt.Sprintf("%d %s verified, %d replaced, %d newly archived, %d still without one",
+		s.verified, plural(s.verified, "snapshot"), s.replaced, s.archived, s.missing)
+}
+
+// refresh works through urls one at a time, so the archive sees a single
+// client however many are due; the limits for archive.org in linkcheck.yml
+// space the requests. A snapshot that still resolves is marked verified.
+// One that doesn't, or a missing one, is replaced by the latest capture the
+// archive has or, failing that, a new one from Save Page Now. It returns
+// each replaced snapshot's successor, for rewriting the posts that cite it.
+func (w *wayback) refresh(ctx context.Context, log *slog.Logger, m snapshotMap, urls []string, now time.Time) (map[string]string, snapshotStats) {
+	replaced := map[string]string{}
+	var stats snapshotStats
+	for _, rawURL := range urls {
+		entry := m[rawURL]
+		log := log.With("url", rawURL)
+		if entry.Snapshot != "" {
+			alive, err := w.resolves(ctx, entry.Snapshot)
+			if err != nil {
+				log.Warn("snapshot not verified", "snapshot", entry.Snapshot, "err", err)
+				continue
+			}
+			if alive {
+				entry.Verified = now
+				m[rawURL] = entry
+				stats.verified++
+				continue
+			}
+			log.Info("snapshot gone", "snapshot", entry.Snapshot)
+		}
+
+		snap, err := w.closest(ctx, rawURL, "")
+		if err == nil && (snap == "" || snap == entry.Snapshot) {
+			snap, err = w.save(ctx, rawURL)
+		}
+		if err != nil || snap == "" {
+			log.Warn("no snapshot", "err", err)
+			stats.missing++
+			continue
+		}
+		if entry.Snapshot != "" {
+			replaced[entry.Snapshot] = snap
+			stats.replaced++
+		} else {
+			stats.archived++
+		}
+		m[rawURL] = snapshotEntry{Snapshot: snap, Verified: now}
+	}
+	return replaced, stats
+}
+
+// resolves reports whether snap still serves a capture. The archive
+// redirects a timestamp to the nearest capture it has, so redirects are
+// followed.
+func (w *wayback) resolves(ctx context.Context, snap string) (bool, error) {
+	r, err := fetch(ctx, w.client, snap, validators{}, 0)
+	if err != nil {
+		return false, err
+	}
+	if r.status == http.StatusTooManyRequests || r.status >= 500 {
+		return false, fmt.Errorf("wayback: HTTP %d", r.status)
+	}
+	return r.status == http.StatusOK, nil
+}
+
+// save asks Save Page Now to capture rawURL and returns the new snapshot,
+// which the service redirects to once the capture is done.
+func (w *wayback) save(ctx context.Context, rawURL string) (string, error) {
+	r, err := fetch(ctx, w.client, w.saveEndpoint+rawURL, validators{}, 0)
+	if err != nil {
+		return "", err
+	}
+	if r.status != http.StatusOK {
+		return "", fmt.Errorf("save page now: HTTP %d", r.status)
+	}
+	if loc := r.header.Get("Content-Location"); strings.HasPrefix(loc, "/web/") {
+		return "https://web.archive.org" + loc, nil
+	}
+	if !strings.Contains(r.finalURL, "/web/") {
+		return "", fmt.Errorf("save page now: no snapshot in the answer, which ended at %s", r.finalURL)
+	}
+	if rest, ok := strings.CutPrefix(r.finalURL, "http://web.archive.org/"); ok {
+		return "https://web.archive.org/" + rest, nil
+	}
+	return r.finalURL, nil
+}
+
+// record notes the snapshots -wayback-fix pointed dead links at, so the
+// map holds them before the posts stop citing the original URLs.
+func (m snapshotMap) record(archived map[string]string, now time.Time) {
+	for rawURL, snap := range archived {
+		m[rawURL] = snapshotEntry{Snapshot: snap, Verified: now}
+	}
+}
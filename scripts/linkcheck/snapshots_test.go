@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotMapTracksLinksAndPicksTheDueOnes(t *testing.T) {
+	now := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	m := snapshotMap{
+		"https://fresh.example/": {Snapshot: "https://web.archive.org/web/2026/https://fresh.example/", Verified: now.Add(-time.Hour)},
+		"https://stale.example/": {Snapshot: "https://web.archive.org/web/2020/https://stale.example/", Verified: now.AddDate(0, -3, 0)},
+		"https://older.example/": {Snapshot: "https://web.archive.org/web/2019/https://older.example/", Verified: now.AddDate(-1, 0, 0)},
+	}
+	m.track([]link{
+		{URL: "https://new.example/"},
+		{URL: "https://fresh.example/"},
+		{URL: "https://web.archive.org/web/2015id_/https://dead.example/post"},
+	})
+	if got := m["https://dead.example/post"].Snapshot; got != "https://web.archive.org/web/2015id_/https://dead.example/post" {
+		t.Fatalf("a link rewritten to its snapshot was entered as %q", got)
+	}
+
+	due := m.due(now, defaultSnapshotAge, 0)
+	want := []string{"https://new.example/", "https://dead.example/post", "https://older.example/", "https://stale.example/"}
+	if !slices.Equal(due, want) {
+		t.Fatalf("due = %q, want %q", due, want)
+	}
+	if due := m.due(now, defaultSnapshotAge, 2); len(due) != 2 {
+		t.Fatalf("batch of 2 gave %d", len(due))
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshots.json")
+	if err := m.save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadSnapshots(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(m) || !loaded["https://stale.example/"].Verified.Equal(m["https://stale.example/"].Verified) {
+		t.Fatalf("round trip lost entries: %v", loaded)
+	}
+}
+
+func TestRefreshReplacesDeadSnapshots(t *testing.T) {
+	archive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/available":
+			closest := map[string]any{"available": false}
+			if target := r.URL.Query().Get("url"); target == "https://dead.example/" {
+				closest = map[string]any{"available": true, "status": "200", "url": "http://web.archive.org/web/2024/" + target}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"archived_snapshots": map[string]any{"closest": closest}})
+		case r.URL.Path == "/save/https://new.example/":
+			// Set by hand: http.Redirect would clean the // out of the path.
+			w.Header().Set("Location", "/web/2026/https://new.example/")
+			w.WriteHeader(http.StatusFound)
+		case r.URL.Path == "/save/https://gone.example/":
+			http.Error(w, "can't reach the page", http.StatusBadGateway)
+		case r.URL.Path == "/web/2020/https://alive.example/", r.URL.Path == "/web/2026/https://new.example/":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer archive.Close()
+
+	now := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	m := snapshotMap{
+		"https://alive.example/": {Snapshot: archive.URL + "/web/2020/https://alive.example/"},
+		"https://dead.example/":  {Snapshot: archive.URL + "/web/2020/https://dead.example/"},
+		"https://new.example/":   {},
+		"https://gone.example/":  {},
+	}
+	wb := &wayback{client: archive.Client(), endpoint: archive.URL + "/available", saveEndpoint: archive.URL + "/save/"}
+	replaced, stats := wb.refresh(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), m, m.due(now, defaultSnapshotAge, 0), now)
+
+	if got := stats.String(); got != "1 snapshot verified, 1 replaced, 1 newly archived, 1 still without one" {
+		t.Fatalf("stats = %q", got)
+	}
+	if got, want := replaced[archive.URL+"/web/2020/https://dead.example/"], "https://web.archive.org/web/2024/https://dead.example/"; got != want || len(replaced) != 1 {
+		t.Fatalf("replaced = %v, want the dead snapshot replaced by %s", replaced, want)
+	}
+	if got := m["https://new.example/"]; got.Snapshot != archive.URL+"/web/2026/https://new.example/" || !got.Verified.Equal(now) {
+		t.Fatalf("new.example = %+v, want Save Page Now's capture", got)
+	}
+	if !m["https://alive.example/"].Verified.Equal(now) {
+		t.Fatal("a live snapshot wasn't marked verified")
+	}
+	if got := m["https://gone.example/"]; got.Snapshot != "" {
+		t.Fatalf("gone.example = %+v, want it left without a snapshot", got)
+	}
+}
+
+func TestPlanSnapshots(t *testing.T) {
+	m := snapshotMap{
+		"https://a.example/": {Snapshot: "https://web.archive.org/web/2020/https://a.example/"},
+		"https://b.example/": {},
+		"https://c.example/": {Snapshot: "https://web.archive.org/web/2026/https://c.example/", Verified: time.Now()},
+	}
+	p := &plan{}
+	p.snapshots(m, m.due(time.Now(), defaultSnapshotAge, 0), "linkcheck.snapshots.json")
+	var out strings.Builder
+	if err := p.print(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"requests (1):\n  GET https://web.archive.org/web/2020/https://a.example/\n",
+		"1 link without a snapshot would be looked up",
+		"1 link verified recently",
+		"files written (1):\n  linkcheck.snapshots.json\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// placeholderSize is the HTML body size below which a 200 is treated as a
+// placeholder rather than a page. Even a bare-bones real page clears it.
+const placeholderSize = 256
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	h1Pattern    = regexp.MustCompile(`(?is)<h1[^>]*>(.*?)</h1>`)
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// notFoundPhrases mark a page that says it doesn't exist. They are only
+// matched against the title and first heading: a post about 404 handling
+// mentions them in its body without being dead.
+var notFoundPhrases = []string{
+	"404",
+	"not found",
+	"page doesn't exist",
+	"page does not exist",
+	"no longer available",
+	"page cannot be found",
+	"page can't be found",
+	"nothing found",
+}
+
+// parkingMarkers are phrases and parking services that show up on
+// domain-parking pages, matched anywhere in the body.
+var parkingMarkers = []string{
+	"this domain is for sale",
+	"this domain may be for sale",
+	"buy this domain",
+	"the domain name is for sale",
+	"domain is parked",
+	"parked free",
+	"sedoparking.com",
+	"parkingcrew.net",
+	"bodis.com",
+	"above.com/marketplace",
+	"dan.com",
+	"afternic.com",
+	"hugedomains.com",
+}
+
+// suspect reports why a live HTML response is probably dead anyway: a
+// "not found" page served with 200, a domain-parking page, or a body too
+// small to be the content the link pointed at.
+func suspect(r fetchResult) (reason string, ok bool) {
+	if !r.html || r.status < 200 || r.status >= 300 {
+		return "", false
+	}
+	body := bytes.ToLower(r.body)
+	for _, pattern := range []*regexp.Regexp{titlePattern, h1Pattern} {
+		m := pattern.FindSubmatch(body)
+		if m == nil {
+			continue
+		}
+		text := strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(string(m[1]), " "))), " ")
+		for _, phrase := range notFoundPhrases {
+			if strings.Contains(text, phrase) {
+				return fmt.Sprintf("soft 404: page says %q", text), true
+			}
+		}
+	}
+	for _, marker := range parkingMarkers {
+		if bytes.Contains(body, []byte(marker)) {
+			return fmt.Sprintf("parked domain: page mentions %q", marker), true
+		}
+	}
+	if len(bytes.TrimSpace(body)) < placeholderSize {
+		return fmt.Sprintf("placeholder: %d-byte page", len(bytes.TrimSpace(body))), true
+	}
+	return "", false
+}
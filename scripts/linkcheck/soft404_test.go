@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSuspect(t *testing.T) {
+	article := "<html><head><title>Go errors</title></head><body><h1>Go errors</h1><p>" +
+		strings.Repeat("Wrapping errors keeps the chain inspectable. ", 20) + "</p></body></html>"
+	for _, tc := range []struct {
+		name string
+		r    fetchResult
+		want string
+	}{
+		{"real page", fetchResult{status: 200, html: true, body: []byte(article)}, ""},
+		{"not found title", fetchResult{status: 200, html: true, body: []byte(strings.Replace(article, "<title>Go errors", "<title>Page Not Found", 1))}, `soft 404: page says "page not found"`},
+		{"404 heading", fetchResult{status: 200, html: true, body: []byte(strings.Replace(article, "<h1>Go errors", "<h1>Error <em>404</em>", 1))}, `soft 404: page says "error 404"`},
+		{"404 in body only", fetchResult{status: 200, html: true, body: []byte(strings.Replace(article, "<p>", "<p>Handling a 404 not found. ", 1))}, ""},
+		{"parked", fetchResult{status: 200, html: true, body: []byte(strings.Replace(article, "<p>", "<p>This domain is for sale! ", 1))}, `parked domain: page mentions "this domain is for sale"`},
+		{"placeholder", fetchResult{status: 200, html: true, body: []byte("<html><body>Coming soon</body></html>")}, "placeholder: 37-byte page"},
+		{"not html", fetchResult{status: 200, body: nil}, ""},
+		{"not modified", fetchResult{status: 304, html: true}, ""},
+	} {
+		got, _ := suspect(tc.r)
+		if got != tc.want {
+			t.Errorf("%s: suspect = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSweepFlagsSoft404sWithoutFailing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved-on" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head><title>Oops! Page not found</title></head><body>" + strings.Repeat("x", 500) + "</body></html>"))
+		}
+	}))
+	defer server.Close()
+
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(server.Client())}
+	links := []link{{URL: server.URL + "/moved-on", File: "a.md", Line: 3}}
+	got := c.sweep(context.Background(), links, 1)
+	if len(got) != 1 || got[0].Rule != ruleSuspect || got[0].failed() {
+		t.Fatalf("sweep = %v; want one suspect finding that doesn't fail the run", got)
+	}
+}
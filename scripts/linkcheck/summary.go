@@ -0,0 +1,95 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// bucket is how many failures one rule produced in one content section.
+type bucket struct {
+	section, rule string
+	count         int
+}
+
+// summarize counts the failing findings per section and rule, biggest
+// bucket first, so sixty failures read as "python's links are broken"
+// instead of sixty lines.
+func summarize(findings []finding) []bucket {
+	counts := map[[2]string]int{}
+	for _, f := range findings {
+		if f.failed() {
+			counts[[2]string{section(f.Link.File), f.Rule}]++
+		}
+	}
+	buckets := make([]bucket, 0, len(counts))
+	for key, n := range counts {
+		buckets = append(buckets, bucket{section: key[0], rule: key[1], count: n})
+	}
+	slices.SortFunc(buckets, func(a, b bucket) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), strings.Compare(a.section, b.section), strings.Compare(a.rule, b.rule))
+	})
+	return buckets
+}
+
+// summary renders the top buckets, all of them when top is zero, followed
+// by the failure totals per section and per rule. It is empty when nothing
+// failed.
+func summary(findings []finding, top int, st style) string {
+	buckets := summarize(findings)
+	if len(buckets) == 0 {
+		return ""
+	}
+	shown := buckets
+	if top > 0 && top < len(buckets) {
+		shown = buckets[:top]
+	}
+
+	var total int
+	bySection, byRule := map[string]int{}, map[string]int{}
+	for _, b := range buckets {
+		total += b.count
+		bySection[b.section] += b.count
+		byRule[b.rule] += b.count
+	}
+	sectionWidth := len("section")
+	for _, b := range shown {
+		sectionWidth = max(sectionWidth, len(b.section))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:\n", st.paint(fmt.Sprintf("failures by section and rule (%d)", total), ansiBold))
+	for _, b := range shown {
+		fmt.Fprintf(&out, "  %-*s  %-9s %4d\n", sectionWidth, b.section, b.rule, b.count)
+	}
+	if hidden := len(buckets) - len(shown); hidden > 0 {
+		fmt.Fprintf(&out, "  %d more %s; drop -top to see them\n", hidden, plural(hidden, "bucket"))
+	}
+	fmt.Fprintf(&out, "  by section: %s\n", totals(bySection))
+	fmt.Fprintf(&out, "  by rule: %s\n", totals(byRule))
+	return out.String()
+}
+
+// totals renders counts as "python 42, go 7", largest first.
+func totals(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSummaryGroupsFailuresBySectionAndRule(t *testing.T) {
+	var findings []finding
+	add := func(n int, file, rule string) {
+		for i := range n {
+			findings = append(findings, newFinding(link{URL: fmt.Sprintf("https://x.example/%d", i), File: file, Line: i + 1}, rule, "broken"))
+		}
+	}
+	add(5, "content/python/a.md", ruleHTTP)
+	add(2, "content/go/b.md", ruleNXDomain)
+	add(1, "content/go/c.md", ruleHTTP)
+	add(3, "content/go/c.md", ruleSuspect) // warnings aren't failures
+
+	want := "failures by section and rule (8):\n" +
+		"  python   http         5\n" +
+		"  go       nxdomain     2\n" +
+		"  go       http         1\n" +
+		"  by section: python 5, go 3\n" +
+		"  by rule: http 6, nxdomain 2\n"
+	if got := summary(findings, 0, style{}); got != want {
+		t.Fatalf("summary =\n%s\nwant\n%s", got, want)
+	}
+
+	top := summary(findings, 1, style{})
+	if !strings.Contains(top, "  python   http         5\n  2 more buckets; drop -top to see them\n") {
+		t.Fatalf("summary with -top=1 =\n%s", top)
+	}
+	if got := textReport(findings, style{}, 1); strings.Contains(got, "x.example") {
+		t.Fatalf("-top view lists individual links:\n%s", got)
+	}
+	if got := summary(findings[8:], 0, style{}); got != "" {
+		t.Fatalf("summary of warnings only = %q, want nothing", got)
+	}
+}
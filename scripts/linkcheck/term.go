@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// style is how the report is drawn on its terminal: whether it may use ANSI
+// colors, and whether it must stick to ASCII.
+type style struct {
+	color bool
+	ascii bool
+}
+
+// ANSI SGR sequences used by the report.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+)
+
+// detectStyle decides whether output to f gets colors, following the
+// NO_COLOR and FORCE_COLOR conventions: NO_COLOR always wins, FORCE_COLOR
+// turns colors on for pipes and CI logs that render them, and otherwise f
+// must be a terminal that isn't TERM=dumb and, on Windows, one that accepts
+// ANSI sequences.
+func detectStyle(f *os.File, getenv func(string) string, ascii bool) style {
+	s := style{ascii: ascii}
+	switch force := getenv("FORCE_COLOR"); {
+	case getenv("NO_COLOR") != "":
+	case force != "" && force != "0" && !strings.EqualFold(force, "false"):
+		s.color = true
+	default:
+		s.color = isTerminal(f) && getenv("TERM") != "dumb" && enableANSI(f)
+	}
+	return s
+}
+
+// isTerminal reports whether f is a character device rather than a file
+// or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps text in the SGR codes when colors are on.
+func (s style) paint(text string, codes ...string) string {
+	if !s.color || len(codes) == 0 {
+		return text
+	}
+	return strings.Join(codes, "") + text + ansiReset
+}
+
+// sep is the separator between a group and a section in triage headers.
+func (s style) sep() string {
+	if s.ascii {
+		return ">"
+	}
+	return "›"
+}
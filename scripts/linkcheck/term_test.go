@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectStyle(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, tc := range []struct {
+		name  string
+		env   map[string]string
+		color bool
+	}{
+		{"redirected to a file", nil, false},
+		{"FORCE_COLOR on a pipe", map[string]string{"FORCE_COLOR": "1"}, true},
+		{"FORCE_COLOR=0", map[string]string{"FORCE_COLOR": "0"}, false},
+		{"NO_COLOR beats FORCE_COLOR", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, false},
+	} {
+		getenv := func(key string) string { return tc.env[key] }
+		if got := detectStyle(file, getenv, false); got.color != tc.color {
+			t.Errorf("%s: color = %t, want %t", tc.name, got.color, tc.color)
+		}
+	}
+}
+
+func TestReportColorsOnlyWhenAllowed(t *testing.T) {
+	findings := []finding{
+		newFinding(link{URL: "https://a.example/", File: "a.md", Line: 1}, ruleHTTP, "HTTP 404"),
+		newFinding(link{URL: "https://b.example/", File: "a.md", Line: 2}, ruleSkipped, "domain is on the skip list"),
+	}
+	plain := report(findings, style{})
+	if strings.Contains(plain, "\x1b") {
+		t.Fatalf("plain report has escape codes: %q", plain)
+	}
+	colored := report(findings, style{color: true})
+	if !strings.Contains(colored, ansiBold+ansiRed+"broken links (1)"+ansiReset) || !strings.Contains(colored, ansiDim+"skipped by linkcheck.yml (1)"+ansiReset) {
+		t.Fatalf("colored report = %q", colored)
+	}
+	if got := (style{ascii: true}).sep() + (style{}).sep(); got != ">›" {
+		t.Fatalf("sep = %q, want > in ASCII mode and › otherwise", got)
+	}
+}
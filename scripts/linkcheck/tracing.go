@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The sweep's traces follow OpenTelemetry's model and go out over OTLP/HTTP
+// in its JSON encoding, which any collector accepts at /v1/traces. The OTel
+// SDK would bring a large dependency tree for what is one exporter and a
+// handful of span kinds, so this is the minimal subset: spans with
+// attributes and an error status, parented through the context.
+
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+
+	statusError = 2
+
+	// exportBatch is how many finished spans are buffered before a POST.
+	exportBatch = 512
+)
+
+// tracer buffers finished spans and exports them to an OTLP/HTTP endpoint.
+// A nil tracer starts no spans.
+type tracer struct {
+	endpoint string
+	service  string
+	client   *http.Client
+
+	mu      sync.Mutex
+	pending []*span
+	err     error
+}
+
+// newTracer returns a tracer exporting to endpoint, an OTLP/HTTP base URL
+// such as http://localhost:4318, or nil when endpoint is empty.
+func newTracer(endpoint, service string) *tracer {
+	if endpoint == "" {
+		return nil
+	}
+	return &tracer{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// span is one timed operation.
+type span struct {
+	tracer  *tracer
+	traceID [16]byte
+	id      [8]byte
+	parent  [8]byte
+	name    string
+	kind    int
+	start   time.Time
+	end     time.Time
+	attrs   []attr
+	err     error
+}
+
+type attr struct {
+	key   string
+	value any
+}
+
+type spanKey struct{}
+
+// start begins a root span and returns a context carrying it.
+func (t *tracer) start(ctx context.Context, name string, attrs ...any) (context.Context, *span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &span{tracer: t, name: name, kind: spanKindInternal, start: time.Now(), attrs: pairs(attrs)}
+	rand.Read(s.traceID[:])
+	rand.Read(s.id[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// startSpan begins a child of the span in ctx. Without one, tracing is off
+// and the returned span is nil, whose methods do nothing.
+func startSpan(ctx context.Context, name string, attrs ...any) (context.Context, *span) {
+	parent, _ := ctx.Value(spanKey{}).(*span)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := &span{tracer: parent.tracer, traceID: parent.traceID, parent: parent.id, name: name, kind: spanKindInternal, start: time.Now(), attrs: pairs(attrs)}
+	rand.Read(s.id[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// pairs turns alternating keys and values into attributes.
+func pairs(kv []any) []attr {
+	var out []attr
+	for i := 0; i+1 < len(kv); i += 2 {
+		out = append(out, attr{fmt.Sprint(kv[i]), kv[i+1]})
+	}
+	return out
+}
+
+func (s *span) set(attrs ...any) {
+	if s != nil {
+		s.attrs = append(s.attrs, pairs(attrs)...)
+	}
+}
+
+// fail marks the span as errored.
+func (s *span) fail(err error) {
+	if s != nil && err != nil {
+		s.err = err
+	}
+}
+
+// finish ends the span and hands it to the tracer.
+func (s *span) finish() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	t := s.tracer
+	t.mu.Lock()
+	t.pending = append(t.pending, s)
+	full := len(t.pending) >= exportBatch
+	t.mu.Unlock()
+	if full {
+		t.flush(context.Background())
+	}
+}
+
+// flush exports every buffered span. Export errors don't stop the sweep;
+// the first is kept and returned by every later flush.
+func (t *tracer) flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	batch := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(batch) > 0 {
+		if err := t.export(ctx, batch); err != nil {
+			t.mu.Lock()
+			if t.err == nil {
+				t.err = err
+			}
+			t.mu.Unlock()
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *tracer) export(ctx context.Context, batch []*span) error {
+	spans := make([]map[string]any, 0, len(batch))
+	for _, s := range batch {
+		spans = append(spans, s.otlp())
+	}
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": otlpAttrs([]attr{{"service.name", t.service}})},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "rednafi.com/scripts/linkcheck"},
+				"spans": spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export traces: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("export traces: %s answered HTTP %d", t.endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// otlp renders s in OTLP's JSON encoding: hex IDs, nanosecond timestamps as
+// decimal strings.
+func (s *span) otlp() map[string]any {
+	out := map[string]any{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.id[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+		"attributes":        otlpAttrs(s.attrs),
+	}
+	if s.parent != [8]byte{} {
+		out["parentSpanId"] = hex.EncodeToString(s.parent[:])
+	}
+	if s.err != nil {
+		out["status"] = map[string]any{"code": statusError, "message": s.err.Error()}
+	}
+	return out
+}
+
+func otlpAttrs(attrs []attr) []any {
+	out := make([]any, 0, len(attrs))
+	for _, a := range attrs {
+		var value map[string]any
+		switch v := a.value.(type) {
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]any{"key": a.key, "value": value})
+	}
+	return out
+}
+
+// tracingTransport records a client span for every request, redirects
+// included, under the span in the request's context.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, s := startSpan(req.Context(), req.Method+" "+req.URL.Host,
+		"http.request.method", req.Method, "url.full", req.URL.String(), "server.address", req.URL.Hostname())
+	if s == nil {
+		return t.base.RoundTrip(req)
+	}
+	s.kind = spanKindClient
+	defer s.finish()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		s.fail(err)
+		return nil, err
+	}
+	s.set("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		s.fail(fmt.Errorf("HTTP %d", resp.StatusCode))
+	}
+	return resp, nil
+}
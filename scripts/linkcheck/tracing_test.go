@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTracingExportsNestedSpansOverOTLP(t *testing.T) {
+	var mu sync.Mutex
+	var spans []map[string]any
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad export", http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []map[string]any `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		spans = append(spans, body.ResourceSpans[0].ScopeSpans[0].Spans...)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer site.Close()
+
+	tr := newTracer(collector.URL, "linkcheck")
+	client := &http.Client{Transport: &tracingTransport{base: http.DefaultTransport}}
+	c := &checker{client: client, resolver: newDNSCache(netLookup), ignoreRobots: true, tracer: tr}
+	ctx, root := tr.start(context.Background(), "linkcheck")
+	c.sweep(ctx, []link{{URL: site.URL + "/gone", File: "a.md", Line: 1}}, 1)
+	root.finish()
+	if err := tr.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	byName := map[string]map[string]any{}
+	for _, s := range spans {
+		byName[s["name"].(string)] = s
+	}
+	request := byName["GET "+site.Listener.Addr().String()]
+	for child, parent := range map[string]string{"sweep": "linkcheck", "check": "sweep"} {
+		if byName[child] == nil || byName[child]["parentSpanId"] != byName[parent]["spanId"] {
+			t.Fatalf("span %q is not a child of %q: %v", child, parent, spans)
+		}
+	}
+	if request == nil || request["parentSpanId"] != byName["check"]["spanId"] || request["kind"] != 3.0 {
+		t.Fatalf("request span missing or misplaced: %v", spans)
+	}
+	if _, ok := byName["linkcheck"]["parentSpanId"]; ok || byName["check"]["traceId"] != byName["linkcheck"]["traceId"] {
+		t.Fatalf("spans don't share the root's trace: %v", spans)
+	}
+	if !hasAttr(request, "http.response.status_code", map[string]any{"intValue": "404"}) ||
+		!hasAttr(byName["check"], "linkcheck.class", map[string]any{"stringValue": ruleHTTP}) {
+		t.Fatalf("span attributes missing: %v", spans)
+	}
+}
+
+func TestTracingIsOffWithoutEndpoint(t *testing.T) {
+	tr := newTracer("", "linkcheck")
+	ctx, root := tr.start(context.Background(), "linkcheck")
+	if _, s := startSpan(ctx, "child"); root != nil || s != nil {
+		t.Fatal("spans started without an endpoint")
+	}
+	root.finish()
+	if err := tr.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hasAttr(span map[string]any, key string, value map[string]any) bool {
+	attrs, _ := span["attributes"].([]any)
+	for _, a := range attrs {
+		a := a.(map[string]any)
+		if a["key"] == key {
+			got, _ := json.Marshal(a["value"])
+			want, _ := json.Marshal(value)
+			return string(got) == string(want)
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// defaultTracking are the query parameters stripped from outbound links
+// when linkcheck.yml doesn't list its own. A trailing * matches by prefix.
+// It is narrower than trackingParams: ref and si also name real things on
+// some sites (a GitHub branch, say), so they are only a reason not to
+// auto-follow a redirect, never stripped outright.
+var defaultTracking = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid", "mc_cid", "mc_eid", "_ga", "ref_src"}
+
+// tracking returns the parameters to strip: tracking in linkcheck.yml, or
+// defaultTracking.
+func (c *checker) tracking() []string {
+	if len(c.config.Tracking) > 0 {
+		return c.config.Tracking
+	}
+	return defaultTracking
+}
+
+// trackingIn returns the parameters in rawURL's query that match params,
+// in query order.
+func trackingIn(rawURL string, params []string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return nil
+	}
+	var found []string
+	for pair := range strings.SplitSeq(u.RawQuery, "&") {
+		if name := queryName(pair); isTracking(name, params) && !slices.Contains(found, name) {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// stripTracking removes the parameters matching params from rawURL's query,
+// leaving the rest of the URL byte for byte as it was.
+func stripTracking(rawURL string, params []string) string {
+	base, fragment, hasFragment := strings.Cut(rawURL, "#")
+	base, query, ok := strings.Cut(base, "?")
+	if !ok {
+		return rawURL
+	}
+	kept := slices.DeleteFunc(strings.Split(query, "&"), func(pair string) bool {
+		return pair == "" || isTracking(queryName(pair), params)
+	})
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
+
+// trackingRewrites maps each link carrying tracking parameters to the URL
+// without them.
+func trackingRewrites(links []link, params []string) map[string]string {
+	rewrites := map[string]string{}
+	for _, rawURL := range uniqueURLs(links) {
+		if len(trackingIn(rawURL, params)) > 0 {
+			rewrites[rawURL] = stripTracking(rawURL, params)
+		}
+	}
+	return rewrites
+}
+
+func queryName(pair string) string {
+	name, _, _ := strings.Cut(pair, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.ToLower(name)
+}
+
+func isTracking(name string, params []string) bool {
+	for _, p := range params {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok && strings.HasPrefix(name, prefix) || name == p {
+			return true
+		}
+	}
+	return false
+}
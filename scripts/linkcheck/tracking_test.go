@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStripTracking(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+		found    []string
+	}{
+		{"https://a.example/post?utm_source=news&utm_medium=email", "https://a.example/post", []string{"utm_source", "utm_medium"}},
+		{"https://a.example/post?id=7&fbclid=x#intro", "https://a.example/post?id=7#intro", []string{"fbclid"}},
+		{"https://a.example/post?UTM_Campaign=x&q=a%20b&gclid=y", "https://a.example/post?q=a%20b", []string{"utm_campaign", "gclid"}},
+		{"https://github.com/a/b/tree/x?ref=main", "https://github.com/a/b/tree/x?ref=main", nil},
+		{"https://a.example/post?id=7", "https://a.example/post?id=7", nil},
+		{"https://a.example/#utm_source=x", "https://a.example/#utm_source=x", nil},
+	} {
+		if got := trackingIn(tc.in, defaultTracking); !slices.Equal(got, tc.found) {
+			t.Errorf("trackingIn(%q) = %q, want %q", tc.in, got, tc.found)
+		}
+		if got := stripTracking(tc.in, defaultTracking); got != tc.want {
+			t.Errorf("stripTracking(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	if got := stripTracking("https://a.example/?ref=main&x=1", []string{"ref"}); got != "https://a.example/?x=1" {
+		t.Errorf("configured list ignored: %q", got)
+	}
+}
+
+func TestSweepReportsTrackingParameters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	links := []link{
+		{URL: server.URL + "/a?utm_source=newsletter", File: "a.md", Line: 1},
+		{URL: server.URL + "/b?si=abc", File: "a.md", Line: 2},
+	}
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), ignoreRobots: true}
+	got := c.sweep(context.Background(), links, 1)
+	want := []finding{newFinding(links[0], ruleTracking, "tracking parameters utm_source; -fix strips them")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("sweep = %v, want %v", got, want)
+	}
+
+	c.config.Tracking = []string{"si"}
+	if got := c.sweep(context.Background(), links, 1); len(got) != 1 || got[0].Link != links[1] || slices.ContainsFunc(got, finding.failed) {
+		t.Fatalf("sweep with tracking: [si] = %v", got)
+	}
+	if rewrites := trackingRewrites(links, c.tracking()); len(rewrites) != 1 || !strings.HasSuffix(rewrites[links[1].URL], "/b") {
+		t.Fatalf("trackingRewrites = %v", rewrites)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const triageHelp = `commands:
+  enter, n  next finding        p  previous finding
+  e         open file in $EDITOR o  open URL in a browser
+  i         ignore (baseline)   r  recheck the URL
+  g         next group          q  quit
+`
+
+// triage steps through findings one at a time, grouped by rule and then
+// by content section, and lets the user act on each. It reads commands
+// from in and writes to out, so it works in any terminal without a
+// full-screen UI.
+type triage struct {
+	checker      *checker
+	findings     []finding
+	baseline     baseline
+	baselinePath string
+
+	in    *bufio.Scanner
+	out   io.Writer
+	style style
+
+	// edit and browse open a file at a line and a URL; tests replace them.
+	edit   func(file string, line int) error
+	browse func(rawURL string) error
+}
+
+func newTriage(c *checker, findings []finding, b baseline, baselinePath string, in io.Reader, out io.Writer) *triage {
+	t := &triage{
+		checker:      c,
+		findings:     slices.Clone(findings),
+		baseline:     b,
+		baselinePath: baselinePath,
+		in:           bufio.NewScanner(in),
+		out:          out,
+		edit:         openInEditor,
+		browse:       openInBrowser,
+	}
+	slices.SortStableFunc(t.findings, func(a, b finding) int {
+		return cmp.Or(cmp.Compare(groupIndex(a.Rule), groupIndex(b.Rule)), strings.Compare(section(a.Link.File), section(b.Link.File)))
+	})
+	return t
+}
+
+// run loops until the user quits or walks past the last finding.
+func (t *triage) run(ctx context.Context) error {
+	if len(t.findings) == 0 {
+		fmt.Fprintln(t.out, "nothing to triage")
+		return nil
+	}
+	fmt.Fprint(t.out, triageHelp)
+	i := 0
+	for i < len(t.findings) {
+		t.show(i)
+		fmt.Fprint(t.out, "> ")
+		if !t.in.Scan() {
+			return t.in.Err()
+		}
+		f := t.findings[i]
+		switch cmd := strings.TrimSpace(t.in.Text()); cmd {
+		case "", "n":
+			i++
+		case "p":
+			i = max(i-1, 0)
+		case "g":
+			i = t.nextGroup(i)
+		case "e":
+			if err := t.edit(f.Link.File, f.Link.Line); err != nil {
+				fmt.Fprintln(t.out, "edit:", err)
+			}
+		case "o":
+			if err := t.browse(f.Link.URL); err != nil {
+				fmt.Fprintln(t.out, "open:", err)
+			}
+		case "i":
+			t.baseline[f.Link.URL] = true
+			if err := t.baseline.save(t.baselinePath); err != nil {
+				return err
+			}
+			fmt.Fprintf(t.out, "added %s to %s\n", f.Link.URL, t.baselinePath)
+			i++
+		case "r":
+			t.recheck(ctx, f.Link.URL)
+		case "q":
+			return nil
+		default:
+			fmt.Fprintf(t.out, "unknown command %q\n%s", cmd, triageHelp)
+		}
+	}
+	fmt.Fprintln(t.out, "end of findings")
+	return nil
+}
+
+func (t *triage) show(i int) {
+	f := t.findings[i]
+	title := f.Rule
+	if g := groupIndex(f.Rule); g < len(reportGroups) {
+		title = reportGroups[g].title
+	}
+	baselined := ""
+	if t.baseline[f.Link.URL] {
+		baselined = " [baselined]"
+	}
+	fmt.Fprintf(t.out, "\n[%d/%d] %s %s %s%s\n  %s:%d:%d\n  %s\n  %s: %s\n",
+		i+1, len(t.findings), t.style.paint(title, groupColor(f.Rule)...), t.style.sep(), section(f.Link.File), baselined, f.Link.File, f.Link.Line, f.Link.Column, f.Link.URL, f.Severity, f.Message)
+}
+
+// nextGroup returns the index of the first finding after i in a different
+// rule or section, or the end.
+func (t *triage) nextGroup(i int) int {
+	cur := t.findings[i]
+	for j := i + 1; j < len(t.findings); j++ {
+		if t.findings[j].Rule != cur.Rule || section(t.findings[j].Link.File) != section(cur.Link.File) {
+			return j
+		}
+	}
+	return len(t.findings)
+}
+
+// recheck checks rawURL again, bypassing the cache, and updates every
+// finding for it.
+func (t *triage) recheck(ctx context.Context, rawURL string) {
+	r := t.checker.check(ctx, rawURL, validators{})
+	t.checker.remember(rawURL, r)
+	for i := range t.findings {
+		if t.findings[i].Link.URL == rawURL {
+			t.findings[i] = newFinding(t.findings[i].Link, cmp.Or(r.class, ruleOK), cmp.Or(r.reason, "alive now"))
+		}
+	}
+	fmt.Fprintf(t.out, "recheck: %s\n", cmp.Or(r.reason, "alive now"))
+}
+
+func groupIndex(rule string) int {
+	i := slices.IndexFunc(reportGroups, func(g reportGroup) bool { return g.rule == rule })
+	if i < 0 {
+		return len(reportGroups)
+	}
+	return i
+}
+
+// section returns the content section a file belongs to, such as go or
+// python for content/go/foo.md.
+func section(file string) string {
+	rel, err := filepath.Rel(contentDir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Dir(file)
+	}
+	if dir, _, ok := strings.Cut(filepath.ToSlash(rel), "/"); ok {
+		return dir
+	}
+	return "(root)"
+}
+
+func openInEditor(file string, line int) error {
+	editor := cmp.Or(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi")
+	cmd := exec.Command(editor, "+"+strconv.Itoa(line), file)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func openInBrowser(rawURL string) error {
+	name := "xdg-open"
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", rawURL).Start()
+	}
+	return exec.Command(name, rawURL).Start()
+}
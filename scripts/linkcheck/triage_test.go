@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTriageSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	findings := []finding{
+		newFinding(link{URL: server.URL + "/back", File: "content/python/p.md", Line: 4}, ruleHTTP, "HTTP 404"),
+		newFinding(link{URL: "https://gone.example/", File: "content/go/g.md", Line: 9}, ruleNXDomain, "host does not resolve"),
+		newFinding(link{URL: server.URL + "/flaky", File: "content/go/g.md", Line: 12}, ruleHTTP, "HTTP 503"),
+	}
+	baselinePath := filepath.Join(t.TempDir(), "linkcheck.baseline")
+	c := &checker{client: server.Client(), resolver: newDNSCache(netLookup), robots: newRobotsCache(server.Client())}
+	var out strings.Builder
+	session := newTriage(c, findings, baseline{}, baselinePath, strings.NewReader("e\ni\no\ng\nr\nq\n"), &out)
+
+	var edited, browsed []string
+	session.edit = func(file string, line int) error {
+		edited = append(edited, file)
+		return nil
+	}
+	session.browse = func(rawURL string) error {
+		browsed = append(browsed, rawURL)
+		return nil
+	}
+	if err := session.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// NXDOMAIN sorts first; within broken links, go precedes python.
+	if len(edited) != 1 || edited[0] != "content/go/g.md" {
+		t.Errorf("edited %v, want the NXDOMAIN finding's file", edited)
+	}
+	if len(browsed) != 1 || browsed[0] != server.URL+"/flaky" {
+		t.Errorf("browsed %v, want the second finding's URL", browsed)
+	}
+	if !strings.Contains(out.String(), "recheck: alive now") {
+		t.Errorf("recheck of python finding missing from output:\n%s", out.String())
+	}
+
+	saved, err := loadBaseline(baselinePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || !saved["https://gone.example/"] {
+		t.Errorf("baseline = %v, want only the ignored URL", saved)
+	}
+	kept, hidden := saved.filter(findings)
+	if len(kept) != 2 || hidden != 1 {
+		t.Errorf("filter kept %d and hid %d, want 2 and 1", len(kept), hidden)
+	}
+}
+
+func TestSection(t *testing.T) {
+	for file, want := range map[string]string{
+		"content/go/anemic-stack-traces.md": "go",
+		"content/about.md":                  "(root)",
+		"content/shards/2024/x.md":          "shards",
+	} {
+		if got := section(file); got != want {
+			t.Errorf("section(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// defaultUptimeHistory is in Hugo's data directory, so the next build's
+// status page renders from it.
+const defaultUptimeHistory = "data/uptime.json"
+
+// uptimeWindow is how far back the history keeps runs, and so the span the
+// status page's percentages cover.
+const uptimeWindow = 30 * 24 * time.Hour
+
+// uptimeHistory is the file -uptime appends to. Runs is oldest first; URLs
+// summarizes them for the status page, which can't aggregate thousands of
+// runs cheaply in a template.
+type uptimeHistory struct {
+	Updated time.Time   `json:"updated"`
+	URLs    []urlUptime `json:"urls"`
+	Runs    []uptimeRun `json:"runs"`
+}
+
+// uptimeRun is one -uptime pass over a group.
+type uptimeRun struct {
+	Time    time.Time     `json:"time"`
+	Group   string        `json:"group"`
+	Results []probeResult `json:"results"`
+}
+
+type probeResult struct {
+	URL      string   `json:"url"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// urlUptime is one URL's record over the window.
+type urlUptime struct {
+	URL          string    `json:"url"`
+	Group        string    `json:"group"`
+	Checks       int       `json:"checks"`
+	Up           int       `json:"up"`
+	Percent      float64   `json:"percent"`
+	LastChecked  time.Time `json:"lastChecked"`
+	LastOK       bool      `json:"lastOK"`
+	LastProblems []string  `json:"lastProblems,omitempty"`
+}
+
+// uptimeOnce probes every uptime group once, as a cron job would every few
+// minutes, prints each group's result, and appends it to the history at
+// path. It reports whether any URL was down.
+func (d *daemon) uptimeOnce(ctx context.Context, w io.Writer, path string) (bool, error) {
+	h, err := loadUptimeHistory(path)
+	if err != nil {
+		return false, err
+	}
+	var failed, ran bool
+	for _, g := range d.groups {
+		if g.Check != checkUptime || len(g.URLs) == 0 {
+			continue
+		}
+		ran = true
+		run := uptimeRun{Time: d.now().UTC(), Group: g.Name}
+		var down int
+		for i, problems := range d.probeGroup(ctx, g) {
+			run.Results = append(run.Results, probeResult{URL: g.URLs[i], Problems: problems})
+			if len(problems) > 0 {
+				down++
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(w, "%s: %d/%d up\n", g.Name, len(g.URLs)-down, len(g.URLs))
+		for _, r := range run.Results {
+			for _, p := range r.Problems {
+				fmt.Fprintf(w, "  %s\n", p)
+			}
+		}
+		failed = failed || down > 0
+		h.Runs = append(h.Runs, run)
+	}
+	if !ran {
+		return false, errors.New("no uptime groups in the daemon section of linkcheck.yml")
+	}
+	h.trim(d.now().Add(-uptimeWindow))
+	h.summarize()
+	h.Updated = d.now().UTC()
+	return failed, h.save(path)
+}
+
+// trim drops the runs before cutoff.
+func (h *uptimeHistory) trim(cutoff time.Time) {
+	h.Runs = slices.DeleteFunc(h.Runs, func(r uptimeRun) bool { return r.Time.Before(cutoff) })
+}
+
+// summarize recomputes URLs from Runs, in the order URLs were first seen.
+func (h *uptimeHistory) summarize() {
+	index := map[string]int{}
+	h.URLs = nil
+	for _, run := range h.Runs {
+		for _, r := range run.Results {
+			i, ok := index[r.URL]
+			if !ok {
+				i = len(h.URLs)
+				index[r.URL] = i
+				h.URLs = append(h.URLs, urlUptime{URL: r.URL})
+			}
+			u := &h.URLs[i]
+			u.Group = run.Group
+			u.Checks++
+			u.LastChecked = run.Time
+			u.LastOK = len(r.Problems) == 0
+			u.LastProblems = r.Problems
+			if u.LastOK {
+				u.Up++
+			}
+		}
+	}
+	for i := range h.URLs {
+		u := &h.URLs[i]
+		// Truncated to two decimals so the page prints it as is, and never
+		// shows 100 while a check has failed.
+		u.Percent = float64(u.Up*10000/u.Checks) / 100
+	}
+}
+
+func (h *uptimeHistory) save(path string) error {
+	raw, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// loadUptimeHistory reads the history file, treating a missing file as
+// empty.
+func loadUptimeHistory(path string) (*uptimeHistory, error) {
+	h := &uptimeHistory{}
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, h); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return h, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUptimeOnceAppendsHistory(t *testing.T) {
+	down := true
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/feed" && down {
+			http.Error(w, "oops", http.StatusBadGateway)
+		}
+	}))
+	defer site.Close()
+
+	path := filepath.Join(t.TempDir(), "data", "uptime.json")
+	clock := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	d := &daemon{
+		checker: &checker{client: site.Client(), resolver: newDNSCache(netLookup)},
+		groups: []groupConfig{
+			{Name: "links", Check: checkLinks},
+			{Name: "uptime", Check: checkUptime, URLs: []string{site.URL + "/", site.URL + "/feed"}},
+		},
+		workers: 2,
+		now:     func() time.Time { return clock },
+	}
+
+	var out strings.Builder
+	failed, err := d.uptimeOnce(context.Background(), &out, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !failed || !strings.Contains(out.String(), "uptime: 1/2 up\n") || !strings.Contains(out.String(), "/feed: HTTP 502") {
+		t.Fatalf("failed = %t, output:\n%s", failed, out.String())
+	}
+
+	down = false
+	for range 3 {
+		clock = clock.Add(5 * time.Minute)
+		if failed, err := d.uptimeOnce(context.Background(), &out, path); err != nil || failed {
+			t.Fatalf("uptimeOnce = %t, %v; want a clean run", failed, err)
+		}
+	}
+	h, err := loadUptimeHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Runs) != 4 || len(h.URLs) != 2 {
+		t.Fatalf("history has %d runs and %d URLs, want 4 and 2", len(h.Runs), len(h.URLs))
+	}
+	feed := h.URLs[1]
+	if feed.Checks != 4 || feed.Up != 3 || feed.Percent != 75 || !feed.LastOK || !feed.LastChecked.Equal(clock) {
+		t.Fatalf("feed summary = %+v", feed)
+	}
+
+	// A month on, the failed run has aged out of the window.
+	clock = clock.Add(uptimeWindow - 5*time.Minute)
+	if _, err := d.uptimeOnce(context.Background(), &out, path); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ = loadUptimeHistory(path); len(h.Runs) != 3 || h.URLs[1].Percent != 100 {
+		t.Fatalf("after a month: %d runs, feed at %v%%; want 3 runs at 100%%", len(h.Runs), h.URLs[1].Percent)
+	}
+}
+
+func TestUptimeOnceNeedsAnUptimeGroup(t *testing.T) {
+	d := &daemon{groups: []groupConfig{{Name: "links", Check: checkLinks}}, now: time.Now}
+	if _, err := d.uptimeOnce(context.Background(), &strings.Builder{}, filepath.Join(t.TempDir(), "uptime.json")); err == nil {
+		t.Fatal("uptimeOnce without uptime groups succeeded")
+	}
+}
+
+func TestUptimePercentNeverRoundsUpToFull(t *testing.T) {
+	h := &uptimeHistory{}
+	for i := range 10000 {
+		var problems []string
+		if i == 0 {
+			problems = []string{"down"}
+		}
+		h.Runs = append(h.Runs, uptimeRun{Group: "uptime", Results: []probeResult{{URL: "https://rednafi.com/", Problems: problems}}})
+	}
+	h.summarize()
+	if got := h.URLs[0].Percent; got != 99.99 {
+		t.Fatalf("percent = %v, want 99.99", got)
+	}
+}
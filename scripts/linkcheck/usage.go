@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// usage is what a run cost: the HTTP requests it sent, including robots.txt
+// fetches and redirect hops, the bytes it downloaded, the throttled
+// requests it retried, and the links the cache answered without a request.
+type usage struct {
+	Requests  int64 `json:"requests"`
+	Bytes     int64 `json:"bytes"`
+	Retries   int64 `json:"retries"`
+	CacheHits int64 `json:"cacheHits"`
+}
+
+// runUsage reads a run's counters off its transport and checker.
+func runUsage(t *limitedTransport, c *checker) usage {
+	return usage{
+		Requests:  t.sent.Load(),
+		Bytes:     t.received.Load(),
+		Retries:   c.retries.Load(),
+		CacheHits: c.cacheHits.Load(),
+	}
+}
+
+// add sums the usage of the shards of one sweep.
+func (u usage) add(other usage) usage {
+	return usage{
+		Requests:  u.Requests + other.Requests,
+		Bytes:     u.Bytes + other.Bytes,
+		Retries:   u.Retries + other.Retries,
+		CacheHits: u.CacheHits + other.CacheHits,
+	}
+}
+
+func (u usage) String() string {
+	retries := "retries"
+	if u.Retries == 1 {
+		retries = "retry"
+	}
+	return fmt.Sprintf("cost: %d %s, %s downloaded, %d %s, %d %s\n",
+		u.Requests, plural(int(u.Requests), "request"), byteSize(u.Bytes), u.Retries, retries, u.CacheHits, plural(int(u.CacheHits), "cache hit"))
+}
+
+// byteSize formats n in the largest binary unit that keeps it at least 1.
+func byteSize(n int64) string {
+	if n < 1<<10 {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, unit := float64(n)/(1<<10), "KiB"
+	for _, next := range []string{"MiB", "GiB"} {
+		if v < 1<<10 {
+			break
+		}
+		v, unit = v/(1<<10), next
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0") + " " + unit
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunUsageCountsTheSweepsCost(t *testing.T) {
+	const page = "<html><head><title>Busy</title></head><body><p>A page long enough to read as one.</p></body></html>"
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+		case "/busy":
+			hits++
+			if hits == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(page))
+		case "/overloaded":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	limited := &limitedTransport{limiter: newHostLimiter(limitsConfig{}), timeout: time.Second}
+	limited.base = &http.Transport{DialContext: countingDial(new(net.Dialer).DialContext, &limited.received)}
+	client := &http.Client{Transport: limited}
+	cache, err := loadCache(filepath.Join(t.TempDir(), "cache.json"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &checker{client: client, resolver: newDNSCache(netLookup), robots: newRobotsCache(client), cache: cache, retryBudget: time.Minute}
+	links := []link{
+		{URL: server.URL + "/busy", File: "a.md", Line: 1},
+		{URL: server.URL + "/overloaded", File: "a.md", Line: 2},
+	}
+	c.sweep(context.Background(), links, 1)
+	c.sweep(context.Background(), links, 1)
+
+	// robots.txt, /busy twice and /overloaded on the first sweep; the
+	// second finds /busy cached and asks /overloaded again, as throttles
+	// aren't cached.
+	got := runUsage(limited, c)
+	if want := (usage{Requests: 5, Retries: 1, CacheHits: 1}); got.Requests != want.Requests || got.Retries != want.Retries || got.CacheHits != want.CacheHits {
+		t.Fatalf("usage = %+v, want %+v", got, want)
+	}
+	if got.Bytes < int64(len(page)) {
+		t.Errorf("bytes = %d, want at least the page body", got.Bytes)
+	}
+}
+
+func TestRetriedStopsAtTheBudget(t *testing.T) {
+	throttles := []throttle{{Wait: 20 * time.Second}, {Wait: 30 * time.Second}, {Wait: 20 * time.Second}}
+	if got := retried(throttles, time.Minute); got != 2 {
+		t.Errorf("retried = %d, want 2: the third wait is past what the budget has left", got)
+	}
+	if got := retried(nil, time.Minute); got != 0 {
+		t.Errorf("retried = %d with no throttles", got)
+	}
+}
+
+func TestUsageString(t *testing.T) {
+	for _, tc := range []struct {
+		u    usage
+		want string
+	}{
+		{usage{}, "cost: 0 requests, 0 B downloaded, 0 retries, 0 cache hits\n"},
+		{usage{Requests: 1, Bytes: 1536, Retries: 1, CacheHits: 1}, "cost: 1 request, 1.5 KiB downloaded, 1 retry, 1 cache hit\n"},
+		{usage{Requests: 412, Bytes: 3 << 20, Retries: 2, CacheHits: 180}, "cost: 412 requests, 3 MiB downloaded, 2 retries, 180 cache hits\n"},
+	} {
+		if got := tc.u.String(); got != tc.want {
+			t.Errorf("%+v = %q, want %q", tc.u, got, tc.want)
+		}
+	}
+}
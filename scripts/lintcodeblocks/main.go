@@ -1,10 +1,15 @@
 // Command lintcodeblocks normalizes whitespace in the site's Markdown.
 //
 // Markdown files under content/ must use spaces, not tabs (tabs render
-// inconsistently inside fenced code blocks). By default it rewrites every
-// offending file in place, replacing each tab with four spaces. With
-// --check it touches nothing and exits non-zero if any file contains a tab,
-// which is what CI and local checks want.
+// inconsistently inside fenced code blocks). They must also use LF line
+// endings, carry no trailing whitespace, never stack more than two blank lines,
+// and end with a single newline. Fenced code blocks keep their trailing
+// whitespace and blank lines, since those can be part of the example. Hard
+// line breaks need a trailing backslash, not two trailing spaces.
+//
+// By default it rewrites every offending file in place. With --check it
+// touches nothing and exits non-zero if any file needs normalizing, which is
+// what CI and local checks want.
 package main
 
 import (
@@ -20,12 +25,74 @@ const contentDir = "content"
 
 var tab = []byte("\t")
 var fourSpaces = []byte("    ")
+var crlf = []byte("\r\n")
+var lf = []byte("\n")
+
+// maxBlankLines is the longest run of blank lines left alone; longer runs
+// are cut down to it.
+const maxBlankLines = 2
 
 // fixTabs replaces every tab with four spaces.
 func fixTabs(b []byte) []byte {
 	return bytes.ReplaceAll(b, tab, fourSpaces)
 }
 
+// normalizeWhitespace converts CRLF to LF, strips trailing whitespace and
+// cuts blank-line runs down to maxBlankLines outside fenced code blocks, and
+// leaves the file ending in exactly one newline.
+func normalizeWhitespace(b []byte) []byte {
+	b = bytes.ReplaceAll(b, crlf, lf)
+	lines := bytes.Split(bytes.TrimRight(b, "\n"), lf)
+
+	var out [][]byte
+	var fence []byte
+	blank := 0
+	for _, line := range lines {
+		if fence != nil {
+			if isFenceClose(line, fence) {
+				fence = nil
+			}
+			out = append(out, line)
+			continue
+		}
+
+		line = bytes.TrimRight(line, " \t")
+		if len(line) == 0 {
+			if blank++; blank > maxBlankLines {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		fence = fenceOpen(line)
+		out = append(out, line)
+	}
+	return append(bytes.Join(out, lf), '\n')
+}
+
+// fenceOpen returns the backtick or tilde run that opens a fenced code block
+// on line, or nil if the line doesn't open one.
+func fenceOpen(line []byte) []byte {
+	trimmed := bytes.TrimLeft(line, " ")
+	for _, marker := range []byte("`~") {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == marker {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return nil
+}
+
+// isFenceClose reports whether line closes a block opened by fence: the same
+// marker, at least as long, and nothing else on the line.
+func isFenceClose(line, fence []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) >= len(fence) && len(bytes.Trim(trimmed, string(fence[:1]))) == 0
+}
+
 // markdownFiles returns every *.md file under dir, recursively.
 func markdownFiles(dir string) ([]string, error) {
 	var files []string
@@ -42,7 +109,7 @@ func markdownFiles(dir string) ([]string, error) {
 }
 
 func main() {
-	check := flag.Bool("check", false, "report files containing tabs and exit non-zero instead of fixing them")
+	check := flag.Bool("check", false, "report files with tabs or stray whitespace and exit non-zero instead of fixing them")
 	flag.Parse()
 
 	files, err := markdownFiles(contentDir)
@@ -58,12 +125,18 @@ func main() {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		if !bytes.Contains(content, tab) {
+		hasTabs := bytes.Contains(content, tab)
+		fixed := normalizeWhitespace(fixTabs(content))
+		if bytes.Equal(fixed, content) {
 			continue
 		}
 
 		if *check {
-			fmt.Printf("ERROR: %s contains tabs\n", file)
+			if hasTabs {
+				fmt.Printf("ERROR: %s contains tabs\n", file)
+			} else {
+				fmt.Printf("ERROR: %s needs whitespace normalization\n", file)
+			}
 			failed = true
 			continue
 		}
@@ -73,11 +146,11 @@ func main() {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		if err := os.WriteFile(file, fixTabs(content), info.Mode().Perm()); err != nil {
+		if err := os.WriteFile(file, fixed, info.Mode().Perm()); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		fmt.Printf("Fixed tabs in: %s\n", file)
+		fmt.Printf("Fixed whitespace in: %s\n", file)
 	}
 
 	if failed {
@@ -27,6 +27,33 @@ func TestFixTabs(t *testing.T) {
 	}
 }
 
+func TestNormalizeWhitespace(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"already clean", "a\n\nb\n", "a\n\nb\n"},
+		{"crlf", "a\r\nb\r\n", "a\nb\n"},
+		{"trailing whitespace", "a  \nb \t\n", "a\nb\n"},
+		{"two blank lines kept", "a\n\n\nb\n", "a\n\n\nb\n"},
+		{"blank-line run", "a\n\n\n\n\nb\n", "a\n\n\nb\n"},
+		{"missing final newline", "a", "a\n"},
+		{"extra final newlines", "a\n\n\n", "a\n"},
+		{"fence keeps whitespace", "```txt\nx  \n\n\n\ny\n```\n\n\n\nz\n", "```txt\nx  \n\n\n\ny\n```\n\n\nz\n"},
+		{"longer fence needs longer close", "````md\n```\nx  \n````\ny  \n", "````md\n```\nx  \n````\ny\n"},
+		{"tilde fence", "~~~\nx  \n~~~\n", "~~~\nx  \n~~~\n"},
+		{"indented fence", "- item\n\n    ```go\n    x  \n    ```\n", "- item\n\n    ```go\n    x  \n    ```\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := string(normalizeWhitespace([]byte(c.in))); got != c.want {
+				t.Errorf("normalizeWhitespace(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
 func TestMarkdownFiles(t *testing.T) {
 	dir := t.TempDir()
 	mustWrite(t, filepath.Join(dir, "a.md"), "x")
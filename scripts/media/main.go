@@ -34,6 +34,7 @@ func main() {
 	publicBase := flag.String("public-base", defaultPublicBase, "public R2 custom-domain base URL")
 	bucket := flag.String("bucket", defaultBucket, "R2 bucket name")
 	wrangler := flag.String("wrangler", defaultWrangler, "wrangler command")
+	dryRun := flag.Bool("dry-run", false, "print the R2 upload and the image URL without uploading")
 	flag.Parse()
 
 	if *check {
@@ -43,7 +44,7 @@ func main() {
 		return
 	}
 	if *postPath != "" || *filePath != "" || *imageName != "" {
-		if err := uploadPostImage(*publicBase, *bucket, *wrangler, *postPath, *filePath, *imageName); err != nil {
+		if err := uploadPostImage(*publicBase, *bucket, *wrangler, *postPath, *filePath, *imageName, *dryRun); err != nil {
 			fatal(err)
 		}
 		return
@@ -51,7 +52,11 @@ func main() {
 	fatal(fmt.Errorf("pass --check or --post/--file/--name"))
 }
 
-func uploadPostImage(publicBase, bucket, wrangler, postPath, sourcePath, imageName string) error {
+// uploadPostImage optimizes an image, uploads it to R2 under a key derived
+// from the post and the image's content, and prints its public URL. With
+// dryRun it prints the wrangler command instead of running it; the image
+// is still optimized, in a temporary copy, since the key hashes the result.
+func uploadPostImage(publicBase, bucket, wrangler, postPath, sourcePath, imageName string, dryRun bool) error {
 	if postPath == "" || sourcePath == "" || imageName == "" {
 		return fmt.Errorf("--post, --file, and --name are required for image upload")
 	}
@@ -98,7 +103,9 @@ func uploadPostImage(publicBase, bucket, wrangler, postPath, sourcePath, imageNa
 		return err
 	}
 	key := path.Join(dir, canonicalName+"-"+hash+ext)
-	if err := putR2Object(wrangler, bucket, key, tmpFile, contentType(key)); err != nil {
+	if dryRun {
+		fmt.Printf("would upload the optimized %s to R2:\n  %s\n", sourcePath, putR2Command(wrangler, bucket, key, sourcePath, contentType(key)))
+	} else if err := runShell(putR2Command(wrangler, bucket, key, tmpFile, contentType(key))); err != nil {
 		return err
 	}
 
@@ -296,13 +303,15 @@ func optimizeLosslessly(filePath, ext string) error {
 	}
 }
 
-func putR2Object(wrangler, bucket, key, filePath, ct string) error {
-	return runShell(wrangler +
+// putR2Command is the wrangler command that uploads filePath to bucket
+// under key.
+func putR2Command(wrangler, bucket, key, filePath, ct string) string {
+	return wrangler +
 		" r2 object put " + shellQuote(bucket+"/"+key) +
 		" --file " + shellQuote(filePath) +
 		" --content-type " + shellQuote(ct) +
 		" --cache-control " + shellQuote(immutableCache) +
-		" --remote")
+		" --remote"
 }
 
 func contentType(key string) string {
@@ -60,6 +60,14 @@ func TestContentType(t *testing.T) {
 	}
 }
 
+func TestPutR2Command(t *testing.T) {
+	got := putR2Command("wrangler", "blog", "go/it's-a1b2c3.png", "/tmp/x.png", "image/png")
+	want := "wrangler r2 object put 'blog/go/it'\\''s-a1b2c3.png' --file '/tmp/x.png' --content-type 'image/png' --cache-control 'public, max-age=31536000, immutable' --remote"
+	if got != want {
+		t.Fatalf("putR2Command =\n%s\nwant\n%s", got, want)
+	}
+}
+
 func TestCanonicalDir(t *testing.T) {
 	dir := t.TempDir()
 	prev, err := os.Getwd()
@@ -0,0 +1,316 @@
+// Command readingtime computes each post's reading time and writes it to
+// data/readingtime.json, keyed by the post's path under content/. Templates
+// read the value from there instead of Hugo's fixed 213-words-per-minute
+// estimate, which counts a line of code like a line of prose.
+//
+// The speed comes from params.readingTime in config.yml: wordsPerMinute for
+// prose, and codeWeight for how many prose words one word in a code block is
+// worth. Run with -check to fail when the data file is stale.
+//
+// With -drift it checks the build in public/ instead: each post's rendered
+// body should hold about as many words as its source. A page far short of
+// its source points at a shortcode swallowing what follows it, an unclosed
+// raw HTML tag, or a template rendering the summary instead of the post;
+// one far over it, at a shortcode repeating content. Without a build it
+// fails, rather than pass having checked nothing.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentDir = "content"
+	publicDir  = "public"
+	dataFile   = "data/readingtime.json"
+)
+
+// A rendered post drifts when its word count falls outside these shares of
+// its source's. Rendering adds heading anchors and footnote backlinks and
+// drops link targets, so only gaps too wide for that are reported, and
+// only for posts long enough for the share to mean something.
+const (
+	driftLow      = 0.6
+	driftHigh     = 1.6
+	driftMinWords = 100
+)
+
+// Defaults when config.yml sets no reading speed.
+const (
+	defaultWordsPerMinute = 220
+	defaultCodeWeight     = 2
+)
+
+type siteConfig struct {
+	Params struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+		ReadingTime  speed    `yaml:"readingTime"`
+	} `yaml:"params"`
+}
+
+// speed is params.readingTime.
+type speed struct {
+	WordsPerMinute float64 `yaml:"wordsPerMinute"`
+	CodeWeight     float64 `yaml:"codeWeight"`
+}
+
+func main() {
+	check := flag.Bool("check", false, "fail if data/readingtime.json is stale")
+	drift := flag.Bool("drift", false, "fail if a rendered post in public/ holds far more or fewer words than its source")
+	flag.Parse()
+
+	sections, s, err := loadConfig("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	if *drift {
+		drifted, err := wordDrift(contentDir, publicDir, sections)
+		if err != nil {
+			fatal(err)
+		}
+		if len(drifted) > 0 {
+			fatal(fmt.Errorf("rendered posts don't match their source:\n  %s", strings.Join(drifted, "\n  ")))
+		}
+		return
+	}
+	times, err := readingTimes(contentDir, sections, s)
+	if err != nil {
+		fatal(err)
+	}
+	next, err := json.MarshalIndent(times, "", "  ")
+	if err != nil {
+		fatal(err)
+	}
+	next = append(next, '\n')
+
+	current, err := os.ReadFile(dataFile)
+	if err != nil && !os.IsNotExist(err) {
+		fatal(err)
+	}
+	if bytes.Equal(current, next) {
+		return
+	}
+	if *check {
+		fatal(fmt.Errorf("%s is stale; run `go run ./scripts/readingtime`", dataFile))
+	}
+	if err := os.MkdirAll(filepath.Dir(dataFile), 0o755); err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(dataFile, next, 0o644); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("updated reading times for %d posts\n", len(times))
+}
+
+// readingTimes returns the reading time in minutes of every post under
+// sections, keyed by its slash-separated path relative to root.
+func readingTimes(root string, sections []string, s speed) (map[string]int, error) {
+	times := map[string]int{}
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		section, _, _ := strings.Cut(rel, "/")
+		if !slices.Contains(sections, section) {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		_, body, _ := splitFrontmatter(string(raw))
+		prose, code := countWords(body)
+		times[rel] = minutes(prose, code, s)
+		return nil
+	})
+	return times, err
+}
+
+var (
+	fencePattern  = regexp.MustCompile("(?ms)^[ \\t]*(```|~~~)[^\\n]*\\n(.*?)^[ \\t]*(```|~~~)[ \\t]*$")
+	markupPattern = regexp.MustCompile(`<[^>]*>|\{\{[<%].*?[%>]\}\}`)
+)
+
+// countWords splits body into prose words and words inside fenced code
+// blocks. HTML tags and shortcode calls count as neither.
+func countWords(body string) (prose, code int) {
+	for _, m := range fencePattern.FindAllStringSubmatch(body, -1) {
+		code += len(strings.Fields(m[2]))
+	}
+	text := fencePattern.ReplaceAllString(body, "")
+	prose = len(strings.Fields(markupPattern.ReplaceAllString(text, "")))
+	return prose, code
+}
+
+var (
+	linkTargetPattern = regexp.MustCompile(`\]\([^)]*\)`)
+	articlePattern    = regexp.MustCompile(`(?s)<div class="?article-content"?>(.*?)(?:<footer\b|</article>)`)
+	prePattern        = regexp.MustCompile(`(?is)<pre\b[^>]*>(.*?)</pre>`)
+	invisiblePattern  = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>`)
+	tagPattern        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// wordDrift compares every post under sections in root with its page in
+// public, found through the atprotoPath the frontmatter script keeps
+// canonical, and describes the ones whose word counts drifted apart. A
+// public without a build is an error.
+func wordDrift(root, public string, sections []string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(public, "index.html")); err != nil {
+		return nil, fmt.Errorf("no build in %s to check; run hugo first: %w", public, err)
+	}
+	var drifted []string
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" || filepath.Base(filePath) == "_index.md" {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		section, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+		if !slices.Contains(sections, section) {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		fmRaw, body, _ := splitFrontmatter(string(raw))
+		var fm struct {
+			Draft       bool   `yaml:"draft"`
+			AtprotoPath string `yaml:"atprotoPath"`
+		}
+		if err := yaml.Unmarshal([]byte(fmRaw), &fm); err != nil {
+			return fmt.Errorf("%s: parse frontmatter: %w", filePath, err)
+		}
+		if fm.Draft || fm.AtprotoPath == "" {
+			return nil
+		}
+		page, err := os.ReadFile(filepath.Join(public, filepath.FromSlash(fm.AtprotoPath), "index.html"))
+		if os.IsNotExist(err) {
+			drifted = append(drifted, fmt.Sprintf("%s: %s was not rendered", filepath.ToSlash(filePath), fm.AtprotoPath))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		source := sourceWords(body)
+		rendered, ok := renderedWords(string(page))
+		if !ok {
+			drifted = append(drifted, fmt.Sprintf("%s: %s has no article body", filepath.ToSlash(filePath), fm.AtprotoPath))
+			return nil
+		}
+		if source < driftMinWords {
+			return nil
+		}
+		if share := float64(rendered) / float64(source); share < driftLow || share > driftHigh {
+			drifted = append(drifted, fmt.Sprintf("%s: %d words in the source, %d rendered at %s (%.0f%%)", filepath.ToSlash(filePath), source, rendered, fm.AtprotoPath, share*100))
+		}
+		return nil
+	})
+	return drifted, err
+}
+
+// sourceWords counts the words a reader should see in a post's Markdown,
+// prose and code alike, leaving out link targets and markup.
+func sourceWords(body string) int {
+	return wordCount(markupPattern.ReplaceAllString(linkTargetPattern.ReplaceAllString(body, "]"), " "))
+}
+
+// renderedWords counts the words in a rendered post's article body. Code
+// blocks count as their text, since syntax highlighting wraps every token
+// in a tag of its own; elsewhere a tag separates words.
+func renderedWords(page string) (int, bool) {
+	m := articlePattern.FindStringSubmatch(page)
+	if m == nil {
+		return 0, false
+	}
+	article := invisiblePattern.ReplaceAllString(m[1], " ")
+	var words int
+	for _, pre := range prePattern.FindAllStringSubmatch(article, -1) {
+		words += wordCount(html.UnescapeString(tagPattern.ReplaceAllString(pre[1], "")))
+	}
+	article = prePattern.ReplaceAllString(article, " ")
+	return words + wordCount(html.UnescapeString(tagPattern.ReplaceAllString(article, " "))), true
+}
+
+// wordCount counts the fields of text holding a letter or digit, so list
+// bullets, table pipes and heading marks in the source don't count against
+// a page that renders them as layout.
+func wordCount(text string) int {
+	var n int
+	for field := range strings.FieldsSeq(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// minutes rounds the weighted word count up to whole minutes, with a floor
+// of one so no post claims to take zero.
+func minutes(prose, code int, s speed) int {
+	words := float64(prose) + float64(code)*s.CodeWeight
+	return max(1, int(math.Ceil(words/s.WordsPerMinute)))
+}
+
+func loadConfig(configPath string) ([]string, speed, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, speed{}, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config siteConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return nil, speed{}, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	sections := slices.Clone(config.Params.MainSections)
+	if config.Params.NotesSection != "" {
+		sections = append(sections, config.Params.NotesSection)
+	}
+	s := config.Params.ReadingTime
+	if s.WordsPerMinute <= 0 {
+		s.WordsPerMinute = defaultWordsPerMinute
+	}
+	if s.CodeWeight <= 0 {
+		s.CodeWeight = defaultCodeWeight
+	}
+	return sections, s, nil
+}
+
+func splitFrontmatter(raw string) (frontmatter, body string, ok bool) {
+	const start = "---\n"
+	if !strings.HasPrefix(raw, start) {
+		return "", raw, false
+	}
+	idx := strings.Index(raw[len(start):], "\n---\n")
+	if idx == -1 {
+		return "", raw, false
+	}
+	fmEnd := len(start) + idx
+	return raw[len(start):fmEnd], raw[fmEnd+len("\n---\n"):], true
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "readingtime:", err)
+	os.Exit(1)
+}
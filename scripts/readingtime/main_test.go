@@ -0,0 +1,98 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCountWords(t *testing.T) {
+	body := "Three prose words.\n\n```go\nfunc main() {\n\tfmt.Println(1)\n}\n```\n\n<figure>Two more</figure> {{< mermaid >}}\n"
+	prose, code := countWords(body)
+	if prose != 5 || code != 5 {
+		t.Fatalf("countWords = %d prose, %d code; want 5 and 5", prose, code)
+	}
+}
+
+func TestMinutes(t *testing.T) {
+	s := speed{WordsPerMinute: 200, CodeWeight: 2}
+	for _, tc := range []struct {
+		prose, code, want int
+	}{
+		{0, 0, 1},
+		{200, 0, 1},
+		{201, 0, 2},
+		{100, 50, 1},
+		{100, 51, 2},
+	} {
+		if got := minutes(tc.prose, tc.code, s); got != tc.want {
+			t.Errorf("minutes(%d, %d) = %d, want %d", tc.prose, tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestReadingTimes(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "go", "long.md"), "---\ntitle: x\n---\n"+strings.Repeat("word ", 450))
+	mustWrite(t, filepath.Join(root, "shards", "2026", "01", "short.md"), "---\ntitle: x\n---\nBrief.\n")
+	mustWrite(t, filepath.Join(root, "go", "_index.md"), "---\ntitle: Go\n---\n"+strings.Repeat("word ", 900))
+	mustWrite(t, filepath.Join(root, "about.md"), "---\ntitle: About\n---\nNot a post.\n")
+
+	got, err := readingTimes(root, []string{"go", "shards"}, speed{WordsPerMinute: 220, CodeWeight: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"go/long.md": 3, "shards/2026/01/short.md": 1}
+	if !maps.Equal(got, want) {
+		t.Fatalf("readingTimes = %v, want %v", got, want)
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWordDrift(t *testing.T) {
+	root, public := filepath.Join(t.TempDir(), "content"), filepath.Join(t.TempDir(), "public")
+	prose := strings.Repeat("word ", 150)
+	code := "```go\nfmt.Println(x)\n```\n"
+	page := func(body string) string {
+		return `<article><div data-pagefind-body><h1>T</h1><div class=article-content>` + body + `</div><footer class=article-footer><a>go</a></footer></div></article>`
+	}
+	fm := func(path string) string { return "---\ntitle: x\natprotoPath: " + path + "\n---\n" }
+
+	mustWrite(t, filepath.Join(root, "go", "fine.md"), fm("/go/fine/")+"- [A link](https://example.com/long/target) "+prose+"\n"+code)
+	mustWrite(t, filepath.Join(public, "go", "fine", "index.html"), page(`<ul><li><a href="https://example.com/long/target">A link</a> `+prose+`</li></ul><pre><code><span>fmt</span><span>.</span><span>Println</span><span>(x)</span></code></pre>`))
+	mustWrite(t, filepath.Join(root, "go", "swallowed.md"), fm("/go/swallowed/")+prose+"\n<details>\n"+prose)
+	mustWrite(t, filepath.Join(public, "go", "swallowed", "index.html"), page("<p>"+prose+"</p>"))
+	mustWrite(t, filepath.Join(root, "go", "gone.md"), fm("/go/gone/")+prose)
+	mustWrite(t, filepath.Join(root, "go", "draft.md"), "---\ntitle: x\ndraft: true\natprotoPath: /go/draft/\n---\n"+prose)
+	mustWrite(t, filepath.Join(root, "shards", "2026", "01", "short.md"), fm("/shards/2026/01/short/")+"Brief.\n")
+	mustWrite(t, filepath.Join(public, "shards", "2026", "01", "short", "index.html"), page(""))
+
+	if _, err := wordDrift(root, public, []string{"go", "shards"}); err == nil || !strings.Contains(err.Error(), "no build") {
+		t.Fatalf("err = %v, want a missing build reported", err)
+	}
+	mustWrite(t, filepath.Join(public, "index.html"), "<h1>Home</h1>")
+
+	got, err := wordDrift(root, public, []string{"go", "shards"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.ToSlash(filepath.Join(root, "go", "gone.md")) + ": /go/gone/ was not rendered",
+		filepath.ToSlash(filepath.Join(root, "go", "swallowed.md")) + ": 300 words in the source, 150 rendered at /go/swallowed/ (50%)",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("wordDrift =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
@@ -0,0 +1,215 @@
+// Command searchindex checks that the Pagefind index in public/ matches the
+// posts that were built.
+//
+// Pagefind runs after Hugo as a separate step, so a failed, skipped or
+// stale run still leaves a site that builds and serves: search just stops
+// finding new posts, or keeps offering deleted ones that now 404. This
+// compares the two sides. Every page under the sections pagefind.yml
+// indexes that has a data-pagefind-body element must be in the index, and
+// every page in the index must still exist.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configPath = "pagefind.yml"
+
+	// fragmentSignature prefixes every decompressed Pagefind fragment.
+	fragmentSignature = "pagefind_dcd"
+)
+
+type pagefindConfig struct {
+	Site string `yaml:"site"`
+	Glob string `yaml:"glob"`
+}
+
+func main() {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fatal(err)
+	}
+	sections, err := globSections(cfg.Glob)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %w", configPath, err))
+	}
+	built, err := builtPages(cfg.Site, sections)
+	if err != nil {
+		fatal(err)
+	}
+	indexed, err := indexedPages(filepath.Join(cfg.Site, "pagefind"))
+	if err != nil {
+		fatal(err)
+	}
+	if problems := compare(built, indexed); len(problems) > 0 {
+		fatal(fmt.Errorf("search index is out of date; rerun pagefind:\n  %s", strings.Join(problems, "\n  ")))
+	}
+	fmt.Printf("search index covers all %d posts\n", len(built))
+}
+
+// globSections returns the top-level directories an indexing glob such as
+// {go,python}/**/*.html covers. Pagefind understands any glob; this only
+// has to understand the one pagefind.yml uses, and refuses the rest rather
+// than guess.
+func globSections(glob string) ([]string, error) {
+	dirs, ok := strings.CutSuffix(glob, "/**/*.html")
+	if !ok || strings.ContainsAny(dirs, "/*?[") {
+		return nil, fmt.Errorf("glob %q: want {section,...}/**/*.html", glob)
+	}
+	if inner, ok := strings.CutPrefix(dirs, "{"); ok {
+		inner, ok = strings.CutSuffix(inner, "}")
+		if !ok {
+			return nil, fmt.Errorf("glob %q: unclosed {", glob)
+		}
+		dirs = inner
+	}
+	return strings.Split(dirs, ","), nil
+}
+
+// builtPages returns the URL of every page under sections in site that
+// Pagefind would index: those with a data-pagefind-body element, so alias
+// redirects and section listings don't count.
+func builtPages(site string, sections []string) ([]string, error) {
+	var urls []string
+	for _, section := range sections {
+		err := filepath.WalkDir(filepath.Join(site, section), func(filePath string, entry fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".html" {
+				return err
+			}
+			raw, err := os.ReadFile(filePath)
+			if err != nil {
+				return err
+			}
+			if !bytes.Contains(raw, []byte("data-pagefind-body")) {
+				return nil
+			}
+			rel, err := filepath.Rel(site, filePath)
+			if err != nil {
+				return err
+			}
+			urls = append(urls, pageURL(filepath.ToSlash(rel)))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(urls)
+	return urls, nil
+}
+
+// pageURL is the URL Pagefind records for a file under the site root.
+func pageURL(rel string) string {
+	if path.Base(rel) == "index.html" {
+		return "/" + strings.TrimSuffix(rel, "index.html")
+	}
+	return "/" + rel
+}
+
+// indexedPages returns the URL of every page in the Pagefind index at dir,
+// one per fragment.
+func indexedPages(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "pagefind-entry.json")); err != nil {
+		return nil, fmt.Errorf("%s has no search index; run pagefind after hugo", dir)
+	}
+	fragments, err := filepath.Glob(filepath.Join(dir, "fragment", "*.pf_fragment"))
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, f := range fragments {
+		u, err := fragmentURL(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		urls = append(urls, u)
+	}
+	slices.Sort(urls)
+	return urls, nil
+}
+
+// fragmentURL reads the page URL out of a Pagefind fragment: gzipped JSON
+// behind a signature.
+func fragmentURL(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		return "", err
+	}
+	raw, ok := bytes.CutPrefix(raw, []byte(fragmentSignature))
+	if !ok {
+		return "", errors.New("not a Pagefind fragment")
+	}
+	var fragment struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(raw, &fragment); err != nil {
+		return "", err
+	}
+	if fragment.URL == "" {
+		return "", errors.New("fragment has no url")
+	}
+	return fragment.URL, nil
+}
+
+// compare lists the built pages missing from the index and the indexed
+// pages that are no longer built. Both lists are sorted.
+func compare(built, indexed []string) []string {
+	var problems []string
+	for _, u := range built {
+		if _, found := slices.BinarySearch(indexed, u); !found {
+			problems = append(problems, u+" is published but not in the search index")
+		}
+	}
+	for _, u := range indexed {
+		if _, found := slices.BinarySearch(built, u); !found {
+			problems = append(problems, u+" is in the search index but no longer published")
+		}
+	}
+	return problems
+}
+
+func loadConfig(filePath string) (pagefindConfig, error) {
+	cfg := pagefindConfig{Site: "public"}
+	raw, err := os.ReadFile(filePath)
+	if err != nil {
+		return cfg, fmt.Errorf("read %s: %w", filePath, err)
+	}
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", filePath, err)
+	}
+	if cfg.Glob == "" {
+		return cfg, fmt.Errorf("%s: no glob; searchindex can't tell which pages should be indexed", filePath)
+	}
+	return cfg, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "searchindex:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGlobSections(t *testing.T) {
+	for _, tc := range []struct {
+		glob string
+		want []string
+		err  bool
+	}{
+		{"{python,go,shards}/**/*.html", []string{"python", "go", "shards"}, false},
+		{"go/**/*.html", []string{"go"}, false},
+		{"**/*.html", nil, true},
+		{"{go,python/**/*.html", nil, true},
+		{"{go,python}/*.html", nil, true},
+	} {
+		got, err := globSections(tc.glob)
+		if (err != nil) != tc.err || !slices.Equal(got, tc.want) {
+			t.Errorf("globSections(%q) = %q, %v; want %q, error %t", tc.glob, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestIndexMatchesBuild(t *testing.T) {
+	site := t.TempDir()
+	post := `<html><body><div data-pagefind-body><h1>Post</h1></div></body></html>`
+	mustWrite(t, filepath.Join(site, "go", "index.html"), "<html><body>Go posts</body></html>")
+	mustWrite(t, filepath.Join(site, "go", "circuit-breaker", "index.html"), post)
+	mustWrite(t, filepath.Join(site, "go", "old-name", "index.html"), `<meta http-equiv="refresh" content="0; url=/go/circuit-breaker/">`)
+	mustWrite(t, filepath.Join(site, "shards", "2026", "03", "note", "index.html"), post)
+	mustWrite(t, filepath.Join(site, "about", "index.html"), post)
+
+	built, err := builtPages(site, []string{"go", "shards", "zephyr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/go/circuit-breaker/", "/shards/2026/03/note/"}
+	if !slices.Equal(built, want) {
+		t.Fatalf("built = %q, want %q", built, want)
+	}
+
+	index := filepath.Join(site, "pagefind")
+	if _, err := indexedPages(index); err == nil || !strings.Contains(err.Error(), "run pagefind") {
+		t.Fatalf("err = %v, want a missing index", err)
+	}
+	mustWrite(t, filepath.Join(index, "pagefind-entry.json"), `{"version":"1.5.2"}`)
+	writeFragment(t, filepath.Join(index, "fragment", "en_1.pf_fragment"), `{"url":"/go/circuit-breaker/"}`)
+	writeFragment(t, filepath.Join(index, "fragment", "en_2.pf_fragment"), `{"url":"/go/deleted/"}`)
+	indexed, err := indexedPages(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := compare(built, indexed)
+	wantProblems := []string{
+		"/shards/2026/03/note/ is published but not in the search index",
+		"/go/deleted/ is in the search index but no longer published",
+	}
+	if !slices.Equal(got, wantProblems) {
+		t.Fatalf("compare =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(wantProblems, "\n  "))
+	}
+}
+
+func TestFragmentNeedsSignature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "en_1.pf_fragment")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(`{"url":"/go/x/"}`))
+	gz.Close()
+	f.Close()
+	if _, err := fragmentURL(path); err == nil {
+		t.Fatal("fragment without the Pagefind signature was accepted")
+	}
+}
+
+func writeFragment(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(fragmentSignature + data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,289 @@
+// Command selflinks finds links to the site's own domain written as
+// absolute URLs.
+//
+// A link to https://rednafi.com/go/foo/ works in production but sends a
+// reader on a branch preview or hugo server off to the live site, so posts
+// should link to /go/foo/ instead. The link render hook already rewrites
+// Markdown links; this catches what it can't see (raw HTML and reference
+// definitions) before it ships, and audits the rendered HTML in public/,
+// when there is a build, for anything that slipped through.
+//
+// By default it rewrites each Markdown link target, HTML href, and
+// reference definition in content/ that points at the site to its
+// root-relative path, keeping the query and fragment. URLs in running text
+// and code are left alone: there the URL is the text. With --check it
+// touches nothing and exits non-zero if anything needs attention; with
+// --dry-run it prints each rewrite it would make and writes no file.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentDir = "content"
+	publicDir  = "public"
+)
+
+// leak is one absolute link to the site, located for a human to find it.
+type leak struct {
+	line     int
+	col      int
+	url      string
+	relative string
+}
+
+func (l leak) String() string {
+	return fmt.Sprintf("%d:%d: %s should be %s", l.line, l.col, l.url, l.relative)
+}
+
+// auditor finds self links for one set of site hosts.
+type auditor struct {
+	// target matches a link target that may hold a URL: group 1 is what
+	// leads up to it, group 2 the URL.
+	target *regexp.Regexp
+	// anchor matches an <a> element in rendered HTML: group 1 is the href,
+	// quoted or not, group 2 the text.
+	anchor *regexp.Regexp
+	hosts  []string
+}
+
+var inlineCodePattern = regexp.MustCompile("`[^`]*`")
+
+// newAuditor builds an auditor for the site at baseURL, counting its www
+// and bare hosts as the same site.
+func newAuditor(baseURL string) (*auditor, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("baseURL %q is not an absolute URL", baseURL)
+	}
+	bare := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return &auditor{
+		target: regexp.MustCompile(`(\]\(\s*<?|\bhref=["']|^ {0,3}\[[^\]^][^\]]*\]:[ \t]*<?)(https?://[^\s)"'>]+)`),
+		anchor: regexp.MustCompile(`(?is)<a\s[^>]*?\bhref=["']?(https?://(?:www\.)?` + regexp.QuoteMeta(bare) + `[^"'\s>]*)["']?[^>]*>(.*?)</a>`),
+		hosts:  []string{bare, "www." + bare},
+	}, nil
+}
+
+// relative returns rawURL as a root-relative path when it points at the
+// site.
+func (a *auditor) relative(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || u.Port() != "" {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != a.hosts[0] && host != a.hosts[1] {
+		return "", false
+	}
+	rel := &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery, Fragment: u.Fragment, RawFragment: u.RawFragment}
+	if rel.Path == "" {
+		rel.Path = "/"
+	}
+	return rel.String(), true
+}
+
+// audit returns every self link in a Markdown file, skipping fenced code
+// blocks and inline code.
+func (a *auditor) audit(raw []byte) []leak {
+	var found []leak
+	var fence []byte
+	for i, line := range bytes.Split(raw, []byte("\n")) {
+		if fence != nil {
+			if isFenceClose(line, fence) {
+				fence = nil
+			}
+			continue
+		}
+		if fence = fenceOpen(line); fence != nil {
+			continue
+		}
+		text := inlineCodePattern.ReplaceAllStringFunc(string(line), func(code string) string {
+			return strings.Repeat(" ", len(code))
+		})
+		for _, m := range a.target.FindAllStringSubmatchIndex(text, -1) {
+			rawURL := text[m[4]:m[5]]
+			if rel, ok := a.relative(rawURL); ok {
+				found = append(found, leak{line: i + 1, col: m[4] + 1, url: rawURL, relative: rel})
+			}
+		}
+	}
+	return found
+}
+
+// fix rewrites every self link audit reports to its relative path.
+func (a *auditor) fix(raw []byte) []byte {
+	leaks := a.audit(raw)
+	if len(leaks) == 0 {
+		return raw
+	}
+	lines := bytes.Split(raw, []byte("\n"))
+	// Later leaks first, so earlier columns stay put.
+	for i := len(leaks) - 1; i >= 0; i-- {
+		l := leaks[i]
+		line := lines[l.line-1]
+		start := l.col - 1
+		lines[l.line-1] = append(append(append([]byte{}, line[:start]...), l.relative...), line[start+len(l.url):]...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// auditHTML returns the <a> elements in a rendered page that link to the
+// site absolutely. An autolink, whose text is its URL, is left alone.
+func (a *auditor) auditHTML(raw []byte) []string {
+	var found []string
+	for _, m := range a.anchor.FindAllSubmatch(raw, -1) {
+		href, text := string(m[1]), strings.TrimSpace(string(m[2]))
+		if text == href {
+			continue
+		}
+		if _, ok := a.relative(href); ok {
+			found = append(found, href)
+		}
+	}
+	return found
+}
+
+// fenceOpen returns the backtick or tilde run that opens a fenced code block
+// on line, or nil if the line doesn't open one.
+func fenceOpen(line []byte) []byte {
+	trimmed := bytes.TrimLeft(line, " ")
+	for _, marker := range []byte("`~") {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == marker {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return nil
+}
+
+// isFenceClose reports whether line closes a block opened by fence: the same
+// marker, at least as long, and nothing else on the line.
+func isFenceClose(line, fence []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) >= len(fence) && len(bytes.Trim(trimmed, string(fence[:1]))) == 0
+}
+
+// filesWithExt returns every file under dir with extension ext,
+// recursively.
+func filesWithExt(dir, ext string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ext {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func loadBaseURL(configPath string) (string, error) {
+	raw, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", configPath, err)
+	}
+	var config struct {
+		BaseURL string `yaml:"baseURL"`
+	}
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return "", fmt.Errorf("parse %s: %w", configPath, err)
+	}
+	return config.BaseURL, nil
+}
+
+func main() {
+	check := flag.Bool("check", false, "report absolute links to the site and exit non-zero instead of fixing them")
+	dryRun := flag.Bool("dry-run", false, "print the links it would make relative without rewriting any file")
+	flag.Parse()
+
+	baseURL, err := loadBaseURL("config.yml")
+	if err != nil {
+		fatal(err)
+	}
+	a, err := newAuditor(baseURL)
+	if err != nil {
+		fatal(err)
+	}
+	files, err := filesWithExt(contentDir, ".md")
+	if err != nil {
+		fatal(err)
+	}
+
+	failed := false
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fatal(err)
+		}
+		found := a.audit(content)
+		if len(found) == 0 {
+			continue
+		}
+		if *check {
+			for _, l := range found {
+				fmt.Printf("ERROR: %s:%s\n", file, l)
+			}
+			failed = true
+			continue
+		}
+		if *dryRun {
+			for _, l := range found {
+				fmt.Printf("%s:%s\n", file, l)
+			}
+			fmt.Printf("Would make %d self link(s) relative in: %s\n", len(found), file)
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			fatal(err)
+		}
+		if err := os.WriteFile(file, a.fix(content), info.Mode().Perm()); err != nil {
+			fatal(err)
+		}
+		fmt.Printf("Made %d self link(s) relative in: %s\n", len(found), file)
+	}
+
+	pages, err := filesWithExt(publicDir, ".html")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fatal(err)
+	}
+	for _, page := range pages {
+		content, err := os.ReadFile(page)
+		if err != nil {
+			fatal(err)
+		}
+		for _, href := range a.auditHTML(content) {
+			fmt.Printf("ERROR: %s: rendered link to %s is absolute; find its source in content/ or layouts/\n", page, href)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	if *check {
+		fmt.Println("No absolute links to the site")
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuditAndFix(t *testing.T) {
+	a, err := newAuditor("https://rednafi.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		in   string
+		want string
+		hits []string
+	}{
+		{"markdown link", "See [this](https://rednafi.com/go/foo/).", "See [this](/go/foo/).", []string{"1:12: https://rednafi.com/go/foo/ should be /go/foo/"}},
+		{"www and fragment", "[a](http://www.rednafi.com/go/foo/#bar \"t\")", "[a](/go/foo/#bar \"t\")", []string{"1:5: http://www.rednafi.com/go/foo/#bar should be /go/foo/#bar"}},
+		{"homepage", "[home](https://rednafi.com)", "[home](/)", []string{"1:8: https://rednafi.com should be /"}},
+		{"raw html", `<a href="https://rednafi.com/misc/x/?q=1">x</a>`, `<a href="/misc/x/?q=1">x</a>`, []string{"1:10: https://rednafi.com/misc/x/?q=1 should be /misc/x/?q=1"}},
+		{"reference definition", "[ref]: https://rednafi.com/go/foo/", "[ref]: /go/foo/", []string{"1:8: https://rednafi.com/go/foo/ should be /go/foo/"}},
+		{"footnote text", "[^1]: https://rednafi.com/index", "[^1]: https://rednafi.com/index", nil},
+		{"bare url", "Visit https://rednafi.com/ today.", "Visit https://rednafi.com/ today.", nil},
+		{"other site", "[x](https://bsky.app/profile/rednafi.com)", "[x](https://bsky.app/profile/rednafi.com)", nil},
+		{"archived copy", "[x](https://web.archive.org/web/2024/https://rednafi.com/go/)", "[x](https://web.archive.org/web/2024/https://rednafi.com/go/)", nil},
+		{"inline code", "`[x](https://rednafi.com/go/)`", "`[x](https://rednafi.com/go/)`", nil},
+		{"fenced code", "```md\n[x](https://rednafi.com/go/)\n```\n", "```md\n[x](https://rednafi.com/go/)\n```\n", nil},
+		{"two on a line", "[a](https://rednafi.com/a/) and [b](https://rednafi.com/b/)", "[a](/a/) and [b](/b/)", []string{"1:5: https://rednafi.com/a/ should be /a/", "1:37: https://rednafi.com/b/ should be /b/"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var hits []string
+			for _, l := range a.audit([]byte(c.in)) {
+				hits = append(hits, l.String())
+			}
+			if strings.Join(hits, "|") != strings.Join(c.hits, "|") {
+				t.Errorf("audit(%q) = %q, want %q", c.in, hits, c.hits)
+			}
+			if got := string(a.fix([]byte(c.in))); got != c.want {
+				t.Errorf("fix(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestAuditHTML(t *testing.T) {
+	a, err := newAuditor("https://rednafi.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := `<link rel=canonical href=https://rednafi.com/go/foo/>
+<a href=/go/bar/>bar</a>
+<a href=https://rednafi.com/go/baz/ class=x>baz</a>
+<a href="https://rednafi.com/index">https://rednafi.com/index</a>
+<a href="https://bsky.app/profile/rednafi.com">Bluesky</a>`
+	got := a.auditHTML([]byte(page))
+	if strings.Join(got, " ") != "https://rednafi.com/go/baz/" {
+		t.Fatalf("auditHTML = %q, want only the linked text with an absolute href", got)
+	}
+}
@@ -39,8 +39,16 @@ func main() {
 	publishCmd := flag.String("publish-cmd", envOrDefault("SEQUOIA_CMD", defaultCommand), "Sequoia publish command")
 	prepareOnly := flag.Bool("prepare-only", false, "prepare the temporary workspace and exit")
 	syncOnly := flag.Bool("sync-only", false, "sync Sequoia state and atUri values back from the temporary workspace")
+	dryRun := flag.Bool("dry-run", false, "print the steps, files and requests the run would take without taking any")
 	flag.Parse()
 
+	if *dryRun {
+		if err := printPlan(os.Stdout, *stageDir, *siteCover, *publishCmd, *prepareOnly, *syncOnly); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
 	if *syncOnly {
 		if err := syncBack(*stageDir); err != nil {
 			fatal(err)
@@ -66,6 +74,33 @@ func main() {
 	}
 }
 
+// printPlan writes the steps a run with these flags would take to w, with
+// the files each writes and the services each calls.
+func printPlan(w io.Writer, stageDir, siteCover, publishCmd string, prepareOnly, syncOnly bool) error {
+	var b strings.Builder
+	if !syncOnly {
+		fmt.Fprintf(&b, "==> prepare the workspace\n    replace %s/ with sequoia.json, .sequoia-state.json, %s and the posts in %s/\n", stageDir, siteCover, defaultContentDir)
+	}
+	if !syncOnly && !prepareOnly {
+		fmt.Fprintf(&b, "==> publish\n    cd %s && %s\n", stageDir, publishCmd)
+	}
+	if !prepareOnly {
+		fmt.Fprintf(&b, "==> sync back\n    write .sequoia-state.json and the atUri of every staged post to its file in %s/\n", defaultContentDir)
+	}
+	if !syncOnly && !prepareOnly {
+		b.WriteString("==> reconcile documents\n")
+		if did, err := publicationDID("sequoia.json"); err != nil {
+			fmt.Fprintf(&b, "    skipped: %v\n", err)
+		} else {
+			fmt.Fprintf(&b, "    GET https://plc.directory/%s for the PDS\n", did)
+			fmt.Fprintf(&b, "    GET <PDS>/xrpc/com.atproto.repo.listRecords for %s's documents\n", did)
+			b.WriteString("    POST <PDS>/xrpc/com.atproto.repo.deleteRecord for each orphaned document, signed in as $ATP_IDENTIFIER\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
 func syncBackAfterPublish(stageDir string, publish func() error) error {
 	publishErr := publish()
 	syncErr := syncBack(stageDir)
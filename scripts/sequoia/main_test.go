@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -261,3 +262,32 @@ func TestSyncBackAfterPublishPreservesPartialPublishState(t *testing.T) {
 		t.Fatalf("root state was not synced after publish failure:\n%s", rootState)
 	}
 }
+
+func TestPrintPlanTouchesNothing(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+	if err := os.WriteFile("sequoia.json", []byte(`{"publicationUri":"at://did:plc:abc/site.standard.publication/x"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := printPlan(&out, "stage", "cover.png", "sequoia publish", false, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"==> prepare the workspace\n", "    cd stage && sequoia publish\n", "==> sync back\n", "GET https://plc.directory/did:plc:abc for the PDS\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan lacks %q:\n%s", want, out.String())
+		}
+	}
+	if _, err := os.Stat("stage"); !os.IsNotExist(err) {
+		t.Fatalf("dry run created the workspace: %v", err)
+	}
+
+	out.Reset()
+	if err := printPlan(&out, "stage", "cover.png", "sequoia publish", false, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "==> sync back\n    write .sequoia-state.json and the atUri of every staged post to its file in content/\n"; out.String() != want {
+		t.Fatalf("-sync-only plan =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,346 @@
+// Command shortcodes checks every shortcode call in content/ against the
+// parameter schemas in shortcodes.yml: required params present, no unknown
+// ones, values of the declared type or allowed set, and paired shortcodes
+// closed. Hugo renders a bad call into broken HTML without complaint, so
+// this catches it first, with the file and line.
+//
+// Calls inside fenced code blocks and escaped calls ({{</* ... */>}}) are
+// ignored.
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentDir    = "content"
+	defaultSchema = "shortcodes.yml"
+)
+
+// schema describes one shortcode's parameters.
+type schema struct {
+	// Inner means the shortcode wraps content and needs a closing tag.
+	Inner  bool    `yaml:"inner"`
+	Params []param `yaml:"params"`
+}
+
+type param struct {
+	Name string `yaml:"name"`
+	// Position is the index the param may be passed at positionally; nil
+	// means named only.
+	Position *int     `yaml:"position"`
+	Required bool     `yaml:"required"`
+	Type     string   `yaml:"type"`
+	Values   []string `yaml:"values"`
+	Pattern  string   `yaml:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// call is one shortcode tag in a content file.
+type call struct {
+	Name       string
+	Line       int
+	Closing    bool
+	Self       bool
+	Positional []string
+	Named      map[string]string
+}
+
+func main() {
+	schemas, err := loadSchemas(defaultSchema)
+	if err != nil {
+		fatal(err)
+	}
+	problems, err := checkContent(contentDir, schemas)
+	if err != nil {
+		fatal(err)
+	}
+	if len(problems) > 0 {
+		fatal(fmt.Errorf("shortcode calls are broken:\n  %s", strings.Join(problems, "\n  ")))
+	}
+}
+
+func loadSchemas(path string) (map[string]schema, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schemas map[string]schema
+	if err := yaml.Unmarshal(raw, &schemas); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for name, s := range schemas {
+		for i := range s.Params {
+			p := &s.Params[i]
+			switch p.Type {
+			case "", "string", "int", "bool", "url", "date":
+			default:
+				return nil, fmt.Errorf("%s: %s.%s: unknown type %q", path, name, p.Name, p.Type)
+			}
+			if p.Pattern != "" {
+				if p.pattern, err = regexp.Compile(p.Pattern); err != nil {
+					return nil, fmt.Errorf("%s: %s.%s: %w", path, name, p.Name, err)
+				}
+			}
+		}
+	}
+	return schemas, nil
+}
+
+// checkContent returns a problem for every bad shortcode call under root.
+func checkContent(root string, schemas map[string]schema) ([]string, error) {
+	var problems []string
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".md" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		calls, err := parseCalls(string(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		for _, problem := range checkCalls(calls, schemas) {
+			problems = append(problems, filepath.ToSlash(filePath)+":"+problem)
+		}
+		return nil
+	})
+	return problems, err
+}
+
+// checkCalls validates calls, in file order, and returns "line: problem"
+// strings.
+func checkCalls(calls []call, schemas map[string]schema) []string {
+	var problems []string
+	report := func(c call, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("%d: %s: ", c.Line, c.Name)+fmt.Sprintf(format, args...))
+	}
+	var open []call
+	for _, c := range calls {
+		s, ok := schemas[c.Name]
+		if !ok {
+			report(c, "no schema in %s", defaultSchema)
+			continue
+		}
+		if c.Closing {
+			if !s.Inner {
+				report(c, "takes no inner content but has a closing tag")
+			} else if len(open) == 0 || open[len(open)-1].Name != c.Name {
+				report(c, "closing tag without an opening one")
+			} else {
+				open = open[:len(open)-1]
+			}
+			continue
+		}
+		if s.Inner && !c.Self {
+			open = append(open, c)
+		}
+		if len(c.Positional) > 0 && len(c.Named) > 0 {
+			report(c, "mixes positional and named params")
+			continue
+		}
+
+		given := map[string]string{}
+		for i, value := range c.Positional {
+			j := slices.IndexFunc(s.Params, func(p param) bool { return p.Position != nil && *p.Position == i })
+			if j < 0 {
+				report(c, "unexpected positional param %d (%q)", i, value)
+				continue
+			}
+			given[s.Params[j].Name] = value
+		}
+		for _, name := range sortedKeys(c.Named) {
+			if !slices.ContainsFunc(s.Params, func(p param) bool { return p.Name == name }) {
+				report(c, "unknown param %q", name)
+				continue
+			}
+			given[name] = c.Named[name]
+		}
+		for _, p := range s.Params {
+			value, ok := given[p.Name]
+			if !ok {
+				if p.Required {
+					report(c, "missing required param %q", p.Name)
+				}
+				continue
+			}
+			if problem := p.check(value); problem != "" {
+				report(c, "param %q: %s", p.Name, problem)
+			}
+		}
+	}
+	for _, c := range open {
+		report(c, "never closed")
+	}
+	return problems
+}
+
+// check returns why value doesn't fit p, or "".
+func (p param) check(value string) string {
+	switch p.Type {
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Sprintf("%q is not an integer", value)
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Sprintf("%q is not true or false", value)
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || (!strings.HasPrefix(value, "/") && (u.Scheme != "http" && u.Scheme != "https" || u.Host == "")) {
+			return fmt.Sprintf("%q is not an http(s) or site-relative URL", value)
+		}
+	case "date":
+		if !isDate(value) {
+			return fmt.Sprintf("%q is not a YYYY-MM-DD or RFC 3339 date", value)
+		}
+	}
+	if len(p.Values) > 0 && !slices.Contains(p.Values, value) {
+		return fmt.Sprintf("%q is not one of %s", value, strings.Join(p.Values, ", "))
+	}
+	if p.pattern != nil && !p.pattern.MatchString(value) {
+		return fmt.Sprintf("%q does not match %s", value, p.Pattern)
+	}
+	return ""
+}
+
+func isDate(value string) bool {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`(?s)\{\{([<%])(.*?)([>%])\}\}`)
+	fencePattern = regexp.MustCompile("(?ms)^[ \\t]*(```|~~~).*?^[ \\t]*(```|~~~)[ \\t]*$")
+	namePattern  = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+)
+
+// parseCalls returns the shortcode tags in raw, skipping fenced code and
+// escaped calls.
+func parseCalls(raw string) ([]call, error) {
+	// Blank out code fences but keep their newlines, so lines still count.
+	raw = fencePattern.ReplaceAllStringFunc(raw, func(block string) string {
+		return strings.Repeat("\n", strings.Count(block, "\n"))
+	})
+	var calls []call
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(raw, -1) {
+		inner := strings.TrimSpace(raw[m[4]:m[5]])
+		line := strings.Count(raw[:m[0]], "\n") + 1
+		if strings.HasPrefix(inner, "/*") {
+			continue
+		}
+		c := call{Line: line}
+		if rest, ok := strings.CutPrefix(inner, "/"); ok {
+			c.Closing = true
+			inner = strings.TrimSpace(rest)
+		}
+		if rest, ok := strings.CutSuffix(inner, "/"); ok {
+			c.Self = true
+			inner = strings.TrimSpace(rest)
+		}
+		tokens, err := tokenize(inner)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(tokens) == 0 || tokens[0].key != "" || !namePattern.MatchString(tokens[0].value) {
+			return nil, fmt.Errorf("line %d: shortcode call without a name", line)
+		}
+		c.Name = tokens[0].value
+		for _, t := range tokens[1:] {
+			if t.key == "" {
+				c.Positional = append(c.Positional, t.value)
+				continue
+			}
+			if c.Named == nil {
+				c.Named = map[string]string{}
+			}
+			c.Named[t.key] = t.value
+		}
+		calls = append(calls, c)
+	}
+	return calls, nil
+}
+
+type token struct{ key, value string }
+
+// tokenize splits a shortcode's arguments the way Hugo does: bare words,
+// "quoted" or `raw` strings, each optionally prefixed with key=.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		var t token
+		if i := strings.IndexAny(s, "= \t\n\"`"); i > 0 && s[i] == '=' {
+			t.key, s = s[:i], s[i+1:]
+		}
+		var err error
+		t.value, s, err = readValue(s)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+func readValue(s string) (value, rest string, err error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("bad quoted value %s", s[:i+1])
+				}
+				return value, s[i+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated quoted value %s", s)
+	case strings.HasPrefix(s, "`"):
+		end := strings.IndexByte(s[1:], '`')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated raw value %s", s)
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	end := strings.IndexAny(s, " \t\n")
+	if end < 0 {
+		return s, "", nil
+	}
+	return s[:end], s[end:], nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "shortcodes:", err)
+	os.Exit(1)
+}
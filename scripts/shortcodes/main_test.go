@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testSchemas = `
+youtube:
+  params:
+    - name: id
+      position: 0
+      required: true
+      pattern: "^[A-Za-z0-9_-]{11}$"
+    - name: start
+      type: int
+    - name: loading
+      values: [eager, lazy]
+mermaid:
+  inner: true
+card:
+  params:
+    - name: url
+      required: true
+      type: url
+    - name: title
+`
+
+func TestParseCalls(t *testing.T) {
+	raw := "---\ntitle: x\n---\n{{< youtube qcJASFx-F5g >}}\n\n```md\n{{< youtube inside-code >}}\n```\n" +
+		"{{</* youtube escaped */>}}\n{{< card\n    url=\"https://a.example/\"\n    title=`Raw \"title\"` >}}\n{{< mermaid >}}\ngraph\n{{</ mermaid >}}\n"
+	calls, err := parseCalls(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range calls {
+		name := c.Name
+		if c.Closing {
+			name = "/" + name
+		}
+		got = append(got, name+"|"+strings.Join(c.Positional, ",")+"|"+c.Named["title"])
+	}
+	want := []string{"youtube|qcJASFx-F5g|", `card||Raw "title"`, "mermaid||", "/mermaid||"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("parseCalls = %q, want %q", got, want)
+	}
+	if lines := []int{calls[0].Line, calls[1].Line, calls[3].Line}; !slices.Equal(lines, []int{4, 10, 15}) {
+		t.Fatalf("call lines = %v, want [4 10 15]", lines)
+	}
+}
+
+func TestCheckContent(t *testing.T) {
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "shortcodes.yml")
+	if err := os.WriteFile(schemaPath, []byte(testSchemas), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := loadSchemas(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := filepath.Join(dir, "content")
+	post := "{{< youtube qcJASFx-F5g >}}\n" +
+		"{{< youtube id=\"short\" start=\"soon\" loading=\"later\" >}}\n" +
+		"{{< youtube >}}\n" +
+		"{{< card title=\"No URL\" >}}\n" +
+		"{{< card url=\"ftp://a.example\" colour=\"red\" >}}\n" +
+		"{{< gallery >}}\n" +
+		"{{< youtube qcJASFx-F5g >}}{{< /youtube >}}\n" +
+		"{{< mermaid >}}\ngraph\n"
+	if err := os.MkdirAll(filepath.Join(content, "go"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(content, "go", "post.md"), []byte(post), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := checkContent(content, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.ToSlash(filepath.Join(content, "go", "post.md"))
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p, file+":"))
+	}
+	want := []string{
+		`2: youtube: param "id": "short" does not match ^[A-Za-z0-9_-]{11}$`,
+		`2: youtube: param "start": "soon" is not an integer`,
+		`2: youtube: param "loading": "later" is not one of eager, lazy`,
+		`3: youtube: missing required param "id"`,
+		`4: card: missing required param "url"`,
+		`5: card: unknown param "colour"`,
+		`5: card: param "url": "ftp://a.example" is not an http(s) or site-relative URL`,
+		`6: gallery: no schema in shortcodes.yml`,
+		`7: youtube: takes no inner content but has a closing tag`,
+		`8: mermaid: never closed`,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("problems =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+func TestRepoShortcodeCallsMatchSchemas(t *testing.T) {
+	schemas, err := loadSchemas(filepath.Join("..", "..", defaultSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := checkContent(filepath.Join("..", "..", contentDir), schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("shortcode calls are broken:\n  %s", strings.Join(problems, "\n  "))
+	}
+}
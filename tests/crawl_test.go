@@ -2,7 +2,12 @@ package site_test
 
 import (
 	"fmt"
+	"html"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -11,8 +16,103 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// TestCrawlAllInternalLinks spiders every internal link found on key pages
-// and verifies they all return 200. This catches broken links site-wide.
+// crawlNoiseParams are query parameters that never change what a page of
+// this static site serves, the tracking tags a copied link brings along.
+// A trailing _ matches by prefix.
+var crawlNoiseParams = []string{"utm_", "fbclid", "gclid", "ref"}
+
+// anchorHrefPattern matches the href of an <a> tag, quoted or not.
+var anchorHrefPattern = regexp.MustCompile(`(?is)<a\b[^>]*?\shref=("[^"]*"|'[^']*'|[^\s>]+)`)
+
+// canonicalCrawlURL normalizes a local href before the spider queues it:
+// the fragment goes, noise parameters go, and the rest are sorted, so
+// /go/x/, /go/x/#intro and /go/x/?utm_source=feed are fetched once.
+func canonicalCrawlURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	u.Fragment, u.RawFragment = "", ""
+	query := u.Query()
+	for name := range query {
+		if slices.ContainsFunc(crawlNoiseParams, func(noise string) bool {
+			return name == noise || (strings.HasSuffix(noise, "_") && strings.HasPrefix(name, noise))
+		}) {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func TestCanonicalCrawlURL(t *testing.T) {
+	t.Parallel()
+	for href, want := range map[string]string{
+		"/go/x/":                          "/go/x/",
+		"/go/x/#intro":                    "/go/x/",
+		"/go/x/?utm_source=feed&ref=home": "/go/x/",
+		"/search/?q=go&a=1#top":           "/search/?a=1&q=go",
+	} {
+		assert.Equal(t, want, canonicalCrawlURL(href), href)
+	}
+}
+
+// localLink resolves href, found on the page at from, to a path on the
+// site, or reports false when it leads off the site.
+func localLink(from *url.URL, href string) (string, bool) {
+	ref, err := url.Parse(html.UnescapeString(strings.TrimSpace(href)))
+	if err != nil {
+		return "", false
+	}
+	u := from.ResolveReference(ref)
+	if u.Host == "rednafi.com" && (u.Scheme == "https" || u.Scheme == "http") {
+		u.Scheme, u.Host = from.Scheme, from.Host
+	}
+	if u.Scheme != from.Scheme || u.Host != from.Host {
+		return "", false
+	}
+	u.Scheme, u.Host = "", ""
+	return u.String(), true
+}
+
+// crawlResult is what the spider learned from fetching one URL.
+type crawlResult struct {
+	link    string
+	failure string
+	hrefs   []string
+}
+
+// crawlLink fetches link and, when it is a page, returns the hrefs of its
+// anchors.
+func crawlLink(link string) crawlResult {
+	resp, err := http.Get(baseURL + link)
+	if err != nil {
+		return crawlResult{link: link, failure: link + " (error: " + err.Error() + ")"}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return crawlResult{link: link, failure: link + " (status: " + http.StatusText(resp.StatusCode) + ")"}
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return crawlResult{link: link}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return crawlResult{link: link, failure: link + " (error: " + err.Error() + ")"}
+	}
+	r := crawlResult{link: link}
+	for _, m := range anchorHrefPattern.FindAllStringSubmatch(string(body), -1) {
+		r.hrefs = append(r.hrefs, strings.Trim(m[1], `"'`))
+	}
+	return r
+}
+
+// TestCrawlAllInternalLinks spiders the site from its key pages, following
+// every internal link on every page it reaches, and verifies they all
+// return 200. This catches broken links site-wide. Links are queued by
+// their canonical URL, so no page is fetched twice under another, and any
+// that carries a query string is reported: the static site serves the
+// same page without it.
 func TestCrawlAllInternalLinks(t *testing.T) {
 	t.Parallel()
 	seedPages := []string{
@@ -21,69 +121,57 @@ func TestCrawlAllInternalLinks(t *testing.T) {
 	}
 
 	seen := make(map[string]bool)
-	var mu sync.Mutex
-
-	page := newPage(t)
-
+	var queried, failures []string
+	var frontier []string
 	for _, seed := range seedPages {
-		goto_(t, page, seed)
-		hrefs, err := page.Locator("a[href]").EvaluateAll(
-			`els => els.map(e => e.getAttribute("href"))`,
-		)
-		require.NoError(t, err)
-
-		for _, h := range toStringSlice(hrefs) {
-			// Normalize: strip production domain, keep only local paths
-			h = strings.Replace(h, "https://rednafi.com", "", 1)
-			if !strings.HasPrefix(h, "/") {
-				continue
-			}
-			// Skip anchors-only, mailto, external
-			if strings.HasPrefix(h, "//") {
-				continue
-			}
-			// Deduplicate
-			mu.Lock()
-			if seen[h] {
-				mu.Unlock()
-				continue
-			}
-			seen[h] = true
-			mu.Unlock()
-		}
+		seen[seed] = true
+		frontier = append(frontier, seed)
 	}
 
-	t.Logf("crawling %d unique internal links", len(seen))
-
-	// Test all discovered links in parallel with bounded concurrency
+	// Crawl a level at a time, with bounded concurrency within each.
 	sem := make(chan struct{}, 20)
-	var wg sync.WaitGroup
-	var failures []string
-	var failMu sync.Mutex
-
-	for link := range seen {
-		wg.Go(func() {
-			sem <- struct{}{}
-			defer func() { <-sem }()
+	for len(frontier) > 0 {
+		results := make([]crawlResult, len(frontier))
+		var wg sync.WaitGroup
+		for i, link := range frontier {
+			wg.Go(func() {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i] = crawlLink(link)
+			})
+		}
+		wg.Wait()
 
-			resp, err := http.Get(baseURL + link)
-			if err != nil {
-				failMu.Lock()
-				failures = append(failures, link+" (error: "+err.Error()+")")
-				failMu.Unlock()
-				return
+		frontier = nil
+		for _, r := range results {
+			if r.failure != "" {
+				failures = append(failures, r.failure)
 			}
-			resp.Body.Close()
-			if resp.StatusCode != 200 {
-				failMu.Lock()
-				failures = append(failures, link+" (status: "+http.StatusText(resp.StatusCode)+")")
-				failMu.Unlock()
+			from, err := url.Parse(baseURL + r.link)
+			require.NoError(t, err)
+			for _, href := range r.hrefs {
+				h, ok := localLink(from, href)
+				if !ok {
+					continue
+				}
+				if strings.Contains(strings.SplitN(h, "#", 2)[0], "?") {
+					queried = append(queried, r.link+" links to "+h)
+				}
+				h = canonicalCrawlURL(h)
+				if !seen[h] {
+					seen[h] = true
+					frontier = append(frontier, h)
+				}
 			}
-		})
+		}
 	}
-	wg.Wait()
 
+	t.Logf("crawled %d unique internal links", len(seen))
+	slices.Sort(failures)
 	assert.Empty(t, failures, "broken internal links:\n%s", strings.Join(failures, "\n"))
+	slices.Sort(queried)
+	queried = slices.Compact(queried)
+	assert.Empty(t, queried, "internal links with needless query strings:\n%s", strings.Join(queried, "\n"))
 }
 
 // TestAllArchiveLinksResolve verifies every post link on the archive page returns 200.
@@ -0,0 +1,221 @@
+package site_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cspDirectiveOrder is the order directives appear in the generated policy.
+var cspDirectiveOrder = []string{
+	"default-src", "script-src", "style-src", "img-src", "font-src", "connect-src",
+	"media-src", "frame-src", "manifest-src", "object-src", "base-uri", "frame-ancestors",
+}
+
+// cspKinds lists, per directive, the resource kinds the third-party
+// inventory reports that it governs: tags, link relations and the
+// browser's resource types. Kinds that aren't listed, like preconnect or
+// preload, fetch nothing a policy restricts on their own; the load they
+// prepare shows up under its own kind.
+var cspKinds = map[string][]string{
+	"script-src":   {"script", "inline script", "modulepreload"},
+	"style-src":    {"stylesheet"},
+	"img-src":      {"img", "image", "source", "icon", "apple-touch-icon"},
+	"font-src":     {"font"},
+	"media-src":    {"video", "audio", "media"},
+	"frame-src":    {"iframe", "document"},
+	"object-src":   {"embed", "object"},
+	"manifest-src": {"manifest"},
+	"connect-src":  {"fetch", "xhr", "eventsource", "websocket", "ping", "other"},
+}
+
+// cspDirective returns the directive governing kind, trying each link
+// relation in a kind like "icon preload" when the whole doesn't match.
+func cspDirective(kind string) (string, bool) {
+	for _, k := range append([]string{kind}, strings.Fields(kind)...) {
+		for directive, kinds := range cspKinds {
+			if slices.Contains(kinds, k) {
+				return directive, true
+			}
+		}
+	}
+	return "", false
+}
+
+var (
+	styleBlockPattern     = regexp.MustCompile(`(?is)<style\b[^>]*>(.*?)</style>`)
+	styleAttributePattern = regexp.MustCompile(`(?i)<[a-z][^>]*\sstyle=`)
+	dataURIPattern        = regexp.MustCompile(`(?i)(?:\ssrc=["']?|url\(\s*["']?)data:`)
+	headersCSPPattern     = regexp.MustCompile(`(?m)^([ \t]+Content-Security-Policy:)[ \t]*(.*)$`)
+)
+
+// TestContentSecurityPolicy derives the tightest Content-Security-Policy
+// the site works under from the third-party inventory, and checks that
+// static/_headers serves it. The policy is logged in the form _headers
+// takes; run with UPDATE_CSP=1 to write it there after a layout starts
+// loading something new. With CSP_CHECK_URL set, the header the live site
+// answers that URL with has to match as well.
+func TestContentSecurityPolicy(t *testing.T) {
+	t.Parallel()
+
+	uses := append(staticResourceUses(t), observeRuntime(t).Uses...)
+	policy := contentSecurityPolicy(uses, scanInlineSources(t))
+	t.Logf("static/_headers, under /*:\n  Content-Security-Policy: %s", policy)
+
+	if os.Getenv("UPDATE_CSP") == "1" {
+		writeHeadersCSP(t, "../static/_headers", policy)
+		return
+	}
+
+	served := headersCSPPattern.FindStringSubmatch(httpGet(t, baseURL+"/_headers"))
+	require.NotNil(t, served, "_headers should set a Content-Security-Policy")
+	assert.Equal(t, policy, served[2],
+		"_headers doesn't serve the generated Content-Security-Policy; run `make csp` to write it")
+
+	if live := os.Getenv("CSP_CHECK_URL"); live != "" {
+		resp := httpGetResp(t, live)
+		resp.Body.Close()
+		assert.Equal(t, policy, resp.Header.Get("Content-Security-Policy"),
+			"%s serves a different Content-Security-Policy than the build generates", live)
+	}
+}
+
+// inlineSources is what the built pages run or style inline, which a
+// policy has to allow by hash or, for style attributes, wholesale.
+type inlineSources struct {
+	scriptHashes []string
+	styleHashes  []string
+	styleAttrs   bool
+	dataImages   bool
+}
+
+// scanInlineSources hashes every inline script and style block in public/
+// and notes style attributes and data: images.
+func scanInlineSources(t *testing.T) inlineSources {
+	t.Helper()
+	var inline inlineSources
+	err := filepath.WalkDir("../public", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(filePath)
+		if ext != ".html" && ext != ".css" {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		body := string(raw)
+		inline.dataImages = inline.dataImages || dataURIPattern.MatchString(body)
+		if ext == ".css" {
+			return nil
+		}
+		for _, m := range inlineScriptPattern.FindAllStringSubmatch(body, -1) {
+			attrs := strings.ToLower(m[1])
+			// JSON-LD is data the browser never runs; src scripts load
+			// from a host the inventory already has.
+			if strings.Contains(attrs, "ld+json") || strings.Contains(attrs, "src=") || strings.TrimSpace(m[2]) == "" {
+				continue
+			}
+			inline.scriptHashes = appendHash(inline.scriptHashes, m[2])
+		}
+		for _, m := range styleBlockPattern.FindAllStringSubmatch(body, -1) {
+			inline.styleHashes = appendHash(inline.styleHashes, m[1])
+		}
+		inline.styleAttrs = inline.styleAttrs || styleAttributePattern.MatchString(body)
+		return nil
+	})
+	require.NoError(t, err)
+	return inline
+}
+
+// appendHash adds content's CSP hash source to hashes unless it is there.
+func appendHash(hashes []string, content string) []string {
+	sum := sha256.Sum256([]byte(content))
+	source := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	if slices.Contains(hashes, source) {
+		return hashes
+	}
+	return append(hashes, source)
+}
+
+// contentSecurityPolicy builds the policy: everything from the site
+// itself, plus the hosts each directive's resources were seen on and the
+// inline code the pages carry.
+func contentSecurityPolicy(uses []thirdPartyUse, inline inlineSources) string {
+	sources := map[string][]string{
+		"default-src":     {"'self'"},
+		"script-src":      {"'self'"},
+		"style-src":       {"'self'"},
+		"img-src":         {"'self'"},
+		"font-src":        {"'self'"},
+		"connect-src":     {"'self'"},
+		"manifest-src":    {"'self'"},
+		"object-src":      {"'none'"},
+		"base-uri":        {"'self'"},
+		"frame-ancestors": {"'none'"},
+	}
+	// Pagefind runs its search in WebAssembly.
+	sources["script-src"] = append(sources["script-src"], "'wasm-unsafe-eval'")
+	sources["script-src"] = append(sources["script-src"], slices.Sorted(slices.Values(inline.scriptHashes))...)
+	// Hashes can't cover style attributes, and a browser ignores
+	// 'unsafe-inline' next to a hash, so it is one or the other.
+	if inline.styleAttrs {
+		sources["style-src"] = append(sources["style-src"], "'unsafe-inline'")
+	} else {
+		sources["style-src"] = append(sources["style-src"], slices.Sorted(slices.Values(inline.styleHashes))...)
+	}
+	if inline.dataImages {
+		sources["img-src"] = append(sources["img-src"], "data:")
+	}
+
+	hosts := map[string][]string{}
+	for _, use := range uses {
+		directive, ok := cspDirective(use.Kind)
+		if !ok {
+			continue
+		}
+		if source := "https://" + use.Host; !slices.Contains(hosts[directive], source) {
+			hosts[directive] = append(hosts[directive], source)
+		}
+	}
+	for directive, list := range hosts {
+		// 'none' only stands alone.
+		if directive == "object-src" {
+			sources[directive] = nil
+		}
+		slices.Sort(list)
+		sources[directive] = append(sources[directive], list...)
+	}
+
+	var parts []string
+	for _, directive := range cspDirectiveOrder {
+		if list := sources[directive]; len(list) > 0 {
+			parts = append(parts, directive+" "+strings.Join(list, " "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// writeHeadersCSP replaces the Content-Security-Policy line in the _headers
+// file at path with policy.
+func writeHeadersCSP(t *testing.T, path, policy string) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	loc := headersCSPPattern.FindSubmatchIndex(raw)
+	require.NotNil(t, loc, "%s has no Content-Security-Policy line to replace", path)
+	updated := string(raw[:loc[3]]) + " " + policy + string(raw[loc[1]:])
+	require.NoError(t, os.WriteFile(path, []byte(updated), 0o644))
+	t.Logf("wrote the policy to %s", path)
+}
@@ -0,0 +1,68 @@
+package site_test
+
+import (
+	"io/fs"
+	"maps"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fingerprintedAssetPattern matches asset URLs carrying a content hash in the
+// filename, like Hugo's fingerprint pipe emits: /css/style.<sha256>.css.
+var fingerprintedAssetPattern = regexp.MustCompile(`(?:src|href)=["']?([^"'\s>]*\.[0-9a-f]{16,}\.(?:css|js|mjs|woff2?|png|jpe?g|gif|svg|webp|avif))`)
+
+// TestFingerprintedAssetsResolve extracts every content-hashed asset URL from
+// the rendered HTML and verifies each is actually served, with the content
+// type its extension calls for. A partial deploy that ships new HTML before
+// (or without) the assets it references leaves pages unstyled with nothing
+// else failing.
+func TestFingerprintedAssetsResolve(t *testing.T) {
+	t.Parallel()
+
+	referencedBy := map[string]string{}
+	err := filepath.WalkDir("../public", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".html" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		page := "/" + filepath.ToSlash(strings.TrimPrefix(filePath, "../public/"))
+		for _, m := range fingerprintedAssetPattern.FindAllStringSubmatch(string(raw), -1) {
+			if thirdPartyHost(m[1]) != "" {
+				continue
+			}
+			if _, seen := referencedBy[m[1]]; !seen {
+				referencedBy[m[1]] = page
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, referencedBy, "expected the stylesheet and scripts to be fingerprinted")
+
+	for _, asset := range slices.Sorted(maps.Keys(referencedBy)) {
+		t.Run(asset, func(t *testing.T) {
+			t.Parallel()
+			resp := httpGetResp(t, resolveURL(asset))
+			defer resp.Body.Close()
+			require.Equal(t, 200, resp.StatusCode,
+				"%s references %s, which isn't deployed", referencedBy[asset], asset)
+
+			want, _, _ := mime.ParseMediaType(mime.TypeByExtension(path.Ext(asset)))
+			got, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "%s served with the wrong content type", asset)
+		})
+	}
+}
@@ -84,7 +84,7 @@ func TestRobotsNoIndex(t *testing.T) {
 	t.Parallel()
 	pages := []string{
 		"/archive/", "/maxims/",
-		"/search/", "/blogroll/",
+		"/search/", "/blogroll/", "/status/",
 		"/python/", "/go/", "/misc/", "/zephyr/", "/shards/",
 		"/page/2/",
 		"/tags/", "/tags/go/",
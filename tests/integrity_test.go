@@ -74,6 +74,8 @@ func TestStaticImageAssetDimensions(t *testing.T) {
 		height int
 	}{
 		{"favicon", "../static/favicon.png", 1024, 1024},
+		{"manifest icon 192", "../static/icon-192.png", 192, 192},
+		{"manifest icon 512", "../static/icon-512.png", 512, 512},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
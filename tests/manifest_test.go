@@ -0,0 +1,174 @@
+package site_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webManifest is the subset of the Web App Manifest the site relies on.
+type webManifest struct {
+	Name            string         `json:"name"`
+	ShortName       string         `json:"short_name"`
+	StartURL        string         `json:"start_url"`
+	Scope           string         `json:"scope"`
+	Display         string         `json:"display"`
+	BackgroundColor string         `json:"background_color"`
+	ThemeColor      string         `json:"theme_color"`
+	Icons           []manifestIcon `json:"icons"`
+}
+
+type manifestIcon struct {
+	Src   string `json:"src"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// iconContentTypes is what each icon format must be served as.
+var iconContentTypes = map[string][]string{
+	".ico":         {"image/vnd.microsoft.icon", "image/x-icon"},
+	".svg":         {"image/svg+xml"},
+	".png":         {"image/png"},
+	".webmanifest": {"application/manifest+json"},
+}
+
+// TestFaviconsAndManifestReferenced verifies every rendered page links the
+// ICO, SVG, and PNG favicons, the apple-touch-icon, and the web manifest,
+// and that each link resolves with the right content type. Theme swaps tend
+// to drop these from head without anything visibly breaking.
+func TestFaviconsAndManifestReferenced(t *testing.T) {
+	t.Parallel()
+
+	for _, pagePath := range []string{"/", findArticle(t, "go"), "/tags/"} {
+		t.Run(pagePath, func(t *testing.T) {
+			page := newPage(t)
+			goto_(t, page, pagePath)
+
+			hrefs := map[string][]string{}
+			for _, rel := range []string{"icon", "apple-touch-icon", "manifest"} {
+				raw, err := page.Locator(fmt.Sprintf(`link[rel=%q]`, rel)).EvaluateAll(
+					`els => els.map(e => e.getAttribute("href"))`,
+				)
+				require.NoError(t, err)
+				hrefs[rel] = toStringSlice(raw)
+			}
+
+			for _, ext := range []string{".ico", ".svg", ".png"} {
+				assert.True(t, hasExt(hrefs["icon"], ext), "%s should link a %s favicon, got %v", pagePath, ext, hrefs["icon"])
+			}
+			require.Len(t, hrefs["apple-touch-icon"], 1, "%s should link one apple-touch-icon", pagePath)
+			require.Len(t, hrefs["manifest"], 1, "%s should link one web manifest", pagePath)
+
+			for _, list := range hrefs {
+				for _, href := range list {
+					requireServedAs(t, href)
+				}
+			}
+		})
+	}
+}
+
+// TestWebManifestIsValid parses site.webmanifest and checks the fields
+// browsers need, and that every declared icon resolves at its declared size.
+func TestWebManifestIsValid(t *testing.T) {
+	t.Parallel()
+
+	var manifest webManifest
+	require.NoError(t, json.Unmarshal(requireServedAs(t, "/site.webmanifest"), &manifest),
+		"site.webmanifest should be valid JSON")
+
+	assert.NotEmpty(t, manifest.Name, "manifest needs a name")
+	assert.NotEmpty(t, manifest.ShortName, "manifest needs a short_name")
+	assert.LessOrEqual(t, len(manifest.ShortName), 12, "short_name is truncated on home screens past ~12 characters")
+	require.NotEmpty(t, manifest.Icons, "manifest needs icons")
+
+	for _, icon := range manifest.Icons {
+		t.Run(icon.Src, func(t *testing.T) {
+			require.NotEmpty(t, icon.Sizes, "icon needs sizes")
+			body := requireServedAs(t, icon.Src)
+			if icon.Type != "" {
+				assert.Contains(t, iconContentTypes[path.Ext(icon.Src)], icon.Type,
+					"declared type %s doesn't match the file extension", icon.Type)
+			}
+			if path.Ext(icon.Src) != ".png" {
+				assert.Equal(t, "any", icon.Sizes, "vector icons should declare sizes any")
+				return
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(body))
+			require.NoError(t, err)
+			assert.Contains(t, strings.Fields(icon.Sizes), fmt.Sprintf("%dx%d", config.Width, config.Height),
+				"declared sizes %q don't match the image", icon.Sizes)
+		})
+	}
+}
+
+// TestFaviconICOIsValid checks favicon.ico is a real ICO with the small
+// sizes browsers fall back to, not a renamed PNG.
+func TestFaviconICOIsValid(t *testing.T) {
+	t.Parallel()
+
+	body := requireServedAs(t, "/favicon.ico")
+	require.GreaterOrEqual(t, len(body), 6, "favicon.ico is truncated")
+
+	var header struct{ Reserved, Type, Count uint16 }
+	require.NoError(t, binary.Read(bytes.NewReader(body), binary.LittleEndian, &header))
+	require.Equal(t, uint16(0), header.Reserved, "favicon.ico has a bad header")
+	require.Equal(t, uint16(1), header.Type, "favicon.ico is not an icon resource")
+	require.NotZero(t, header.Count, "favicon.ico has no images")
+	require.GreaterOrEqual(t, len(body), 6+16*int(header.Count), "favicon.ico directory is truncated")
+
+	var sizes []int
+	for i := range int(header.Count) {
+		entry := body[6+16*i:]
+		size := int(entry[0])
+		if size == 0 {
+			size = 256
+		}
+		length := binary.LittleEndian.Uint32(entry[8:12])
+		offset := binary.LittleEndian.Uint32(entry[12:16])
+		assert.LessOrEqual(t, int(offset)+int(length), len(body), "favicon.ico image %d points past the file", i)
+		sizes = append(sizes, size)
+	}
+	assert.Contains(t, sizes, 16, "favicon.ico should include a 16x16 image")
+	assert.Contains(t, sizes, 32, "favicon.ico should include a 32x32 image")
+}
+
+// requireServedAs fetches a same-origin asset, requires a 200 with the
+// content type its extension calls for, and returns the body.
+func requireServedAs(t *testing.T, href string) []byte {
+	t.Helper()
+	resp := httpGetResp(t, resolveURL(href))
+	defer resp.Body.Close()
+	require.Equal(t, 200, resp.StatusCode, "%s should be served", href)
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	clean, _, _ := strings.Cut(href, "?")
+	if want, ok := iconContentTypes[path.Ext(clean)]; ok {
+		assert.Contains(t, want, mediaType, "%s served with the wrong content type", href)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return body
+}
+
+func hasExt(hrefs []string, ext string) bool {
+	for _, href := range hrefs {
+		clean, _, _ := strings.Cut(href, "?")
+		if path.Ext(clean) == ext {
+			return true
+		}
+	}
+	return false
+}
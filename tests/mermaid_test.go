@@ -306,6 +306,11 @@ func contentURL(relPath string, content string) string {
 
 	dir := filepath.Dir(relPath)
 	base := strings.TrimSuffix(filepath.Base(relPath), filepath.Ext(relPath))
+	base, lang := splitLanguage(base)
+	prefix := ""
+	if lang != "" {
+		prefix = "/" + lang
+	}
 	slug := frontmatterValue(content, "slug")
 	if slug == "" {
 		slug = base
@@ -313,15 +318,15 @@ func contentURL(relPath string, content string) string {
 
 	if base == "_index" {
 		if dir == "." {
-			return "/"
+			return prefix + "/"
 		}
-		return "/" + filepath.ToSlash(dir) + "/"
+		return prefix + "/" + filepath.ToSlash(dir) + "/"
 	}
 
 	if dir == "." {
-		return "/" + slug + "/"
+		return prefix + "/" + slug + "/"
 	}
-	return "/" + filepath.ToSlash(filepath.Join(dir, slug)) + "/"
+	return prefix + "/" + filepath.ToSlash(filepath.Join(dir, slug)) + "/"
 }
 
 func frontmatterValue(content string, key string) string {
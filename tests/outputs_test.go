@@ -0,0 +1,145 @@
+package site_test
+
+import (
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type outputsConfig struct {
+	Outputs       map[string][]string `yaml:"outputs"`
+	OutputFormats map[string]struct {
+		BaseName  string `yaml:"baseName"`
+		MediaType string `yaml:"mediaType"`
+	} `yaml:"outputFormats"`
+	MediaTypes map[string]struct {
+		Suffixes []string `yaml:"suffixes"`
+	} `yaml:"mediaTypes"`
+	Params struct {
+		MainSections []string `yaml:"mainSections"`
+		NotesSection string   `yaml:"notesSection"`
+	} `yaml:"params"`
+}
+
+// builtinOutputFiles names the file Hugo writes for its built-in output
+// formats, relative to the page's directory.
+var builtinOutputFiles = map[string]string{
+	"rss":      "index.xml",
+	"json":     "index.json",
+	"calendar": "index.ics",
+	"csv":      "index.csv",
+}
+
+// TestOutputFormatVariantsResolve walks the outputs block of config.yml and
+// verifies every non-HTML format is served for a sample of pages of each
+// kind, and that formats a kind doesn't list are not emitted. A typo in the
+// outputs config otherwise drops feeds without any HTML page noticing.
+func TestOutputFormatVariantsResolve(t *testing.T) {
+	t.Parallel()
+
+	config := loadOutputsConfig(t)
+	require.NotEmpty(t, config.Outputs, "config.yml should declare outputs")
+
+	pagesByKind := map[string][]string{
+		"home":     {"/"},
+		"section":  outputSectionPaths(config),
+		"taxonomy": {"/tags/"},
+		"term":     outputTermPaths(t),
+		"page":     {findArticle(t, "go"), findArticle(t, "python")},
+	}
+
+	for kind, formats := range config.Outputs {
+		paths := pagesByKind[kind]
+		require.NotEmpty(t, paths, "no sample pages for output kind %q", kind)
+
+		for name, file := range outputFiles(config) {
+			listed := slices.ContainsFunc(formats, func(f string) bool { return strings.EqualFold(f, name) })
+			for _, path := range paths {
+				t.Run(kind+"/"+name+path, func(t *testing.T) {
+					resp := httpGetResp(t, baseURL+path+file)
+					defer resp.Body.Close()
+					if !listed {
+						assert.Equal(t, 404, resp.StatusCode,
+							"%s%s is emitted but %s outputs don't list %s", path, file, kind, name)
+						return
+					}
+					require.Equal(t, 200, resp.StatusCode, "%s%s should be served", path, file)
+					body, err := io.ReadAll(resp.Body)
+					require.NoError(t, err)
+					assert.NotEmpty(t, strings.TrimSpace(string(body)), "%s%s is empty", path, file)
+				})
+			}
+		}
+	}
+}
+
+// outputFiles returns every non-HTML output format the site can emit, keyed
+// by lowercase format name, including custom formats from outputFormats.
+func outputFiles(config outputsConfig) map[string]string {
+	files := map[string]string{}
+	for _, formats := range config.Outputs {
+		for _, format := range formats {
+			name := strings.ToLower(format)
+			if file, ok := builtinOutputFiles[name]; ok {
+				files[name] = file
+			}
+		}
+	}
+	for name, format := range config.OutputFormats {
+		if format.BaseName == "" || format.MediaType == "" {
+			continue
+		}
+		_, suffix, _ := strings.Cut(format.MediaType, "/")
+		if media, ok := config.MediaTypes[format.MediaType]; ok && len(media.Suffixes) > 0 {
+			suffix = media.Suffixes[0]
+		}
+		files[strings.ToLower(name)] = format.BaseName + "." + suffix
+	}
+	return files
+}
+
+func outputSectionPaths(config outputsConfig) []string {
+	var paths []string
+	for _, section := range config.Params.MainSections {
+		paths = append(paths, "/"+section+"/")
+	}
+	if config.Params.NotesSection != "" {
+		paths = append(paths, "/"+config.Params.NotesSection+"/")
+	}
+	return paths
+}
+
+// outputTermPaths samples term pages from the tags index rather than
+// re-deriving Hugo's urlize rules from frontmatter.
+func outputTermPaths(t *testing.T) []string {
+	t.Helper()
+	page := newPage(t)
+	goto_(t, page, "/tags/")
+	hrefs, err := page.Locator(`.tag-cloud a[href^="/tags/"]`).EvaluateAll(
+		`els => els.map(e => e.getAttribute("href"))`,
+	)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, href := range toStringSlice(hrefs) {
+		if href != "/tags/" && !slices.Contains(paths, href) {
+			paths = append(paths, href)
+		}
+	}
+	return paths[:min(len(paths), 5)]
+}
+
+func loadOutputsConfig(t *testing.T) outputsConfig {
+	t.Helper()
+	raw, err := os.ReadFile("../config.yml")
+	require.NoError(t, err)
+	var config outputsConfig
+	require.NoError(t, yaml.Unmarshal(raw, &config))
+	return config
+}
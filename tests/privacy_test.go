@@ -0,0 +1,153 @@
+package site_test
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// knownTrackers are domains whose main business is analytics, advertising,
+// or session recording. Subdomains match too.
+var knownTrackers = []string{
+	"google-analytics.com",
+	"googletagmanager.com",
+	"googleadservices.com",
+	"googlesyndication.com",
+	"doubleclick.net",
+	"facebook.net",
+	"connect.facebook.com",
+	"analytics.twitter.com",
+	"ads-twitter.com",
+	"snap.licdn.com",
+	"hotjar.com",
+	"clarity.ms",
+	"segment.com",
+	"segment.io",
+	"mixpanel.com",
+	"amplitude.com",
+	"heap.io",
+	"heapanalytics.com",
+	"fullstory.com",
+	"mouseflow.com",
+	"quantserve.com",
+	"scorecardresearch.com",
+	"newrelic.com",
+	"nr-data.net",
+	"plausible.io",
+	"usefathom.com",
+	"statcounter.com",
+	"yandex.ru",
+	"mc.yandex.com",
+	"disqus.com",
+	"addthis.com",
+	"sharethis.com",
+}
+
+var inlineBeaconPattern = regexp.MustCompile(`navigator\.sendBeacon|\bfetch\s*\(|\bXMLHttpRequest\b|new\s+Image\s*\(`)
+
+// TestPrivacyAudit checks the site keeps its privacy promise: no known
+// tracker beyond the ones declared in params.thirdParty.trackers, no cookies
+// set by the site itself or by undeclared third parties, and no beacons from
+// inline scripts. Set PRIVACY_REPORT to a file path to keep the report.
+func TestPrivacyAudit(t *testing.T) {
+	t.Parallel()
+
+	config := loadThirdPartyConfig(t)
+	obs := observeRuntime(t)
+	inventory := thirdPartyInventory(t, obs)
+	calls := inlineNetworkCalls(t)
+
+	var report strings.Builder
+	fmt.Fprintln(&report, "# Privacy report")
+
+	fmt.Fprintln(&report, "\n## Trackers")
+	for _, host := range slices.Sorted(maps.Keys(inventory)) {
+		if !isTracker(host) && !domainAllowed(host, config.Trackers) {
+			continue
+		}
+		use := inventory[host]
+		declared := domainAllowed(host, config.Trackers)
+		fmt.Fprintf(&report, "- %s (%s on %s, declared: %t)\n", host, use.Kind, use.Page, declared)
+		assert.True(t, declared,
+			"%s loads tracker %s; remove it or declare it in params.thirdParty.trackers", use.Page, host)
+	}
+
+	fmt.Fprintln(&report, "\n## Cookies")
+	for _, c := range obs.Cookies {
+		fmt.Fprintf(&report, "- %s sets %s on %s\n", c.Host, c.Name, c.Page)
+		if thirdPartyHost("https://"+c.Host) == "" {
+			assert.Fail(t, "site sets a cookie",
+				"%s responds with Set-Cookie %q; the site itself must stay cookie-free", c.Page, c.Header)
+			continue
+		}
+		assert.True(t, domainAllowed(c.Host, config.Trackers),
+			"%s sets cookie %s from %s, which is not a declared tracker", c.Page, c.Name, c.Host)
+	}
+
+	fmt.Fprintln(&report, "\n## Network calls in inline scripts")
+	for _, call := range calls {
+		fmt.Fprintf(&report, "- %s: %s\n", call.Page, call.Snippet)
+		assert.NotContains(t, call.Snippet, "sendBeacon",
+			"%s sends a beacon from an inline script", call.Page)
+	}
+
+	t.Log("\n" + report.String())
+	if path := os.Getenv("PRIVACY_REPORT"); path != "" {
+		require.NoError(t, os.WriteFile(path, []byte(report.String()), 0o644))
+	}
+}
+
+func isTracker(host string) bool {
+	return slices.ContainsFunc(knownTrackers, func(domain string) bool {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	})
+}
+
+// inlineNetworkCall is a network API used by an inline script, reported once
+// per distinct call even though layouts repeat the script on every page.
+type inlineNetworkCall struct {
+	Page    string
+	Snippet string
+}
+
+func inlineNetworkCalls(t *testing.T) []inlineNetworkCall {
+	t.Helper()
+	var calls []inlineNetworkCall
+	seen := map[string]bool{}
+	err := filepath.WalkDir("../public", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(filePath) != ".html" {
+			return err
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		page := "/" + filepath.ToSlash(strings.TrimPrefix(filePath, "../public/"))
+		for _, m := range inlineScriptPattern.FindAllStringSubmatch(string(raw), -1) {
+			if strings.Contains(strings.ToLower(m[1]), "ld+json") {
+				continue
+			}
+			script := m[2]
+			for _, loc := range inlineBeaconPattern.FindAllStringIndex(script, -1) {
+				snippet := script[max(0, loc[0]-40):min(len(script), loc[1]+60)]
+				snippet = strings.Join(strings.Fields(snippet), " ")
+				if !seen[snippet] {
+					seen[snippet] = true
+					calls = append(calls, inlineNetworkCall{Page: page, Snippet: snippet})
+				}
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return calls
+}
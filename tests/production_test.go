@@ -1,8 +1,12 @@
 package site_test
 
 import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"unicode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,6 +49,80 @@ func TestCSSIsMinified(t *testing.T) {
 	lines := strings.Count(css, "\n")
 	assert.Less(t, lines, 20,
 		"CSS should be minified (got %d lines for %d bytes)", lines, len(css))
+	assert.NotContains(t, css, "/*", "minified CSS should not carry comments")
+	assert.NotContains(t, css, "sourceMappingURL", "CSS should not point at a sourcemap")
+}
+
+// preformatted matches elements whose whitespace is content, not layout.
+var preformatted = regexp.MustCompile(`(?s)<(pre|textarea)\b.*?</(pre|textarea)>`)
+
+// whitespaceRatio is the share of whitespace in body once preformatted
+// blocks are dropped. Indented templates sit far above minified output.
+func whitespaceRatio(body string) float64 {
+	body = preformatted.ReplaceAllString(body, "")
+	if body == "" {
+		return 0
+	}
+	spaces := 0
+	for _, r := range body {
+		if unicode.IsSpace(r) {
+			spaces++
+		}
+	}
+	return float64(spaces) / float64(len(body))
+}
+
+// TestHTMLIsMinified verifies production pages went through Hugo's minifier:
+// no HTML comments survive and indentation whitespace is gone. A build run
+// without --minify still renders fine, so nothing else would notice.
+func TestHTMLIsMinified(t *testing.T) {
+	t.Parallel()
+	for _, path := range []string{"/", "/archive/", "/tags/", findArticle(t, "go")} {
+		t.Run(path, func(t *testing.T) {
+			body := httpGet(t, baseURL+path)
+			assert.NotContains(t, body, "<!--", "minified HTML should not carry comments")
+			assert.NotContains(t, body, "sourceMappingURL", "inline scripts should not point at sourcemaps")
+			ratio := whitespaceRatio(body)
+			assert.Less(t, ratio, 0.2,
+				"%s looks unminified: %.0f%% of it is whitespace", path, ratio*100)
+		})
+	}
+}
+
+// TestHeroScriptIsMinified verifies the fingerprinted landing script is
+// served minified, like the stylesheet.
+func TestHeroScriptIsMinified(t *testing.T) {
+	t.Parallel()
+	page := newPage(t)
+	goto_(t, page, "/")
+
+	src, err := page.Locator(`script[src*="hero-fluid"]`).First().GetAttribute("src")
+	require.NoError(t, err)
+
+	js := httpGet(t, resolveURL(src))
+	assert.NotContains(t, js, "sourceMappingURL", "JS should not point at a sourcemap")
+	assert.NotRegexp(t, `(?m)^\s*//`, js, "minified JS should not carry line comments")
+	lines := strings.Count(js, "\n")
+	assert.Less(t, lines, 10,
+		"JS should be minified (got %d lines for %d bytes)", lines, len(js))
+}
+
+// TestNoSourceMapsShipped verifies the deploy artifact has no sourcemaps,
+// which would leak unminified sources and bloat the Pages budget.
+func TestNoSourceMapsShipped(t *testing.T) {
+	t.Parallel()
+	var maps []string
+	err := filepath.WalkDir("../public", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".map" {
+			maps = append(maps, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, maps, "public/ should not ship sourcemaps")
 }
 
 // TestAllContentSectionsReturn200 verifies every configured content section
@@ -0,0 +1,110 @@
+package site_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// manifestDisplayModes are the values browsers accept for display.
+var manifestDisplayModes = []string{"fullscreen", "standalone", "minimal-ui", "browser"}
+
+// TestPWA groups the checks that make the site installable: theme-color
+// metas, the manifest's display and start_url, and icons at the sizes
+// install prompts need. Set params.pwa.enabled to false in config.yml to
+// skip the group for a plain static blog.
+func TestPWA(t *testing.T) {
+	t.Parallel()
+	if !pwaEnabled(t) {
+		t.Skip("params.pwa.enabled is false")
+	}
+
+	var manifest webManifest
+	require.NoError(t, json.Unmarshal(requireServedAs(t, "/site.webmanifest"), &manifest))
+
+	t.Run("theme-color metas", func(t *testing.T) {
+		page := newPage(t)
+		goto_(t, page, "/")
+		raw, err := page.Locator(`meta[name="theme-color"]`).EvaluateAll(
+			`els => els.map(e => (e.getAttribute("media") || "") + "|" + e.getAttribute("content"))`,
+		)
+		require.NoError(t, err)
+		metas := toStringSlice(raw)
+		require.NotEmpty(t, metas, "pages need a theme-color meta")
+
+		var medias []string
+		for _, meta := range metas {
+			media, color, _ := strings.Cut(meta, "|")
+			assert.Regexp(t, hexColorPattern, color, "theme-color %q is not a hex color", color)
+			medias = append(medias, media)
+		}
+		assert.Contains(t, medias, "(prefers-color-scheme: light)", "light scheme needs its own theme-color")
+		assert.Contains(t, medias, "(prefers-color-scheme: dark)", "dark scheme needs its own theme-color")
+	})
+
+	t.Run("manifest display and colors", func(t *testing.T) {
+		assert.Contains(t, manifestDisplayModes, manifest.Display, "manifest display %q is not a valid mode", manifest.Display)
+		assert.Regexp(t, hexColorPattern, manifest.ThemeColor, "manifest theme_color should be a hex color")
+		assert.Regexp(t, hexColorPattern, manifest.BackgroundColor, "manifest background_color should be a hex color")
+	})
+
+	t.Run("start_url within scope and served", func(t *testing.T) {
+		require.NotEmpty(t, manifest.StartURL, "manifest needs a start_url")
+		start, err := url.Parse(manifest.StartURL)
+		require.NoError(t, err)
+		assert.Empty(t, start.Host, "start_url should be relative so it works on any deploy host")
+		if manifest.Scope != "" {
+			assert.True(t, strings.HasPrefix(start.Path, manifest.Scope),
+				"start_url %s is outside scope %s", manifest.StartURL, manifest.Scope)
+		}
+		resp := httpGetResp(t, resolveURL(manifest.StartURL))
+		resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode, "start_url %s should be served", manifest.StartURL)
+	})
+
+	t.Run("installable icon sizes", func(t *testing.T) {
+		var sizes []int
+		for _, icon := range manifest.Icons {
+			body := requireServedAs(t, icon.Src)
+			if icon.Type != "image/png" {
+				continue
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(body))
+			require.NoError(t, err, "%s is not a PNG", icon.Src)
+			assert.Equal(t, config.Width, config.Height, "%s should be square", icon.Src)
+			sizes = append(sizes, config.Width)
+		}
+		for _, want := range []int{192, 512} {
+			assert.True(t, slices.Contains(sizes, want),
+				"manifest needs a %s PNG icon for install prompts", fmt.Sprintf("%dx%d", want, want))
+		}
+	})
+}
+
+func pwaEnabled(t *testing.T) bool {
+	t.Helper()
+	raw, err := os.ReadFile("../config.yml")
+	require.NoError(t, err)
+	var config struct {
+		Params struct {
+			PWA struct {
+				Enabled bool `yaml:"enabled"`
+			} `yaml:"pwa"`
+		} `yaml:"params"`
+	}
+	require.NoError(t, yaml.Unmarshal(raw, &config))
+	return config.Params.PWA.Enabled
+}
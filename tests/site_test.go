@@ -3,6 +3,7 @@ package site_test
 import (
 	"fmt"
 	"io"
+	"mime"
 	"net"
 	"net/http"
 	"os"
@@ -31,7 +32,10 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
-	// Start a static file server
+	// Start a static file server. Register the types GitHub Pages serves that
+	// Go's built-in table lacks, so content-type checks match production.
+	mime.AddExtensionType(".ico", "image/vnd.microsoft.icon")
+	mime.AddExtensionType(".webmanifest", "application/manifest+json")
 	absDir, _ := filepath.Abs(publicDir)
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
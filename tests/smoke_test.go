@@ -1,9 +1,13 @@
 package site_test
 
 import (
+	"fmt"
+	"os"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/mxschmitt/playwright-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -69,28 +73,86 @@ func TestSiteBuildSmokeTest(t *testing.T) {
 	})
 }
 
-// TestNoConsoleErrors verifies no JavaScript errors are thrown on key pages.
-// Console errors signal broken scripts (theme toggle, back-to-top, Pagefind).
+// TestNoConsoleErrors loads pages in headless Chromium and fails on console
+// errors, uncaught exceptions, unhandled promise rejections, and failed
+// same-origin requests. Static HTTP checks can't see client-side breakage
+// like a broken theme toggle or search script. Set BROWSER_CHECK_PAGES to a
+// comma-separated list of paths to check other pages.
 func TestNoConsoleErrors(t *testing.T) {
 	t.Parallel()
-	pages := []string{"/", "/go/anemic-stack-traces/", "/archive/"}
 
-	for _, url := range pages {
+	for _, url := range browserCheckPages(t) {
 		t.Run(url, func(t *testing.T) {
 			page := newPage(t)
 
+			var mu sync.Mutex
 			var errors []string
+			report := func(format string, args ...any) {
+				mu.Lock()
+				defer mu.Unlock()
+				errors = append(errors, fmt.Sprintf(format, args...))
+			}
+			// pageerror covers uncaught exceptions and unhandled rejections.
 			page.On("pageerror", func(err error) {
-				errors = append(errors, err.Error())
+				report("uncaught: %s", err.Error())
+			})
+			page.OnConsole(func(msg playwright.ConsoleMessage) {
+				// Third-party scripts are outside our control and unreachable
+				// from sandboxed CI runners.
+				if msg.Type() == "error" && thirdPartyHost(msg.Location().URL) == "" {
+					report("console: %s", msg.Text())
+				}
+			})
+			page.OnRequestFailed(func(req playwright.Request) {
+				// Aborted requests are ones the page cancelled itself, like
+				// superseded search fragment fetches.
+				if thirdPartyHost(req.URL()) == "" && !strings.Contains(fmt.Sprint(req.Failure()), "ERR_ABORTED") {
+					report("request failed: %s %s", req.URL(), req.Failure())
+				}
+			})
+			page.OnResponse(func(resp playwright.Response) {
+				if resp.Status() >= 400 && thirdPartyHost(resp.URL()) == "" {
+					report("HTTP %d: %s", resp.Status(), resp.URL())
+				}
 			})
 
 			goto_(t, page, url)
 
+			// Exercise the search script rather than just loading it.
+			if input := page.Locator(".pagefind-ui__search-input"); mustCount(t, input) > 0 {
+				require.NoError(t, input.Fill("go"))
+				page.Locator(".pagefind-ui__result").First().WaitFor(playwright.LocatorWaitForOptions{
+					Timeout: playwright.Float(5000),
+				})
+			}
+
 			// Give scripts time to execute
 			page.Evaluate(`() => new Promise(r => setTimeout(r, 500))`)
 
+			mu.Lock()
+			defer mu.Unlock()
 			assert.Empty(t, errors,
 				"page %s has JS errors: %v", url, errors)
 		})
 	}
 }
+
+// browserCheckPages returns the pages TestNoConsoleErrors loads: key pages
+// covering every script the site ships, or BROWSER_CHECK_PAGES when set.
+func browserCheckPages(t *testing.T) []string {
+	t.Helper()
+	if env := os.Getenv("BROWSER_CHECK_PAGES"); env != "" {
+		var pages []string
+		for p := range strings.SplitSeq(env, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				pages = append(pages, p)
+			}
+		}
+		return pages
+	}
+	pages := []string{"/", "/go/anemic-stack-traces/", "/archive/", "/search/", "/tags/"}
+	if mermaid := scanMermaidPages(t); len(mermaid) > 0 {
+		pages = append(pages, mermaid[0].URL)
+	}
+	return pages
+}
@@ -0,0 +1,142 @@
+package site_test
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sriExempt lists external resources that can't carry a subresource
+// integrity hash, keyed by URL prefix, with the reason. Everything else
+// loaded from another origin must be pinned.
+var sriExempt = map[string]string{
+	"https://www.googletagmanager.com/gtag/js": "served dynamically per measurement ID; no stable hash exists",
+}
+
+type externalResource struct {
+	URL         string
+	Integrity   string
+	CrossOrigin string
+}
+
+// TestExternalResourcesHaveIntegrity verifies every script and stylesheet
+// loaded from another origin declares an integrity hash that matches what
+// the CDN actually serves. A CDN-hosted asset changing under the site is a
+// supply-chain problem; SRI makes the browser refuse it instead.
+func TestExternalResourcesHaveIntegrity(t *testing.T) {
+	t.Parallel()
+
+	paths := []string{"/", "/search/", findArticle(t, "go")}
+	if mermaid := scanMermaidPages(t); len(mermaid) > 0 {
+		paths = append(paths, mermaid[0].URL)
+	}
+
+	resources := map[string]externalResource{}
+	for _, path := range paths {
+		page := newPage(t)
+		goto_(t, page, path)
+		raw, err := page.Evaluate(`() => [...document.querySelectorAll('script[src], link[rel="stylesheet"][href]')]
+			.map(el => ({
+				url: el.src || el.href,
+				integrity: el.getAttribute("integrity") || "",
+				crossOrigin: el.getAttribute("crossorigin") || "",
+			}))
+			.filter(r => new URL(r.url).origin !== location.origin)`)
+		require.NoError(t, err)
+		for _, item := range toMapSlice(raw) {
+			r := externalResource{URL: item["url"], Integrity: item["integrity"], CrossOrigin: item["crossOrigin"]}
+			resources[r.URL] = r
+		}
+	}
+
+	for _, r := range resources {
+		t.Run(r.URL, func(t *testing.T) {
+			if reason, ok := sriExemption(r.URL); ok {
+				t.Skipf("exempt from SRI: %s", reason)
+			}
+			require.NotEmpty(t, r.Integrity, "%s has no integrity attribute", r.URL)
+			assert.Equal(t, "anonymous", r.CrossOrigin,
+				"%s needs crossorigin=anonymous for the browser to check its hash", r.URL)
+
+			resp, err := http.Get(r.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, 200, resp.StatusCode, "%s should be reachable", r.URL)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.True(t, integrityMatches(r.Integrity, body),
+				"%s no longer matches its integrity hash %q", r.URL, r.Integrity)
+		})
+	}
+}
+
+func sriExemption(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	u.RawQuery = ""
+	for prefix, reason := range sriExempt {
+		if strings.HasPrefix(u.String(), prefix) {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+// integrityMatches reports whether any of the space-separated hashes in an
+// integrity attribute matches body, as the browser would check it.
+func integrityMatches(integrity string, body []byte) bool {
+	for _, token := range strings.Fields(integrity) {
+		algo, want, ok := strings.Cut(token, "-")
+		if !ok {
+			continue
+		}
+		var h hash.Hash
+		switch algo {
+		case "sha256":
+			h = sha256.New()
+		case "sha384":
+			h = sha512.New384()
+		case "sha512":
+			h = sha512.New()
+		default:
+			continue
+		}
+		h.Write(body)
+		if base64.StdEncoding.EncodeToString(h.Sum(nil)) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func toMapSlice(v any) []map[string]string {
+	arr, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	out := make([]map[string]string, 0, len(arr))
+	for _, item := range arr {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		m := map[string]string{}
+		for k, v := range obj {
+			m[k], _ = v.(string)
+		}
+		out = append(out, m)
+	}
+	return out
+}
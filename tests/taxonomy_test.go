@@ -1,11 +1,16 @@
 package site_test
 
 import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 // TestTagPageFiltersContent verifies that a tag page only shows posts
@@ -96,3 +101,62 @@ func TestSectionPageDescription(t *testing.T) {
 		})
 	}
 }
+
+// TestAuthorPagesRender verifies every author a post names, from
+// data/authors.yml, gets a page under /author/ with their name and avatar,
+// and that the post's byline credits them. Posts without an author keep the
+// site author's byline, linking to /about/.
+func TestAuthorPagesRender(t *testing.T) {
+	t.Parallel()
+	raw, err := os.ReadFile("../data/authors.yml")
+	require.NoError(t, err)
+	var authors map[string]struct {
+		Name   string `yaml:"name"`
+		Avatar string `yaml:"avatar"`
+	}
+	require.NoError(t, yaml.Unmarshal(raw, &authors))
+
+	byAuthor := map[string][]string{}
+	authorField := regexp.MustCompile(`(?m)^author:\s*"?([^"\s]+)"?\s*$`)
+	err = filepath.WalkDir("../content", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		parts := strings.SplitN(string(data), "---", 3)
+		if len(parts) < 3 {
+			return nil
+		}
+		if m := authorField.FindStringSubmatch(parts[1]); m != nil {
+			byAuthor[m[1]] = append(byAuthor[m[1]], path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for id, posts := range byAuthor {
+		t.Run(id, func(t *testing.T) {
+			author, ok := authors[id]
+			require.True(t, ok, "%v name author %q, who is not in data/authors.yml", posts, id)
+			body := httpGet(t, baseURL+"/author/"+id+"/")
+			assert.Contains(t, body, author.Name, "/author/%s/ should carry the author's name", id)
+			assert.Contains(t, body, author.Avatar, "/author/%s/ should show the author's avatar", id)
+			assert.Contains(t, body, "post-list", "/author/%s/ should list the author's posts", id)
+		})
+	}
+
+	t.Run("site author byline", func(t *testing.T) {
+		page := newPage(t)
+		goto_(t, page, "/go/anemic-stack-traces/")
+		byline := page.Locator(".post-meta-author")
+		name, err := byline.TextContent()
+		require.NoError(t, err)
+		href, err := byline.GetAttribute("href")
+		require.NoError(t, err)
+		assert.Equal(t, "Redowan Delowar", name)
+		assert.Equal(t, "/about/", href)
+	})
+}
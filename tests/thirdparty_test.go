@@ -0,0 +1,302 @@
+package site_test
+
+import (
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mxschmitt/playwright-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	resourceTagPattern  = regexp.MustCompile(`(?is)<(script|img|iframe|source|video|audio|embed|object|link)\b[^>]*>`)
+	resourceAttrPattern = regexp.MustCompile(`(?i)\s(src|href|srcset|poster|data|rel)=("[^"]*"|'[^']*'|[^\s>]+)`)
+	cssURLPattern       = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+	inlineScriptPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	urlLiteralPattern   = regexp.MustCompile(`["'\x60]((?:https?:)?//[^"'\x60\s]+)`)
+)
+
+// loadingRels are the link relations that make the browser fetch or connect
+// to the href. Plain navigation links don't count as a dependency.
+var loadingRels = []string{"stylesheet", "preload", "modulepreload", "prefetch", "preconnect", "dns-prefetch", "icon", "apple-touch-icon", "manifest"}
+
+// thirdPartyUse records where an external host is first loaded from.
+type thirdPartyUse struct {
+	Host string
+	Kind string
+	Page string
+}
+
+// TestThirdPartyDomainsAllowlisted inventories every external host the
+// rendered site loads resources from and fails when one isn't listed in
+// params.thirdParty.allowedDomains. It reads every HTML and CSS file in
+// public/ for static references and loads a sample of pages in the browser
+// to catch requests scripts make at runtime.
+func TestThirdPartyDomainsAllowlisted(t *testing.T) {
+	t.Parallel()
+
+	allowed := loadThirdPartyConfig(t).AllowedDomains
+	require.NotEmpty(t, allowed, "config.yml should declare params.thirdParty.allowedDomains")
+
+	inventory := thirdPartyInventory(t, observeRuntime(t))
+	hosts := slices.Sorted(maps.Keys(inventory))
+	for _, host := range hosts {
+		use := inventory[host]
+		t.Logf("%-32s %-10s %s", host, use.Kind, use.Page)
+	}
+
+	for _, host := range hosts {
+		use := inventory[host]
+		assert.True(t, domainAllowed(host, allowed),
+			"%s loads a %s from %s, which is not in params.thirdParty.allowedDomains", use.Page, use.Kind, host)
+	}
+	for _, pattern := range allowed {
+		if !slices.ContainsFunc(hosts, func(host string) bool { return domainAllowed(host, []string{pattern}) }) {
+			t.Logf("allowlisted domain %s was not seen; drop it from config.yml if it is no longer used", pattern)
+		}
+	}
+}
+
+// thirdPartyInventory merges the hosts referenced by the built files with
+// the ones requested at runtime, keeping the first use seen.
+func thirdPartyInventory(t *testing.T, obs runtimeObservations) map[string]thirdPartyUse {
+	t.Helper()
+	inventory := staticThirdParties(t)
+	for host, use := range obs.Hosts {
+		if _, ok := inventory[host]; !ok {
+			inventory[host] = use
+		}
+	}
+	return inventory
+}
+
+// staticThirdParties scans the built HTML and CSS for resource references
+// to other hosts, keeping each host's first use.
+func staticThirdParties(t *testing.T) map[string]thirdPartyUse {
+	t.Helper()
+	inventory := map[string]thirdPartyUse{}
+	for _, use := range staticResourceUses(t) {
+		if _, seen := inventory[use.Host]; !seen {
+			inventory[use.Host] = use
+		}
+	}
+	return inventory
+}
+
+// staticResourceUses lists every distinct host and kind of resource the
+// built HTML and CSS reference on other hosts, with the first page seen.
+func staticResourceUses(t *testing.T) []thirdPartyUse {
+	t.Helper()
+	var uses []thirdPartyUse
+	seen := map[[2]string]bool{}
+	record := func(rawURL, kind, page string) {
+		host := thirdPartyHost(rawURL)
+		if key := [2]string{host, kind}; host != "" && !seen[key] {
+			seen[key] = true
+			uses = append(uses, thirdPartyUse{Host: host, Kind: kind, Page: page})
+		}
+	}
+
+	err := filepath.WalkDir("../public", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		ext := filepath.Ext(filePath)
+		if ext != ".html" && ext != ".css" {
+			return nil
+		}
+		raw, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		page := "/" + filepath.ToSlash(strings.TrimPrefix(filePath, "../public/"))
+		body := string(raw)
+
+		for _, m := range cssURLPattern.FindAllStringSubmatch(body, -1) {
+			record(m[1], cssURLKind(m[1]), page)
+		}
+		if ext == ".css" {
+			return nil
+		}
+		// Scripts that inject tags at runtime, like the lazy GA loader, only
+		// show their URLs as string literals. JSON-LD is data, not a load.
+		for _, m := range inlineScriptPattern.FindAllStringSubmatch(body, -1) {
+			if strings.Contains(strings.ToLower(m[1]), "ld+json") {
+				continue
+			}
+			for _, u := range urlLiteralPattern.FindAllStringSubmatch(m[2], -1) {
+				record(u[1], "inline script", page)
+			}
+		}
+		for _, tag := range resourceTagPattern.FindAllStringSubmatch(body, -1) {
+			kind := strings.ToLower(tag[1])
+			attrs := map[string]string{}
+			for _, a := range resourceAttrPattern.FindAllStringSubmatch(tag[0], -1) {
+				attrs[strings.ToLower(a[1])] = strings.Trim(a[2], `"'`)
+			}
+			if kind == "link" {
+				rels := strings.Fields(strings.ToLower(attrs["rel"]))
+				if !slices.ContainsFunc(rels, func(rel string) bool { return slices.Contains(loadingRels, rel) }) {
+					continue
+				}
+				kind = strings.Join(rels, " ")
+				record(attrs["href"], kind, page)
+				continue
+			}
+			for _, name := range []string{"src", "poster", "data"} {
+				record(attrs[name], kind, page)
+			}
+			for candidate := range strings.SplitSeq(attrs["srcset"], ",") {
+				if fields := strings.Fields(candidate); len(fields) > 0 {
+					record(fields[0], kind, page)
+				}
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return uses
+}
+
+// cssURLKind tells fonts from images among the files CSS url() loads.
+func cssURLKind(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err == nil && slices.Contains([]string{".woff2", ".woff", ".ttf", ".otf", ".eot"}, strings.ToLower(path.Ext(u.Path))) {
+		return "font"
+	}
+	return "image"
+}
+
+// runtimeObservations is what loading pages in the browser revealed: the
+// external hosts requested, with the first request to each, every distinct
+// host and resource type requested, and the cookies responses tried to set.
+type runtimeObservations struct {
+	Hosts   map[string]thirdPartyUse
+	Uses    []thirdPartyUse
+	Cookies []cookieUse
+}
+
+// cookieUse records a Set-Cookie header and the response that sent it.
+type cookieUse struct {
+	Host   string
+	Name   string
+	Page   string
+	Header string
+}
+
+// observeRuntime loads representative pages and records every request that
+// leaves the local server, including ones analytics or diagram scripts make
+// after load. It scrolls each page so interaction-deferred scripts run too.
+func observeRuntime(t *testing.T) runtimeObservations {
+	t.Helper()
+	paths := []string{"/", "/search/", findArticle(t, "go")}
+	if mermaid := scanMermaidPages(t); len(mermaid) > 0 {
+		paths = append(paths, mermaid[0].URL)
+	}
+
+	var mu sync.Mutex
+	obs := runtimeObservations{Hosts: map[string]thirdPartyUse{}}
+	for _, path := range paths {
+		page := newPage(t)
+		page.OnRequest(func(req playwright.Request) {
+			host := thirdPartyHost(req.URL())
+			if host == "" {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			use := thirdPartyUse{Host: host, Kind: req.ResourceType(), Page: path}
+			if _, seen := obs.Hosts[host]; !seen {
+				obs.Hosts[host] = use
+			}
+			if !slices.ContainsFunc(obs.Uses, func(u thirdPartyUse) bool { return u.Host == host && u.Kind == use.Kind }) {
+				obs.Uses = append(obs.Uses, use)
+			}
+		})
+		page.OnResponse(func(resp playwright.Response) {
+			headers, err := resp.HeadersArray()
+			if err != nil {
+				return
+			}
+			u, _ := url.Parse(resp.URL())
+			mu.Lock()
+			defer mu.Unlock()
+			for _, h := range headers {
+				if !strings.EqualFold(h.Name, "set-cookie") {
+					continue
+				}
+				name, _, _ := strings.Cut(h.Value, "=")
+				obs.Cookies = append(obs.Cookies, cookieUse{Host: u.Hostname(), Name: strings.TrimSpace(name), Page: path, Header: h.Value})
+			}
+		})
+		goto_(t, page, path)
+		page.Mouse().Wheel(0, 400)
+		page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: playwright.LoadStateNetworkidle})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return obs
+}
+
+// thirdPartyHost returns the host of an absolute http(s) URL that isn't the
+// site itself or the local test server, and "" otherwise.
+func thirdPartyHost(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && !strings.HasPrefix(rawURL, "//")) {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	local, _ := url.Parse(baseURL)
+	if host == "" || host == "rednafi.com" || host == "www.rednafi.com" || host == local.Hostname() {
+		return ""
+	}
+	return host
+}
+
+// domainAllowed matches host against allowlist entries, where "*.example.com"
+// covers any subdomain of example.com.
+func domainAllowed(host string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// thirdPartyConfig is params.thirdParty from config.yml.
+type thirdPartyConfig struct {
+	AllowedDomains []string `yaml:"allowedDomains"`
+	Trackers       []string `yaml:"trackers"`
+}
+
+func loadThirdPartyConfig(t *testing.T) thirdPartyConfig {
+	t.Helper()
+	raw, err := os.ReadFile("../config.yml")
+	require.NoError(t, err)
+	var config struct {
+		Params struct {
+			ThirdParty thirdPartyConfig `yaml:"thirdParty"`
+		} `yaml:"params"`
+	}
+	require.NoError(t, yaml.Unmarshal(raw, &config))
+	return config.Params.ThirdParty
+}
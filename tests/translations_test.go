@@ -0,0 +1,209 @@
+package site_test
+
+import (
+	"cmp"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// translationSuffix matches Hugo's translation-by-filename convention:
+// post.bn.md is the Bengali variant of post.md.
+var translationSuffix = regexp.MustCompile(`^(.+)\.([a-z]{2}(?:-[a-z]{2})?)$`)
+
+// splitLanguage strips a translation suffix from a content file's base name
+// and returns the language it names, or "" for the default language.
+func splitLanguage(base string) (string, string) {
+	m := translationSuffix.FindStringSubmatch(base)
+	if m == nil {
+		return base, ""
+	}
+	return m[1], m[2]
+}
+
+type translatedPage struct {
+	Lang        string
+	URL         string
+	OriginalURL string
+}
+
+// TestTranslatedPostVariantsResolve verifies every translated post renders at
+// its language-prefixed URL and that the original post advertises it through
+// an hreflang link, which is what language switchers and search engines use.
+func TestTranslatedPostVariantsResolve(t *testing.T) {
+	t.Parallel()
+
+	pages := scanTranslatedPages(t, "..")
+	if len(pages) == 0 {
+		t.Skip("no translated posts")
+	}
+
+	for _, tc := range pages {
+		t.Run(tc.URL, func(t *testing.T) {
+			resp := httpGetResp(t, baseURL+tc.URL)
+			resp.Body.Close()
+			require.Equal(t, 200, resp.StatusCode, "%s should render", tc.URL)
+
+			page := newPage(t)
+			goto_(t, page, tc.OriginalURL)
+			hrefs, err := page.Locator(`[hreflang="` + tc.Lang + `"]`).EvaluateAll(
+				`els => els.map(e => e.getAttribute("href"))`,
+			)
+			require.NoError(t, err)
+			var paths []string
+			for _, href := range toStringSlice(hrefs) {
+				paths = append(paths, strings.TrimPrefix(resolveURL(href), baseURL))
+			}
+			assert.Contains(t, paths, tc.URL,
+				"%s should link to its %s translation", tc.OriginalURL, tc.Lang)
+		})
+	}
+}
+
+// TestHreflangLinksResolve verifies every language alternate advertised on
+// key pages points at a page that exists, so a deleted translation doesn't
+// leave the switcher linking to a 404.
+func TestHreflangLinksResolve(t *testing.T) {
+	t.Parallel()
+	for _, path := range []string{"/", "/archive/", findArticle(t, "go")} {
+		t.Run(path, func(t *testing.T) {
+			page := newPage(t)
+			goto_(t, page, path)
+			hrefs, err := page.Locator("[hreflang][href]").EvaluateAll(
+				`els => els.map(e => e.getAttribute("href"))`,
+			)
+			require.NoError(t, err)
+			for _, href := range toStringSlice(hrefs) {
+				resp := httpGetResp(t, resolveURL(href))
+				resp.Body.Close()
+				assert.Equal(t, 200, resp.StatusCode, "hreflang link %s on %s", href, path)
+			}
+		})
+	}
+}
+
+// siteLanguages is the languages block of a Hugo config, with the content
+// directory each language reads from when it keeps one of its own.
+type siteLanguages struct {
+	DefaultContentLanguage string `yaml:"defaultContentLanguage"`
+	ContentDir             string `yaml:"contentDir"`
+	Languages              map[string]struct {
+		ContentDir string `yaml:"contentDir"`
+	} `yaml:"languages"`
+}
+
+func loadSiteLanguages(t *testing.T, site string) siteLanguages {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join(site, "config.yml"))
+	require.NoError(t, err)
+	var config siteLanguages
+	require.NoError(t, yaml.Unmarshal(raw, &config))
+	config.DefaultContentLanguage = cmp.Or(config.DefaultContentLanguage, "en")
+	config.ContentDir = cmp.Or(config.Languages[config.DefaultContentLanguage].ContentDir, config.ContentDir, "content")
+	return config
+}
+
+// scanTranslatedPages lists the translated posts of the Hugo site in site,
+// in both of Hugo's layouts: a post.bn.md next to post.md, and a post at
+// the same path under another language's contentDir.
+func scanTranslatedPages(t *testing.T, site string) []translatedPage {
+	t.Helper()
+	config := loadSiteLanguages(t, site)
+	defaultDir := filepath.Join(site, config.ContentDir)
+	languageDirs := map[string]string{}
+	for lang, language := range config.Languages {
+		if dir := filepath.Join(site, language.ContentDir); lang != config.DefaultContentLanguage && language.ContentDir != "" && dir != defaultDir {
+			languageDirs[dir] = lang
+		}
+	}
+
+	var pages []translatedPage
+	err := filepath.WalkDir(defaultDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, ok := languageDirs[path]; ok && d.IsDir() {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		base := strings.TrimSuffix(filepath.Base(path), ".md")
+		original, lang := splitLanguage(base)
+		if lang == "" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(defaultDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		originalPath := filepath.Join(filepath.Dir(path), original+".md")
+		originalData, err := os.ReadFile(originalPath)
+		if err != nil {
+			return err
+		}
+		originalRel := filepath.Join(filepath.Dir(rel), original+".md")
+
+		pages = append(pages, translatedPage{
+			Lang:        lang,
+			URL:         contentURL(rel, string(data)),
+			OriginalURL: contentURL(originalRel, string(originalData)),
+		})
+		return nil
+	})
+	require.NoError(t, err)
+
+	for dir, lang := range languageDirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			// A post only this language has is no one's translation.
+			originalData, err := os.ReadFile(filepath.Join(defaultDir, rel))
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			pages = append(pages, translatedPage{
+				Lang:        lang,
+				URL:         "/" + lang + contentURL(rel, string(data)),
+				OriginalURL: contentURL(rel, string(originalData)),
+			})
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	slices.SortFunc(pages, func(a, b translatedPage) int { return strings.Compare(a.URL, b.URL) })
+	return pages
+}
+
+func TestScanTranslatedPagesFindsBothLayouts(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []translatedPage{
+		{Lang: "bn", URL: "/bn/go/hello/", OriginalURL: "/go/hello/"},
+		{Lang: "fr", URL: "/fr/go/bonjour/", OriginalURL: "/go/hello/"},
+	}, scanTranslatedPages(t, "testdata/translations"))
+}
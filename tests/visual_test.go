@@ -0,0 +1,151 @@
+package site_test
+
+import (
+	"bytes"
+	"cmp"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/mxschmitt/playwright-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	visualBaselineDir = "testdata/visual"
+	// visualChannelTolerance absorbs antialiasing noise: a pixel only counts
+	// as changed when a channel moves by more than this.
+	visualChannelTolerance = 24
+)
+
+// visualPages are the key pages compared against baselines. They avoid the
+// homepage and archive, whose content changes with every post.
+var visualPages = map[string]string{
+	"post":     "/go/anemic-stack-traces/",
+	"about":    "/about/",
+	"maxims":   "/maxims/",
+	"blogroll": "/blogroll/",
+	"missing":  "/definitely-missing-404/",
+}
+
+// TestVisualRegression screenshots key pages at desktop and mobile widths and
+// diffs them against the baselines in testdata/visual. A page fails when more
+// than VISUAL_THRESHOLD percent of its pixels changed (default 0.5); the diff
+// image lands in VISUAL_DIFF_DIR (default .cache/visual-diff) for CI to
+// upload. A page without a baseline is skipped, with its screenshot left in
+// the same place, so only a real pixel diff fails. Run with UPDATE_VISUAL=1
+// to rewrite the baselines after an intentional change.
+func TestVisualRegression(t *testing.T) {
+	t.Parallel()
+
+	threshold := 0.5
+	if env := os.Getenv("VISUAL_THRESHOLD"); env != "" {
+		var err error
+		threshold, err = strconv.ParseFloat(env, 64)
+		require.NoError(t, err, "VISUAL_THRESHOLD should be a percentage")
+	}
+	diffDir := cmp.Or(os.Getenv("VISUAL_DIFF_DIR"), "../.cache/visual-diff")
+	update := os.Getenv("UPDATE_VISUAL") == "1"
+
+	viewports := map[string]func(*testing.T) playwright.Page{
+		"desktop": newPage,
+		"mobile":  newMobilePage,
+	}
+	for name, path := range visualPages {
+		for viewport, open := range viewports {
+			shot := name + "-" + viewport + ".png"
+			t.Run(shot, func(t *testing.T) {
+				page := open(t)
+				require.NoError(t, page.EmulateMedia(playwright.PageEmulateMediaOptions{
+					ReducedMotion: playwright.ReducedMotionReduce,
+				}))
+				goto_(t, page, path)
+				got, err := page.Screenshot(playwright.PageScreenshotOptions{
+					FullPage:   playwright.Bool(true),
+					Animations: playwright.ScreenshotAnimationsDisabled,
+				})
+				require.NoError(t, err)
+
+				baselinePath := filepath.Join(visualBaselineDir, shot)
+				if update {
+					require.NoError(t, os.MkdirAll(visualBaselineDir, 0o755))
+					require.NoError(t, os.WriteFile(baselinePath, got, 0o644))
+					return
+				}
+				want, err := os.ReadFile(baselinePath)
+				if os.IsNotExist(err) {
+					// Keep the screenshot with the diffs, so a baseline
+					// rendered by CI's browser can be committed as is.
+					require.NoError(t, os.MkdirAll(diffDir, 0o755))
+					require.NoError(t, os.WriteFile(filepath.Join(diffDir, shot), got, 0o644))
+					t.Skipf("no baseline %s; run `make visual-baseline`, or commit %s from CI's visual-diff artifact", baselinePath, shot)
+				}
+				require.NoError(t, err)
+
+				changed, diff, err := diffScreenshots(want, got)
+				require.NoError(t, err)
+				if changed > threshold {
+					require.NoError(t, os.MkdirAll(diffDir, 0o755))
+					require.NoError(t, os.WriteFile(filepath.Join(diffDir, shot), diff, 0o644))
+					require.NoError(t, os.WriteFile(filepath.Join(diffDir, name+"-"+viewport+".actual.png"), got, 0o644))
+				}
+				assert.LessOrEqual(t, changed, threshold,
+					"%s at %s changed %.2f%% of pixels; see %s", path, viewport, changed, filepath.Join(diffDir, shot))
+			})
+		}
+	}
+}
+
+// diffScreenshots returns the percentage of pixels that differ between two
+// PNG screenshots and a diff image: the baseline faded, with changed pixels
+// in red. A height change counts every pixel outside the overlap as changed.
+func diffScreenshots(baseline, actual []byte) (float64, []byte, error) {
+	a, err := png.Decode(bytes.NewReader(baseline))
+	if err != nil {
+		return 0, nil, err
+	}
+	b, err := png.Decode(bytes.NewReader(actual))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	bounds := a.Bounds().Union(b.Bounds())
+	diff := image.NewNRGBA(bounds)
+	changed := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pt := image.Pt(x, y)
+			if !pt.In(a.Bounds()) || !pt.In(b.Bounds()) || pixelsDiffer(a.At(x, y), b.At(x, y)) {
+				changed++
+				diff.Set(x, y, color.NRGBA{R: 255, A: 255})
+				continue
+			}
+			g := color.GrayModel.Convert(a.At(x, y)).(color.Gray)
+			diff.Set(x, y, color.NRGBA{R: g.Y, G: g.Y, B: g.Y, A: 64})
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, diff); err != nil {
+		return 0, nil, err
+	}
+	total := bounds.Dx() * bounds.Dy()
+	return float64(changed) / float64(total) * 100, out.Bytes(), nil
+}
+
+func pixelsDiffer(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	for _, pair := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}, {aa, ba}} {
+		d := int(pair[0]>>8) - int(pair[1]>>8)
+		if d > visualChannelTolerance || d < -visualChannelTolerance {
+			return true
+		}
+	}
+	return false
+}